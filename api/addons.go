@@ -0,0 +1,576 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	helmclient "github.com/mittwald/go-helm-client"
+	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
+)
+
+// chartRepositoryClient fetches the indexes of chart repositories, giving up on ones that are too slow to answer.
+var chartRepositoryClient = &http.Client{Timeout: 60 * time.Second}
+
+// Addon is a helm chart that the bootstrapper can install into a cluster. The catalog in Addons drives the add-on
+// endpoints, the operators CLI commands and the operators of bootstrap manifests, so supporting another chart only
+// takes a new entry.
+type Addon struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Required add-ons are needed by Mattermost installations, and are the ones deployed when none are selected.
+	Required    bool   `json:"required"`
+	RepoName    string `json:"repoName"`
+	RepoURL     string `json:"repoUrl"`
+	Chart       string `json:"chart"`
+	ReleaseName string `json:"releaseName"`
+	Namespace   string `json:"namespace"`
+	// Version is the chart version installed when the request doesn't select one. Versions are pinned to ones that
+	// have been tested together, so that bootstraps are reproducible.
+	Version string `json:"version"`
+	// Values are the default helm values of the release.
+	Values map[string]interface{} `json:"values,omitempty"`
+	// Dependencies are the names of add-ons that are installed first, unless they are already deployed.
+	Dependencies []string      `json:"dependencies,omitempty"`
+	Timeout      time.Duration `json:"-"`
+	// MinKubernetesVersion and MaxKubernetesVersion are the minor Kubernetes versions, such as 1.26, that the pinned
+	// version supports. The cluster pre-flight report fails below the minimum and warns above the maximum.
+	MinKubernetesVersion string `json:"minKubernetesVersion,omitempty"`
+	MaxKubernetesVersion string `json:"maxKubernetesVersion,omitempty"`
+	// CRDs are the custom resource definitions that the chart installs, which the pre-flight report checks for
+	// conflicts with ones from other sources.
+	CRDs []string `json:"-"`
+	// Hooks run before and after the chart is installed, together with any hooks the provider has for the add-on.
+	Hooks []Hook `json:"-"`
+	// ProviderValues returns values that depend on the provider. They are merged over Values.
+	ProviderValues func(c *Context) (map[string]interface{}, error) `json:"-"`
+	// MirrorValues returns values that pull the add-on's images from registry instead of their public registries.
+	// They are merged over the provider values when an image mirror is configured.
+	MirrorValues func(registry string) map[string]interface{} `json:"-"`
+}
+
+// Addons is the catalog of add-ons, with the required ones first in the order they are deployed.
+var Addons = []Addon{
+	{
+		Name:        "mattermost-operator",
+		Description: "Manages Mattermost installations",
+		Required:    true,
+		RepoName:    "mattermost",
+		RepoURL:     "https://helm.mattermost.com",
+		Chart:       "mattermost-operator",
+		ReleaseName: "mattermost-operator",
+		Namespace:   "mattermost-operator",
+		Version:     "1.0.2",
+		Timeout:     300 * time.Second,
+
+		MinKubernetesVersion: "1.22",
+		CRDs:                 []string{"mattermosts.installation.mattermost.com"},
+		Hooks: []Hook{
+			waitForCRDsHook(2*time.Minute, "mattermosts.installation.mattermost.com"),
+		},
+		MirrorValues: func(registry string) map[string]interface{} {
+			return mirrorImageValues(registry, map[string]string{
+				"mattermostOperator.image.repository": "mattermost/mattermost-operator",
+			})
+		},
+	},
+	{
+		Name:        "ingress-nginx",
+		Description: "Routes traffic from a load balancer to Mattermost installations",
+		Required:    true,
+		RepoName:    "nginx",
+		RepoURL:     "https://kubernetes.github.io/ingress-nginx",
+		Chart:       "ingress-nginx",
+		ReleaseName: "ingress-nginx",
+		Namespace:   "ingress-nginx",
+		Version:     "4.10.1",
+		Timeout:     3000 * time.Second,
+
+		MinKubernetesVersion: "1.26",
+		MaxKubernetesVersion: "1.30",
+		ProviderValues: func(c *Context) (map[string]interface{}, error) {
+			return NginxValues(c, nil)
+		},
+		MirrorValues: func(registry string) map[string]interface{} {
+			return mirrorImageValues(registry, map[string]string{
+				"controller.image.repository":                         "registry.k8s.io/ingress-nginx/controller",
+				"controller.admissionWebhooks.patch.image.repository": "registry.k8s.io/ingress-nginx/kube-webhook-certgen",
+			})
+		},
+	},
+	{
+		Name:        "cnpg",
+		Description: "Runs the PostgreSQL databases of Mattermost installations",
+		Required:    true,
+		RepoName:    "cnpg",
+		RepoURL:     "https://cloudnative-pg.github.io/charts",
+		Chart:       "cloudnative-pg",
+		ReleaseName: "cnpg-system",
+		Namespace:   "cnpg-system",
+		Version:     "0.20.1",
+		Timeout:     300 * time.Second,
+
+		MinKubernetesVersion: "1.26",
+		MaxKubernetesVersion: "1.29",
+		CRDs:                 []string{"clusters.postgresql.cnpg.io", "backups.postgresql.cnpg.io", "scheduledbackups.postgresql.cnpg.io", "poolers.postgresql.cnpg.io"},
+		Hooks: []Hook{
+			waitForCRDsHook(2*time.Minute, "clusters.postgresql.cnpg.io"),
+		},
+		MirrorValues: func(registry string) map[string]interface{} {
+			return mirrorImageValues(registry, map[string]string{
+				"image.repository": "ghcr.io/cloudnative-pg/cloudnative-pg",
+			})
+		},
+	},
+	{
+		Name:        "cert-manager",
+		Description: "Issues TLS certificates for ingresses",
+		RepoName:    "jetstack",
+		RepoURL:     "https://charts.jetstack.io",
+		Chart:       "cert-manager",
+		ReleaseName: "cert-manager",
+		Namespace:   "cert-manager",
+		Version:     "v1.14.5",
+		Timeout:     300 * time.Second,
+
+		MinKubernetesVersion: "1.24",
+		CRDs:                 []string{"certificates.cert-manager.io", "issuers.cert-manager.io", "clusterissuers.cert-manager.io"},
+		Values: map[string]interface{}{
+			"crds": map[string]interface{}{"enabled": true},
+		},
+		MirrorValues: func(registry string) map[string]interface{} {
+			return mirrorImageValues(registry, map[string]string{
+				"image.repository":                 "quay.io/jetstack/cert-manager-controller",
+				"webhook.image.repository":         "quay.io/jetstack/cert-manager-webhook",
+				"cainjector.image.repository":      "quay.io/jetstack/cert-manager-cainjector",
+				"acmesolver.image.repository":      "quay.io/jetstack/cert-manager-acmesolver",
+				"startupapicheck.image.repository": "quay.io/jetstack/cert-manager-startupapicheck",
+			})
+		},
+	},
+	{
+		Name:        "external-dns",
+		Description: "Creates DNS records for ingresses and load balancers",
+		RepoName:    "external-dns",
+		RepoURL:     "https://kubernetes-sigs.github.io/external-dns",
+		Chart:       "external-dns",
+		ReleaseName: "external-dns",
+		Namespace:   "external-dns",
+		Version:     "1.14.4",
+		Timeout:     300 * time.Second,
+		MirrorValues: func(registry string) map[string]interface{} {
+			return mirrorImageValues(registry, map[string]string{
+				"image.repository": "registry.k8s.io/external-dns/external-dns",
+			})
+		},
+	},
+	{
+		Name:        "metrics-server",
+		Description: "Provides the resource metrics used by autoscalers and kubectl top",
+		RepoName:    "metrics-server",
+		RepoURL:     "https://kubernetes-sigs.github.io/metrics-server",
+		Chart:       "metrics-server",
+		ReleaseName: "metrics-server",
+		Namespace:   "kube-system",
+		Version:     "3.12.1",
+		Timeout:     300 * time.Second,
+
+		MinKubernetesVersion: "1.19",
+		MirrorValues: func(registry string) map[string]interface{} {
+			return mirrorImageValues(registry, map[string]string{
+				"image.repository": "registry.k8s.io/metrics-server/metrics-server",
+			})
+		},
+	},
+	{
+		Name:        "prometheus",
+		Description: "Monitors the cluster and Mattermost installations with Prometheus and Grafana",
+		RepoName:    "prometheus-community",
+		RepoURL:     "https://prometheus-community.github.io/helm-charts",
+		Chart:       "kube-prometheus-stack",
+		ReleaseName: "kube-prometheus-stack",
+		Namespace:   "monitoring",
+		Version:     "58.7.2",
+		Timeout:     600 * time.Second,
+
+		MinKubernetesVersion: "1.19",
+		CRDs:                 []string{"prometheuses.monitoring.coreos.com", "servicemonitors.monitoring.coreos.com", "podmonitors.monitoring.coreos.com"},
+		// The chart and its subcharts take the registry of all their images from one value.
+		MirrorValues: func(registry string) map[string]interface{} {
+			return map[string]interface{}{
+				"global": map[string]interface{}{"imageRegistry": registry},
+			}
+		},
+	},
+}
+
+// GetAddon returns the add-on with the given name.
+func GetAddon(name string) (Addon, bool) {
+	for _, addon := range Addons {
+		if addon.Name == name {
+			return addon, true
+		}
+	}
+
+	return Addon{}, false
+}
+
+// AddonNames returns the names of every add-on in the catalog.
+func AddonNames() []string {
+	names := []string{}
+	for _, addon := range Addons {
+		names = append(names, addon.Name)
+	}
+
+	return names
+}
+
+// RequiredAddonNames returns the names of the add-ons needed by Mattermost installations, in deployment order.
+func RequiredAddonNames() []string {
+	names := []string{}
+	for _, addon := range Addons {
+		if addon.Required {
+			names = append(names, addon.Name)
+		}
+	}
+
+	return names
+}
+
+// AddonInstallOrder returns the dependencies of the named add-on, recursively and each before the add-ons that
+// depend on it, followed by the add-on itself.
+func AddonInstallOrder(name string) ([]Addon, error) {
+	order := []Addon{}
+	visited := map[string]bool{}
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		for _, parent := range path {
+			if parent == name {
+				return fmt.Errorf("add-on dependency cycle: %s", strings.Join(append(path, name), " -> "))
+			}
+		}
+		if visited[name] {
+			return nil
+		}
+
+		addon, ok := GetAddon(name)
+		if !ok {
+			return model.NewNotFoundError(fmt.Sprintf("Unknown add-on: %s", name))
+		}
+
+		for _, dependency := range addon.Dependencies {
+			if err := visit(dependency, append(path, name)); err != nil {
+				return err
+			}
+		}
+
+		visited[name] = true
+		order = append(order, addon)
+		return nil
+	}
+
+	if err := visit(name, nil); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// InstallAddon installs or upgrades an add-on, after installing any of its dependencies that aren't deployed yet.
+// The request may select another chart version and values to merge over the add-on's own. A nil request uses the
+// defaults.
+func InstallAddon(c *Context, clusterName string, addon Addon, request *model.InstallAddonRequest) error {
+	if request == nil {
+		request = &model.InstallAddonRequest{}
+	}
+	if request.Version != "" {
+		addon.Version = request.Version
+	}
+
+	order, err := AddonInstallOrder(addon.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, dependency := range order[:len(order)-1] {
+		deployed, err := isAddonDeployed(c, clusterName, dependency)
+		if err != nil {
+			return err
+		}
+		if deployed {
+			continue
+		}
+
+		logger.FromContext(c.Ctx).Infof("Installing %s, which %s depends on", dependency.Name, addon.Name)
+		err = installAddonRelease(c, clusterName, dependency, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return installAddonRelease(c, clusterName, addon, request.Values)
+}
+
+// UninstallAddon removes the release of an add-on. Add-ons that depend on it are left in place.
+func UninstallAddon(c *Context, clusterName string, addon Addon) error {
+	err := uninstallRelease(c, clusterName, addon.Namespace, addon.ReleaseName)
+	if err != nil {
+		return err
+	}
+
+	err = UpdateStateAddonVersion(c.BootstrapperState, clusterName, addon.Name, "")
+	if err != nil {
+		logger.FromContext(c.Ctx).WithError(err).Warn("Failed to remove add-on version from state")
+	}
+
+	return nil
+}
+
+// ListAddonVersions returns the versions of an add-on's chart where it is installed from, newest first: the mirror
+// directory or OCI registry when one is set, or its public repository otherwise.
+func ListAddonVersions(c *Context, addon Addon) ([]model.AddonVersion, error) {
+	var index *repo.IndexFile
+	var err error
+
+	mirror := mirrorConfig(c)
+	switch {
+	case mirror.ChartDirectory != "":
+		index, err = mirrorDirectoryIndex(mirror.ChartDirectory, addon)
+	case mirror.ChartRegistry != "":
+		index, err = mirrorRegistryIndex(mirror.ChartRegistry, addon)
+	case mirror.ImageRegistry != "":
+		return nil, model.NewAppError(model.ErrorCodeNotImplemented, http.StatusNotImplemented, fmt.Sprintf("Versions of %s can't be listed, since the mirror has no chart registry or directory", addon.Name))
+	default:
+		index, err = repositoryIndex(c, addon)
+	}
+	if err != nil {
+		return nil, err
+	}
+	index.SortEntries()
+
+	versions := []model.AddonVersion{}
+	for _, chartVersion := range index.Entries[addon.Chart] {
+		if chartVersion.Metadata == nil {
+			continue
+		}
+		versions = append(versions, model.AddonVersion{
+			Version:    chartVersion.Version,
+			AppVersion: chartVersion.AppVersion,
+			Created:    chartVersion.Created,
+			Pinned:     chartVersion.Version == addon.Version,
+		})
+	}
+
+	return versions, nil
+}
+
+// repositoryIndex fetches the index of an add-on's public chart repository.
+func repositoryIndex(c *Context, addon Addon) (*repo.IndexFile, error) {
+	indexURL := strings.TrimSuffix(addon.RepoURL, "/") + "/index.yaml"
+	request, err := http.NewRequestWithContext(c.Ctx, http.MethodGet, indexURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := chartRepositoryClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch chart repository index: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch chart repository index: %s returned %s", indexURL, response.Status)
+	}
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chart repository index: %w", err)
+	}
+
+	index := &repo.IndexFile{}
+	err = yaml.Unmarshal(data, index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse chart repository index: %w", err)
+	}
+
+	return index, nil
+}
+
+func installAddonRelease(c *Context, clusterName string, addon Addon, values map[string]interface{}) error {
+	ctx := c.Ctx
+	defer func() { c.Ctx = ctx }()
+	c.Ctx = logger.WithField(c.Ctx, "action", "deploy-"+addon.Name)
+	c.Ctx = logger.WithNamespace(c.Ctx, addon.Namespace)
+	c.Ctx = logger.WithClusterName(c.Ctx, clusterName)
+
+	version, err := installChart(c, clusterName, addon, values)
+	if err != nil {
+		return err
+	}
+
+	err = UpdateStateAddonVersion(c.BootstrapperState, clusterName, addon.Name, version)
+	if err != nil {
+		logger.FromContext(c.Ctx).WithError(err).Warn("Failed to record add-on version in state")
+	}
+
+	return nil
+}
+
+// installChart installs or upgrades the chart of addon into its namespace, running its hooks around it, and returns
+// the chart version that was deployed. values are merged over the add-on's provider and mirror values.
+func installChart(c *Context, clusterName string, addon Addon, values map[string]interface{}) (string, error) {
+	releaseValues := addon.Values
+	if addon.ProviderValues != nil {
+		providerValues, err := addon.ProviderValues(c)
+		if err != nil {
+			return "", err
+		}
+		releaseValues = mergeValues(releaseValues, providerValues)
+	}
+	if registry := mirrorConfig(c).ImageRegistry; registry != "" && addon.MirrorValues != nil {
+		releaseValues = mergeValues(releaseValues, addon.MirrorValues(registry))
+	}
+	releaseValues = mergeValues(releaseValues, values)
+
+	valuesYaml, err := yaml.Marshal(releaseValues)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal %s values: %w", addon.Name, err)
+	}
+
+	err = runAddonHooks(c, clusterName, addon, model.HookPhasePreInstall)
+	if err != nil {
+		return "", err
+	}
+
+	helmClient, err := c.CloudProvider.HelmClient(c.Ctx, clusterName, addon.Namespace)
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate helm client: %w", err)
+	}
+
+	chartName, addRepo, err := addonChartName(c, addon)
+	if err != nil {
+		return "", err
+	}
+	if addRepo {
+		err = helmClient.AddOrUpdateChartRepo(repo.Entry{Name: addon.RepoName, URL: addon.RepoURL})
+		if err != nil {
+			return "", fmt.Errorf("failed to add or update chart repo: %w", err)
+		}
+	}
+
+	chartSpec := helmclient.ChartSpec{
+		ReleaseName:     addon.ReleaseName,
+		ChartName:       chartName,
+		Namespace:       addon.Namespace,
+		UpgradeCRDs:     true,
+		Wait:            true,
+		Timeout:         addon.Timeout,
+		CreateNamespace: true,
+		CleanupOnFail:   true,
+		ValuesYaml:      string(valuesYaml),
+		Version:         addon.Version,
+	}
+
+	// Note that helmclient.Options.Namespace should ideally match the namespace in chartSpec.Namespace.
+	release, err := helmClient.InstallOrUpgradeChart(context.Background(), &chartSpec, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to install %s: %w", addon.Name, err)
+	}
+
+	version := addon.Version
+	if release != nil && release.Chart != nil && release.Chart.Metadata != nil {
+		version = release.Chart.Metadata.Version
+	}
+	logger.FromContext(c.Ctx).Infof("Deployed %s chart version %s", addon.Name, version)
+
+	err = runAddonHooks(c, clusterName, addon, model.HookPhasePostInstall)
+	if err != nil {
+		return "", err
+	}
+
+	return version, nil
+}
+
+// mergeValues returns base with overrides merged into it. Nested maps are merged key by key, and any other value in
+// overrides replaces the one in base. Neither argument is modified.
+func mergeValues(base, overrides map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base))
+	for key, value := range base {
+		merged[key] = value
+	}
+
+	for key, value := range overrides {
+		baseMap, baseIsMap := merged[key].(map[string]interface{})
+		overrideMap, overrideIsMap := value.(map[string]interface{})
+		if baseIsMap && overrideIsMap {
+			merged[key] = mergeValues(baseMap, overrideMap)
+		} else {
+			merged[key] = value
+		}
+	}
+
+	return merged
+}
+
+func isAddonDeployed(c *Context, clusterName string, addon Addon) (bool, error) {
+	helmClient, err := c.CloudProvider.HelmClient(c.Ctx, clusterName, addon.Namespace)
+	if err != nil {
+		return false, fmt.Errorf("failed to authenticate helm client: %w", err)
+	}
+
+	releases, err := helmClient.ListDeployedReleases()
+	if err != nil {
+		return false, fmt.Errorf("failed to list deployed releases: %w", err)
+	}
+
+	for _, release := range releases {
+		if release.Name == addon.ReleaseName {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func initAddons(apiRouter *mux.Router, context *Context) {
+	addContext := func(handler contextHandlerFunc) *contextHandler {
+		return newContextHandler(context, handler)
+	}
+
+	apiRouter.Handle("/addons", addContext(handleListAddons)).Methods(http.MethodGet)
+	apiRouter.Handle("/addons/{addon:[A-Za-z0-9_-]+}/versions", addContext(handleListAddonVersions)).Methods(http.MethodGet)
+}
+
+func handleListAddons(c *Context, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(Addons)
+}
+
+func handleListAddonVersions(c *Context, w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["addon"]
+	addon, ok := GetAddon(name)
+	if !ok {
+		c.Err = model.NewNotFoundError(fmt.Sprintf("Unknown add-on: %s", name))
+		return
+	}
+
+	versions, err := ListAddonVersions(c, addon)
+	if err != nil {
+		c.SetError(err, "Failed to list add-on versions")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(versions)
+}
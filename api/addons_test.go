@@ -0,0 +1,206 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func TestAddons(t *testing.T) {
+	_, router := newTestRouter(t)
+
+	t.Run("Catalog", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/addons", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var addons []api.Addon
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&addons))
+		assert.Len(t, addons, len(api.Addons))
+		assert.Equal(t, []string{"mattermost-operator", "ingress-nginx", "cnpg"}, api.RequiredAddonNames())
+	})
+
+	t.Run("UnknownAddon", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/custom/cluster/test/addons/nope", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/custom/cluster/test/addons/nope", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestAddonInstallOrder(t *testing.T) {
+	catalog := api.Addons
+	t.Cleanup(func() {
+		api.Addons = catalog
+	})
+
+	api.Addons = []api.Addon{
+		{Name: "app", Dependencies: []string{"ingress", "certificates"}},
+		{Name: "ingress", Dependencies: []string{"certificates"}},
+		{Name: "certificates"},
+		{Name: "first", Dependencies: []string{"second"}},
+		{Name: "second", Dependencies: []string{"first"}},
+		{Name: "broken", Dependencies: []string{"missing"}},
+	}
+
+	names := func(addons []api.Addon) []string {
+		names := []string{}
+		for _, addon := range addons {
+			names = append(names, addon.Name)
+		}
+		return names
+	}
+
+	t.Run("Dependencies", func(t *testing.T) {
+		order, err := api.AddonInstallOrder("app")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"certificates", "ingress", "app"}, names(order))
+	})
+
+	t.Run("NoDependencies", func(t *testing.T) {
+		order, err := api.AddonInstallOrder("certificates")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"certificates"}, names(order))
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		_, err := api.AddonInstallOrder("first")
+		require.ErrorContains(t, err, "first -> second -> first")
+	})
+
+	t.Run("UnknownDependency", func(t *testing.T) {
+		_, err := api.AddonInstallOrder("broken")
+		require.ErrorContains(t, err, "missing")
+	})
+}
+
+func TestAddonHooks(t *testing.T) {
+	addon := api.Addon{
+		Name: "cnpg",
+		Hooks: []api.Hook{
+			{Name: "before", Phase: model.HookPhasePreInstall},
+			{Name: "after", Phase: model.HookPhasePostInstall},
+		},
+	}
+
+	names := func(hooks []api.Hook) []string {
+		names := []string{}
+		for _, hook := range hooks {
+			names = append(names, hook.Name)
+		}
+		return names
+	}
+
+	t.Run("ProviderHooksFirst", func(t *testing.T) {
+		c := &api.Context{Ctx: context.Background(), CloudProvider: providers.GetAWSProvider(nil)}
+		assert.Equal(t, []string{"aws-ebs-csi-driver", "before"}, names(api.AddonHooks(c, addon, model.HookPhasePreInstall)))
+		assert.Equal(t, []string{"after"}, names(api.AddonHooks(c, addon, model.HookPhasePostInstall)))
+	})
+
+	t.Run("ProviderWithoutHooks", func(t *testing.T) {
+		c := &api.Context{Ctx: context.Background(), CloudProvider: providers.GetCustomProvider(nil)}
+		assert.Equal(t, []string{"before"}, names(api.AddonHooks(c, addon, model.HookPhasePreInstall)))
+	})
+}
+
+func TestProviderCharts(t *testing.T) {
+	for _, chart := range api.ProviderCharts {
+		assert.NotEmpty(t, chart.Version, chart.Name)
+		assert.NotNil(t, chart.MirrorValues, chart.Name)
+	}
+
+	hooks := providers.GetAWSProvider(nil).AddonHooks("cnpg", model.HookPhasePreInstall)
+	require.Len(t, hooks, 1)
+	assert.True(t, slices.ContainsFunc(api.ProviderCharts, func(chart api.Addon) bool { return chart.Name == hooks[0].Chart }))
+
+	values := api.ProviderCharts[0].MirrorValues("registry.example.com")
+	assert.Equal(t, "registry.example.com/ebs-csi-driver/aws-ebs-csi-driver", values["image"].(map[string]interface{})["repository"])
+}
+
+func TestListAddonVersions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/charts/index.yaml", r.URL.Path)
+		w.Write([]byte(`apiVersion: v1
+entries:
+  example:
+  - name: example
+    version: 1.1.0
+    appVersion: v2.1.0
+    created: "2024-05-01T00:00:00Z"
+  - name: example
+    version: 1.2.0
+    appVersion: v2.2.0
+    created: "2024-06-01T00:00:00Z"
+  - name: example
+    version: 1.0.0
+    appVersion: v2.0.0
+    created: "2024-04-01T00:00:00Z"
+  other:
+  - name: other
+    version: 9.0.0
+`))
+	}))
+	defer server.Close()
+
+	addon := api.Addon{Name: "example", RepoURL: server.URL + "/charts/", Chart: "example", Version: "1.1.0"}
+	versions, err := api.ListAddonVersions(&api.Context{Ctx: context.Background()}, addon)
+	require.NoError(t, err)
+
+	require.Len(t, versions, 3)
+	assert.Equal(t, "1.2.0", versions[0].Version)
+	assert.Equal(t, "v2.2.0", versions[0].AppVersion)
+	assert.False(t, versions[0].Pinned)
+	assert.Equal(t, "1.1.0", versions[1].Version)
+	assert.True(t, versions[1].Pinned)
+	assert.Equal(t, "1.0.0", versions[2].Version)
+}
+
+func TestListAddonVersionsFromMirror(t *testing.T) {
+	addon := api.Addon{Name: "example", RepoURL: "https://charts.example.com/", Chart: "example", Version: "1.1.0"}
+
+	t.Run("ChartDirectory", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, metadata := range []chart.Metadata{
+			{Name: "example", Version: "1.1.0", AppVersion: "v2.1.0"},
+			{Name: "example", Version: "1.2.0", AppVersion: "v2.2.0"},
+			{Name: "example-operator", Version: "3.0.0"},
+		} {
+			metadata.APIVersion = chart.APIVersionV2
+			_, err := chartutil.Save(&chart.Chart{Metadata: &metadata}, dir)
+			require.NoError(t, err)
+		}
+
+		c := &api.Context{Ctx: context.Background(), BootstrapperState: api.BootstrapperState{Mirror: &model.MirrorConfig{ChartDirectory: dir}}}
+		versions, err := api.ListAddonVersions(c, addon)
+		require.NoError(t, err)
+
+		require.Len(t, versions, 2)
+		assert.Equal(t, "1.2.0", versions[0].Version)
+		assert.Equal(t, "v2.2.0", versions[0].AppVersion)
+		assert.Equal(t, "1.1.0", versions[1].Version)
+		assert.True(t, versions[1].Pinned)
+	})
+
+	t.Run("ImageRegistryOnly", func(t *testing.T) {
+		c := &api.Context{Ctx: context.Background(), BootstrapperState: api.BootstrapperState{Mirror: &model.MirrorConfig{ImageRegistry: "registry.example.com/mirror"}}}
+		_, err := api.ListAddonVersions(c, addon)
+
+		var appErr *model.AppError
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, http.StatusNotImplemented, appErr.StatusCode)
+	})
+}
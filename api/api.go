@@ -6,6 +6,10 @@ import (
 
 func Register(rootRouter *mux.Router, c *Context) {
 	apiRouter := rootRouter.PathPrefix("/api/v1").Subrouter()
+	// Jobs and providers must be registered before the bootstrapper, whose routes start with a {cloudProvider} variable
+	initJobs(apiRouter, c)
+	initProviders(apiRouter, c)
+	initAddons(apiRouter, c)
 	initBootstrapper(apiRouter, c)
 	initState(apiRouter, c)
 }
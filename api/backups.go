@@ -0,0 +1,371 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	cnpgv1 "github.com/cloudnative-pg/cloudnative-pg/api/v1"
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	v1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	cnpgBackupGVR          = schema.GroupVersionResource{Group: "postgresql.cnpg.io", Version: "v1", Resource: "backups"}
+	cnpgScheduledBackupGVR = schema.GroupVersionResource{Group: "postgresql.cnpg.io", Version: "v1", Resource: "scheduledbackups"}
+)
+
+// cnpgRestoreSource is the name of the external cluster that a restored cluster recovers from.
+const cnpgRestoreSource = "origin"
+
+// cnpgBackupSecretName is the name of the secret holding the object storage credentials of a CNPG cluster.
+func cnpgBackupSecretName(clusterName string) string {
+	return clusterName + "-backup-credentials"
+}
+
+// cnpgBackupConfiguration builds the backup section of a CNPG cluster. WAL and base backups are compressed, and
+// credentials come from the secret created by applyCNPGBackupSecret, or from the IAM role of the pods without keys.
+func cnpgBackupConfiguration(clusterName string, config model.CNPGBackupConfig) *cnpgv1.BackupConfiguration {
+	secretKey := func(key string) *cnpgv1.SecretKeySelector {
+		return &cnpgv1.SecretKeySelector{LocalObjectReference: cnpgv1.LocalObjectReference{Name: cnpgBackupSecretName(clusterName)}, Key: key}
+	}
+
+	credentials := &cnpgv1.S3Credentials{InheritFromIAMRole: true}
+	if config.AccessKeyID != "" {
+		credentials = &cnpgv1.S3Credentials{
+			AccessKeyIDReference:     secretKey("ACCESS_KEY_ID"),
+			SecretAccessKeyReference: secretKey("ACCESS_SECRET_KEY"),
+		}
+	}
+	if config.Region != "" {
+		credentials.RegionReference = secretKey("REGION")
+	}
+
+	return &cnpgv1.BackupConfiguration{
+		BarmanObjectStore: &cnpgv1.BarmanObjectStoreConfiguration{
+			BarmanCredentials: cnpgv1.BarmanCredentials{AWS: credentials},
+			EndpointURL:       config.EndpointURL,
+			DestinationPath:   config.DestinationPath,
+			Wal:               &cnpgv1.WalBackupConfiguration{Compression: cnpgv1.CompressionTypeGzip},
+			Data:              &cnpgv1.DataBackupConfiguration{Compression: cnpgv1.CompressionTypeGzip},
+		},
+		RetentionPolicy: config.RetentionPolicy,
+	}
+}
+
+// applyCNPGBackupSecret creates or updates the secret with the object storage credentials and region of a CNPG
+// cluster. Nothing is stored when the IAM role of the pods is used in the default region.
+func applyCNPGBackupSecret(c *Context, kubeClient *model.KubeClient, namespace, clusterName string, config model.CNPGBackupConfig) error {
+	data := map[string]string{}
+	if config.AccessKeyID != "" {
+		data["ACCESS_KEY_ID"] = config.AccessKeyID
+		data["ACCESS_SECRET_KEY"] = config.SecretAccessKey
+	}
+	if config.Region != "" {
+		data["REGION"] = config.Region
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cnpgBackupSecretName(clusterName),
+			Namespace: namespace,
+		},
+		Type:       v1.SecretTypeOpaque,
+		StringData: data,
+	}
+
+	secrets := kubeClient.Clientset.CoreV1().Secrets(namespace)
+	_, err := secrets.Create(c.Ctx, secret, metav1.CreateOptions{})
+	if apiErrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(c.Ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store backup credentials: %w", err)
+	}
+
+	return nil
+}
+
+// applyCNPGScheduledBackup creates or updates the scheduled backup of a CNPG cluster. The first backup is taken
+// right away, so that the cluster can be restored before the first scheduled one.
+func applyCNPGScheduledBackup(c *Context, kubeClient *model.KubeClient, cluster *cnpgv1.Cluster, config model.CNPGBackupConfig) error {
+	scheduledBackup := &cnpgv1.ScheduledBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cluster.Name + "-scheduled",
+			Namespace: cluster.Namespace,
+		},
+		Spec: cnpgv1.ScheduledBackupSpec{
+			Schedule:             config.Schedule,
+			Immediate:            aws.Bool(true),
+			Cluster:              cnpgv1.LocalObjectReference{Name: cluster.Name},
+			BackupOwnerReference: "self",
+			Method:               cnpgv1.BackupMethodBarmanObjectStore,
+		},
+	}
+
+	err := createCNPGResource(c, kubeClient, cnpgScheduledBackupGVR, "ScheduledBackup", scheduledBackup)
+	if err == nil || !apiErrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	scheduledBackups := kubeClient.DynamicClient.Resource(cnpgScheduledBackupGVR).Namespace(cluster.Namespace)
+	existing, err := scheduledBackups.Get(c.Ctx, scheduledBackup.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get scheduled backup: %w", err)
+	}
+	existing.Object["spec"], err = runtime.DefaultUnstructuredConverter.ToUnstructured(&scheduledBackup.Spec)
+	if err != nil {
+		return fmt.Errorf("failed to convert to unstructured: %w", err)
+	}
+
+	_, err = scheduledBackups.Update(c.Ctx, existing, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update scheduled backup: %w", err)
+	}
+
+	return nil
+}
+
+// ListDatabaseBackups returns the backups in the namespace of an installation, newest first. Backups of clusters
+// restored from the installation's database are included.
+func ListDatabaseBackups(c *Context, clusterName, installationName string) ([]model.DatabaseBackup, error) {
+	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	list, err := kubeClient.DynamicClient.Resource(cnpgBackupGVR).Namespace(installationName).List(c.Ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	backups := []model.DatabaseBackup{}
+	created := map[string]time.Time{}
+	for _, item := range list.Items {
+		backup := &cnpgv1.Backup{}
+		err = runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, backup)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert backup: %w", err)
+		}
+		backups = append(backups, databaseBackup(backup))
+		created[backup.Name] = backup.CreationTimestamp.Time
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return created[backups[i].Name].After(created[backups[j].Name])
+	})
+
+	return backups, nil
+}
+
+// CreateDatabaseBackup starts an on-demand backup of the CNPG cluster of an installation, which must have backups
+// configured.
+func CreateDatabaseBackup(c *Context, clusterName, installationName string) (*model.DatabaseBackup, error) {
+	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	cluster, err := getCNPGCluster(c, kubeClient, installationName)
+	if err != nil {
+		return nil, err
+	}
+	if cluster.Spec.Backup == nil || cluster.Spec.Backup.BarmanObjectStore == nil {
+		return nil, model.NewInvalidRequestError(fmt.Sprintf("The database of %s has no backup configuration", installationName))
+	}
+
+	backup := &cnpgv1.Backup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", cluster.Name, time.Now().UTC().Format("20060102150405")),
+			Namespace: cluster.Namespace,
+		},
+		Spec: cnpgv1.BackupSpec{
+			Cluster: cnpgv1.LocalObjectReference{Name: cluster.Name},
+			Method:  cnpgv1.BackupMethodBarmanObjectStore,
+		},
+	}
+
+	logger.FromContext(c.Ctx).Infof("Starting backup %s of CNPG cluster %s", backup.Name, cluster.Name)
+	err = createCNPGResource(c, kubeClient, cnpgBackupGVR, "Backup", backup)
+	if err != nil {
+		return nil, err
+	}
+
+	created := databaseBackup(backup)
+	created.Phase = cnpgv1.BackupPhasePending
+	return &created, nil
+}
+
+// RestoreDatabase restores the database of an installation into a new CNPG cluster in the same namespace, recovering
+// from its base backups and WAL archive up to the target time of the request. The new cluster has the sizing of the
+// original and backs up to the same destination, under its own name. The installation keeps using the original
+// cluster until its connection strings are changed.
+func RestoreDatabase(c *Context, clusterName, installationName string, request *model.RestoreDatabaseRequest) (*model.DatabaseRestore, error) {
+	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	source, err := getCNPGCluster(c, kubeClient, installationName)
+	if err != nil {
+		return nil, err
+	}
+	if source.Spec.Backup == nil || source.Spec.Backup.BarmanObjectStore == nil {
+		return nil, model.NewInvalidRequestError(fmt.Sprintf("The database of %s has no backup configuration", installationName))
+	}
+
+	name := request.ClusterName
+	if name == "" {
+		name = fmt.Sprintf("%s-restore-%s", source.Name, time.Now().UTC().Format("20060102150405"))
+	}
+	restored := newCNPGRestoreCluster(source, name, request.TargetTime)
+
+	target := "the latest archived WAL"
+	if request.TargetTime != nil {
+		target = request.TargetTime.UTC().Format(time.RFC3339)
+	}
+	logger.FromContext(c.Ctx).Infof("Restoring CNPG cluster %s into %s, recovering to %s", source.Name, name, target)
+
+	err = createCNPGResource(c, kubeClient, cnpgClusterGVR, "Cluster", restored)
+	if err != nil {
+		return nil, err
+	}
+
+	err = waitForCNPGCluster(c, kubeClient, restored)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.DatabaseRestore{ClusterName: name, Namespace: source.Namespace, TargetTime: request.TargetTime}, nil
+}
+
+// newCNPGRestoreCluster builds a CNPG cluster named name that recovers from the backups of source up to targetTime, or
+// the latest archived WAL when it is nil. source must have a backup configuration.
+func newCNPGRestoreCluster(source *cnpgv1.Cluster, name string, targetTime *time.Time) *cnpgv1.Cluster {
+	objectStore := source.Spec.Backup.BarmanObjectStore.DeepCopy()
+	if objectStore.ServerName == "" {
+		objectStore.ServerName = source.Name
+	}
+
+	recovery := &cnpgv1.BootstrapRecovery{Source: cnpgRestoreSource}
+	if targetTime != nil {
+		recovery.RecoveryTarget = &cnpgv1.RecoveryTarget{TargetTime: targetTime.UTC().Format(time.RFC3339)}
+	}
+
+	restored := &cnpgv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: source.Namespace,
+		},
+		Spec: cnpgv1.ClusterSpec{
+			Instances:             source.Spec.Instances,
+			ImageName:             source.Spec.ImageName,
+			StorageConfiguration:  source.Spec.StorageConfiguration,
+			Resources:             source.Spec.Resources,
+			PostgresConfiguration: cnpgv1.PostgresConfiguration{Parameters: source.Spec.PostgresConfiguration.Parameters},
+			Bootstrap:             &cnpgv1.BootstrapConfiguration{Recovery: recovery},
+			ExternalClusters:      []cnpgv1.ExternalCluster{{Name: cnpgRestoreSource, BarmanObjectStore: objectStore}},
+			Backup:                source.Spec.Backup.DeepCopy(),
+		},
+	}
+	// The restored cluster archives under its own name, so that it doesn't write over the backups it recovers from
+	restored.Spec.Backup.BarmanObjectStore.ServerName = ""
+
+	return restored
+}
+
+func databaseBackup(backup *cnpgv1.Backup) model.DatabaseBackup {
+	databaseBackup := model.DatabaseBackup{
+		Name:        backup.Name,
+		ClusterName: backup.Spec.Cluster.Name,
+		Phase:       string(backup.Status.Phase),
+		Method:      string(backup.Spec.Method),
+		Error:       backup.Status.Error,
+	}
+	if backup.Status.StartedAt != nil {
+		databaseBackup.StartedAt = &backup.Status.StartedAt.Time
+	}
+	if backup.Status.StoppedAt != nil {
+		databaseBackup.StoppedAt = &backup.Status.StoppedAt.Time
+	}
+
+	return databaseBackup
+}
+
+func handleListDatabaseBackups(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName := vars["name"]
+	if clusterName == "" || clusterName == "undefined" {
+		c.SetInvalidParam("name")
+		return
+	}
+
+	backups, err := ListDatabaseBackups(c, clusterName, vars["installationName"])
+	if err != nil {
+		c.SetError(err, "Failed to list database backups")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(backups)
+}
+
+func handleCreateDatabaseBackup(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName := vars["name"]
+	if clusterName == "" || clusterName == "undefined" {
+		c.SetInvalidParam("name")
+		return
+	}
+
+	backup, err := CreateDatabaseBackup(c, clusterName, vars["installationName"])
+	if err != nil {
+		c.SetError(err, "Failed to create database backup")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(backup)
+}
+
+func handleRestoreDatabase(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName := vars["name"]
+	if clusterName == "" || clusterName == "undefined" {
+		c.SetInvalidParam("name")
+		return
+	}
+
+	request, err := model.NewRestoreDatabaseRequestFromReader(r.Body)
+	if err != nil {
+		c.Err = model.NewInvalidRequestError("Failed to parse restore database request").Wrap(err)
+		return
+	}
+	defer r.Body.Close()
+
+	err = request.IsValid()
+	if err != nil {
+		c.Err = model.NewInvalidRequestError("Invalid restore database request").Wrap(err)
+		return
+	}
+
+	installationName := vars["installationName"]
+	job := &model.Job{Type: model.JobTypeRestoreDatabase, ClusterName: clusterName, Target: installationName}
+	startJob(c, w, job, func(c *Context) (interface{}, error) {
+		return RestoreDatabase(c, clusterName, installationName, request)
+	})
+}
@@ -0,0 +1,97 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	cnpgv1 "github.com/cloudnative-pg/cloudnative-pg/api/v1"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDatabaseBackupEndpoints(t *testing.T) {
+	_, router := newTestRouter(t)
+
+	t.Run("RestoreToTheFuture", func(t *testing.T) {
+		body := `{"targetTime": "` + time.Now().Add(time.Hour).Format(time.RFC3339) + `"}`
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/custom/cluster/test/installation/example/database/restore", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("InvalidBody", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/custom/cluster/test/installation/example/database/restore", strings.NewReader(`{"targetTime": "yesterday"}`)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestNewCNPGRestoreCluster(t *testing.T) {
+	source := &cnpgv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "example-cnpg-cluster", Namespace: "example"},
+		Spec: cnpgv1.ClusterSpec{
+			Instances:             3,
+			ImageName:             "ghcr.io/cloudnative-pg/postgresql:16",
+			StorageConfiguration:  cnpgv1.StorageConfiguration{Size: "20Gi"},
+			PostgresConfiguration: cnpgv1.PostgresConfiguration{Parameters: map[string]string{"max_connections": "300"}},
+			Backup: &cnpgv1.BackupConfiguration{
+				BarmanObjectStore: &cnpgv1.BarmanObjectStoreConfiguration{DestinationPath: "s3://backups/mattermost"},
+				RetentionPolicy:   "30d",
+			},
+		},
+	}
+
+	t.Run("TargetTime", func(t *testing.T) {
+		targetTime := time.Date(2024, 6, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+		restored := api.NewCNPGRestoreCluster(source, "example-restore", &targetTime)
+
+		assert.Equal(t, "example-restore", restored.Name)
+		assert.Equal(t, "example", restored.Namespace)
+		assert.Equal(t, 3, restored.Spec.Instances)
+		assert.Equal(t, "ghcr.io/cloudnative-pg/postgresql:16", restored.Spec.ImageName)
+		assert.Equal(t, "20Gi", restored.Spec.StorageConfiguration.Size)
+		assert.Equal(t, map[string]string{"max_connections": "300"}, restored.Spec.PostgresConfiguration.Parameters)
+
+		require.NotNil(t, restored.Spec.Bootstrap)
+		require.NotNil(t, restored.Spec.Bootstrap.Recovery)
+		assert.Equal(t, "origin", restored.Spec.Bootstrap.Recovery.Source)
+		require.NotNil(t, restored.Spec.Bootstrap.Recovery.RecoveryTarget)
+		assert.Equal(t, "2024-06-01T10:00:00Z", restored.Spec.Bootstrap.Recovery.RecoveryTarget.TargetTime)
+
+		require.Len(t, restored.Spec.ExternalClusters, 1)
+		assert.Equal(t, "origin", restored.Spec.ExternalClusters[0].Name)
+		assert.Equal(t, "s3://backups/mattermost", restored.Spec.ExternalClusters[0].BarmanObjectStore.DestinationPath)
+		assert.Equal(t, "example-cnpg-cluster", restored.Spec.ExternalClusters[0].BarmanObjectStore.ServerName)
+
+		// The restored cluster backs up to the same destination, under its own name
+		assert.Equal(t, "s3://backups/mattermost", restored.Spec.Backup.BarmanObjectStore.DestinationPath)
+		assert.Empty(t, restored.Spec.Backup.BarmanObjectStore.ServerName)
+		assert.Equal(t, "30d", restored.Spec.Backup.RetentionPolicy)
+		assert.Empty(t, source.Spec.Backup.BarmanObjectStore.ServerName, "the source cluster is unchanged")
+	})
+
+	t.Run("LatestWAL", func(t *testing.T) {
+		restored := api.NewCNPGRestoreCluster(source, "example-restore", nil)
+
+		require.NotNil(t, restored.Spec.Bootstrap.Recovery)
+		assert.Nil(t, restored.Spec.Bootstrap.Recovery.RecoveryTarget)
+	})
+
+	t.Run("RestoredSource", func(t *testing.T) {
+		// A cluster that was itself restored keeps reading the backups it recovered from
+		restoredSource := source.DeepCopy()
+		restoredSource.Name = "example-restore"
+		restoredSource.Spec.Backup.BarmanObjectStore.ServerName = "example-cnpg-cluster"
+
+		restored := api.NewCNPGRestoreCluster(restoredSource, "example-restore-2", nil)
+
+		assert.Equal(t, "example-cnpg-cluster", restored.Spec.ExternalClusters[0].BarmanObjectStore.ServerName)
+		assert.Empty(t, restored.Spec.Backup.BarmanObjectStore.ServerName)
+		assert.Equal(t, "example-cnpg-cluster", restoredSource.Spec.Backup.BarmanObjectStore.ServerName)
+	})
+}
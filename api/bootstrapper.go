@@ -6,25 +6,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 	"sync"
-	"time"
-
-	mmv1beta1 "github.com/mattermost/mattermost-operator/apis/mattermost/v1beta1"
 
 	"github.com/aws/aws-sdk-go/aws"
-	cnpgv1 "github.com/cloudnative-pg/cloudnative-pg/api/v1"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
 	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
-	helmclient "github.com/mittwald/go-helm-client"
-	"helm.sh/helm/v3/pkg/release"
-	"helm.sh/helm/v3/pkg/repo"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
 	v1 "k8s.io/api/core/v1"
 	apiErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -47,15 +39,17 @@ func initBootstrapper(apiRouter *mux.Router, context *Context) {
 	bootstrapperRouter.Handle("/clusters", addContext(handleListClusters)).Methods(http.MethodGet)
 	bootstrapperRouter.Handle("/cluster", addContext(handleCreateCluster)).Methods(http.MethodPost)
 
-	rdsRouter := bootstrapperRouter.PathPrefix("/rds").Subrouter()
-	rdsRouter.Handle("", addContext(handleCreateDatabase)).Methods(http.MethodPost)
-
 	// TODO: Add middleware to handle checking that the cluster name passed won't send a 400, so that we don't have to do it in every api handler
 	clusterNameRouter := bootstrapperRouter.PathPrefix("/cluster/{name:[A-Za-z0-9_-]+}").Subrouter()
 	clusterNameRouter.Handle("", addContext(handleGetCluster)).Methods(http.MethodGet)
+	clusterNameRouter.Handle("", addContext(handleDeleteCluster)).Methods(http.MethodDelete)
+	clusterNameRouter.Handle("/upgrade", addContext(handleUpgradeCluster)).Methods(http.MethodPost)
 	clusterNameRouter.Handle("/nodegroups", addContext(handleGetNodegroups)).Methods(http.MethodGet)
 	clusterNameRouter.Handle("/nodegroups", addContext(handleCreateNodeGroup)).Methods(http.MethodPost)
+	clusterNameRouter.Handle("/nodegroups/{nodegroup:[A-Za-z0-9_-]+}", addContext(handleUpdateNodegroup)).Methods(http.MethodPatch)
+	clusterNameRouter.Handle("/nodegroups/{nodegroup:[A-Za-z0-9_-]+}", addContext(handleDeleteNodegroup)).Methods(http.MethodDelete)
 	clusterNameRouter.Handle("/kubeconfig", addContext(handleGetKubeConfig)).Methods(http.MethodGet)
+	clusterNameRouter.Handle("/preflight", addContext(handleGetClusterPreflight)).Methods(http.MethodGet)
 	clusterNameRouter.Handle("/installed_charts", addContext(handleGetInstalledCharts)).Methods(http.MethodGet)
 	clusterNameRouter.Handle("/deploy_mattermost_operator", addContext(handleDeployMattermostOperator)).Methods(http.MethodPost)
 	clusterNameRouter.Handle("/deploy_nginx_operator", addContext(handleDeployNginxOperator)).Methods(http.MethodPost)
@@ -63,10 +57,20 @@ func initBootstrapper(apiRouter *mux.Router, context *Context) {
 	clusterNameRouter.Handle("/pg_operator", addContext(handleDeletePGOperator)).Methods(http.MethodDelete)
 	clusterNameRouter.Handle("/mattermost_operator", addContext(handleDeleteMattermostOperator)).Methods(http.MethodDelete)
 	clusterNameRouter.Handle("/nginx_operator", addContext(handleDeleteNginxOperator)).Methods(http.MethodDelete)
+	clusterNameRouter.Handle("/addons/{addon:[A-Za-z0-9_-]+}", addContext(handleInstallAddon)).Methods(http.MethodPost)
+	clusterNameRouter.Handle("/addons/{addon:[A-Za-z0-9_-]+}", addContext(handleUninstallAddon)).Methods(http.MethodDelete)
 	clusterNameRouter.Handle("/namespaces", addContext(handleGetClusterNamespaces)).Methods(http.MethodGet)
 	clusterNameRouter.Handle("/installation", addContext(handleCreateMattermostInstallation)).Methods(http.MethodPost)
+	clusterNameRouter.Handle("/installation/validate", addContext(handleValidateMattermostInstallation)).Methods(http.MethodPost)
 	clusterNameRouter.Handle("/installations", addContext(handleGetMattermostInstallations)).Methods(http.MethodGet)
 	clusterNameRouter.Handle("/cnpg/cluster", addContext(handleCreateCNPGCluster)).Methods(http.MethodPost)
+	clusterNameRouter.Handle("/rds", addContext(handleCreateManagedDatabase)).Methods(http.MethodPost)
+
+	releaseRouter := clusterNameRouter.PathPrefix("/releases/{namespace:[A-Za-z0-9_-]+}/{release:[A-Za-z0-9_.-]+}").Subrouter()
+	releaseRouter.Handle("/history", addContext(handleGetReleaseHistory)).Methods(http.MethodGet)
+	releaseRouter.Handle("/diff", addContext(handleGetReleaseValuesDiff)).Methods(http.MethodGet)
+	releaseRouter.Handle("/upgrade", addContext(handleUpgradeRelease)).Methods(http.MethodPost)
+	releaseRouter.Handle("/rollback", addContext(handleRollbackRelease)).Methods(http.MethodPost)
 
 	installationNameRouter := clusterNameRouter.PathPrefix("/installation/{installationName:[A-Za-z0-9_-]+}").Subrouter()
 	installationNameRouter.HandleFunc("/ws_logs", wsAdapter(handleInstallationLogsWebsocket, addContext(handleInstallationLogsWebsocket)))
@@ -74,6 +78,9 @@ func initBootstrapper(apiRouter *mux.Router, context *Context) {
 	installationNameRouter.Handle("", addContext(handleDeleteMattermostInstallation)).Methods(http.MethodDelete)
 	installationNameRouter.Handle("", addContext(handlePatchMattermostInstallation)).Methods(http.MethodPatch)
 	installationNameRouter.Handle("/secrets", addContext(handleGetMattermostInstallationSecrets)).Methods(http.MethodGet)
+	installationNameRouter.Handle("/database/backups", addContext(handleListDatabaseBackups)).Methods(http.MethodGet)
+	installationNameRouter.Handle("/database/backups", addContext(handleCreateDatabaseBackup)).Methods(http.MethodPost)
+	installationNameRouter.Handle("/database/restore", addContext(handleRestoreDatabase)).Methods(http.MethodPost)
 }
 
 func handleSetCredentials(c *Context, w http.ResponseWriter, r *http.Request) {
@@ -81,12 +88,15 @@ func handleSetCredentials(c *Context, w http.ResponseWriter, r *http.Request) {
 	cloudProvider := vars["cloudProvider"]
 
 	var credentials model.Credentials
-	json.NewDecoder(r.Body).Decode(&credentials)
+	err := json.NewDecoder(r.Body).Decode(&credentials)
+	if err != nil {
+		c.Err = model.NewInvalidRequestError("Failed to parse credentials").Wrap(err)
+		return
+	}
 
-	err := c.CloudProvider.SetCredentials(c.Ctx, &credentials)
+	err = c.CloudProvider.SetCredentials(c.Ctx, &credentials)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to set credentials")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to set credentials")
 		return
 	}
 
@@ -95,10 +105,7 @@ func handleSetCredentials(c *Context, w http.ResponseWriter, r *http.Request) {
 	success, err := c.CloudProvider.ValidateCredentials(c.Ctx, &credentials)
 	response.Success = success
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to validate credentials")
-		response.Message = err.Error()
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		c.SetError(err, "Failed to validate credentials")
 		return
 	}
 
@@ -113,22 +120,33 @@ func handleSetCredentials(c *Context, w http.ResponseWriter, r *http.Request) {
 }
 
 func handleSetRegion(c *Context, w http.ResponseWriter, r *http.Request) {
+	if _, ok := c.CloudProvider.(providers.RegionSelector); !ok {
+		c.Err = newNotImplementedError(c, "selecting a region")
+		return
+	}
+
 	var updateRegion model.UpdateRegionRequest
-	json.NewDecoder(r.Body).Decode(&updateRegion)
+	err := json.NewDecoder(r.Body).Decode(&updateRegion)
+	if err != nil {
+		c.Err = model.NewInvalidRequestError("Failed to parse region request").Wrap(err)
+		return
+	}
 
 	if updateRegion.Region == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("region")
 		return
 	}
 
 	credentials := c.BootstrapperState.Credentials
+	if credentials == nil {
+		credentials = &model.Credentials{}
+	}
 
 	credentials.Region = updateRegion.Region
 
-	err := c.CloudProvider.SetCredentials(c.Ctx, credentials)
+	err = c.CloudProvider.SetCredentials(c.Ctx, credentials)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to set region")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to set region")
 		return
 	}
 
@@ -141,10 +159,15 @@ func handleSetRegion(c *Context, w http.ResponseWriter, r *http.Request) {
 }
 
 func handleListRoles(c *Context, w http.ResponseWriter, r *http.Request) {
-	roles, err := c.CloudProvider.ListRoles(c.Ctx)
+	roleLister, ok := c.CloudProvider.(providers.RoleLister)
+	if !ok {
+		c.Err = newNotImplementedError(c, "listing roles")
+		return
+	}
+
+	roles, err := roleLister.ListRoles(c.Ctx)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to list roles")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to list roles")
 		return
 	}
 
@@ -155,8 +178,7 @@ func handleListClusters(c *Context, w http.ResponseWriter, r *http.Request) {
 	region := r.URL.Query().Get("region")
 	result, err := c.CloudProvider.ListClusters(c.Ctx, region)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to list clusters")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to list clusters")
 		return
 	}
 
@@ -166,21 +188,29 @@ func handleListClusters(c *Context, w http.ResponseWriter, r *http.Request) {
 func handleCreateCluster(c *Context, w http.ResponseWriter, r *http.Request) {
 	logger.FromContext(c.Ctx).Info("Creating cluster")
 
-	create, err := model.NewCreateClusterRequestFromReader(r.Body)
-	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to parse create cluster request")
-		w.WriteHeader(http.StatusBadRequest)
+	clusterCreator, ok := c.CloudProvider.(providers.ClusterCreator)
+	if !ok {
+		c.Err = newNotImplementedError(c, "creating clusters")
 		return
 	}
-	defer r.Body.Close()
 
-	result, err := c.CloudProvider.CreateCluster(c.Ctx, create)
+	create, err := model.NewCreateClusterRequestFromReader(r.Body)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to create cluster")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.Err = model.NewInvalidRequestError("Failed to parse create cluster request").Wrap(err)
 		return
 	}
-	json.NewEncoder(w).Encode(result)
+	defer r.Body.Close()
+
+	job := &model.Job{Type: model.JobTypeCreateCluster, ClusterName: aws.StringValue(create.ClusterName)}
+	startJob(c, w, job, func(c *Context) (interface{}, error) {
+		result, err := clusterCreator.CreateCluster(c.Ctx, create)
+		if err != nil {
+			return nil, err
+		}
+
+		logger.FromContext(c.Ctx).Infof("Cluster creation requested for %s", aws.StringValue(create.ClusterName))
+		return result, nil
+	})
 }
 
 func handleGetCluster(c *Context, w http.ResponseWriter, r *http.Request) {
@@ -190,7 +220,7 @@ func handleGetCluster(c *Context, w http.ResponseWriter, r *http.Request) {
 	clusterName := vars["name"]
 
 	if clusterName == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
 
@@ -199,8 +229,8 @@ func handleGetCluster(c *Context, w http.ResponseWriter, r *http.Request) {
 	result, err := c.CloudProvider.GetCluster(c.Ctx, clusterName)
 
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to describe cluster")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to describe cluster")
+		return
 	}
 
 	// Update cluster name in state when accessing a cluster
@@ -212,21 +242,82 @@ func handleGetCluster(c *Context, w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
+func handleDeleteCluster(c *Context, w http.ResponseWriter, r *http.Request) {
+	clusterDeleter, ok := c.CloudProvider.(providers.ClusterDeleter)
+	if !ok {
+		c.Err = newNotImplementedError(c, "deleting clusters")
+		return
+	}
+
+	clusterName := mux.Vars(r)["name"]
+	c.Ctx = logger.WithClusterName(c.Ctx, clusterName)
+	logger.FromContext(c.Ctx).Info("Deleting cluster")
+
+	job := &model.Job{Type: model.JobTypeDeleteCluster, ClusterName: clusterName}
+	startJob(c, w, job, func(c *Context) (interface{}, error) {
+		err := clusterDeleter.DeleteCluster(c.Ctx, clusterName)
+		if err != nil {
+			return nil, err
+		}
+
+		// Forget the cluster if it was the current one, so that the UI doesn't try to load it
+		state, err := GetState(c.BootstrapperState.StateFilePath)
+		if err == nil && state.ClusterName == clusterName {
+			err = UpdateStateClusterName(c.BootstrapperState, "")
+		}
+		if err != nil {
+			logger.FromContext(c.Ctx).WithError(err).Error("Failed to clear cluster name in state")
+		}
+
+		return nil, nil
+	})
+}
+
+func handleUpgradeCluster(c *Context, w http.ResponseWriter, r *http.Request) {
+	clusterUpgrader, ok := c.CloudProvider.(providers.ClusterUpgrader)
+	if !ok {
+		c.Err = newNotImplementedError(c, "upgrading clusters")
+		return
+	}
+
+	clusterName := mux.Vars(r)["name"]
+	c.Ctx = logger.WithClusterName(c.Ctx, clusterName)
+
+	upgrade, err := model.NewUpgradeClusterRequestFromReader(r.Body)
+	if err != nil {
+		c.Err = model.NewInvalidRequestError("Failed to parse upgrade cluster request").Wrap(err)
+		return
+	}
+	defer r.Body.Close()
+
+	if upgrade.KubernetesVersion == "" {
+		c.SetInvalidParam("kubernetesVersion")
+		return
+	}
+
+	logger.FromContext(c.Ctx).Infof("Upgrading cluster to %s", upgrade.KubernetesVersion)
+
+	job := &model.Job{Type: model.JobTypeUpgradeCluster, ClusterName: clusterName, Target: upgrade.KubernetesVersion}
+	startJob(c, w, job, func(c *Context) (interface{}, error) {
+		return clusterUpgrader.UpgradeCluster(c.Ctx, clusterName, upgrade.KubernetesVersion)
+	})
+}
+
 func handleGetNodegroups(c *Context, w http.ResponseWriter, r *http.Request) {
 	logger.FromContext(c.Ctx).Info("Refreshing node groups")
 	vars := mux.Vars(r)
 	clusterName := vars["name"]
 
 	if clusterName == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
 
 	nodes, err := c.CloudProvider.GetNodegroups(c.Ctx, clusterName)
 
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to list node groups")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to list node groups")
+		return
 	}
 
 	json.NewEncoder(w).Encode(nodes)
@@ -235,30 +326,80 @@ func handleGetNodegroups(c *Context, w http.ResponseWriter, r *http.Request) {
 func handleCreateNodeGroup(c *Context, w http.ResponseWriter, r *http.Request) {
 	logger.FromContext(c.Ctx).Info("Creating node group")
 
+	nodegroupManager, ok := c.CloudProvider.(providers.NodegroupManager)
+	if !ok {
+		c.Err = newNotImplementedError(c, "creating node groups")
+		return
+	}
+
 	vars := mux.Vars(r)
 	clusterName := vars["name"]
 
 	create, err := model.NewCreateNodeGroupRequestFromReader(r.Body)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to parse create node group request")
-		w.WriteHeader(http.StatusBadRequest)
+		c.Err = model.NewInvalidRequestError("Failed to parse create node group request").Wrap(err)
 		return
 	}
 	defer r.Body.Close()
 
-	result, err := c.CloudProvider.CreateNodegroup(c.Ctx, clusterName, create)
+	result, err := nodegroupManager.CreateNodegroup(c.Ctx, clusterName, create)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to create node group")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to create node group")
 		return
 	}
 
 	json.NewEncoder(w).Encode(result)
 }
 
-func handleCreateDatabase(c *Context, w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement
-	w.Write([]byte("Not implemented"))
+func handleUpdateNodegroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	nodegroupUpdater, ok := c.CloudProvider.(providers.NodegroupUpdater)
+	if !ok {
+		c.Err = newNotImplementedError(c, "updating node groups")
+		return
+	}
+
+	vars := mux.Vars(r)
+	clusterName := vars["name"]
+	nodegroupName := vars["nodegroup"]
+	c.Ctx = logger.WithClusterName(c.Ctx, clusterName)
+
+	update, err := model.NewUpdateNodegroupRequestFromReader(r.Body)
+	if err != nil {
+		c.Err = model.NewInvalidRequestError("Failed to parse update node group request").Wrap(err)
+		return
+	}
+	defer r.Body.Close()
+
+	if err := update.IsValid(); err != nil {
+		c.Err = model.NewInvalidRequestError("Invalid update node group request").Wrap(err)
+		return
+	}
+
+	logger.FromContext(c.Ctx).Infof("Updating node group %s", nodegroupName)
+
+	job := &model.Job{Type: model.JobTypeUpdateNodegroup, ClusterName: clusterName, Target: nodegroupName}
+	startJob(c, w, job, func(c *Context) (interface{}, error) {
+		return nodegroupUpdater.UpdateNodegroup(c.Ctx, clusterName, nodegroupName, update)
+	})
+}
+
+func handleDeleteNodegroup(c *Context, w http.ResponseWriter, r *http.Request) {
+	nodegroupDeleter, ok := c.CloudProvider.(providers.NodegroupDeleter)
+	if !ok {
+		c.Err = newNotImplementedError(c, "deleting node groups")
+		return
+	}
+
+	vars := mux.Vars(r)
+	clusterName := vars["name"]
+	nodegroupName := vars["nodegroup"]
+	c.Ctx = logger.WithClusterName(c.Ctx, clusterName)
+	logger.FromContext(c.Ctx).Infof("Deleting node group %s", nodegroupName)
+
+	job := &model.Job{Type: model.JobTypeDeleteNodegroup, ClusterName: clusterName, Target: nodegroupName}
+	startJob(c, w, job, func(c *Context) (interface{}, error) {
+		return nil, nodegroupDeleter.DeleteNodegroup(c.Ctx, clusterName, nodegroupName)
+	})
 }
 
 func handleGetKubeConfig(c *Context, w http.ResponseWriter, r *http.Request) {
@@ -267,27 +408,25 @@ func handleGetKubeConfig(c *Context, w http.ResponseWriter, r *http.Request) {
 	clusterName := vars["name"]
 
 	if clusterName == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
 
 	config, err := c.CloudProvider.GetKubeConfig(c.Ctx, clusterName)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		c.SetError(err, "Failed to get kubeconfig")
 		return
 	}
 
 	rawConfig, err := config.RawConfig()
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to get raw config")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to get raw config")
 		return
 	}
 
 	kubeconfigBytes, err := clientcmd.Write(rawConfig)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to write kubeconfig")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to write kubeconfig")
 		return
 	}
 
@@ -302,266 +441,152 @@ func handleGetInstalledCharts(c *Context, w http.ResponseWriter, r *http.Request
 
 	clusterName := vars["name"]
 	if clusterName == "" || clusterName == "undefined" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
 
 	c.Ctx = logger.WithClusterName(c.Ctx, clusterName)
 
-	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
+	releases, err := ListInstalledReleases(c, clusterName)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to create k8s client")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to list installed charts")
 		return
 	}
 
-	// Get all namespaces to loop through
-	namespaces, err := kubeClient.Clientset.CoreV1().Namespaces().List(c.Ctx, metav1.ListOptions{})
-	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to list namespaces")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	if len(namespaces.Items) == 0 {
-		json.NewEncoder(w).Encode([]model.InstalledReleases{})
-		return
-	}
-	allReleases := []*release.Release{}
-	for _, namespace := range namespaces.Items {
-		logger.FromContext(c.Ctx).Info("Getting releases for namespace", namespace.Name)
-
-		helmClient, err := c.CloudProvider.HelmClient(c.Ctx, clusterName, namespace.Name)
-		if err != nil {
-			logger.FromContext(c.Ctx).WithError(err).Error("Failed to authenticate helm client")
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		release, err := helmClient.ListDeployedReleases()
-		if err != nil {
-			logger.FromContext(c.Ctx).WithError(err).Error("Failed to list deployed releases")
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		allReleases = append(allReleases, release...)
-	}
-
-	logger.FromContext(c.Ctx).Errorf("%+v", allReleases)
-
-	releasesRes := []model.InstalledReleases{}
-	for _, release := range allReleases {
-		releasesRes = append(releasesRes, model.InstalledReleases{
-			Name:    release.Name,
-			Version: release.Chart.Metadata.Version,
-			Status:  release.Info.Status.String(),
-		})
-	}
-
-	json.NewEncoder(w).Encode(releasesRes)
+	json.NewEncoder(w).Encode(releases)
 }
 
 func handleDeleteNginxOperator(c *Context, w http.ResponseWriter, r *http.Request) {
-	c.Ctx = logger.WithField(c.Ctx, "action", "delete-ingress-nginx")
-	c.Ctx = logger.WithNamespace(c.Ctx, "ingress-nginx")
-
 	vars := mux.Vars(r)
 	clusterName := vars["name"]
 	if clusterName == "" || clusterName == "undefined" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
-	c.Ctx = logger.WithClusterName(c.Ctx, clusterName)
-
-	helmClient, err := c.CloudProvider.HelmClient(c.Ctx, clusterName, "ingress-nginx")
 
+	err := DeleteNginxOperator(c, clusterName)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to authenticate helm client")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	err = helmClient.UninstallReleaseByName("ingress-nginx")
-	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to delete ingress-nginx operator")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to delete ingress-nginx operator")
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-
 }
 
 func handleDeployNginxOperator(c *Context, w http.ResponseWriter, r *http.Request) {
-	c.Ctx = logger.WithField(c.Ctx, "action", "deploy-ingress-nginx")
-	c.Ctx = logger.WithNamespace(c.Ctx, "ingress-nginx")
-
 	vars := mux.Vars(r)
 	clusterName := vars["name"]
 	if clusterName == "" || clusterName == "undefined" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
-	c.Ctx = logger.WithClusterName(c.Ctx, clusterName)
-
-	// TODO: Before this can run, the subnets that the cluster was created on must be updated to have tags with the format:
-	// kubernetes.io/cluster/cluster-name: shared (TODO: Confirm "shared" is correct?)
-
-	helmClient, err := c.CloudProvider.HelmClient(c.Ctx, clusterName, "ingress-nginx")
 
+	request, err := model.NewDeployNginxRequestFromReader(r.Body)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to authenticate helm client")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.Err = model.NewInvalidRequestError("Failed to parse deploy ingress-nginx request").Wrap(err)
 		return
 	}
+	defer r.Body.Close()
 
-	chartRepo := repo.Entry{
-		Name: "nginx",
-		URL:  "https://kubernetes.github.io/ingress-nginx",
-	}
-
-	err = helmClient.AddOrUpdateChartRepo(chartRepo)
-
+	// Build the values up front, so that an invalid configuration is rejected before a job is started
+	_, err = NginxValues(c, request)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to add or update chart repo")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	// TODO - we need some sort of pre-post hooks for the install to allow for environment specific configurations
-	valuesYaml := `controller:
-    config:
-      use-forwarded-headers: "true"
-	service:
-	  targetPorts:
-	    http: http
-		https: http
-    service:
-      annotations:
-        service.beta.kubernetes.io/aws-load-balancer-backend-protocol: "tcp"
-        service.beta.kubernetes.io/aws-load-balancer-ssl-ports: "https"
-        service.beta.kubernetes.io/aws-load-balancer-ssl-cert: arn:aws:acm:us-east-1:110643744285:certificate/8fcc5250-8a60-4ab8-8337-7491fb447906`
-
-	chartSpec := helmclient.ChartSpec{
-		ReleaseName:     "ingress-nginx",
-		ChartName:       "nginx/ingress-nginx",
-		Namespace:       "ingress-nginx",
-		UpgradeCRDs:     true,
-		Wait:            true,
-		Timeout:         3000 * time.Second,
-		CreateNamespace: true,
-		CleanupOnFail:   true,
-		ValuesYaml:      valuesYaml,
-	}
-
-	// Install a chart release.
-	// Note that helmclient.Options.Namespace should ideally match the namespace in chartSpec.Namespace.
-	if _, err := helmClient.InstallOrUpgradeChart(context.Background(), &chartSpec, nil); err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to install mattermost operator")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Invalid ingress-nginx configuration")
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-
+	job := &model.Job{Type: model.JobTypeDeployOperator, ClusterName: clusterName, Target: "ingress-nginx"}
+	startJob(c, w, job, func(c *Context) (interface{}, error) {
+		return nil, DeployNginxOperator(c, clusterName, request)
+	})
 }
 
-func handleDeletePGOperator(c *Context, w http.ResponseWriter, r *http.Request) {
-	c.Ctx = logger.WithField(c.Ctx, "action", "delete-cnpg")
-	c.Ctx = logger.WithNamespace(c.Ctx, "cnpg-system")
+func handleInstallAddon(c *Context, w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	clusterName := vars["name"]
 	if clusterName == "" || clusterName == "undefined" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
 
-	c.Ctx = logger.WithClusterName(c.Ctx, clusterName)
-
-	helmClient, err := c.CloudProvider.HelmClient(c.Ctx, clusterName, "cnpg-system")
-
-	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to authenticate helm client")
-		w.WriteHeader(http.StatusInternalServerError)
+	addon, ok := GetAddon(vars["addon"])
+	if !ok {
+		c.Err = model.NewNotFoundError(fmt.Sprintf("Unknown add-on: %s", vars["addon"]))
 		return
 	}
 
-	err = helmClient.UninstallReleaseByName("cnpg-system")
+	request, err := model.NewInstallAddonRequestFromReader(r.Body)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to delete cnpg-system operator")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.Err = model.NewInvalidRequestError("Failed to parse install add-on request").Wrap(err)
 		return
 	}
+	defer r.Body.Close()
 
-	w.WriteHeader(http.StatusOK)
+	job := &model.Job{Type: model.JobTypeInstallAddon, ClusterName: clusterName, Target: addon.Name}
+	startJob(c, w, job, func(c *Context) (interface{}, error) {
+		return nil, InstallAddon(c, clusterName, addon, request)
+	})
 }
 
-func handleDeployPGOperator(c *Context, w http.ResponseWriter, r *http.Request) {
-	c.Ctx = logger.WithField(c.Ctx, "action", "deploy-cnpg")
-	c.Ctx = logger.WithNamespace(c.Ctx, "cnpg")
+func handleUninstallAddon(c *Context, w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	clusterName := vars["name"]
 	if clusterName == "" || clusterName == "undefined" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
 
-	c.Ctx = logger.WithClusterName(c.Ctx, clusterName)
-
-	helmClient, err := c.CloudProvider.HelmClient(c.Ctx, clusterName, "kube-system")
-	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to authenticate helm client")
-		w.WriteHeader(http.StatusInternalServerError)
+	addon, ok := GetAddon(vars["addon"])
+	if !ok {
+		c.Err = model.NewNotFoundError(fmt.Sprintf("Unknown add-on: %s", vars["addon"]))
 		return
 	}
 
-	err = c.CloudProvider.HelmFileStorePre(c.Ctx, clusterName, "kube-system")
+	err := UninstallAddon(c, clusterName, addon)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to execute file system preinstall steps for cnpg operator")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, fmt.Sprintf("Failed to uninstall %s", addon.Name))
 		return
 	}
 
-	chartRepo := repo.Entry{
-		Name: "cnpg",
-		URL:  "https://cloudnative-pg.github.io/charts",
-	}
+	w.WriteHeader(http.StatusOK)
+}
 
-	err = helmClient.AddOrUpdateChartRepo(chartRepo)
-	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to add or update chart repo")
-		w.WriteHeader(http.StatusInternalServerError)
+func handleDeletePGOperator(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName := vars["name"]
+	if clusterName == "" || clusterName == "undefined" {
+		c.SetInvalidParam("name")
 		return
 	}
 
-	helmClient, err = c.CloudProvider.HelmClient(c.Ctx, clusterName, "cnpg-system")
+	err := DeletePGOperator(c, clusterName)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to authenticate helm client")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to delete cnpg operator")
 		return
 	}
 
-	chartSpec := helmclient.ChartSpec{
-		ReleaseName:     "cnpg-system",
-		ChartName:       "cnpg/cloudnative-pg",
-		Namespace:       "cnpg-system",
-		UpgradeCRDs:     true,
-		Wait:            true,
-		Timeout:         300 * time.Second,
-		CreateNamespace: true,
-		CleanupOnFail:   true,
+	w.WriteHeader(http.StatusOK)
+}
+
+func handleDeployPGOperator(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName := vars["name"]
+	if clusterName == "" || clusterName == "undefined" {
+		c.SetInvalidParam("name")
+		return
 	}
 
-	// Install a chart release.
-	// Note that helmclient.Options.Namespace should ideally match the namespace in chartSpec.Namespace.
-	if _, err := helmClient.InstallOrUpgradeChart(context.Background(), &chartSpec, nil); err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to install cnpg operator")
-		w.WriteHeader(http.StatusInternalServerError)
+	request, err := model.NewInstallAddonRequestFromReader(r.Body)
+	if err != nil {
+		c.Err = model.NewInvalidRequestError("Failed to parse deploy operator request").Wrap(err)
 		return
 	}
+	defer r.Body.Close()
 
-	w.WriteHeader(http.StatusCreated)
+	job := &model.Job{Type: model.JobTypeDeployOperator, ClusterName: clusterName, Target: "cnpg"}
+	startJob(c, w, job, func(c *Context) (interface{}, error) {
+		return nil, DeployPGOperator(c, clusterName, request)
+	})
 }
 
 func handleGetClusterNamespaces(c *Context, w http.ResponseWriter, r *http.Request) {
@@ -569,21 +594,19 @@ func handleGetClusterNamespaces(c *Context, w http.ResponseWriter, r *http.Reque
 	clusterName := vars["name"]
 
 	if clusterName == "" || clusterName == "undefined" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
 
 	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to create clientset")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to create clientset")
 		return
 	}
 
 	namespaces, err := kubeClient.Clientset.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to list namespaces")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to list namespaces")
 		return
 	}
 
@@ -595,64 +618,45 @@ func handleCreateCNPGCluster(c *Context, w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	clusterName := vars["name"]
 	if clusterName == "" || clusterName == "undefined" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
 
-	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
+	config, err := model.NewCNPGDatabaseConfigFromReader(r.Body)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to create clientset")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.Err = model.NewInvalidRequestError("Failed to parse CNPG database config").Wrap(err)
 		return
 	}
 
-	namespace := &v1.Namespace{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "cnpg-cluster",
-			Namespace: "cnpg-cluster",
-		},
+	err = config.IsValid()
+	if err != nil {
+		c.Err = model.NewInvalidRequestError("Invalid CNPG database config").Wrap(err)
+		return
 	}
 
-	// TODO: Check for existence before creating to avoid error
-	_, err = kubeClient.Clientset.CoreV1().Namespaces().Create(context.TODO(), namespace, metav1.CreateOptions{})
+	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to create namespace")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to create clientset")
 		return
 	}
 
-	dbCluster := &cnpgv1.Cluster{
+	namespace := &v1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      clusterName + "-cnpg-cluster",
+			Name:      "cnpg-cluster",
 			Namespace: "cnpg-cluster",
 		},
-		Spec: cnpgv1.ClusterSpec{
-			Instances:            1,
-			StorageConfiguration: cnpgv1.StorageConfiguration{Size: "1Gi"},
-		},
-	}
-
-	gvr := schema.GroupVersionResource{
-		Group:    "postgresql.cnpg.io",
-		Version:  "v1",
-		Resource: "clusters",
 	}
 
-	unstructuredObj, err := model.ConvertToUnstructured(dbCluster)
+	// TODO: Check for existence before creating to avoid error
+	_, err = kubeClient.Clientset.CoreV1().Namespaces().Create(context.TODO(), namespace, metav1.CreateOptions{})
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to convert to unstructured")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to create namespace")
 		return
 	}
 
-	unstructuredObj.Object["apiVersion"] = "postgresql.cnpg.io/v1"
-	unstructuredObj.Object["kind"] = "Cluster"
-
-	// Create the CRD Instance
-	_, err = kubeClient.DynamicClient.Resource(gvr).Namespace("cnpg-cluster").Create(context.TODO(), unstructuredObj, metav1.CreateOptions{})
+	_, err = CreateCNPGDatabase(c, kubeClient, clusterName+"-cnpg-cluster", "cnpg-cluster", config.WithDefaults(""))
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to create CRD")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to create CNPG cluster")
 		return
 	}
 
@@ -663,7 +667,7 @@ func handleGetMattermostInstallationSecrets(c *Context, w http.ResponseWriter, r
 	vars := mux.Vars(r)
 	clusterName := vars["name"]
 	if clusterName == "" || clusterName == "undefined" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
 
@@ -671,7 +675,7 @@ func handleGetMattermostInstallationSecrets(c *Context, w http.ResponseWriter, r
 
 	installationName := vars["installationName"]
 	if installationName == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("installationName")
 		return
 	}
 
@@ -679,15 +683,13 @@ func handleGetMattermostInstallationSecrets(c *Context, w http.ResponseWriter, r
 
 	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to create clientset")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to create clientset")
 		return
 	}
 
 	installation, err := kubeClient.MattermostClientsetV1Beta.MattermostV1beta1().Mattermosts(installationName).Get(context.TODO(), installationName, metav1.GetOptions{})
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to get installation")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to get installation")
 		return
 	}
 
@@ -697,15 +699,13 @@ func handleGetMattermostInstallationSecrets(c *Context, w http.ResponseWriter, r
 
 	databaseSecret, err := kubeClient.Clientset.CoreV1().Secrets(namespaceName).Get(c.Ctx, model.SecretNameDatabase, metav1.GetOptions{})
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to get database secret")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to get database secret")
 		return
 	}
 
 	filestoreSecret, err := kubeClient.Clientset.CoreV1().Secrets(namespaceName).Get(c.Ctx, model.SecretNameFilestore, metav1.GetOptions{})
 	if err != nil && !apiErrors.IsNotFound(err) {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to get filestore secret")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to get filestore secret")
 		return
 	}
 
@@ -714,8 +714,7 @@ func handleGetMattermostInstallationSecrets(c *Context, w http.ResponseWriter, r
 	if licenseSecretName != "" {
 		licenseSecret, err = kubeClient.Clientset.CoreV1().Secrets(namespaceName).Get(c.Ctx, licenseSecretName, metav1.GetOptions{})
 		if err != nil && !apiErrors.IsNotFound(err) {
-			logger.FromContext(c.Ctx).WithError(err).Error("Failed to get license secret")
-			w.WriteHeader(http.StatusInternalServerError)
+			c.SetError(err, "Failed to get license secret")
 			return
 		}
 	}
@@ -728,8 +727,7 @@ func handleGetMattermostInstallationSecrets(c *Context, w http.ResponseWriter, r
 
 	installationSecretsResponse, err := installationSecrets.ToInstallationSecretsResponse()
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to convert installation secrets to response")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to convert installation secrets to response")
 		return
 	}
 
@@ -740,7 +738,7 @@ func handlePatchMattermostInstallation(c *Context, w http.ResponseWriter, r *htt
 	vars := mux.Vars(r)
 	clusterName := vars["name"]
 	if clusterName == "" || clusterName == "undefined" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
 
@@ -748,7 +746,7 @@ func handlePatchMattermostInstallation(c *Context, w http.ResponseWriter, r *htt
 
 	installationName := vars["installationName"]
 	if installationName == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("installationName")
 		return
 	}
 
@@ -756,218 +754,51 @@ func handlePatchMattermostInstallation(c *Context, w http.ResponseWriter, r *htt
 
 	patchRequest, err := model.NewMattermostWorkspacePatchRequestFromReader(r.Body)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to parse patch mattermost workspace request")
-		w.WriteHeader(http.StatusBadRequest)
+		c.Err = model.NewInvalidRequestError("Failed to parse patch mattermost workspace request").Wrap(err)
 		return
 	}
 
 	logger.FromContext(c.Ctx).Infof("Patch request: %+v", patchRequest.FilestorePatch)
 
 	if !patchRequest.IsValid() {
-		logger.FromContext(c.Ctx).Errorf("Invalid patch request - version validation failed for version: %s", patchRequest.Version)
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
-	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to create clientset")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	installation, err := kubeClient.MattermostClientsetV1Beta.MattermostV1beta1().Mattermosts(installationName).Get(context.TODO(), installationName, metav1.GetOptions{})
-	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to get installation")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.Err = model.NewInvalidRequestError("Invalid patch request").WithDetails(fmt.Sprintf("version validation failed for version: %s", patchRequest.Version))
 		return
 	}
 
-	MMFilestore := installation.Spec.FileStore
-	if patchRequest.HasFilestoreChanges() {
-		logger.FromContext(c.Ctx).Info("Filestore changes detected")
-		// Fetch the installation's existing filestore secrets
-		// Merge the new updated ones into the old one
-		// Push the updated secret to k8s
-		// Update the installation CRD with anything necessary (ie, if the bucket url or name changes)
-		filestorePatch := patchRequest.FilestorePatch
-		if filestorePatch.FilestoreOption == model.FilestoreOptionExistingS3 {
-			existingFilestoreSecret, err := kubeClient.Clientset.CoreV1().Secrets(installationName).Get(c.Ctx, model.SecretNameFilestore, metav1.GetOptions{})
-			if err != nil {
-				logger.FromContext(c.Ctx).WithError(err).Error("Failed to get filestore secret")
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-
-			filestore := model.KubeS3FilestoreSecretToS3Filestore(existingFilestoreSecret, &installation.Spec.FileStore)
-
-			// Update the existing filestore with the new values
-			if filestorePatch.S3Filestore.BucketName != "" {
-				filestore.BucketName = filestorePatch.S3Filestore.BucketName
-			}
-
-			if filestorePatch.S3Filestore.BucketURL != "" {
-				filestore.BucketURL = filestorePatch.S3Filestore.BucketURL
-			}
-
-			if filestorePatch.S3Filestore.AccessKey != "" {
-				filestore.AccessKey = filestorePatch.S3Filestore.AccessKey
-			}
-
-			if filestorePatch.S3Filestore.SecretKey != "" {
-				filestore.SecretKey = filestorePatch.S3Filestore.SecretKey
-			}
-
-			existingFilestoreSecret.StringData = map[string]string{
-				"accesskey": filestore.AccessKey,
-				"secretkey": filestore.SecretKey,
-			}
-
-			_, err = kubeClient.Clientset.CoreV1().Secrets(installationName).Update(context.TODO(), existingFilestoreSecret, metav1.UpdateOptions{})
-			if err != nil {
-				logger.FromContext(c.Ctx).WithError(err).Error("Failed to update filestore secret")
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-
-			MMFilestore.External.Bucket = filestore.BucketName
-			MMFilestore.External.URL = filestore.BucketURL
-			MMFilestore.External.Secret = model.SecretNameFilestore
-		} else if filestorePatch.FilestoreOption == model.FilestoreOptionInClusterLocal {
-			MMFilestore.Local.StorageSize = filestorePatch.LocalFileStore.StorageSize
-			MMFilestore.Local.Enabled = true
-		} else if filestorePatch.FilestoreOption == model.FilestoreOptionInClusterExternal {
-			MMFilestore.ExternalVolume.VolumeClaimName = filestorePatch.LocalExternalFileStore.VolumeClaimName
-		}
-	}
-
-	if patchRequest.License != nil {
-
-		existingLicenseSecretName := model.GetLicenseSecretName(installation)
-
-		// A secret for this already exists, so delete it
-		if existingLicenseSecretName != "" {
-			err := kubeClient.Clientset.CoreV1().Secrets(installationName).Delete(c.Ctx, existingLicenseSecretName, metav1.DeleteOptions{})
-			if err != nil {
-				logger.FromContext(c.Ctx).WithError(err).Error("Failed to delete existing license secret")
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-		}
-
-		licenseSecret := model.NewMattermostLicenseSecret(installationName, *patchRequest.License)
-		_, err = kubeClient.Clientset.CoreV1().Secrets(installationName).Create(context.TODO(), licenseSecret, metav1.CreateOptions{})
-		if err != nil {
-			logger.FromContext(c.Ctx).WithError(err).Error("Failed to update license secret")
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		setEnv := false
-		for i, envVar := range installation.Spec.MattermostEnv {
-			if envVar.Name == model.MMENVLicense {
-				setEnv = true
-				installation.Spec.MattermostEnv[i] = v1.EnvVar{Name: model.MMENVLicense, ValueFrom: &v1.EnvVarSource{
-					SecretKeyRef: &v1.SecretKeySelector{Key: "license", LocalObjectReference: v1.LocalObjectReference{Name: licenseSecret.ObjectMeta.Name}, Optional: aws.Bool(true)}, // Add comma to separate items
-				}}
-			}
-		}
-
-		if !setEnv {
-			installation.Spec.MattermostEnv = append(installation.Spec.MattermostEnv, v1.EnvVar{Name: model.MMENVLicense, ValueFrom: &v1.EnvVarSource{
-				SecretKeyRef: &v1.SecretKeySelector{Key: "license", LocalObjectReference: v1.LocalObjectReference{Name: licenseSecret.ObjectMeta.Name}, Optional: aws.Bool(true)}, // Add comma to separate items
-			}})
-		}
-	}
-
-	database := installation.Spec.Database
-	if patchRequest.DatabasePatch != nil {
-		databaseSecret, err := kubeClient.Clientset.CoreV1().Secrets(installationName).Get(c.Ctx, model.SecretNameDatabase, metav1.GetOptions{})
-		if err != nil {
-			logger.FromContext(c.Ctx).WithError(err).Error("Failed to get database secret")
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		existingStringData := databaseSecret.Data
-
-		if patchRequest.DatabasePatch.ConnectionString != "" {
-			existingStringData["DB_CONNECTION_CHECK_URL"] = []byte(patchRequest.DatabasePatch.ConnectionString)
-			existingStringData["DB_CONNECTION_STRING"] = []byte(patchRequest.DatabasePatch.ConnectionString)
-		}
-
-		if patchRequest.DatabasePatch.ReplicaConnectionString != "" {
-			existingStringData["MM_SQLSETTINGS_DATASOURCEREPLICAS"] = []byte(patchRequest.DatabasePatch.ReplicaConnectionString)
-		}
-
-		updatedSecret, err := kubeClient.Clientset.CoreV1().Secrets(installationName).Update(c.Ctx, databaseSecret, metav1.UpdateOptions{})
+	if patchRequest.CNPGDatabasePatch != nil {
+		err = patchRequest.CNPGDatabasePatch.IsValidPatch()
 		if err != nil {
-			logger.FromContext(c.Ctx).WithError(err).Error("Failed to update database secret")
-			w.WriteHeader(http.StatusInternalServerError)
+			c.Err = model.NewInvalidRequestError("Invalid CNPG database patch").Wrap(err)
 			return
 		}
-
-		database.External.Secret = updatedSecret.ObjectMeta.Name
 	}
 
-	// Update environment variables if provided
-	if len(patchRequest.MattermostEnv) > 0 {
-		logger.FromContext(c.Ctx).Infof("Updating environment variables, count: %d", len(patchRequest.MattermostEnv))
-		installation.Spec.MattermostEnv = patchRequest.MattermostEnv
-	}
-
-	installation.Spec.Version = patchRequest.Version
-	installation.Spec.Image = patchRequest.Image
-	installation.Spec.FileStore = MMFilestore
-	installation.Spec.Database = database
-
-	installation, err = kubeClient.MattermostClientsetV1Beta.MattermostV1beta1().Mattermosts(installationName).Update(context.TODO(), installation, metav1.UpdateOptions{})
+	installation, err := PatchMattermostInstallation(c, clusterName, installationName, patchRequest)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to update installation")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to patch installation")
 		return
 	}
 
 	json.NewEncoder(w).Encode(installation)
-
 }
 
 func handleDeleteMattermostInstallation(c *Context, w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	clusterName := vars["name"]
 	if clusterName == "" || clusterName == "undefined" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
 
 	installationName := vars["installationName"]
 	if installationName == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
-	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to create clientset")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	// Delete the CRD
-
-	err = kubeClient.MattermostClientsetV1Beta.MattermostV1beta1().Mattermosts(installationName).Delete(context.TODO(), installationName, metav1.DeleteOptions{})
-	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to delete CRD")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetInvalidParam("installationName")
 		return
 	}
 
-	// Delete the namespace
-
-	err = kubeClient.Clientset.CoreV1().Namespaces().Delete(context.TODO(), installationName, metav1.DeleteOptions{})
+	err := DeleteMattermostInstallation(c, clusterName, installationName)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to delete namespace")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to delete mattermost installation")
 		return
 	}
 }
@@ -976,339 +807,126 @@ func handleCreateMattermostInstallation(c *Context, w http.ResponseWriter, r *ht
 	vars := mux.Vars(r)
 	clusterName := vars["name"]
 	if clusterName == "" || clusterName == "undefined" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
 
 	create, err := model.NewCreateMattermostWorkspaceRequestFromReader(r.Body)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to parse create mattermost workspace request")
-		w.WriteHeader(http.StatusBadRequest)
+		c.Err = model.NewInvalidRequestError("Failed to parse create mattermost workspace request").Wrap(err)
 		return
 	}
 
-	if !create.IsValid() {
-		w.WriteHeader(http.StatusBadRequest)
+	c.Err = validateCreateMattermostWorkspaceRequest(c, create)
+	if c.Err != nil {
 		return
 	}
 
-	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
-	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to create clientset")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	namespaceName := "mm-installation-" + create.InstallationName
-
-	namespace := &v1.Namespace{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: namespaceName,
-		},
-	}
-
-	_, err = kubeClient.Clientset.CoreV1().Namespaces().Get(context.TODO(), namespaceName, metav1.GetOptions{})
-	if err != nil {
-		if apiErrors.IsNotFound(err) { // If not found, create it
-			_, err = kubeClient.Clientset.CoreV1().Namespaces().Create(context.TODO(), namespace, metav1.CreateOptions{})
-			if err != nil {
-				logger.FromContext(c.Ctx).WithError(err).Error("Failed to create namespace")
-				w.WriteHeader(http.StatusInternalServerError)
-				return
-			}
-			logger.FromContext(c.Ctx).Info("Namespace created successfully")
-		} else { // Some other error occurred
-			logger.FromContext(c.Ctx).WithError(err).Error("Error while checking namespace existence")
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-	}
-
-	var writer string
-	var reader string
-	var databaseSecretName string
-
-	if create.DBConnectionOption == model.DatabaseOptionCreateForMe {
-		dbCluster := &cnpgv1.Cluster{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      namespaceName + "-cnpg-cluster",
-				Namespace: namespaceName,
-			},
-			Spec: cnpgv1.ClusterSpec{
-				Instances:            1,
-				StorageConfiguration: cnpgv1.StorageConfiguration{Size: "1Gi"},
-			},
-		}
-
-		gvr := schema.GroupVersionResource{
-			Group:    "postgresql.cnpg.io",
-			Version:  "v1",
-			Resource: "clusters",
-		}
+	job := &model.Job{Type: model.JobTypeCreateInstallation, ClusterName: clusterName, Target: create.InstallationName}
+	startJob(c, w, job, func(c *Context) (interface{}, error) {
+		return CreateMattermostInstallation(c, clusterName, create)
+	})
+}
 
-		unstructuredObj, err := model.ConvertToUnstructured(dbCluster)
+// validateCreateMattermostWorkspaceRequest checks the options and configs of a create installation request, and that
+// the provider supports the resources it asks to create.
+func validateCreateMattermostWorkspaceRequest(c *Context, create *model.CreateMattermostWorkspaceRequest) *model.AppError {
+	if create.CNPGDatabase != nil {
+		err := create.CNPGDatabase.IsValid()
 		if err != nil {
-			logger.FromContext(c.Ctx).WithError(err).Error("Failed to convert to unstructured")
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			return model.NewInvalidRequestError("Invalid CNPG database config").Wrap(err)
 		}
+	}
 
-		unstructuredObj.Object["apiVersion"] = "postgresql.cnpg.io/v1"
-		unstructuredObj.Object["kind"] = "Cluster"
-
-		secretName := namespaceName + "-cnpg-cluster-app"
-
-		// Create the CRD Instance
-		_, err = kubeClient.DynamicClient.Resource(gvr).Namespace(namespaceName).Create(context.TODO(), unstructuredObj, metav1.CreateOptions{})
-		if err != nil {
-			logger.FromContext(c.Ctx).WithError(err).Error("Failed to create CRD")
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		// TODO: add a proper poll to wait for secret to be created
-		time.Sleep(5 * time.Second)
-
-		secret, err := kubeClient.Clientset.CoreV1().Secrets(namespaceName).Get(context.TODO(), secretName, metav1.GetOptions{})
+	if create.RDSDatabase != nil {
+		err := create.RDSDatabase.IsValid()
 		if err != nil {
-			logger.FromContext(c.Ctx).WithError(err).Error("Failed to get secret")
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-
-		decodedUri := secret.Data["uri"]
-
-		initial := string(decodedUri)
-
-		// Replacements
-		writer = strings.Replace(initial, "postgresql:", "postgres:", 1) // Replace once
-		reader = strings.Replace(writer, fmt.Sprintf("%s-rw:", secretName), fmt.Sprintf("%s-ro:", secretName), 1)
-	} else if create.DBConnectionOption == model.DatabaseOptionExisting {
-		if create.ExistingDBSecretName != "" {
-			databaseSecretName = create.ExistingDBSecretName
-		} else {
-			writer = create.ExistingDBConnection.ConnectionString
-			reader = create.ExistingDBConnection.ConnectionString
+			return model.NewInvalidRequestError("Invalid RDS database config").Wrap(err)
 		}
 	}
 
-	if databaseSecretName == "" {
-		databaseSecret := &v1.Secret{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      model.SecretNameDatabase,
-				Namespace: namespaceName,
-			},
-			Type: v1.SecretTypeOpaque,
-			StringData: map[string]string{
-				"DB_CONNECTION_CHECK_URL":           writer,
-				"DB_CONNECTION_STRING":              writer,
-				"MM_SQLSETTINGS_DATASOURCEREPLICAS": reader, // Assuming read replicas for now
-				"MM_CONFIG":                         writer,
-			},
-		}
+	if _, ok := c.CloudProvider.(providers.DatabaseProvisioner); !ok && create.DBConnectionOption == model.DatabaseOptionCreateForMeRDS {
+		return newNotImplementedError(c, "creating managed databases")
+	}
 
-		// Create the database secret
-		_, err = kubeClient.Clientset.CoreV1().Secrets(namespaceName).Create(context.TODO(), databaseSecret, metav1.CreateOptions{})
+	if create.AWSS3Filestore != nil {
+		err := create.AWSS3Filestore.IsValid()
 		if err != nil {
-			logger.FromContext(c.Ctx).Errorf("Error creating database secret:", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			return model.NewInvalidRequestError("Invalid AWS S3 filestore config").Wrap(err)
 		}
-
-		databaseSecretName = databaseSecret.ObjectMeta.Name
 	}
 
-	// License Secret
-	licenseSecret := model.NewMattermostLicenseSecret(namespaceName, create.License)
-
-	// Create the secret
-	_, err = kubeClient.Clientset.CoreV1().Secrets(namespaceName).Create(context.TODO(), licenseSecret, metav1.CreateOptions{})
-	if err != nil {
-		logger.FromContext(c.Ctx).Errorf("Error creating license secret:", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	if _, ok := c.CloudProvider.(providers.BucketProvisioner); !ok && create.FilestoreOption == model.FilestoreOptionAWSS3 {
+		return newNotImplementedError(c, "creating buckets")
 	}
 
-	filestoreSecret := create.GetMMOperatorFilestoreSecret(namespaceName)
-	if filestoreSecret != nil {
-		// Create the filestore secret
-		filestoreSecret, err = kubeClient.Clientset.CoreV1().Secrets(namespaceName).Create(context.TODO(), filestoreSecret, metav1.CreateOptions{})
+	if create.MinioFilestore != nil {
+		err := create.MinioFilestore.IsValid()
 		if err != nil {
-			logger.FromContext(c.Ctx).Errorf("Error creating filestore secret:", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
+			return model.NewInvalidRequestError("Invalid MinIO filestore config").Wrap(err)
 		}
 	}
 
-	filestore := create.GetMMOperatorFilestore(namespaceName, filestoreSecret)
-	if filestore.External != nil && filestore.External.Secret == "" && create.FilestoreSecretName != "" {
-		filestore.External.Secret = create.FilestoreSecretName
-	}
-
-	mattermostCRD := &mmv1beta1.Mattermost{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      namespaceName,
-			Namespace: namespaceName,
-		},
-		Spec: mmv1beta1.MattermostSpec{
-			Size:    create.Size,
-			Version: create.Version,
-			Ingress: &mmv1beta1.Ingress{
-				Enabled:      true,
-				Host:         create.FullDomainName,
-				IngressClass: aws.String("nginx"),
-				Annotations: map[string]string{
-					"kubernetes.io/ingress.class": "nginx",
-				},
-			},
-			Database: mmv1beta1.Database{
-				External: &mmv1beta1.ExternalDatabase{
-					Secret: func() string {
-						if databaseSecretName != "" {
-							return databaseSecretName
-						}
-						return model.SecretNameDatabase
-					}(),
-				},
-			},
-			FileStore: filestore,
-			MattermostEnv: []v1.EnvVar{
-				{Name: "MM_FILESETTINGS_AMAZONS3SSE", Value: "true"},
-				{Name: "MM_FILESETTINGS_AMAZONS3SSL", Value: "true"},
-				{Name: model.MMENVLicense, ValueFrom: &v1.EnvVarSource{
-					SecretKeyRef: &v1.SecretKeySelector{Key: "license", LocalObjectReference: v1.LocalObjectReference{Name: licenseSecret.ObjectMeta.Name}, Optional: aws.Bool(true)}, // Add comma to separate items
-				}},
-				{Name: "MM_CONFIG", ValueFrom: &v1.EnvVarSource{
-					SecretKeyRef: &v1.SecretKeySelector{
-						Key: "MM_CONFIG",
-						LocalObjectReference: v1.LocalObjectReference{Name: func() string {
-							if databaseSecretName != "" {
-								return databaseSecretName
-							}
-							return model.SecretNameDatabase
-						}()},
-					},
-				}},
-			},
-			PodTemplate: &mmv1beta1.PodTemplate{
-				SecurityContext: &v1.PodSecurityContext{
-					FSGroup: aws.Int64(2000),
-				},
-			},
-		},
+	if !create.IsValid() {
+		return model.NewInvalidRequestError("Invalid create mattermost workspace request")
 	}
 
-	// Create the Mattermost CRD
-	mattermost, err := kubeClient.MattermostClientsetV1Beta.MattermostV1beta1().Mattermosts(namespaceName).Create(context.TODO(), mattermostCRD, metav1.CreateOptions{})
-	if err != nil {
-		logger.FromContext(c.Ctx).Errorf("Error creating Mattermost CRD:", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	json.NewEncoder(w).Encode(mattermost)
+	return nil
 }
 
 func handleDeleteMattermostOperator(c *Context, w http.ResponseWriter, r *http.Request) {
-	c.Ctx = logger.WithField(c.Ctx, "action", "delete-mattermost")
-	c.Ctx = logger.WithNamespace(c.Ctx, "mattermost-operator")
 	vars := mux.Vars(r)
 	clusterName := vars["name"]
 	if clusterName == "" || clusterName == "undefined" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
 
-	c.Ctx = logger.WithClusterName(c.Ctx, clusterName)
-
-	helmClient, err := c.CloudProvider.HelmClient(c.Ctx, clusterName, "mattermost-operator")
-
+	err := DeleteMattermostOperator(c, clusterName)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to authenticate helm client")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to delete mattermost operator")
 		return
 	}
 
-	err = helmClient.UninstallReleaseByName("mattermost-operator")
-	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to delete mattermost-operator operator")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
 	w.WriteHeader(http.StatusOK)
 }
 
 func handleDeployMattermostOperator(c *Context, w http.ResponseWriter, r *http.Request) {
-	logger.FromContext(c.Ctx).Info("Getting kubeconfig")
 	vars := mux.Vars(r)
 	clusterName := vars["name"]
-
-	if clusterName == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	helmClient, err := c.CloudProvider.HelmClient(c.Ctx, clusterName, "mattermost-operator")
-	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to authenticate helm client")
-		w.WriteHeader(http.StatusInternalServerError)
+	if clusterName == "" || clusterName == "undefined" {
+		c.SetInvalidParam("name")
 		return
 	}
 
-	chartRepo := repo.Entry{Name: "mattermost", URL: "https://helm.mattermost.com"}
-
-	err = helmClient.AddOrUpdateChartRepo(chartRepo)
-
+	request, err := model.NewInstallAddonRequestFromReader(r.Body)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to add or update chart repo")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.Err = model.NewInvalidRequestError("Failed to parse deploy operator request").Wrap(err)
 		return
 	}
+	defer r.Body.Close()
 
-	chartSpec := helmclient.ChartSpec{
-		ReleaseName: "mattermost-operator",
-		ChartName:   "mattermost/mattermost-operator",
-		Namespace:   "mattermost-operator",
-		UpgradeCRDs: true,
-		// Version:         "1.25.2",
-		Wait:            true,
-		Timeout:         300 * time.Second,
-		CreateNamespace: true,
-		CleanupOnFail:   true,
-	}
-
-	// Install a chart release.
-	// Note that helmclient.Options.Namespace should ideally match the namespace in chartSpec.Namespace.
-	if _, err := helmClient.InstallOrUpgradeChart(context.Background(), &chartSpec, nil); err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to install mattermost operator")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	w.WriteHeader(http.StatusCreated)
+	job := &model.Job{Type: model.JobTypeDeployOperator, ClusterName: clusterName, Target: "mattermost-operator"}
+	startJob(c, w, job, func(c *Context) (interface{}, error) {
+		return nil, DeployMattermostOperator(c, clusterName, request)
+	})
 }
 
 func handleGetMattermostInstallations(c *Context, w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	clusterName := vars["name"]
 	if clusterName == "" || clusterName == "undefined" {
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
-	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to create clientset")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetInvalidParam("name")
 		return
 	}
 
-	installations, err := kubeClient.MattermostClientsetV1Beta.MattermostV1beta1().Mattermosts("").List(context.Background(), metav1.ListOptions{})
+	installations, err := ListMattermostInstallations(c, clusterName)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to list mattermost installations")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to list mattermost installations")
 		return
 	}
 
-	json.NewEncoder(w).Encode(installations.Items)
+	json.NewEncoder(w).Encode(installations)
 }
 
 var upgrader = websocket.Upgrader{
@@ -1323,8 +941,7 @@ func handleInstallationLogsWebsocket(c *Context, w http.ResponseWriter, r *http.
 	logger.FromContext(c.Ctx).Info("Attempting to upgrade HTTP request to websocket connection")
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to upgrade websocket connection")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to upgrade websocket connection")
 		return
 	}
 
@@ -1337,30 +954,26 @@ func handleInstallationLogsWebsocket(c *Context, w http.ResponseWriter, r *http.
 	vars := mux.Vars(r)
 	installationName := vars["installationName"]
 	if installationName == "" {
-		logger.FromContext(c.Ctx).Error("No installation name provided")
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("installationName")
 		return
 	}
 
 	clusterName := vars["name"]
 	if clusterName == "" {
-		logger.FromContext(c.Ctx).Error("No cluster name provided")
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
 
 	pods := query["pods"]
 	if len(pods) == 0 {
-		logger.FromContext(c.Ctx).Error("No pods provided")
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("pods")
 		return
 	}
 
 	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
 
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to create clientset")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to create clientset")
 		return
 	}
 
@@ -1414,27 +1027,25 @@ func handleGetPodsForNamespace(c *Context, w http.ResponseWriter, r *http.Reques
 	vars := mux.Vars(r)
 	clusterName := vars["name"]
 	if clusterName == "" || clusterName == "undefined" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("name")
 		return
 	}
 
 	namespace := vars["installationName"]
 	if namespace == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		c.SetInvalidParam("installationName")
 		return
 	}
 
 	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to create clientset")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to create clientset")
 		return
 	}
 
 	kubePods, err := kubeClient.Clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
-		logger.FromContext(c.Ctx).WithError(err).Error("Failed to list pods")
-		w.WriteHeader(http.StatusInternalServerError)
+		c.SetError(err, "Failed to list pods")
 		return
 	}
 
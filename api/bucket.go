@@ -0,0 +1,83 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	v1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// irsaRoleAnnotation is the service account annotation that EKS uses to give pods an IAM role.
+const irsaRoleAnnotation = "eks.amazonaws.com/role-arn"
+
+// CreateFilestoreBucket provisions a bucket for an installation with the cluster's provider. With IRSA access, the
+// installation's service account is created with the bucket's role, since the Mattermost operator expects it to
+// exist when the filestore uses a service account. The access key in the installation's filestore secret, if any, is
+// passed on so that the bucket's IAM user keeps it.
+func CreateFilestoreBucket(c *Context, kubeClient *model.KubeClient, clusterName string, create *model.CreateBucketRequest) (*model.Bucket, error) {
+	provisioner, ok := c.CloudProvider.(providers.BucketProvisioner)
+	if !ok {
+		return nil, newNotImplementedError(c, "creating buckets")
+	}
+
+	namespace := model.InstallationNamespace(create.InstallationName)
+	withAccessKey := *create
+	secret, err := kubeClient.Clientset.CoreV1().Secrets(namespace).Get(c.Ctx, model.SecretNameFilestore, metav1.GetOptions{})
+	if err == nil {
+		withAccessKey.AccessKeyID = string(secret.Data["accesskey"])
+		withAccessKey.SecretAccessKey = string(secret.Data["secretkey"])
+	} else if !apiErrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get filestore secret: %w", err)
+	}
+
+	bucket, err := provisioner.CreateBucket(c.Ctx, clusterName, &withAccessKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if bucket.RoleARN != "" {
+		err = ensureNamespace(c, kubeClient, namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		err = applyIRSAServiceAccount(c, kubeClient, namespace, namespace, bucket.RoleARN)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return bucket, nil
+}
+
+// applyIRSAServiceAccount creates a service account with an IAM role, or sets the role of an existing one.
+func applyIRSAServiceAccount(c *Context, kubeClient *model.KubeClient, namespace, name, roleARN string) error {
+	serviceAccounts := kubeClient.Clientset.CoreV1().ServiceAccounts(namespace)
+	serviceAccount, err := serviceAccounts.Get(c.Ctx, name, metav1.GetOptions{})
+	if apiErrors.IsNotFound(err) {
+		serviceAccount = &v1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   namespace,
+				Annotations: map[string]string{irsaRoleAnnotation: roleARN},
+			},
+		}
+		_, err = serviceAccounts.Create(c.Ctx, serviceAccount, metav1.CreateOptions{})
+	} else if err == nil {
+		if serviceAccount.Annotations == nil {
+			serviceAccount.Annotations = map[string]string{}
+		}
+		serviceAccount.Annotations[irsaRoleAnnotation] = roleARN
+		_, err = serviceAccounts.Update(c.Ctx, serviceAccount, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply service account %s: %w", name, err)
+	}
+	logger.FromContext(c.Ctx).Infof("Service account %s uses IAM role %s", name, roleARN)
+
+	return nil
+}
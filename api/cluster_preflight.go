@@ -0,0 +1,467 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	mmv1alpha1 "github.com/mattermost/mattermost-operator/apis/mattermost/v1alpha1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+const (
+	ingressClassDefaultAnnotation  = "ingressclass.kubernetes.io/is-default-class"
+	helmReleaseNameAnnotation      = "meta.helm.sh/release-name"
+	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+)
+
+// storageClassDefaultAnnotations mark the default storage class, in their current and beta forms.
+var storageClassDefaultAnnotations = []string{"storageclass.kubernetes.io/is-default-class", "storageclass.beta.kubernetes.io/is-default-class"}
+
+// preflightPermissions are what deploying the add-ons and installations needs cluster-wide, which the permissions
+// check asks the API server about for the bootstrapper's credentials.
+var preflightPermissions = []authorizationv1.ResourceAttributes{
+	{Verb: "create", Resource: "namespaces"},
+	{Verb: "create", Resource: "secrets"},
+	{Verb: "create", Resource: "services"},
+	{Verb: "create", Group: "apps", Resource: "deployments"},
+	{Verb: "create", Group: "batch", Resource: "jobs"},
+	{Verb: "create", Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions"},
+	{Verb: "create", Group: "rbac.authorization.k8s.io", Resource: "clusterroles"},
+	{Verb: "create", Group: "rbac.authorization.k8s.io", Resource: "clusterrolebindings"},
+	{Verb: "create", Group: "admissionregistration.k8s.io", Resource: "validatingwebhookconfigurations"},
+}
+
+// ClusterPreflight inspects a cluster before the named add-ons and their dependencies are deployed into it, and
+// before an installation of the given size. The required add-ons are checked when none are named, and the 100users
+// size when none is given.
+func ClusterPreflight(c *Context, clusterName, size string, addonNames []string) (*model.ClusterPreflightReport, error) {
+	if len(addonNames) == 0 {
+		addonNames = RequiredAddonNames()
+	}
+
+	addons := []Addon{}
+	selected := map[string]bool{}
+	for _, name := range addonNames {
+		order, err := AddonInstallOrder(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, addon := range order {
+			if !selected[addon.Name] {
+				selected[addon.Name] = true
+				addons = append(addons, addon)
+			}
+		}
+	}
+
+	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	return RunClusterPreflight(c, kubeClient, size, addons)
+}
+
+// RunClusterPreflight runs the checks of the cluster pre-flight report. Checks that can't read what they inspect
+// warn rather than fail, since the bootstrapper's credentials may only lack permission to read it.
+func RunClusterPreflight(c *Context, kubeClient *model.KubeClient, size string, addons []Addon) (*model.ClusterPreflightReport, error) {
+	if size == "" {
+		size = mmv1alpha1.Size100String
+	}
+	clusterSize, err := mmv1alpha1.GetClusterSize(size)
+	if err != nil {
+		return nil, model.NewInvalidRequestError(fmt.Sprintf("Unknown installation size %q", size))
+	}
+
+	checks := []model.PreflightCheck{
+		kubernetesVersionCheck(kubeClient, addons),
+		defaultStorageClassCheck(c, kubeClient),
+		nodeCapacityCheck(c, kubeClient, size, clusterSize),
+		loadBalancerCheck(c, kubeClient),
+		ingressClassesCheck(c, kubeClient, addons),
+		crdsCheck(c, kubeClient, addons),
+		permissionsCheck(c, kubeClient),
+	}
+
+	return model.NewClusterPreflightReport(checks), nil
+}
+
+// kubernetesVersionCheck fails when the cluster is older than an add-on supports, and warns when it is newer than
+// an add-on has been tested with.
+func kubernetesVersionCheck(kubeClient *model.KubeClient, addons []Addon) model.PreflightCheck {
+	check := model.PreflightCheck{Name: model.PreflightCheckKubernetesVersion}
+
+	info, err := kubeClient.Clientset.Discovery().ServerVersion()
+	if err != nil {
+		return warnPreflight(check, "Failed to get the Kubernetes version: %s", err)
+	}
+	serverVersion, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return warnPreflight(check, "Failed to parse Kubernetes version %s: %s", info.GitVersion, err)
+	}
+
+	unsupported := []string{}
+	untested := []string{}
+	for _, addon := range addons {
+		if addon.MinKubernetesVersion != "" && serverVersion.LessThan(version.MustParseGeneric(addon.MinKubernetesVersion)) {
+			unsupported = append(unsupported, fmt.Sprintf("%s needs %s or later", addon.Name, addon.MinKubernetesVersion))
+		}
+		if addon.MaxKubernetesVersion != "" {
+			maxVersion := version.MustParseGeneric(addon.MaxKubernetesVersion)
+			if serverVersion.Major() > maxVersion.Major() || (serverVersion.Major() == maxVersion.Major() && serverVersion.Minor() > maxVersion.Minor()) {
+				untested = append(untested, fmt.Sprintf("%s is tested up to %s", addon.Name, addon.MaxKubernetesVersion))
+			}
+		}
+	}
+
+	switch {
+	case len(unsupported) > 0:
+		check.Status = model.PreflightCheckFailed
+		check.Message = fmt.Sprintf("Kubernetes %s is too old: %s", info.GitVersion, strings.Join(unsupported, ", "))
+	case len(untested) > 0:
+		check.Status = model.PreflightCheckWarning
+		check.Message = fmt.Sprintf("Kubernetes %s is newer than tested: %s", info.GitVersion, strings.Join(untested, ", "))
+	default:
+		check.Status = model.PreflightCheckPassed
+		check.Message = fmt.Sprintf("Kubernetes %s is supported", info.GitVersion)
+	}
+	return check
+}
+
+// defaultStorageClassCheck fails without a default storage class, which CNPG databases and in-cluster filestores
+// need for their volumes.
+func defaultStorageClassCheck(c *Context, kubeClient *model.KubeClient) model.PreflightCheck {
+	check := model.PreflightCheck{Name: model.PreflightCheckDefaultStorageClass}
+
+	storageClasses, err := kubeClient.Clientset.StorageV1().StorageClasses().List(c.Ctx, metav1.ListOptions{})
+	if err != nil {
+		return warnPreflight(check, "Failed to list storage classes: %s", err)
+	}
+
+	defaults := []string{}
+	for _, storageClass := range storageClasses.Items {
+		for _, annotation := range storageClassDefaultAnnotations {
+			if storageClass.Annotations[annotation] == "true" {
+				defaults = append(defaults, fmt.Sprintf("%s (%s)", storageClass.Name, storageClass.Provisioner))
+				break
+			}
+		}
+	}
+
+	switch {
+	case len(defaults) == 1:
+		check.Status = model.PreflightCheckPassed
+		check.Message = "Default storage class " + defaults[0]
+	case len(defaults) > 1:
+		check.Status = model.PreflightCheckWarning
+		check.Message = fmt.Sprintf("More than one default storage class, the newest is used: %s", strings.Join(defaults, ", "))
+	default:
+		check.Status = model.PreflightCheckFailed
+		check.Message = "No default storage class, which CNPG databases and in-cluster filestores need for their volumes"
+	}
+	return check
+}
+
+// nodeCapacityCheck compares the CPU and memory requests of an installation of the size, and its database, with
+// what the ready and schedulable nodes have allocatable and left over after the requests of their pods.
+func nodeCapacityCheck(c *Context, kubeClient *model.KubeClient, size string, clusterSize mmv1alpha1.ClusterInstallationSize) model.PreflightCheck {
+	check := model.PreflightCheck{Name: model.PreflightCheckNodeCapacity}
+	neededCPU, neededMemory := clusterSize.CalculateResourceMilliRequirements(true, false)
+
+	nodes, err := kubeClient.Clientset.CoreV1().Nodes().List(c.Ctx, metav1.ListOptions{})
+	if err != nil {
+		return warnPreflight(check, "Failed to list nodes: %s", err)
+	}
+
+	schedulable := map[string]bool{}
+	var allocatableCPU, allocatableMemory int64
+	for _, node := range nodes.Items {
+		if !nodeAcceptsPods(node) {
+			continue
+		}
+		schedulable[node.Name] = true
+		allocatableCPU += node.Status.Allocatable.Cpu().MilliValue()
+		allocatableMemory += node.Status.Allocatable.Memory().MilliValue()
+	}
+	if len(schedulable) == 0 {
+		check.Status = model.PreflightCheckFailed
+		check.Message = "No nodes are ready to run pods"
+		return check
+	}
+
+	pods, err := kubeClient.Clientset.CoreV1().Pods("").List(c.Ctx, metav1.ListOptions{})
+	if err != nil {
+		return warnPreflight(check, "Failed to list pods: %s", err)
+	}
+
+	freeCPU, freeMemory := allocatableCPU, allocatableMemory
+	for _, pod := range pods.Items {
+		if !schedulable[pod.Spec.NodeName] || pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			freeCPU -= container.Resources.Requests.Cpu().MilliValue()
+			freeMemory -= container.Resources.Requests.Memory().MilliValue()
+		}
+	}
+
+	needed := fmt.Sprintf("The %s size requests %s CPU and %s of memory", size, formatMilliCPU(neededCPU), formatMilliMemory(neededMemory))
+	switch {
+	case allocatableCPU < neededCPU || allocatableMemory < neededMemory:
+		check.Status = model.PreflightCheckFailed
+		check.Message = fmt.Sprintf("%s, but the %d schedulable nodes only have %s CPU and %s allocatable", needed, len(schedulable), formatMilliCPU(allocatableCPU), formatMilliMemory(allocatableMemory))
+	case freeCPU < neededCPU || freeMemory < neededMemory:
+		check.Status = model.PreflightCheckWarning
+		check.Message = fmt.Sprintf("%s, but only %s CPU and %s are free on the %d schedulable nodes", needed, formatMilliCPU(freeCPU), formatMilliMemory(freeMemory), len(schedulable))
+	default:
+		check.Status = model.PreflightCheckPassed
+		check.Message = fmt.Sprintf("%s, and %s CPU and %s are free on the %d schedulable nodes", needed, formatMilliCPU(freeCPU), formatMilliMemory(freeMemory), len(schedulable))
+	}
+	return check
+}
+
+// nodeAcceptsPods reports whether a node is ready, schedulable and untainted, so that pods without tolerations can
+// run on it.
+func nodeAcceptsPods(node v1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Effect == v1.TaintEffectNoSchedule || taint.Effect == v1.TaintEffectNoExecute {
+			return false
+		}
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func formatMilliCPU(milli int64) string {
+	return fmt.Sprintf("%.1f", float64(milli)/1000)
+}
+
+func formatMilliMemory(milli int64) string {
+	return fmt.Sprintf("%.1fGi", float64(milli)/1000/(1<<30))
+}
+
+// loadBalancerCheck looks for evidence that the cluster provisions the load balancer of ingress-nginx: a provider
+// that knows how to expose it, or existing LoadBalancer services that got an address.
+func loadBalancerCheck(c *Context, kubeClient *model.KubeClient) model.PreflightCheck {
+	check := model.PreflightCheck{Name: model.PreflightCheckLoadBalancer}
+
+	values, err := NginxValues(c, nil)
+	if err == nil && nginxServiceType(values) == string(v1.ServiceTypeNodePort) {
+		check.Status = model.PreflightCheckPassed
+		check.Message = "ingress-nginx is exposed on a node port, so no load balancer is needed"
+		return check
+	}
+
+	services, err := kubeClient.Clientset.CoreV1().Services("").List(c.Ctx, metav1.ListOptions{})
+	if err != nil {
+		return warnPreflight(check, "Failed to list services: %s", err)
+	}
+
+	provisioned := 0
+	pending := []string{}
+	for _, service := range services.Items {
+		if service.Spec.Type != v1.ServiceTypeLoadBalancer {
+			continue
+		}
+		if len(service.Status.LoadBalancer.Ingress) > 0 {
+			provisioned++
+		} else {
+			pending = append(pending, service.Namespace+"/"+service.Name)
+		}
+	}
+
+	_, providerDefaults := c.CloudProvider.(providers.IngressDefaulter)
+	switch {
+	case provisioned > 0:
+		check.Status = model.PreflightCheckPassed
+		check.Message = fmt.Sprintf("%d LoadBalancer services have an address", provisioned)
+	case len(pending) > 0:
+		check.Status = model.PreflightCheckWarning
+		check.Message = fmt.Sprintf("LoadBalancer services have no address, the cluster may not provision load balancers: %s", strings.Join(pending, ", "))
+	case providerDefaults:
+		check.Status = model.PreflightCheckPassed
+		check.Message = fmt.Sprintf("The %s provider provisions load balancers", c.CloudProviderName)
+	default:
+		check.Status = model.PreflightCheckWarning
+		check.Message = "No LoadBalancer services show whether the cluster provisions load balancers, which ingress-nginx needs"
+	}
+	return check
+}
+
+// nginxServiceType returns the type of the controller service in ingress-nginx values, which is empty for the
+// chart's default of LoadBalancer.
+func nginxServiceType(values map[string]interface{}) string {
+	controller, _ := values["controller"].(map[string]interface{})
+	service, _ := controller["service"].(map[string]interface{})
+	serviceType, _ := service["type"].(string)
+	return serviceType
+}
+
+// ingressClassesCheck lists the existing ingress classes, and warns when the nginx class that ingress-nginx creates
+// already belongs to another controller.
+func ingressClassesCheck(c *Context, kubeClient *model.KubeClient, addons []Addon) model.PreflightCheck {
+	check := model.PreflightCheck{Name: model.PreflightCheckIngressClasses}
+
+	classes, err := kubeClient.Clientset.NetworkingV1().IngressClasses().List(c.Ctx, metav1.ListOptions{})
+	if err != nil {
+		return warnPreflight(check, "Failed to list ingress classes: %s", err)
+	}
+
+	nginx, deploysNginx := findAddon(addons, "ingress-nginx")
+	names := []string{}
+	for _, class := range classes.Items {
+		name := class.Name
+		if class.Annotations[ingressClassDefaultAnnotation] == "true" {
+			name += " (default)"
+		}
+		names = append(names, name)
+
+		if deploysNginx && class.Name == "nginx" && !managedByRelease(class.ObjectMeta, nginx) {
+			check.Status = model.PreflightCheckWarning
+			check.Message = fmt.Sprintf("Ingress class nginx already exists for controller %s, and ingress-nginx can't create it", class.Spec.Controller)
+			return check
+		}
+	}
+
+	check.Status = model.PreflightCheckPassed
+	if len(names) == 0 {
+		check.Message = "No ingress classes yet"
+		return check
+	}
+	sort.Strings(names)
+	check.Message = "Ingress classes: " + strings.Join(names, ", ")
+	return check
+}
+
+// crdsCheck warns about CRDs of the add-ons that exist but weren't installed by the add-on's release, such as
+// ones from another installation of the same operator.
+func crdsCheck(c *Context, kubeClient *model.KubeClient, addons []Addon) model.PreflightCheck {
+	check := model.PreflightCheck{Name: model.PreflightCheckCRDs}
+
+	conflicts := []string{}
+	for _, addon := range addons {
+		for _, name := range addon.CRDs {
+			crd, err := kubeClient.ApixClientset.ApiextensionsV1().CustomResourceDefinitions().Get(c.Ctx, name, metav1.GetOptions{})
+			if apiErrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return warnPreflight(check, "Failed to get CRD %s: %s", name, err)
+			}
+			if managedByRelease(crd.ObjectMeta, addon) {
+				continue
+			}
+
+			owner := "outside of helm"
+			if release := crd.Annotations[helmReleaseNameAnnotation]; release != "" {
+				owner = fmt.Sprintf("by release %s/%s", crd.Annotations[helmReleaseNamespaceAnnotation], release)
+			}
+			conflicts = append(conflicts, fmt.Sprintf("%s of %s, installed %s", name, addon.Name, owner))
+		}
+	}
+
+	if len(conflicts) > 0 {
+		check.Status = model.PreflightCheckWarning
+		check.Message = "CRDs exist from another source, which can block or conflict with the add-ons: " + strings.Join(conflicts, ", ")
+		return check
+	}
+
+	check.Status = model.PreflightCheckPassed
+	check.Message = "No conflicting CRDs"
+	return check
+}
+
+// managedByRelease reports whether helm installed an object as part of an add-on's release.
+func managedByRelease(meta metav1.ObjectMeta, addon Addon) bool {
+	return meta.Annotations[helmReleaseNameAnnotation] == addon.ReleaseName && meta.Annotations[helmReleaseNamespaceAnnotation] == addon.Namespace
+}
+
+func findAddon(addons []Addon, name string) (Addon, bool) {
+	for _, addon := range addons {
+		if addon.Name == name {
+			return addon, true
+		}
+	}
+	return Addon{}, false
+}
+
+// permissionsCheck asks the API server whether the bootstrapper's credentials can create what the add-ons and
+// installations need, with a SelfSubjectAccessReview for each.
+func permissionsCheck(c *Context, kubeClient *model.KubeClient) model.PreflightCheck {
+	check := model.PreflightCheck{Name: model.PreflightCheckPermissions}
+
+	denied := []string{}
+	for _, attributes := range preflightPermissions {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attributes},
+		}
+		result, err := kubeClient.Clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(c.Ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return warnPreflight(check, "Failed to review permissions: %s", err)
+		}
+		if !result.Status.Allowed {
+			resource := attributes.Resource
+			if attributes.Group != "" {
+				resource += "." + attributes.Group
+			}
+			denied = append(denied, attributes.Verb+" "+resource)
+		}
+	}
+
+	if len(denied) > 0 {
+		check.Status = model.PreflightCheckFailed
+		check.Message = "Missing cluster-wide permissions: " + strings.Join(denied, ", ")
+		return check
+	}
+
+	check.Status = model.PreflightCheckPassed
+	check.Message = "Has the cluster-wide permissions the add-ons need"
+	return check
+}
+
+// warnPreflight returns the check as a warning, for when what it inspects couldn't be read.
+func warnPreflight(check model.PreflightCheck, format string, args ...interface{}) model.PreflightCheck {
+	check.Status = model.PreflightCheckWarning
+	check.Message = fmt.Sprintf(format, args...)
+	return check
+}
+
+func handleGetClusterPreflight(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName := vars["name"]
+	if clusterName == "" || clusterName == "undefined" {
+		c.SetInvalidParam("name")
+		return
+	}
+
+	addonNames := []string{}
+	if addons := r.URL.Query().Get("addons"); addons != "" {
+		addonNames = strings.Split(addons, ",")
+	}
+
+	report, err := ClusterPreflight(c, clusterName, r.URL.Query().Get("size"), addonNames)
+	if err != nil {
+		c.SetError(err, "Failed to inspect cluster")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
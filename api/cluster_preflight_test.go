@@ -0,0 +1,133 @@
+package api_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apixv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apixfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRunClusterPreflight(t *testing.T) {
+	c := &api.Context{Ctx: context.Background(), CloudProviderName: "aws", CloudProvider: providers.GetAWSProvider(nil)}
+
+	addons := []api.Addon{}
+	for _, name := range api.RequiredAddonNames() {
+		addon, ok := api.GetAddon(name)
+		require.True(t, ok)
+		addons = append(addons, addon)
+	}
+
+	node := func(cpu, memory string) *v1.Node {
+		return &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-" + cpu},
+			Status: v1.NodeStatus{
+				Allocatable: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu), v1.ResourceMemory: resource.MustParse(memory)},
+				Conditions:  []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+			},
+		}
+	}
+
+	// newKubeClient returns clients of a cluster at the Kubernetes version, where the bootstrapper can't create the
+	// denied resources
+	newKubeClient := func(gitVersion string, denied string, objects ...runtime.Object) (*model.KubeClient, *apixfake.Clientset) {
+		clientset := fake.NewSimpleClientset(objects...)
+		clientset.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: gitVersion}
+		clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+			review.Status.Allowed = review.Spec.ResourceAttributes.Resource != denied
+			return true, review, nil
+		})
+		apixClientset := apixfake.NewSimpleClientset()
+		return &model.KubeClient{Clientset: clientset, ApixClientset: apixClientset}, apixClientset
+	}
+
+	statuses := func(report *model.ClusterPreflightReport) map[string]string {
+		result := map[string]string{}
+		for _, check := range report.Checks {
+			result[check.Name] = check.Status
+		}
+		return result
+	}
+
+	t.Run("ready cluster", func(t *testing.T) {
+		defaultClass := &storagev1.StorageClass{
+			ObjectMeta:  metav1.ObjectMeta{Name: "gp2", Annotations: map[string]string{"storageclass.kubernetes.io/is-default-class": "true"}},
+			Provisioner: "kubernetes.io/aws-ebs",
+		}
+		kubeClient, _ := newKubeClient("v1.29.3-eks-adc7111", "", defaultClass, node("4", "16Gi"))
+
+		report, err := api.RunClusterPreflight(c, kubeClient, "", addons)
+		require.NoError(t, err)
+		assert.Equal(t, model.PreflightCheckPassed, report.Status, report.Checks)
+		assert.Len(t, report.Checks, 7)
+	})
+
+	t.Run("unprepared cluster", func(t *testing.T) {
+		nginxClass := &networkingv1.IngressClass{ObjectMeta: metav1.ObjectMeta{Name: "nginx"}, Spec: networkingv1.IngressClassSpec{Controller: "example.com/other"}}
+		pendingService := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Spec: v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer}}
+		kubeClient, apixClientset := newKubeClient("v1.25.16", "clusterroles", node("100m", "512Mi"), nginxClass, pendingService)
+		_, err := apixClientset.ApiextensionsV1().CustomResourceDefinitions().Create(context.Background(), &apixv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "clusters.postgresql.cnpg.io", Annotations: map[string]string{"meta.helm.sh/release-name": "cnpg", "meta.helm.sh/release-namespace": "other"}},
+		}, metav1.CreateOptions{})
+		require.NoError(t, err)
+
+		report, err := api.RunClusterPreflight(c, kubeClient, "1000users", addons)
+		require.NoError(t, err)
+		assert.Equal(t, model.PreflightCheckFailed, report.Status)
+		assert.Equal(t, map[string]string{
+			model.PreflightCheckKubernetesVersion:   model.PreflightCheckFailed,
+			model.PreflightCheckDefaultStorageClass: model.PreflightCheckFailed,
+			model.PreflightCheckNodeCapacity:        model.PreflightCheckFailed,
+			model.PreflightCheckLoadBalancer:        model.PreflightCheckWarning,
+			model.PreflightCheckIngressClasses:      model.PreflightCheckWarning,
+			model.PreflightCheckCRDs:                model.PreflightCheckWarning,
+			model.PreflightCheckPermissions:         model.PreflightCheckFailed,
+		}, statuses(report))
+
+		for _, check := range report.Checks {
+			switch check.Name {
+			case model.PreflightCheckKubernetesVersion:
+				assert.Contains(t, check.Message, "cnpg needs 1.26 or later")
+			case model.PreflightCheckCRDs:
+				assert.Contains(t, check.Message, "clusters.postgresql.cnpg.io of cnpg, installed by release other/cnpg")
+			case model.PreflightCheckPermissions:
+				assert.Equal(t, "Missing cluster-wide permissions: create clusterroles.rbac.authorization.k8s.io", check.Message)
+			}
+		}
+	})
+
+	t.Run("newer Kubernetes than tested", func(t *testing.T) {
+		kubeClient, _ := newKubeClient("v1.31.0", "")
+
+		report, err := api.RunClusterPreflight(c, kubeClient, "", addons)
+		require.NoError(t, err)
+		assert.Equal(t, model.PreflightCheckWarning, statuses(report)[model.PreflightCheckKubernetesVersion])
+	})
+
+	t.Run("unknown size", func(t *testing.T) {
+		kubeClient, _ := newKubeClient("v1.29.0", "")
+
+		_, err := api.RunClusterPreflight(c, kubeClient, "lots", addons)
+		var appErr *model.AppError
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, http.StatusBadRequest, appErr.StatusCode)
+	})
+}
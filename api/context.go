@@ -17,6 +17,10 @@ type BootstrapperState struct {
 	Credentials   *model.Credentials   `json:"credentials"`
 	StateFilePath string               `json:"stateFilePath"`
 	Telemetry     model.TelemetryState `json:"telemetry"`
+	// Addons records the chart version of each add-on the bootstrapper deployed, by cluster and add-on name.
+	Addons map[string]map[string]string `json:"addons,omitempty"`
+	// Mirror configures internal chart and image mirrors for air-gapped clusters.
+	Mirror *model.MirrorConfig `json:"mirror,omitempty"`
 	// TODO: Support setting a KubeConfigPath via CLI flag or env var for authentication
 	// KubeConfigPath string `json:"kubeConfigPath"`
 }
@@ -29,6 +33,13 @@ type Context struct {
 	CloudProviderName string
 	CloudProvider     providers.CloudProvider
 	BootstrapperState BootstrapperState
+	// Jobs runs long-running operations in the background. It is only set when running the server.
+	Jobs *JobManager
+	// Err is set by a handler that failed, and is written as the JSON error response.
+	Err *model.AppError
+
+	// root is the server's context that this one was cloned from, whose state later requests start with.
+	root *Context
 }
 
 func NewContext(ctx context.Context, statePath string, telemetryDisabled bool) (*Context, error) {
@@ -81,6 +92,14 @@ func NewContext(ctx context.Context, statePath string, telemetryDisabled bool) (
 }
 
 func (c *Context) Clone() *Context {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	root := c.root
+	if root == nil {
+		root = c
+	}
+
 	return &Context{
 		RequestID:         c.RequestID,
 		Ctx:               c.Ctx,
@@ -88,9 +107,35 @@ func (c *Context) Clone() *Context {
 		CloudProviderName: c.CloudProviderName,
 		CloudProvider:     c.CloudProvider,
 		BootstrapperState: c.BootstrapperState,
+		Jobs:              c.Jobs,
+		root:              root,
 	}
 }
 
+// setState replaces the in-memory state of the context and of the context it was cloned from, so that a state that
+// was just saved, such as new mirror settings, applies to later requests without a restart.
+func (c *Context) setState(state BootstrapperState) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	state.StateFilePath = c.BootstrapperState.StateFilePath
+	c.BootstrapperState = state
+	if c.root != nil {
+		c.root.BootstrapperState = state
+	}
+}
+
+// SetInvalidParam fails the request with a 400 for a missing or malformed parameter.
+func (c *Context) SetInvalidParam(param string) {
+	c.Err = model.NewInvalidParamError(param)
+}
+
+// SetError fails the request with err translated to an AppError. The message describes what failed, and is used
+// unless err already is an AppError.
+func (c *Context) SetError(err error, message string) {
+	c.Err = providers.ToAppError(err, message)
+}
+
 func (bs BootstrapperState) Merge(newState BootstrapperState) BootstrapperState {
 	if newState.Provider != "" {
 		bs.Provider = newState.Provider
@@ -101,6 +146,9 @@ func (bs BootstrapperState) Merge(newState BootstrapperState) BootstrapperState
 	if newState.Credentials != nil {
 		bs.Credentials = newState.Credentials
 	}
+	if newState.Mirror != nil {
+		bs.Mirror = newState.Mirror
+	}
 	return bs
 }
 
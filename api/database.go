@@ -0,0 +1,435 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	cnpgv1 "github.com/cloudnative-pg/cloudnative-pg/api/v1"
+	"github.com/cloudnative-pg/cloudnative-pg/pkg/versions"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	v1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// cnpgClusterReadyTimeout is how long a new CNPG cluster has to become healthy, which includes provisioning its
+	// volumes and cloning its replicas.
+	cnpgClusterReadyTimeout = 15 * time.Minute
+	cnpgSecretTimeout       = 2 * time.Minute
+)
+
+var (
+	cnpgClusterGVR = schema.GroupVersionResource{Group: "postgresql.cnpg.io", Version: "v1", Resource: "clusters"}
+	cnpgPoolerGVR  = schema.GroupVersionResource{Group: "postgresql.cnpg.io", Version: "v1", Resource: "poolers"}
+)
+
+// cnpgClusterName is the name of the CNPG cluster created for an installation.
+func cnpgClusterName(namespaceName string) string {
+	return namespaceName + "-cnpg-cluster"
+}
+
+// cnpgPoolerName is the name of the PgBouncer pooler in front of the primary of a CNPG cluster, which is also the
+// name of its service.
+func cnpgPoolerName(clusterName string) string {
+	return clusterName + "-pooler-rw"
+}
+
+// cnpgImageName returns the PostgreSQL image for a major version, moved to the mirror registry if one is set. It is
+// empty when the operator's default image can be used as is.
+func cnpgImageName(c *Context, postgresVersion string) string {
+	image := versions.DefaultImageName
+	if postgresVersion != "" {
+		repository, _, _ := strings.Cut(versions.DefaultImageName, ":")
+		image = repository + ":" + postgresVersion
+	}
+
+	registry := mirrorConfig(c).ImageRegistry
+	if registry != "" {
+		return MirrorImage(registry, image)
+	}
+	if postgresVersion == "" {
+		return ""
+	}
+	return image
+}
+
+// newCNPGCluster builds a CNPG cluster from a database config that has its defaults set.
+func newCNPGCluster(c *Context, name, namespace string, config model.CNPGDatabaseConfig) *cnpgv1.Cluster {
+	cluster := &cnpgv1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: cnpgv1.ClusterSpec{
+			Instances:            config.Instances,
+			ImageName:            cnpgImageName(c, config.PostgresVersion),
+			StorageConfiguration: cnpgv1.StorageConfiguration{Size: config.StorageSize},
+			PostgresConfiguration: cnpgv1.PostgresConfiguration{
+				Parameters: config.Parameters,
+			},
+		},
+	}
+	if config.StorageClass != "" {
+		cluster.Spec.StorageConfiguration.StorageClass = &config.StorageClass
+	}
+	if config.Resources != nil {
+		cluster.Spec.Resources = *config.Resources
+	}
+	if config.Backup != nil {
+		cluster.Spec.Backup = cnpgBackupConfiguration(name, *config.Backup)
+	}
+
+	return cluster
+}
+
+// newCNPGPooler builds the PgBouncer pooler in front of the primary of a CNPG cluster.
+func newCNPGPooler(cluster *cnpgv1.Cluster, config model.CNPGPoolerConfig) *cnpgv1.Pooler {
+	instances := int32(config.Instances)
+	return &cnpgv1.Pooler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cnpgPoolerName(cluster.Name),
+			Namespace: cluster.Namespace,
+		},
+		Spec: cnpgv1.PoolerSpec{
+			Cluster:   cnpgv1.LocalObjectReference{Name: cluster.Name},
+			Type:      cnpgv1.PoolerTypeRW,
+			Instances: &instances,
+			PgBouncer: &cnpgv1.PgBouncerSpec{
+				PoolMode:   cnpgv1.PgBouncerPoolMode(config.PoolMode),
+				Parameters: config.Parameters,
+			},
+		},
+	}
+}
+
+// createCNPGResource creates a CNPG custom resource through the dynamic client.
+func createCNPGResource(c *Context, kubeClient *model.KubeClient, gvr schema.GroupVersionResource, kind string, obj metav1.Object) error {
+	unstructuredObj, err := model.ConvertToUnstructured(obj)
+	if err != nil {
+		return fmt.Errorf("failed to convert to unstructured: %w", err)
+	}
+
+	unstructuredObj.Object["apiVersion"] = "postgresql.cnpg.io/v1"
+	unstructuredObj.Object["kind"] = kind
+
+	_, err = kubeClient.DynamicClient.Resource(gvr).Namespace(obj.GetNamespace()).Create(c.Ctx, unstructuredObj, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to create %s %s: %w", kind, obj.GetName(), err)
+	}
+
+	return nil
+}
+
+// CreateCNPGDatabase creates a CNPG cluster, with its pooler and scheduled backups when they are enabled, from a
+// database config that has its defaults set.
+func CreateCNPGDatabase(c *Context, kubeClient *model.KubeClient, name, namespace string, config model.CNPGDatabaseConfig) (*cnpgv1.Cluster, error) {
+	logger.FromContext(c.Ctx).Infof("Creating CNPG cluster %s with %d instances and %s of storage", name, config.Instances, config.StorageSize)
+
+	if config.Backup != nil {
+		err := applyCNPGBackupSecret(c, kubeClient, namespace, name, *config.Backup)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cluster := newCNPGCluster(c, name, namespace, config)
+	err := createCNPGResource(c, kubeClient, cnpgClusterGVR, "Cluster", cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Backup != nil {
+		err = applyCNPGScheduledBackup(c, kubeClient, cluster, *config.Backup)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Pooler != nil && config.Pooler.Enabled {
+		err = createCNPGResource(c, kubeClient, cnpgPoolerGVR, "Pooler", newCNPGPooler(cluster, *config.Pooler))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cluster, nil
+}
+
+// cnpgDatabaseConnectionStrings waits for a CNPG cluster to be ready and returns its writer and reader connection
+// strings, read from the secret of its app user.
+func cnpgDatabaseConnectionStrings(c *Context, kubeClient *model.KubeClient, cluster *cnpgv1.Cluster, config model.CNPGDatabaseConfig) (string, string, error) {
+	err := waitForCNPGCluster(c, kubeClient, cluster)
+	if err != nil {
+		return "", "", err
+	}
+
+	secrets := kubeClient.Clientset.CoreV1().Secrets(cluster.Namespace)
+	obj, err := WaitForObject(c, "secret "+cluster.GetApplicationSecretName(), cnpgSecretTimeout, namedListWatch(c.Ctx, secrets, cluster.GetApplicationSecretName()), &v1.Secret{}, func(obj runtime.Object) (bool, string, error) {
+		return true, "created", nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+	secret := obj.(*v1.Secret)
+
+	writer, reader := cnpgConnectionStrings(string(secret.Data["uri"]), cluster, config.Pooler != nil && config.Pooler.Enabled)
+	return writer, reader, nil
+}
+
+// cnpgConnectionStrings returns the writer and reader connection strings of a CNPG cluster from the uri of its app
+// user. The writer goes through the pooler when one is enabled, and the reader goes to the replicas.
+func cnpgConnectionStrings(uri string, cluster *cnpgv1.Cluster, pooler bool) (string, string) {
+	uri = strings.Replace(uri, "postgresql:", "postgres:", 1)
+	primaryHost := "@" + cluster.GetServiceReadWriteName()
+
+	writer := uri
+	if pooler {
+		writer = strings.Replace(uri, primaryHost, "@"+cnpgPoolerName(cluster.Name), 1)
+	}
+	reader := strings.Replace(uri, primaryHost, "@"+cnpgReaderService(cluster, cluster.Spec.Instances), 1)
+
+	return writer, reader
+}
+
+// cnpgReaderService is the service that the reader connection string of a CNPG cluster with the number of instances
+// points at. A cluster with a single instance has no replicas, so its readers use the primary.
+func cnpgReaderService(cluster *cnpgv1.Cluster, instances int) string {
+	if instances > 1 {
+		return cluster.GetServiceReadOnlyName()
+	}
+
+	return cluster.GetServiceReadWriteName()
+}
+
+// waitForCNPGCluster waits until all the instances of a CNPG cluster are ready.
+func waitForCNPGCluster(c *Context, kubeClient *model.KubeClient, cluster *cnpgv1.Cluster) error {
+	clusters := kubeClient.DynamicClient.Resource(cnpgClusterGVR).Namespace(cluster.Namespace)
+	_, err := WaitForObject(c, "CNPG cluster "+cluster.Name, cnpgClusterReadyTimeout, namedListWatch(c.Ctx, clusters, cluster.Name), &unstructured.Unstructured{}, func(obj runtime.Object) (bool, string, error) {
+		observed := &cnpgv1.Cluster{}
+		err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.(*unstructured.Unstructured).Object, observed)
+		if err != nil {
+			return false, "", fmt.Errorf("failed to convert CNPG cluster: %w", err)
+		}
+
+		phase := observed.Status.Phase
+		if phase == "" {
+			phase = "pending"
+		}
+		state := fmt.Sprintf("%s, %d of %d instances ready", phase, observed.Status.ReadyInstances, observed.Spec.Instances)
+		return observed.Status.Phase == cnpgv1.PhaseHealthy && observed.Status.ReadyInstances >= observed.Spec.Instances, state, nil
+	})
+
+	return err
+}
+
+// getCNPGCluster returns the CNPG cluster of an installation.
+func getCNPGCluster(c *Context, kubeClient *model.KubeClient, installationName string) (*cnpgv1.Cluster, error) {
+	unstructuredCluster, err := kubeClient.DynamicClient.Resource(cnpgClusterGVR).Namespace(installationName).Get(c.Ctx, cnpgClusterName(installationName), metav1.GetOptions{})
+	if err != nil {
+		if apiErrors.IsNotFound(err) {
+			return nil, model.NewInvalidRequestError("Installation doesn't have a CNPG database").Wrap(err)
+		}
+		return nil, fmt.Errorf("failed to get CNPG cluster: %w", err)
+	}
+
+	cluster := &cnpgv1.Cluster{}
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredCluster.Object, cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert CNPG cluster: %w", err)
+	}
+
+	return cluster, nil
+}
+
+// PatchCNPGDatabase resizes the CNPG cluster of an installation, or sets up its backups. Only the fields of the patch
+// that are set are changed, and a backup config replaces the current one. Enabling or disabling the pooler also
+// moves the writer connection string of the installation to or from it. Scaling down to a single instance moves the
+// reader connection string to the primary first, and scaling up from one moves it to the replicas once they are
+// ready.
+func PatchCNPGDatabase(c *Context, kubeClient *model.KubeClient, installationName string, patch *model.CNPGDatabaseConfig) error {
+	cluster, err := getCNPGCluster(c, kubeClient, installationName)
+	if err != nil {
+		return err
+	}
+
+	currentReader := cnpgReaderService(cluster, cluster.Spec.Instances)
+	if patch.Instances != 0 {
+		cluster.Spec.Instances = patch.Instances
+	}
+	if patch.StorageSize != "" {
+		cluster.Spec.StorageConfiguration.Size = patch.StorageSize
+	}
+	if patch.Resources != nil {
+		cluster.Spec.Resources = *patch.Resources
+	}
+	for key, value := range patch.Parameters {
+		if cluster.Spec.PostgresConfiguration.Parameters == nil {
+			cluster.Spec.PostgresConfiguration.Parameters = map[string]string{}
+		}
+		cluster.Spec.PostgresConfiguration.Parameters[key] = value
+	}
+
+	reader := cnpgReaderService(cluster, cluster.Spec.Instances)
+	if reader != currentReader && reader == cluster.GetServiceReadWriteName() {
+		err = setDatabaseReaderHost(c, kubeClient, cluster.Namespace, currentReader, reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	var backup model.CNPGBackupConfig
+	if patch.Backup != nil {
+		backup = patch.Backup.WithDefaults()
+		err = applyCNPGBackupSecret(c, kubeClient, cluster.Namespace, cluster.Name, backup)
+		if err != nil {
+			return err
+		}
+		cluster.Spec.Backup = cnpgBackupConfiguration(cluster.Name, backup)
+	}
+
+	updatedCluster, err := model.ConvertToUnstructured(cluster)
+	if err != nil {
+		return fmt.Errorf("failed to convert to unstructured: %w", err)
+	}
+
+	logger.FromContext(c.Ctx).Infof("Updating CNPG cluster %s to %d instances and %s of storage", cluster.Name, cluster.Spec.Instances, cluster.Spec.StorageConfiguration.Size)
+	_, err = kubeClient.DynamicClient.Resource(cnpgClusterGVR).Namespace(cluster.Namespace).Update(c.Ctx, updatedCluster, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update CNPG cluster: %w", err)
+	}
+
+	if reader != currentReader && reader == cluster.GetServiceReadOnlyName() {
+		err = waitForCNPGCluster(c, kubeClient, cluster)
+		if err != nil {
+			return err
+		}
+		err = setDatabaseReaderHost(c, kubeClient, cluster.Namespace, currentReader, reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	if patch.Backup != nil {
+		err = applyCNPGScheduledBackup(c, kubeClient, cluster, backup)
+		if err != nil {
+			return err
+		}
+	}
+
+	if patch.Pooler != nil {
+		err = patchCNPGPooler(c, kubeClient, cluster, patch.Pooler)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// patchCNPGPooler resizes, creates or deletes the pooler of a CNPG cluster.
+func patchCNPGPooler(c *Context, kubeClient *model.KubeClient, cluster *cnpgv1.Cluster, patch *model.CNPGPoolerConfig) error {
+	name := cnpgPoolerName(cluster.Name)
+	poolerClient := kubeClient.DynamicClient.Resource(cnpgPoolerGVR).Namespace(cluster.Namespace)
+
+	unstructuredPooler, err := poolerClient.Get(c.Ctx, name, metav1.GetOptions{})
+	if err != nil && !apiErrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get CNPG pooler: %w", err)
+	}
+	exists := err == nil
+
+	switch {
+	case !patch.Enabled && exists:
+		logger.FromContext(c.Ctx).Infof("Removing CNPG pooler %s", name)
+		err = setDatabaseWriterHost(c, kubeClient, cluster.Namespace, name, cluster.GetServiceReadWriteName())
+		if err != nil {
+			return err
+		}
+		err = poolerClient.Delete(c.Ctx, name, metav1.DeleteOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to delete CNPG pooler: %w", err)
+		}
+	case patch.Enabled && !exists:
+		logger.FromContext(c.Ctx).Infof("Adding CNPG pooler %s", name)
+		config := model.CNPGDatabaseConfig{Pooler: patch}
+		err = createCNPGResource(c, kubeClient, cnpgPoolerGVR, "Pooler", newCNPGPooler(cluster, *config.WithDefaults("").Pooler))
+		if err != nil {
+			return err
+		}
+		err = setDatabaseWriterHost(c, kubeClient, cluster.Namespace, cluster.GetServiceReadWriteName(), name)
+		if err != nil {
+			return err
+		}
+	case patch.Enabled && exists:
+		pooler := &cnpgv1.Pooler{}
+		err = runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredPooler.Object, pooler)
+		if err != nil {
+			return fmt.Errorf("failed to convert CNPG pooler: %w", err)
+		}
+
+		if patch.Instances != 0 {
+			instances := int32(patch.Instances)
+			pooler.Spec.Instances = &instances
+		}
+		if patch.PoolMode != "" {
+			pooler.Spec.PgBouncer.PoolMode = cnpgv1.PgBouncerPoolMode(patch.PoolMode)
+		}
+		for key, value := range patch.Parameters {
+			if pooler.Spec.PgBouncer.Parameters == nil {
+				pooler.Spec.PgBouncer.Parameters = map[string]string{}
+			}
+			pooler.Spec.PgBouncer.Parameters[key] = value
+		}
+
+		updatedPooler, err := model.ConvertToUnstructured(pooler)
+		if err != nil {
+			return fmt.Errorf("failed to convert to unstructured: %w", err)
+		}
+		_, err = poolerClient.Update(c.Ctx, updatedPooler, metav1.UpdateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to update CNPG pooler: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// setDatabaseWriterHost points the writer connection strings in the database secret of an installation from one
+// service to another.
+func setDatabaseWriterHost(c *Context, kubeClient *model.KubeClient, namespace, from, to string) error {
+	return setDatabaseSecretHost(c, kubeClient, namespace, []string{"DB_CONNECTION_CHECK_URL", "DB_CONNECTION_STRING", "MM_CONFIG"}, from, to)
+}
+
+// setDatabaseReaderHost points the reader connection string in the database secret of an installation from one
+// service to another.
+func setDatabaseReaderHost(c *Context, kubeClient *model.KubeClient, namespace, from, to string) error {
+	logger.FromContext(c.Ctx).Infof("Moving the database readers from %s to %s", from, to)
+	return setDatabaseSecretHost(c, kubeClient, namespace, []string{"MM_SQLSETTINGS_DATASOURCEREPLICAS"}, from, to)
+}
+
+// setDatabaseSecretHost points the connection strings under the keys of the database secret of an installation from
+// one service to another.
+func setDatabaseSecretHost(c *Context, kubeClient *model.KubeClient, namespace string, keys []string, from, to string) error {
+	secret, err := kubeClient.Clientset.CoreV1().Secrets(namespace).Get(c.Ctx, model.SecretNameDatabase, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get database secret: %w", err)
+	}
+
+	for _, key := range keys {
+		if value, ok := secret.Data[key]; ok {
+			secret.Data[key] = []byte(strings.Replace(string(value), "@"+from, "@"+to, 1))
+		}
+	}
+
+	_, err = kubeClient.Clientset.CoreV1().Secrets(namespace).Update(c.Ctx, secret, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update database secret: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+package api
+
+// Exported for the tests of the api_test package.
+var (
+	CNPGConnectionStrings = cnpgConnectionStrings
+	DiffReleaseHistory    = diffReleaseHistory
+	NewCNPGRestoreCluster = newCNPGRestoreCluster
+	SortReleaseHistory    = sortReleaseHistory
+)
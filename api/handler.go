@@ -39,28 +39,47 @@ func (h contextHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	context.CloudProviderName = cloudProviderName
 
-	var provider providers.CloudProvider
-	switch context.CloudProviderName {
-	case "aws":
-		provider = providers.GetAWSProvider(context.BootstrapperState.Credentials)
-	case "custom":
-		provider = providers.GetCustomProvider(context.BootstrapperState.Credentials)
-	// case "gcp":
-	//     provider = &GCPCloudProvider{}
-	// ... other cases
-	default:
-		// err = fmt.Errorf("unsupported cloud provider: %s", context.CloudProviderName)
+	// Routes without a provider in their path, such as the state and jobs routes, don't get one
+	if cloudProviderName != "" {
+		provider, err := providers.GetCloudProvider(cloudProviderName, context.BootstrapperState.Credentials)
+		if err != nil {
+			context.SetError(err, "Failed to get cloud provider")
+			h.writeError(context, ww)
+			return
+		}
+
+		// Associate with context
+		context.CloudProvider = provider
 	}
 
-	// if err != nil {
-	// 	// TODO: Graceful error handling and exit
-	// 	return nil
-	// }
+	h.handler(context, ww, r)
 
-	// Associate with context
-	context.CloudProvider = provider
+	if context.Err != nil {
+		h.writeError(context, ww)
+	}
+}
 
-	h.handler(context, ww, r)
+// writeError logs the AppError set by the handler and writes it as a JSON error envelope, unless the handler
+// already started writing its own response.
+func (h contextHandler) writeError(c *Context, w *ResponseWriterWrapper) {
+	log := logger.FromContext(c.Ctx).WithError(c.Err).WithFields(logrus.Fields{
+		"handler":     h.handlerName,
+		"error_code":  c.Err.Code,
+		"status_code": c.Err.StatusCode,
+	})
+	if c.Err.StatusCode >= http.StatusInternalServerError {
+		log.Error(c.Err.Message)
+	} else {
+		log.Warn(c.Err.Message)
+	}
+
+	if w.statusCodeWritten {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(c.Err.StatusCode)
+	c.Err.ToJSON(w)
 }
 
 func newContextHandler(context *Context, handler contextHandlerFunc) *contextHandler {
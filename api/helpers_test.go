@@ -0,0 +1,32 @@
+package api_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestContext returns a context whose state file is in a temporary directory, with telemetry disabled.
+func newTestContext(t *testing.T) *api.Context {
+	t.Helper()
+
+	c, err := api.NewContext(context.Background(), filepath.Join(t.TempDir(), "state.json"), true)
+	require.NoError(t, err)
+
+	return c
+}
+
+// newTestRouter returns a router serving the API with a new test context.
+func newTestRouter(t *testing.T) (*api.Context, *mux.Router) {
+	t.Helper()
+
+	c := newTestContext(t)
+	router := mux.NewRouter()
+	api.Register(router, c)
+
+	return c, router
+}
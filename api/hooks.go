@@ -0,0 +1,163 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	apixv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Hook is a named step that runs before or after an add-on is installed, for setup that the chart can't do itself.
+type Hook struct {
+	Name  string
+	Phase model.HookPhase
+	Run   func(c *Context, clusterName string) error
+}
+
+// AddonHooks returns the hooks to run for an add-on in the given phase, in order. Hooks of the provider wrap those of
+// the add-on: they run first before the install, so that the cluster is prepared, and last after it.
+func AddonHooks(c *Context, addon Addon, phase model.HookPhase) []Hook {
+	addonHooks := []Hook{}
+	for _, hook := range addon.Hooks {
+		if hook.Phase == phase {
+			addonHooks = append(addonHooks, hook)
+		}
+	}
+
+	providerHooks := []Hook{}
+	if hooker, ok := c.CloudProvider.(providers.AddonHooker); ok {
+		for _, hook := range hooker.AddonHooks(addon.Name, phase) {
+			providerHooks = append(providerHooks, Hook{
+				Name:  hook.Name,
+				Phase: phase,
+				Run:   providerHookRun(hook),
+			})
+		}
+	}
+
+	if phase == model.HookPhasePostInstall {
+		return append(addonHooks, providerHooks...)
+	}
+	return append(providerHooks, addonHooks...)
+}
+
+// ProviderCharts are the charts that provider hooks install to prepare a cluster for add-ons. Like the add-ons, they
+// are pinned, bundled and installed from the mirror when one is configured.
+var ProviderCharts = []Addon{
+	{
+		Name:        "aws-ebs-csi-driver",
+		Description: "Provisions EBS volumes for EKS clusters",
+		RepoName:    "aws-ebs-csi-driver",
+		RepoURL:     "https://kubernetes-sigs.github.io/aws-ebs-csi-driver/",
+		Chart:       "aws-ebs-csi-driver",
+		ReleaseName: "aws-ebs-csi-driver",
+		Namespace:   "kube-system",
+		Version:     "2.32.0",
+		Timeout:     300 * time.Second,
+		MirrorValues: func(registry string) map[string]interface{} {
+			return mirrorImageValues(registry, map[string]string{
+				"image.repository":                              "public.ecr.aws/ebs-csi-driver/aws-ebs-csi-driver",
+				"sidecars.provisioner.image.repository":         "public.ecr.aws/eks-distro/kubernetes-csi/external-provisioner",
+				"sidecars.attacher.image.repository":            "public.ecr.aws/eks-distro/kubernetes-csi/external-attacher",
+				"sidecars.snapshotter.image.repository":         "public.ecr.aws/eks-distro/kubernetes-csi/external-snapshotter/csi-snapshotter",
+				"sidecars.livenessProbe.image.repository":       "public.ecr.aws/eks-distro/kubernetes-csi/livenessprobe",
+				"sidecars.resizer.image.repository":             "public.ecr.aws/eks-distro/kubernetes-csi/external-resizer",
+				"sidecars.nodeDriverRegistrar.image.repository": "public.ecr.aws/eks-distro/kubernetes-csi/node-driver-registrar",
+				"sidecars.volumemodifier.image.repository":      "public.ecr.aws/ebs-csi-driver/volume-modifier-for-k8s",
+			})
+		},
+	},
+}
+
+// providerHookRun returns the run of a provider hook, which installs the hook's chart after the provider's step,
+// unless the provider skips it.
+func providerHookRun(hook providers.Hook) func(c *Context, clusterName string) error {
+	return func(c *Context, clusterName string) error {
+		if hook.Run != nil {
+			err := hook.Run(c.Ctx, clusterName)
+			if errors.Is(err, providers.ErrSkipChart) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if hook.Chart == "" {
+			return nil
+		}
+
+		index := slices.IndexFunc(ProviderCharts, func(chart Addon) bool { return chart.Name == hook.Chart })
+		if index < 0 {
+			return fmt.Errorf("unknown provider chart %s", hook.Chart)
+		}
+		_, err := installChart(c, clusterName, ProviderCharts[index], nil)
+		return err
+	}
+}
+
+// runAddonHooks runs the hooks of an add-on for a phase, stopping at the first one that fails.
+func runAddonHooks(c *Context, clusterName string, addon Addon, phase model.HookPhase) error {
+	hooks := AddonHooks(c, addon, phase)
+	for i, hook := range hooks {
+		log := logger.FromContext(c.Ctx).WithField("hook", hook.Name)
+		log.Infof("Running %s hook %d of %d for %s: %s", phase, i+1, len(hooks), addon.Name, hook.Name)
+
+		start := time.Now()
+		err := hook.Run(c, clusterName)
+		if err != nil {
+			log.WithError(err).Errorf("The %s hook %s failed", phase, hook.Name)
+			return fmt.Errorf("%s hook %s for %s failed: %w", phase, hook.Name, addon.Name, err)
+		}
+
+		log.Infof("Finished %s hook %s in %s", phase, hook.Name, time.Since(start).Round(time.Second))
+	}
+
+	return nil
+}
+
+// waitForCRDsHook returns a post-install hook that waits until the named CRDs are established, so that resources of
+// those kinds can be created as soon as the add-on is installed.
+func waitForCRDsHook(timeout time.Duration, names ...string) Hook {
+	return Hook{
+		Name:  "wait-for-crds",
+		Phase: model.HookPhasePostInstall,
+		Run: func(c *Context, clusterName string) error {
+			kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
+			if err != nil {
+				return fmt.Errorf("failed to create kube client: %w", err)
+			}
+
+			deadline := time.Now().Add(timeout)
+			for _, name := range names {
+				crds := kubeClient.ApixClientset.ApiextensionsV1().CustomResourceDefinitions()
+				_, err = WaitForObject(c, "CRD "+name, time.Until(deadline), namedListWatch(c.Ctx, crds, name), &apixv1.CustomResourceDefinition{}, func(obj runtime.Object) (bool, string, error) {
+					if isCRDEstablished(obj.(*apixv1.CustomResourceDefinition)) {
+						return true, "established", nil
+					}
+					return false, "not established", nil
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+func isCRDEstablished(crd *apixv1.CustomResourceDefinition) bool {
+	for _, condition := range crd.Status.Conditions {
+		if condition.Type == apixv1.Established {
+			return condition.Status == apixv1.ConditionTrue
+		}
+	}
+
+	return false
+}
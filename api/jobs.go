@@ -0,0 +1,489 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	awatModel "github.com/mattermost/awat/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	jobQueueSize = 100
+
+	// maxFinishedJobs and finishedJobRetention limit how many finished jobs are kept, and for how long, so that the
+	// jobs directory doesn't grow without bound.
+	maxFinishedJobs      = 100
+	finishedJobRetention = 7 * 24 * time.Hour
+
+	// jobStepPersistInterval is how often the steps a job logs are written to disk while it runs. Status changes are
+	// written right away.
+	jobStepPersistInterval = 2 * time.Second
+)
+
+func newJobsNotConfiguredError() *model.AppError {
+	return model.NewInternalError("Job manager is not configured")
+}
+
+// JobFunc performs the work of a background job. Anything it logs through the context logger at info level or
+// above is recorded as a step of the job, and the returned result is stored on the job as JSON.
+type JobFunc func(c *Context) (interface{}, error)
+
+// JobManager runs jobs on a pool of background workers and persists their status to disk, so that clients can
+// poll or stream the progress of long-running operations instead of holding a request open.
+type JobManager struct {
+	dir         string
+	lock        sync.Mutex
+	jobs        map[string]*model.Job
+	subscribers map[string]map[chan *model.Job]struct{}
+	queue       chan queuedJob
+	// persistedAt is when each unfinished job was last written to disk.
+	persistedAt map[string]time.Time
+}
+
+type queuedJob struct {
+	id  string
+	c   *Context
+	run JobFunc
+}
+
+// DefaultJobsDir returns the directory jobs are persisted to, alongside the state file.
+func DefaultJobsDir(stateFilePath string) string {
+	if stateFilePath == "" {
+		stateFilePath = DefaultStateFilePath()
+	}
+	return filepath.Join(filepath.Dir(stateFilePath), "jobs")
+}
+
+// NewJobManager loads the jobs persisted in dir and starts the given number of workers. Jobs that were queued or
+// running when the previous process exited are marked as failed, since their work was interrupted, and finished jobs
+// past their retention are removed.
+func NewJobManager(ctx context.Context, dir string, workers int) (*JobManager, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &JobManager{
+		dir:         dir,
+		jobs:        map[string]*model.Job{},
+		subscribers: map[string]map[chan *model.Job]struct{}{},
+		queue:       make(chan queuedJob, jobQueueSize),
+		persistedAt: map[string]time.Time{},
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		var job model.Job
+		err = json.Unmarshal(data, &job)
+		if err != nil {
+			logger.FromContext(ctx).WithError(err).Warnf("Skipping unreadable job file %s", file)
+			continue
+		}
+
+		if !job.IsFinished() {
+			job.Status = model.JobStatusFailed
+			job.Error = "job was interrupted by a server restart"
+			job.UpdatedAt = time.Now()
+			err = m.persist(&job)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		m.jobs[job.ID] = &job
+	}
+	m.prune()
+
+	for i := 0; i < workers; i++ {
+		go m.worker()
+	}
+
+	return m, nil
+}
+
+// Enqueue records a new queued job and schedules run on a worker. The job runs with a copy of the given context
+// whose logger also writes to the job's step log.
+func (m *JobManager) Enqueue(c *Context, job *model.Job, run JobFunc) (*model.Job, error) {
+	now := time.Now()
+	job.ID = awatModel.NewID()
+	job.Status = model.JobStatusQueued
+	job.Steps = []model.JobStep{{Time: now, Level: logrus.InfoLevel.String(), Message: "Job queued"}}
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	m.lock.Lock()
+	err := m.persist(job)
+	if err != nil {
+		m.lock.Unlock()
+		return nil, err
+	}
+	m.jobs[job.ID] = job
+	queued := job.Copy()
+	m.lock.Unlock()
+
+	jobLogger := logrus.New()
+	jobLogger.SetFormatter(&logrus.JSONFormatter{})
+	jobLogger.SetLevel(logrus.DebugLevel)
+	jobLogger.AddHook(&jobStepHook{manager: m, jobID: job.ID})
+
+	// The job outlives the request that queued it, so keep the request's log fields but not its cancellation
+	jobContext := c.Clone()
+	jobContext.Ctx = logger.WithField(logger.WithLogger(context.WithoutCancel(c.Ctx), jobLogger), "job", job.ID)
+
+	select {
+	case m.queue <- queuedJob{id: job.ID, c: jobContext, run: run}:
+	default:
+		m.finish(job.ID, nil, fmt.Errorf("job queue is full"))
+		return nil, fmt.Errorf("job queue is full")
+	}
+
+	return queued, nil
+}
+
+// Get returns a copy of the job with the given ID.
+func (m *JobManager) Get(id string) (*model.Job, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return nil, false
+	}
+
+	return job.Copy(), true
+}
+
+// List returns copies of all known jobs, newest first.
+func (m *JobManager) List() []*model.Job {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	jobs := []*model.Job{}
+	for _, job := range m.jobs {
+		jobs = append(jobs, job.Copy())
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+
+	return jobs
+}
+
+// Subscribe returns a channel that receives a snapshot of the job every time it changes, and a function to stop
+// the subscription. Only the latest snapshot is buffered, so slow readers skip intermediate updates.
+func (m *JobManager) Subscribe(id string) (<-chan *model.Job, func()) {
+	updates := make(chan *model.Job, 1)
+
+	m.lock.Lock()
+	if m.subscribers[id] == nil {
+		m.subscribers[id] = map[chan *model.Job]struct{}{}
+	}
+	m.subscribers[id][updates] = struct{}{}
+	m.lock.Unlock()
+
+	unsubscribe := func() {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		delete(m.subscribers[id], updates)
+		if len(m.subscribers[id]) == 0 {
+			delete(m.subscribers, id)
+		}
+	}
+
+	return updates, unsubscribe
+}
+
+func (m *JobManager) worker() {
+	for queued := range m.queue {
+		m.update(queued.id, false, func(job *model.Job) {
+			job.Status = model.JobStatusRunning
+			job.Steps = append(job.Steps, model.JobStep{Time: time.Now(), Level: logrus.InfoLevel.String(), Message: "Job started"})
+		})
+
+		result, err := runJob(queued)
+		m.finish(queued.id, result, err)
+	}
+}
+
+func runJob(queued queuedJob) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return queued.run(queued.c)
+}
+
+func (m *JobManager) finish(id string, result interface{}, err error) {
+	var resultJSON json.RawMessage
+	if err == nil && result != nil {
+		resultJSON, err = json.Marshal(result)
+	}
+
+	m.update(id, false, func(job *model.Job) {
+		if err != nil {
+			job.Status = model.JobStatusFailed
+			job.Error = err.Error()
+			job.ErrorCode = providers.ToAppError(err, "Job failed").Code
+			job.Steps = append(job.Steps, model.JobStep{Time: time.Now(), Level: logrus.ErrorLevel.String(), Message: "Job failed: " + err.Error()})
+			return
+		}
+
+		job.Status = model.JobStatusSucceeded
+		job.Result = resultJSON
+		job.Steps = append(job.Steps, model.JobStep{Time: time.Now(), Level: logrus.InfoLevel.String(), Message: "Job succeeded"})
+	})
+
+	m.lock.Lock()
+	m.prune()
+	m.lock.Unlock()
+}
+
+func (m *JobManager) appendStep(id string, step model.JobStep) {
+	m.update(id, true, func(job *model.Job) {
+		job.Steps = append(job.Steps, step)
+	})
+}
+
+// update applies mutate to the job, persists it and notifies subscribers. Throttled updates are only persisted when
+// the job hasn't been for jobStepPersistInterval, since a later update persists them along with its own changes.
+func (m *JobManager) update(id string, throttle bool, mutate func(job *model.Job)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return
+	}
+
+	mutate(job)
+	job.UpdatedAt = time.Now()
+
+	if !throttle || time.Since(m.persistedAt[id]) >= jobStepPersistInterval {
+		err := m.persist(job)
+		if err != nil {
+			logrus.WithError(err).WithField("job", id).Error("Failed to persist job")
+		}
+	}
+
+	for updates := range m.subscribers[id] {
+		// Replace any snapshot the subscriber hasn't read yet with the latest one
+		select {
+		case <-updates:
+		default:
+		}
+		updates <- job.Copy()
+	}
+}
+
+func (m *JobManager) persist(job *model.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(m.jobFile(job.ID), data, 0600)
+	if err != nil {
+		return err
+	}
+
+	if job.IsFinished() {
+		delete(m.persistedAt, job.ID)
+	} else {
+		m.persistedAt[job.ID] = time.Now()
+	}
+
+	return nil
+}
+
+// prune removes the finished jobs beyond the newest maxFinishedJobs, and the ones that finished more than
+// finishedJobRetention ago, along with their files. The caller must hold the lock.
+func (m *JobManager) prune() {
+	finished := []*model.Job{}
+	for _, job := range m.jobs {
+		if job.IsFinished() {
+			finished = append(finished, job)
+		}
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].UpdatedAt.After(finished[j].UpdatedAt)
+	})
+
+	for i, job := range finished {
+		if i < maxFinishedJobs && time.Since(job.UpdatedAt) < finishedJobRetention {
+			continue
+		}
+
+		delete(m.jobs, job.ID)
+		err := os.Remove(m.jobFile(job.ID))
+		if err != nil && !os.IsNotExist(err) {
+			logrus.WithError(err).WithField("job", job.ID).Warn("Failed to remove job file")
+		}
+	}
+}
+
+func (m *JobManager) jobFile(id string) string {
+	return filepath.Join(m.dir, id+".json")
+}
+
+// jobStepHook records log entries made while running a job as steps of that job.
+type jobStepHook struct {
+	manager *JobManager
+	jobID   string
+}
+
+func (h *jobStepHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel, logrus.InfoLevel}
+}
+
+func (h *jobStepHook) Fire(entry *logrus.Entry) error {
+	message := entry.Message
+	if err, ok := entry.Data[logrus.ErrorKey]; ok {
+		message = fmt.Sprintf("%s: %v", message, err)
+	}
+
+	h.manager.appendStep(h.jobID, model.JobStep{
+		Time:    entry.Time,
+		Level:   entry.Level.String(),
+		Message: strings.TrimSpace(message),
+	})
+
+	return nil
+}
+
+func initJobs(apiRouter *mux.Router, context *Context) {
+	addContext := func(handler contextHandlerFunc) *contextHandler {
+		return newContextHandler(context, handler)
+	}
+
+	jobsRouter := apiRouter.PathPrefix("/jobs").Subrouter()
+	jobsRouter.Handle("", addContext(handleListJobs)).Methods(http.MethodGet)
+	jobsRouter.Handle("/{jobID:[A-Za-z0-9]+}", addContext(handleGetJob)).Methods(http.MethodGet)
+	jobsRouter.Handle("/{jobID:[A-Za-z0-9]+}/ws", addContext(handleJobWebsocket))
+}
+
+// startJob queues run as a background job and responds with 202 Accepted and the queued job.
+func startJob(c *Context, w http.ResponseWriter, job *model.Job, run JobFunc) {
+	if c.Jobs == nil {
+		c.Err = newJobsNotConfiguredError()
+		return
+	}
+
+	job.Provider = c.CloudProviderName
+	queued, err := c.Jobs.Enqueue(c, job, run)
+	if err != nil {
+		c.SetError(err, "Failed to queue job")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/v1/jobs/"+queued.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(queued)
+}
+
+func handleListJobs(c *Context, w http.ResponseWriter, r *http.Request) {
+	if c.Jobs == nil {
+		c.Err = newJobsNotConfiguredError()
+		return
+	}
+
+	json.NewEncoder(w).Encode(c.Jobs.List())
+}
+
+func handleGetJob(c *Context, w http.ResponseWriter, r *http.Request) {
+	if c.Jobs == nil {
+		c.Err = newJobsNotConfiguredError()
+		return
+	}
+
+	job, ok := c.Jobs.Get(mux.Vars(r)["jobID"])
+	if !ok {
+		c.Err = model.NewNotFoundError("Job not found")
+		return
+	}
+
+	json.NewEncoder(w).Encode(job)
+}
+
+// handleJobWebsocket streams a snapshot of the job each time it changes, closing once the job has finished.
+func handleJobWebsocket(c *Context, w http.ResponseWriter, r *http.Request) {
+	if c.Jobs == nil {
+		c.Err = newJobsNotConfiguredError()
+		return
+	}
+
+	jobID := mux.Vars(r)["jobID"]
+	job, ok := c.Jobs.Get(jobID)
+	if !ok {
+		c.Err = model.NewNotFoundError("Job not found")
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.FromContext(c.Ctx).WithError(err).Error("Failed to upgrade websocket connection")
+		return
+	}
+	defer conn.Close()
+
+	updates, unsubscribe := c.Jobs.Subscribe(jobID)
+	defer unsubscribe()
+
+	// Drain incoming messages so that a closed connection is noticed
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// The job may have changed between fetching it and subscribing, so fetch it again before the first write
+	job, _ = c.Jobs.Get(jobID)
+	for {
+		if err := conn.WriteJSON(job); err != nil {
+			logger.FromContext(c.Ctx).WithError(err).Error("Error writing to WebSocket")
+			return
+		}
+
+		if job.IsFinished() {
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		}
+
+		select {
+		case job = <-updates:
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func waitForJob(t *testing.T, manager *api.JobManager, id string) *model.Job {
+	updates, unsubscribe := manager.Subscribe(id)
+	defer unsubscribe()
+
+	job, ok := manager.Get(id)
+	require.True(t, ok)
+	for !job.IsFinished() {
+		select {
+		case job = <-updates:
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "timed out waiting for job to finish")
+		}
+	}
+
+	return job
+}
+
+func TestJobManager(t *testing.T) {
+	tempDir := t.TempDir()
+	c := &api.Context{Ctx: context.Background(), CloudProviderName: "custom"}
+
+	manager, err := api.NewJobManager(context.Background(), tempDir, 1)
+	require.NoError(t, err)
+
+	t.Run("Succeeds", func(t *testing.T) {
+		job, err := manager.Enqueue(c, &model.Job{Type: model.JobTypeDeployOperator, ClusterName: "test"}, func(c *api.Context) (interface{}, error) {
+			logger.FromContext(c.Ctx).Info("Installing chart")
+			logger.FromContext(c.Ctx).Debug("Debug output is not recorded")
+			return map[string]string{"name": "test"}, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, model.JobStatusQueued, job.Status)
+
+		job = waitForJob(t, manager, job.ID)
+		assert.Equal(t, model.JobStatusSucceeded, job.Status)
+		assert.JSONEq(t, `{"name":"test"}`, string(job.Result))
+
+		var messages []string
+		for _, step := range job.Steps {
+			messages = append(messages, step.Message)
+		}
+		assert.Equal(t, []string{"Job queued", "Job started", "Installing chart", "Job succeeded"}, messages)
+	})
+
+	t.Run("Fails", func(t *testing.T) {
+		job, err := manager.Enqueue(c, &model.Job{Type: model.JobTypeDeployOperator}, func(c *api.Context) (interface{}, error) {
+			return nil, errors.New("chart not found")
+		})
+		require.NoError(t, err)
+
+		job = waitForJob(t, manager, job.ID)
+		assert.Equal(t, model.JobStatusFailed, job.Status)
+		assert.Equal(t, "chart not found", job.Error)
+	})
+
+	t.Run("InterruptedJobsFailOnLoad", func(t *testing.T) {
+		interrupted := model.Job{ID: "interrupted", Status: model.JobStatusRunning, CreatedAt: time.Now()}
+		data, err := json.Marshal(interrupted)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, "interrupted.json"), data, 0600))
+
+		reloaded, err := api.NewJobManager(context.Background(), tempDir, 0)
+		require.NoError(t, err)
+
+		job, ok := reloaded.Get("interrupted")
+		require.True(t, ok)
+		assert.Equal(t, model.JobStatusFailed, job.Status)
+		assert.NotEmpty(t, job.Error)
+		assert.Len(t, reloaded.List(), 3)
+	})
+	t.Run("FinishedJobsArePrunedOnLoad", func(t *testing.T) {
+		dir := t.TempDir()
+		writeJob := func(job model.Job) {
+			data, err := json.Marshal(job)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(filepath.Join(dir, job.ID+".json"), data, 0600))
+		}
+
+		now := time.Now()
+		writeJob(model.Job{ID: "expired", Status: model.JobStatusSucceeded, CreatedAt: now.Add(-30 * 24 * time.Hour), UpdatedAt: now.Add(-30 * 24 * time.Hour)})
+		for i := 0; i < 105; i++ {
+			updated := now.Add(-time.Duration(i) * time.Minute)
+			writeJob(model.Job{ID: fmt.Sprintf("finished%d", i), Status: model.JobStatusFailed, CreatedAt: updated, UpdatedAt: updated})
+		}
+
+		reloaded, err := api.NewJobManager(context.Background(), dir, 0)
+		require.NoError(t, err)
+
+		assert.Len(t, reloaded.List(), 100)
+		_, ok := reloaded.Get("expired")
+		assert.False(t, ok)
+		assert.NoFileExists(t, filepath.Join(dir, "expired.json"))
+		_, ok = reloaded.Get("finished99")
+		assert.True(t, ok)
+		_, ok = reloaded.Get("finished100")
+		assert.False(t, ok)
+		assert.NoFileExists(t, filepath.Join(dir, "finished100.json"))
+	})
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	v1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CreateManagedDatabase provisions a managed database for an installation with the cluster's provider. The
+// installation doesn't need to exist yet; its namespace is created if needed and its database secret is created
+// or replaced with the connection strings of the new database.
+func CreateManagedDatabase(c *Context, clusterName string, create *model.CreateManagedDatabaseRequest) (*model.ManagedDatabase, error) {
+	provisioner, ok := c.CloudProvider.(providers.DatabaseProvisioner)
+	if !ok {
+		return nil, newNotImplementedError(c, "creating managed databases")
+	}
+
+	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	namespace := model.InstallationNamespace(create.InstallationName)
+	err = ensureNamespace(c, kubeClient, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := managedDatabasePassword(c, kubeClient, namespace, create)
+	if err != nil {
+		return nil, err
+	}
+	withPassword := *create
+	withPassword.MasterPassword = password
+
+	database, err := provisioner.CreateDatabase(c.Ctx, clusterName, &withPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := database.ReaderConnectionString
+	if reader == "" {
+		reader = database.ConnectionString
+	}
+	err = applyDatabaseSecret(c, kubeClient, namespace, database.ConnectionString, reader)
+	if err != nil {
+		return nil, err
+	}
+	logger.FromContext(c.Ctx).Infof("Stored connection strings for %s in the %s secret", database.Identifier, model.SecretNameDatabase)
+
+	return database, nil
+}
+
+// managedDatabasePassword returns the master password of an installation's managed database. It is generated and
+// stored in the managed database secret the first time, before the database exists, so that a database whose
+// creation was interrupted can still be reached when it is created again.
+func managedDatabasePassword(c *Context, kubeClient *model.KubeClient, namespace string, create *model.CreateManagedDatabaseRequest) (string, error) {
+	secrets := kubeClient.Clientset.CoreV1().Secrets(namespace)
+	secret, err := secrets.Get(c.Ctx, model.SecretNameManagedDatabase, metav1.GetOptions{})
+	if err == nil && len(secret.Data["password"]) > 0 {
+		logger.FromContext(c.Ctx).Infof("Reusing the master password in the %s secret", model.SecretNameManagedDatabase)
+		return string(secret.Data["password"]), nil
+	}
+	if err != nil && !apiErrors.IsNotFound(err) {
+		return "", fmt.Errorf("failed to get managed database secret: %w", err)
+	}
+	exists := err == nil
+
+	password, err := model.NewRandomAlphanumeric(32)
+	if err != nil {
+		return "", model.NewInternalError("Failed to generate database password").Wrap(err)
+	}
+
+	passwordSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      model.SecretNameManagedDatabase,
+			Namespace: namespace,
+		},
+		Type: v1.SecretTypeOpaque,
+		StringData: map[string]string{
+			"username": create.WithDefaults("").Username,
+			"password": password,
+		},
+	}
+	if exists {
+		_, err = secrets.Update(c.Ctx, passwordSecret, metav1.UpdateOptions{})
+	} else {
+		_, err = secrets.Create(c.Ctx, passwordSecret, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to store managed database password: %w", err)
+	}
+
+	return password, nil
+}
+
+// ensureNamespace creates a namespace unless it already exists.
+func ensureNamespace(c *Context, kubeClient *model.KubeClient, name string) error {
+	_, err := kubeClient.Clientset.CoreV1().Namespaces().Get(c.Ctx, name, metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !apiErrors.IsNotFound(err) {
+		return fmt.Errorf("error while checking namespace existence: %w", err)
+	}
+
+	namespace := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+	_, err = kubeClient.Clientset.CoreV1().Namespaces().Create(c.Ctx, namespace, metav1.CreateOptions{})
+	if err != nil && !apiErrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create namespace: %w", err)
+	}
+	logger.FromContext(c.Ctx).Info("Namespace created successfully")
+
+	return nil
+}
+
+// applyDatabaseSecret creates or updates the database secret of an installation, which the Mattermost operator
+// reads the writer and reader connection strings from.
+func applyDatabaseSecret(c *Context, kubeClient *model.KubeClient, namespace, writer, reader string) error {
+	databaseSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      model.SecretNameDatabase,
+			Namespace: namespace,
+		},
+		Type: v1.SecretTypeOpaque,
+		StringData: map[string]string{
+			"DB_CONNECTION_CHECK_URL":           writer,
+			"DB_CONNECTION_STRING":              writer,
+			"MM_SQLSETTINGS_DATASOURCEREPLICAS": reader,
+			"MM_CONFIG":                         writer,
+		},
+	}
+
+	secrets := kubeClient.Clientset.CoreV1().Secrets(namespace)
+	_, err := secrets.Create(c.Ctx, databaseSecret, metav1.CreateOptions{})
+	if apiErrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(c.Ctx, databaseSecret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("error creating database secret: %w", err)
+	}
+
+	return nil
+}
+
+func handleCreateManagedDatabase(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName := vars["name"]
+	if clusterName == "" || clusterName == "undefined" {
+		c.SetInvalidParam("name")
+		return
+	}
+
+	if _, ok := c.CloudProvider.(providers.DatabaseProvisioner); !ok {
+		c.Err = newNotImplementedError(c, "creating managed databases")
+		return
+	}
+
+	create, err := model.NewCreateManagedDatabaseRequestFromReader(r.Body)
+	if err != nil && err != io.EOF {
+		c.Err = model.NewInvalidRequestError("Failed to decode managed database request").Wrap(err)
+		return
+	}
+	if create == nil || create.InstallationName == "" {
+		c.SetInvalidParam("installationName")
+		return
+	}
+	if err = create.IsValid(); err != nil {
+		c.Err = model.NewInvalidRequestError("Invalid managed database request").Wrap(err)
+		return
+	}
+
+	c.Ctx = logger.WithClusterName(c.Ctx, clusterName)
+	logger.FromContext(c.Ctx).Infof("Creating managed database for installation %s", create.InstallationName)
+
+	job := &model.Job{Type: model.JobTypeCreateDatabase, ClusterName: clusterName, Target: create.InstallationName}
+	startJob(c, w, job, func(c *Context) (interface{}, error) {
+		return CreateManagedDatabase(c, clusterName, create)
+	})
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+)
+
+const clusterReadyTimeout = 30 * time.Minute
+
+var clusterReadyBackoff = Backoff{Initial: 10 * time.Second, Max: time.Minute}
+
+// ObserveManifest collects the current state of the resources described by the manifest.
+func ObserveManifest(c *Context, manifest *model.BootstrapManifest) (*model.ObservedState, error) {
+	observed := &model.ObservedState{}
+
+	clusters, err := c.CloudProvider.ListClusters(c.Ctx, manifest.Cluster.Region)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %w", err)
+	}
+	for _, cluster := range clusters {
+		if aws.StringValue(cluster) == manifest.Cluster.Name {
+			observed.ClusterExists = true
+			break
+		}
+	}
+
+	// Nothing else can exist on a cluster that hasn't been created yet
+	if !observed.ClusterExists {
+		return observed, nil
+	}
+
+	nodegroups, err := c.CloudProvider.GetNodegroups(c.Ctx, manifest.Cluster.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodegroups: %w", err)
+	}
+	for _, nodegroup := range nodegroups {
+		observed.Nodegroups = append(observed.Nodegroups, aws.StringValue(nodegroup.NodegroupName))
+	}
+
+	observed.Releases, err = ListInstalledReleases(c, manifest.Cluster.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The Mattermost CRD only exists once the operator has been deployed
+	if !releaseDeployed(observed.Releases, "mattermost-operator") {
+		return observed, nil
+	}
+
+	installations, err := ListMattermostInstallations(c, manifest.Cluster.Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, installation := range installations {
+		observed.Installations = append(observed.Installations, model.ObservedInstallation{
+			Name:    installation.Name,
+			Version: installation.Spec.Version,
+			Image:   installation.Spec.Image,
+		})
+	}
+
+	return observed, nil
+}
+
+// PlanManifest diffs the manifest against the observed state of the cluster.
+func PlanManifest(c *Context, manifest *model.BootstrapManifest) (*model.Plan, *model.ObservedState, error) {
+	if err := manifest.IsValid(); err != nil {
+		return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	observed, err := ObserveManifest(c, manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	operatorReleases := map[string]string{}
+	for _, addon := range Addons {
+		operatorReleases[addon.Name] = addon.ReleaseName
+	}
+
+	plan, err := model.DiffManifest(manifest, observed, operatorReleases)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return plan, observed, nil
+}
+
+// ApplyPlan converges the cluster towards the manifest by executing only the changes in the plan. Changes are
+// applied in order: cluster, nodegroups, operators and then installations.
+func ApplyPlan(c *Context, manifest *model.BootstrapManifest, plan *model.Plan, observed *model.ObservedState) error {
+	clusterName := manifest.Cluster.Name
+	c.Ctx = logger.WithClusterName(c.Ctx, clusterName)
+
+	nodegroups := map[string]model.CreateNodegroupRequest{}
+	for _, nodegroup := range manifest.Cluster.Nodegroups {
+		nodegroups[nodegroup.NodegroupName] = nodegroup
+	}
+
+	installations := map[string]model.CreateMattermostWorkspaceRequest{}
+	for _, installation := range manifest.Installations {
+		installations[installation.InstallationName] = installation
+	}
+
+	existingInstallations := map[string]model.ObservedInstallation{}
+	for _, installation := range observed.Installations {
+		existingInstallations[installation.Name] = installation
+	}
+
+	for _, change := range plan.Changes {
+		if change.Action == model.PlanActionNone {
+			continue
+		}
+
+		logger.FromContext(c.Ctx).Infof("Applying %s %s %s", change.Action, change.Resource, change.Name)
+
+		var err error
+		switch change.Resource {
+		case model.PlanResourceCluster:
+			err = applyCluster(c, manifest)
+		case model.PlanResourceNodegroup:
+			nodegroupManager, ok := c.CloudProvider.(providers.NodegroupManager)
+			if !ok {
+				err = newNotImplementedError(c, "creating node groups")
+				break
+			}
+			create := nodegroups[change.Name]
+			_, err = nodegroupManager.CreateNodegroup(c.Ctx, clusterName, &create)
+		case model.PlanResourceOperator:
+			addon, _ := GetAddon(change.Name)
+			err = InstallAddon(c, clusterName, addon, nil)
+		case model.PlanResourceInstallation:
+			installation := installations[change.Name]
+			if change.Action == model.PlanActionCreate {
+				_, err = CreateMattermostInstallation(c, clusterName, &installation)
+			} else {
+				namespace := model.InstallationNamespace(change.Name)
+				_, err = PatchMattermostInstallation(c, clusterName, namespace, &model.PatchMattermostWorkspaceRequest{
+					Version: installation.Version,
+					Image:   existingInstallations[namespace].Image,
+				})
+			}
+		default:
+			err = fmt.Errorf("unknown resource type %s", change.Resource)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to %s %s %s: %w", change.Action, change.Resource, change.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func applyCluster(c *Context, manifest *model.BootstrapManifest) error {
+	clusterCreator, ok := c.CloudProvider.(providers.ClusterCreator)
+	if !ok {
+		return newNotImplementedError(c, "creating clusters")
+	}
+
+	create := *manifest.Cluster.Create
+	create.ClusterName = aws.String(manifest.Cluster.Name)
+
+	_, err := clusterCreator.CreateCluster(c.Ctx, &create)
+	if err != nil {
+		return err
+	}
+
+	// Nodegroups and operators can only be added once the cluster is active
+	if clusterWaiter, ok := c.CloudProvider.(providers.ClusterWaiter); ok {
+		_, err = clusterWaiter.WaitForCluster(c.Ctx, manifest.Cluster.Name)
+		return err
+	}
+
+	return PollUntil(c, "cluster "+manifest.Cluster.Name+" to become active", clusterReadyTimeout, clusterReadyBackoff, func() (bool, string, error) {
+		cluster, err := c.CloudProvider.GetCluster(c.Ctx, manifest.Cluster.Name)
+		if err != nil {
+			return false, "", err
+		}
+
+		if cluster.Status == model.ClusterStatusFailed {
+			return false, "", fmt.Errorf("cluster %s failed to create", manifest.Cluster.Name)
+		}
+		return cluster.Status == model.ClusterStatusActive, string(cluster.Status), nil
+	})
+}
+
+func releaseDeployed(releases []model.InstalledReleases, releaseName string) bool {
+	for _, release := range releases {
+		if release.Name == releaseName {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	v1 "k8s.io/api/core/v1"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// minioPort is the port of the S3 API of the MinIO service.
+const minioPort = 9000
+
+// MinioChart is the chart that InClusterMinio filestores are deployed from. It isn't in the add-on catalog, since
+// each installation has its own release in its namespace, but it uses the same chart mirroring.
+var MinioChart = Addon{
+	Name:        "minio",
+	Description: "Stores the files of a Mattermost installation in its namespace",
+	RepoName:    "minio",
+	RepoURL:     "https://charts.min.io/",
+	Chart:       "minio",
+	ReleaseName: "minio",
+	Version:     "5.2.0",
+	Timeout:     600 * time.Second,
+	Values: map[string]interface{}{
+		// The root credentials are generated into the installation's filestore secret
+		"existingSecret": model.SecretNameFilestore,
+		// The chart requests 16Gi by default, which is sized for large deployments
+		"resources": map[string]interface{}{
+			"requests": map[string]interface{}{"memory": "1Gi"},
+		},
+		"drivesPerNode": 1,
+	},
+	MirrorValues: func(registry string) map[string]interface{} {
+		return mirrorImageValues(registry, map[string]string{
+			"image.repository":   "quay.io/minio/minio",
+			"mcImage.repository": "quay.io/minio/mc",
+		})
+	},
+}
+
+// DeployMinioFilestore deploys MinIO into the namespace of an installation, with credentials in its filestore
+// secret, and returns the filestore config that points Mattermost at it. Deploying again keeps the credentials.
+func DeployMinioFilestore(c *Context, kubeClient *model.KubeClient, clusterName, namespace string, config model.MinioFilestore) (*model.S3Filestore, error) {
+	accessKey, secretKey, err := applyMinioSecret(c, kubeClient, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	addon := MinioChart
+	addon.Namespace = namespace
+
+	values := minioValues(config)
+	logger.FromContext(c.Ctx).Infof("Deploying MinIO with %d replicas and %s of storage each", config.Replicas, config.StorageSize)
+	_, err = installChart(c, clusterName, addon, values)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.S3Filestore{
+		BucketURL:  fmt.Sprintf("%s.%s.svc.cluster.local:%d", addon.ReleaseName, namespace, minioPort),
+		BucketName: config.BucketName,
+		AccessKey:  accessKey,
+		SecretKey:  secretKey,
+	}, nil
+}
+
+// minioValues returns the helm values for the mode, storage and bucket of a MinIO filestore.
+func minioValues(config model.MinioFilestore) map[string]interface{} {
+	persistence := map[string]interface{}{"size": config.StorageSize}
+	if config.StorageClass != "" {
+		persistence["storageClass"] = config.StorageClass
+	}
+
+	mode := "standalone"
+	if config.Replicas > 1 {
+		mode = "distributed"
+	}
+
+	return map[string]interface{}{
+		"mode":        mode,
+		"replicas":    config.Replicas,
+		"persistence": persistence,
+		"buckets": []map[string]interface{}{
+			{"name": config.BucketName, "policy": "none", "purge": false},
+		},
+	}
+}
+
+// applyMinioSecret creates the filestore secret with generated credentials, under the keys that the MinIO chart
+// and the Mattermost operator each expect, unless it already has them. It returns the credentials.
+func applyMinioSecret(c *Context, kubeClient *model.KubeClient, namespace string) (string, string, error) {
+	secrets := kubeClient.Clientset.CoreV1().Secrets(namespace)
+	existing, err := secrets.Get(c.Ctx, model.SecretNameFilestore, metav1.GetOptions{})
+	if err == nil && len(existing.Data["rootUser"]) > 0 && len(existing.Data["rootPassword"]) > 0 {
+		logger.FromContext(c.Ctx).Info("Using existing MinIO credentials")
+		return string(existing.Data["rootUser"]), string(existing.Data["rootPassword"]), nil
+	}
+	if err != nil && !apiErrors.IsNotFound(err) {
+		return "", "", fmt.Errorf("failed to get filestore secret: %w", err)
+	}
+
+	accessKey, err := model.NewRandomAlphanumeric(20)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate MinIO credentials: %w", err)
+	}
+	secretKey, err := model.NewRandomAlphanumeric(40)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate MinIO credentials: %w", err)
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      model.SecretNameFilestore,
+			Namespace: namespace,
+		},
+		Type: v1.SecretTypeOpaque,
+		StringData: map[string]string{
+			"rootUser":     accessKey,
+			"rootPassword": secretKey,
+			"accesskey":    accessKey,
+			"secretkey":    secretKey,
+		},
+	}
+
+	if existing != nil && existing.Name != "" {
+		existing.StringData = secret.StringData
+		_, err = secrets.Update(c.Ctx, existing, metav1.UpdateOptions{})
+	} else {
+		_, err = secrets.Create(c.Ctx, secret, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("error creating filestore secret: %w", err)
+	}
+
+	return accessKey, secretKey, nil
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// mirrorConfig returns the mirror settings in state, which are empty unless the bootstrapper runs air-gapped.
+func mirrorConfig(c *Context) model.MirrorConfig {
+	if c.BootstrapperState.Mirror == nil {
+		return model.MirrorConfig{}
+	}
+	return *c.BootstrapperState.Mirror
+}
+
+// addonChartName returns the chart reference that an add-on is installed from: a chart archive in the mirror
+// directory, a chart in the mirror OCI registry, or the chart in its public repository. addRepo is set for the
+// public repository, which has to be added to the helm client first.
+func addonChartName(c *Context, addon Addon) (chartName string, addRepo bool, err error) {
+	mirror := mirrorConfig(c)
+	switch {
+	case mirror.ChartDirectory != "":
+		archive := filepath.Join(mirror.ChartDirectory, bundleArchiveName(addon))
+		_, err := os.Stat(archive)
+		if err != nil {
+			return "", false, fmt.Errorf("chart archive for %s version %s not found in %s, run mcnb bundle to create it: %w", addon.Name, addon.Version, mirror.ChartDirectory, err)
+		}
+		return archive, false, nil
+	case mirror.ChartRegistry != "":
+		return strings.TrimSuffix(mirror.ChartRegistry, "/") + "/" + addon.Chart, false, nil
+	default:
+		return addon.RepoName + "/" + addon.Chart, true, nil
+	}
+}
+
+// mirrorDirectoryIndex returns an index of the archives of an add-on's chart in the mirror directory.
+func mirrorDirectoryIndex(dir string, addon Addon) (*repo.IndexFile, error) {
+	archives, err := filepath.Glob(filepath.Join(dir, addon.Chart+"-*.tgz"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chart archives: %w", err)
+	}
+
+	index := repo.NewIndexFile()
+	for _, archive := range archives {
+		loaded, err := loader.LoadFile(archive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load chart archive %s: %w", archive, err)
+		}
+		// The archives of charts whose names start with the name of this one match too
+		if loaded.Metadata.Name != addon.Chart {
+			continue
+		}
+		index.Entries[addon.Chart] = append(index.Entries[addon.Chart], &repo.ChartVersion{Metadata: loaded.Metadata})
+	}
+
+	return index, nil
+}
+
+// mirrorRegistryIndex returns an index of the tags of an add-on's chart in the mirror OCI registry.
+func mirrorRegistryIndex(chartRegistry string, addon Addon) (*repo.IndexFile, error) {
+	client, err := registry.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry client: %w", err)
+	}
+
+	ref := strings.TrimPrefix(strings.TrimSuffix(chartRegistry, "/"), "oci://") + "/" + addon.Chart
+	tags, err := client.Tags(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags of %s: %w", ref, err)
+	}
+
+	index := repo.NewIndexFile()
+	for _, tag := range tags {
+		index.Entries[addon.Chart] = append(index.Entries[addon.Chart], &repo.ChartVersion{Metadata: &chart.Metadata{Name: addon.Chart, Version: tag}})
+	}
+
+	return index, nil
+}
+
+// MirrorImage moves an image to registry, keeping its repository path. The registry of the image is the first path
+// component when it looks like a host, and Docker Hub otherwise.
+func MirrorImage(registry, image string) string {
+	first, rest, found := strings.Cut(image, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		image = rest
+	}
+
+	return strings.TrimSuffix(registry, "/") + "/" + image
+}
+
+// BundleAddons downloads the chart archives of the add-ons into dir, for transfer to a mirror directory or OCI
+// registry. Each add-on's pinned version is downloaded. It returns the paths of the archives.
+func BundleAddons(c *Context, dir string, addons []Addon) ([]string, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundle directory: %w", err)
+	}
+
+	settings := cli.New()
+	archives := []string{}
+	for _, addon := range addons {
+		logger.FromContext(c.Ctx).Infof("Downloading %s chart version %s from %s", addon.Chart, addon.Version, addon.RepoURL)
+
+		pull := action.NewPullWithOpts(action.WithConfig(&action.Configuration{}))
+		pull.Settings = settings
+		pull.RepoURL = addon.RepoURL
+		pull.Version = addon.Version
+		pull.DestDir = dir
+		_, err = pull.Run(addon.Chart)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download %s chart: %w", addon.Name, err)
+		}
+
+		archives = append(archives, filepath.Join(dir, bundleArchiveName(addon)))
+	}
+
+	return archives, nil
+}
+
+// bundleArchiveName is the file name that helm gives the chart archive of an add-on's version.
+func bundleArchiveName(addon Addon) string {
+	return fmt.Sprintf("%s-%s.tgz", addon.Chart, addon.Version)
+}
+
+// mirrorImageValues returns helm values that set each value path in images to its image, moved to registry. It
+// implements MirrorValues for charts that take an image repository per component.
+func mirrorImageValues(registry string, images map[string]string) map[string]interface{} {
+	values := map[string]interface{}{}
+	for path, image := range images {
+		keys := strings.Split(path, ".")
+		var value interface{} = MirrorImage(registry, image)
+		for i := len(keys) - 1; i >= 0; i-- {
+			value = map[string]interface{}{keys[i]: value}
+		}
+		values = mergeValues(values, value.(map[string]interface{}))
+	}
+
+	return values
+}
@@ -0,0 +1,62 @@
+package api_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+func TestMirrorImage(t *testing.T) {
+	assert.Equal(t, "registry.example.com/mirror/ingress-nginx/controller", api.MirrorImage("registry.example.com/mirror", "registry.k8s.io/ingress-nginx/controller"))
+	assert.Equal(t, "registry.example.com/cloudnative-pg/postgresql:16.1", api.MirrorImage("registry.example.com/", "ghcr.io/cloudnative-pg/postgresql:16.1"))
+	assert.Equal(t, "registry.example.com/mattermost/mattermost-operator", api.MirrorImage("registry.example.com", "mattermost/mattermost-operator"))
+	assert.Equal(t, "registry.example.com/mirror/busybox", api.MirrorImage("registry.example.com/mirror", "localhost/busybox"))
+}
+
+func TestBundleAddons(t *testing.T) {
+	charts := t.TempDir()
+	archive, err := chartutil.Save(&chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "example", Version: "1.2.0"}}, charts)
+	require.NoError(t, err)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/index.yaml":
+			fmt.Fprintf(w, `apiVersion: v1
+entries:
+  example:
+  - name: example
+    version: 1.2.0
+    urls:
+    - %s/example-1.2.0.tgz
+`, server.URL)
+		case "/example-1.2.0.tgz":
+			http.ServeFile(w, r, archive)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("HELM_REPOSITORY_CONFIG", filepath.Join(t.TempDir(), "repositories.yaml"))
+	t.Setenv("HELM_REPOSITORY_CACHE", t.TempDir())
+
+	dir := filepath.Join(t.TempDir(), "bundle")
+	addon := api.Addon{Name: "example", RepoURL: server.URL, Chart: "example", Version: "1.2.0"}
+	archives, err := api.BundleAddons(&api.Context{Ctx: context.Background()}, dir, []api.Addon{addon})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{filepath.Join(dir, "example-1.2.0.tgz")}, archives)
+	_, err = os.Stat(archives[0])
+	require.NoError(t, err)
+}
@@ -0,0 +1,585 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	mmv1beta1 "github.com/mattermost/mattermost-operator/apis/mattermost/v1beta1"
+	"helm.sh/helm/v3/pkg/release"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// The functions in this file hold the bootstrap logic shared by the HTTP handlers and the mcnb CLI. They expect
+// c.CloudProvider to already be set, and return plain errors that callers translate into a response.
+
+// mattermostReadyTimeout is how long the operator has to roll out a new installation.
+const mattermostReadyTimeout = 20 * time.Minute
+
+// ListInstalledReleases returns the deployed helm releases across every namespace of the cluster.
+func ListInstalledReleases(c *Context, clusterName string) ([]model.InstalledReleases, error) {
+	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create k8s client: %w", err)
+	}
+
+	// Get all namespaces to loop through
+	namespaces, err := kubeClient.Clientset.CoreV1().Namespaces().List(c.Ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	allReleases := []*release.Release{}
+	for _, namespace := range namespaces.Items {
+		logger.FromContext(c.Ctx).Debugf("Getting releases for namespace %s", namespace.Name)
+
+		helmClient, err := c.CloudProvider.HelmClient(c.Ctx, clusterName, namespace.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to authenticate helm client: %w", err)
+		}
+
+		releases, err := helmClient.ListDeployedReleases()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deployed releases: %w", err)
+		}
+
+		allReleases = append(allReleases, releases...)
+	}
+
+	releasesRes := []model.InstalledReleases{}
+	for _, release := range allReleases {
+		releasesRes = append(releasesRes, model.InstalledReleases{
+			Name:      release.Name,
+			Version:   release.Chart.Metadata.Version,
+			Namespace: release.Namespace,
+			Status:    release.Info.Status.String(),
+		})
+	}
+
+	return releasesRes, nil
+}
+
+// DeployMattermostOperator installs or upgrades the Mattermost operator add-on. A nil request uses the pinned
+// chart version.
+func DeployMattermostOperator(c *Context, clusterName string, request *model.InstallAddonRequest) error {
+	return installAddonByName(c, clusterName, "mattermost-operator", request)
+}
+
+// DeployNginxOperator installs or upgrades the ingress-nginx add-on, configured by request. A nil request uses the
+// provider's defaults.
+func DeployNginxOperator(c *Context, clusterName string, request *model.DeployNginxRequest) error {
+	addon, _ := GetAddon("ingress-nginx")
+	addon.ProviderValues = func(c *Context) (map[string]interface{}, error) {
+		return NginxValues(c, request)
+	}
+
+	installRequest := &model.InstallAddonRequest{}
+	if request != nil {
+		installRequest.Version = request.Version
+	}
+
+	return InstallAddon(c, clusterName, addon, installRequest)
+}
+
+// NginxValues builds the helm values for the ingress-nginx release. The provider's defaults for the request's TLS
+// mode and load balancer type are layered over the bootstrapper's, followed by the request's service annotations
+// and value overrides.
+func NginxValues(c *Context, request *model.DeployNginxRequest) (map[string]interface{}, error) {
+	if request == nil {
+		request = &model.DeployNginxRequest{}
+	}
+
+	if err := request.IsValid(); err != nil {
+		return nil, model.NewInvalidRequestError("Invalid ingress-nginx configuration").Wrap(err)
+	}
+
+	values := map[string]interface{}{
+		"controller": map[string]interface{}{
+			"config": map[string]interface{}{
+				"use-forwarded-headers": "true",
+			},
+		},
+	}
+
+	if request.TLSMode == "" || request.TLSMode == model.NginxTLSModeNone {
+		values = mergeValues(values, map[string]interface{}{
+			"controller": map[string]interface{}{
+				"service": map[string]interface{}{
+					"enableHttps": false,
+				},
+			},
+		})
+	}
+
+	ingressDefaulter, ok := c.CloudProvider.(providers.IngressDefaulter)
+	if ok {
+		providerValues, err := ingressDefaulter.NginxValues(c.Ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		values = mergeValues(values, providerValues)
+	} else if request.TLSMode == model.NginxTLSModeACM || request.LoadBalancerType != "" {
+		return nil, model.NewInvalidRequestError(fmt.Sprintf("The %s provider has no ingress-nginx defaults, set serviceAnnotations instead of tlsMode acm or loadBalancerType", c.CloudProviderName))
+	}
+
+	if len(request.ServiceAnnotations) > 0 {
+		annotations := map[string]interface{}{}
+		for key, value := range request.ServiceAnnotations {
+			annotations[key] = value
+		}
+		values = mergeValues(values, map[string]interface{}{
+			"controller": map[string]interface{}{
+				"service": map[string]interface{}{
+					"annotations": annotations,
+				},
+			},
+		})
+	}
+
+	return mergeValues(values, request.Values), nil
+}
+
+// DeployPGOperator installs or upgrades the CloudNativePG operator add-on, after the provider's filestore
+// pre-install steps. A nil request uses the pinned chart version.
+func DeployPGOperator(c *Context, clusterName string, request *model.InstallAddonRequest) error {
+	return installAddonByName(c, clusterName, "cnpg", request)
+}
+
+// DeleteMattermostOperator uninstalls the Mattermost operator add-on.
+func DeleteMattermostOperator(c *Context, clusterName string) error {
+	return uninstallAddonByName(c, clusterName, "mattermost-operator")
+}
+
+// DeleteNginxOperator uninstalls the ingress-nginx add-on.
+func DeleteNginxOperator(c *Context, clusterName string) error {
+	return uninstallAddonByName(c, clusterName, "ingress-nginx")
+}
+
+// DeletePGOperator uninstalls the CloudNativePG operator add-on.
+func DeletePGOperator(c *Context, clusterName string) error {
+	return uninstallAddonByName(c, clusterName, "cnpg")
+}
+
+func installAddonByName(c *Context, clusterName string, name string, request *model.InstallAddonRequest) error {
+	addon, ok := GetAddon(name)
+	if !ok {
+		return fmt.Errorf("unknown add-on %s", name)
+	}
+
+	return InstallAddon(c, clusterName, addon, request)
+}
+
+func uninstallAddonByName(c *Context, clusterName string, name string) error {
+	addon, ok := GetAddon(name)
+	if !ok {
+		return fmt.Errorf("unknown add-on %s", name)
+	}
+
+	return UninstallAddon(c, clusterName, addon)
+}
+
+func uninstallRelease(c *Context, clusterName, namespace, releaseName string) error {
+	c.Ctx = logger.WithField(c.Ctx, "action", "delete-"+releaseName)
+	c.Ctx = logger.WithNamespace(c.Ctx, namespace)
+	c.Ctx = logger.WithClusterName(c.Ctx, clusterName)
+
+	helmClient, err := c.CloudProvider.HelmClient(c.Ctx, clusterName, namespace)
+	if err != nil {
+		return fmt.Errorf("failed to authenticate helm client: %w", err)
+	}
+
+	err = helmClient.UninstallReleaseByName(releaseName)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s release: %w", releaseName, err)
+	}
+
+	return nil
+}
+
+// ListMattermostInstallations returns the Mattermost custom resources across all namespaces of the cluster.
+func ListMattermostInstallations(c *Context, clusterName string) ([]mmv1beta1.Mattermost, error) {
+	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	installations, err := kubeClient.MattermostClientsetV1Beta.MattermostV1beta1().Mattermosts("").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mattermost installations: %w", err)
+	}
+
+	return installations.Items, nil
+}
+
+// CreateMattermostInstallation creates the namespace, database, license and filestore secrets and the Mattermost
+// custom resource for a new installation. The request is expected to have been validated by the caller.
+func CreateMattermostInstallation(c *Context, clusterName string, create *model.CreateMattermostWorkspaceRequest) (*mmv1beta1.Mattermost, error) {
+	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	namespaceName := model.InstallationNamespace(create.InstallationName)
+
+	err = ensureNamespace(c, kubeClient, namespaceName)
+	if err != nil {
+		return nil, err
+	}
+
+	var writer string
+	var reader string
+	var databaseSecretName string
+
+	if create.DBConnectionOption == model.DatabaseOptionCreateForMe {
+		config := create.CNPGDatabase.WithDefaults(create.Size)
+		dbCluster, err := CreateCNPGDatabase(c, kubeClient, cnpgClusterName(namespaceName), namespaceName, config)
+		if err != nil {
+			return nil, err
+		}
+
+		writer, reader, err = cnpgDatabaseConnectionStrings(c, kubeClient, dbCluster, config)
+		if err != nil {
+			return nil, err
+		}
+	} else if create.DBConnectionOption == model.DatabaseOptionCreateForMeRDS {
+		rdsDatabase := model.CreateManagedDatabaseRequest{}
+		if create.RDSDatabase != nil {
+			rdsDatabase = *create.RDSDatabase
+		}
+		rdsDatabase.InstallationName = create.InstallationName
+
+		// The database secret is written along with the database
+		_, err = CreateManagedDatabase(c, clusterName, &rdsDatabase)
+		if err != nil {
+			return nil, err
+		}
+		databaseSecretName = model.SecretNameDatabase
+	} else if create.DBConnectionOption == model.DatabaseOptionExisting {
+		if create.ExistingDBSecretName != "" {
+			databaseSecretName = create.ExistingDBSecretName
+		} else {
+			writer = create.ExistingDBConnection.ConnectionString
+			reader = create.ExistingDBConnection.ConnectionString
+		}
+	}
+
+	if databaseSecretName == "" {
+		err = applyDatabaseSecret(c, kubeClient, namespaceName, writer, reader)
+		if err != nil {
+			return nil, err
+		}
+
+		databaseSecretName = model.SecretNameDatabase
+	}
+
+	// License Secret
+	licenseSecret := model.NewMattermostLicenseSecret(namespaceName, create.License)
+
+	// Create the secret
+	_, err = kubeClient.Clientset.CoreV1().Secrets(namespaceName).Create(context.TODO(), licenseSecret, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error creating license secret: %w", err)
+	}
+
+	if create.FilestoreOption == model.FilestoreOptionAWSS3 {
+		bucketRequest := model.CreateBucketRequest{}
+		if create.AWSS3Filestore != nil {
+			bucketRequest = *create.AWSS3Filestore
+		}
+		bucketRequest.InstallationName = create.InstallationName
+
+		bucket, err := CreateFilestoreBucket(c, kubeClient, clusterName, &bucketRequest)
+		if err != nil {
+			return nil, err
+		}
+		create.S3Filestore = bucket.S3Filestore()
+	} else if create.FilestoreOption == model.FilestoreOptionInClusterMinio {
+		filestore, err := DeployMinioFilestore(c, kubeClient, clusterName, namespaceName, create.MinioFilestore.WithDefaults())
+		if err != nil {
+			return nil, err
+		}
+		create.S3Filestore = filestore
+	}
+
+	filestoreSecret := create.GetMMOperatorFilestoreSecret(namespaceName)
+	if filestoreSecret != nil {
+		// Create the filestore secret
+		filestoreSecret, err = kubeClient.Clientset.CoreV1().Secrets(namespaceName).Create(context.TODO(), filestoreSecret, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("error creating filestore secret: %w", err)
+		}
+	}
+
+	filestore := create.GetMMOperatorFilestore(namespaceName, filestoreSecret)
+	if filestore.External != nil && filestore.External.Secret == "" && create.FilestoreSecretName != "" {
+		filestore.External.Secret = create.FilestoreSecretName
+	}
+
+	// MinIO in the installation's namespace is reached over plain HTTP, and has no KMS for server side encryption
+	useS3TLS := create.FilestoreOption != model.FilestoreOptionInClusterMinio
+
+	mattermostCRD := &mmv1beta1.Mattermost{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      namespaceName,
+			Namespace: namespaceName,
+		},
+		Spec: mmv1beta1.MattermostSpec{
+			Size:    create.Size,
+			Version: create.Version,
+			Ingress: &mmv1beta1.Ingress{
+				Enabled:      true,
+				Host:         create.FullDomainName,
+				IngressClass: aws.String("nginx"),
+				Annotations: map[string]string{
+					"kubernetes.io/ingress.class": "nginx",
+				},
+			},
+			Database: mmv1beta1.Database{
+				External: &mmv1beta1.ExternalDatabase{
+					Secret: databaseSecretName,
+				},
+			},
+			FileStore: filestore,
+			MattermostEnv: []v1.EnvVar{
+				{Name: "MM_FILESETTINGS_AMAZONS3SSE", Value: strconv.FormatBool(useS3TLS)},
+				{Name: "MM_FILESETTINGS_AMAZONS3SSL", Value: strconv.FormatBool(useS3TLS)},
+				{Name: model.MMENVLicense, ValueFrom: &v1.EnvVarSource{
+					SecretKeyRef: &v1.SecretKeySelector{Key: "license", LocalObjectReference: v1.LocalObjectReference{Name: licenseSecret.ObjectMeta.Name}, Optional: aws.Bool(true)}, // Add comma to separate items
+				}},
+				{Name: "MM_CONFIG", ValueFrom: &v1.EnvVarSource{
+					SecretKeyRef: &v1.SecretKeySelector{
+						Key:                  "MM_CONFIG",
+						LocalObjectReference: v1.LocalObjectReference{Name: databaseSecretName},
+					},
+				}},
+			},
+			PodTemplate: &mmv1beta1.PodTemplate{
+				SecurityContext: &v1.PodSecurityContext{
+					FSGroup: aws.Int64(2000),
+				},
+			},
+		},
+	}
+
+	if registry := mirrorConfig(c).ImageRegistry; registry != "" {
+		mattermostCRD.Spec.Image = MirrorImage(registry, mmv1beta1.DefaultMattermostImage)
+	}
+
+	// Create the Mattermost CRD
+	mattermost, err := kubeClient.MattermostClientsetV1Beta.MattermostV1beta1().Mattermosts(namespaceName).Create(context.TODO(), mattermostCRD, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error creating Mattermost CRD: %w", err)
+	}
+
+	return waitForMattermost(c, kubeClient, mattermost)
+}
+
+// waitForMattermost waits until the operator has reconciled the latest generation of a Mattermost custom resource
+// and the installation is stable, and returns it.
+func waitForMattermost(c *Context, kubeClient *model.KubeClient, mattermost *mmv1beta1.Mattermost) (*mmv1beta1.Mattermost, error) {
+	mattermosts := kubeClient.MattermostClientsetV1Beta.MattermostV1beta1().Mattermosts(mattermost.Namespace)
+	obj, err := WaitForObject(c, "Mattermost installation "+mattermost.Name, mattermostReadyTimeout, namedListWatch(c.Ctx, mattermosts, mattermost.Name), &mmv1beta1.Mattermost{}, func(obj runtime.Object) (bool, string, error) {
+		observed := obj.(*mmv1beta1.Mattermost)
+		if observed.Status.ObservedGeneration < observed.Generation {
+			return false, "waiting for the operator", nil
+		}
+
+		state := string(observed.Status.State)
+		if observed.Status.Error != "" {
+			state += ": " + observed.Status.Error
+		}
+		return observed.Status.State == mmv1beta1.Stable, state, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return obj.(*mmv1beta1.Mattermost), nil
+}
+
+// PatchMattermostInstallation applies a patch request to an existing installation, updating the filestore,
+// license and database secrets as needed. The request is expected to have been validated by the caller.
+func PatchMattermostInstallation(c *Context, clusterName string, installationName string, patchRequest *model.PatchMattermostWorkspaceRequest) (*mmv1beta1.Mattermost, error) {
+	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	installation, err := kubeClient.MattermostClientsetV1Beta.MattermostV1beta1().Mattermosts(installationName).Get(context.TODO(), installationName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get installation: %w", err)
+	}
+
+	MMFilestore := installation.Spec.FileStore
+	if patchRequest.HasFilestoreChanges() {
+		logger.FromContext(c.Ctx).Info("Filestore changes detected")
+		// Fetch the installation's existing filestore secrets
+		// Merge the new updated ones into the old one
+		// Push the updated secret to k8s
+		// Update the installation CRD with anything necessary (ie, if the bucket url or name changes)
+		filestorePatch := patchRequest.FilestorePatch
+		if filestorePatch.FilestoreOption == model.FilestoreOptionExistingS3 {
+			existingFilestoreSecret, err := kubeClient.Clientset.CoreV1().Secrets(installationName).Get(c.Ctx, model.SecretNameFilestore, metav1.GetOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get filestore secret: %w", err)
+			}
+
+			filestore := model.KubeS3FilestoreSecretToS3Filestore(existingFilestoreSecret, &installation.Spec.FileStore)
+
+			// Update the existing filestore with the new values
+			if filestorePatch.S3Filestore.BucketName != "" {
+				filestore.BucketName = filestorePatch.S3Filestore.BucketName
+			}
+
+			if filestorePatch.S3Filestore.BucketURL != "" {
+				filestore.BucketURL = filestorePatch.S3Filestore.BucketURL
+			}
+
+			if filestorePatch.S3Filestore.AccessKey != "" {
+				filestore.AccessKey = filestorePatch.S3Filestore.AccessKey
+			}
+
+			if filestorePatch.S3Filestore.SecretKey != "" {
+				filestore.SecretKey = filestorePatch.S3Filestore.SecretKey
+			}
+
+			existingFilestoreSecret.StringData = map[string]string{
+				"accesskey": filestore.AccessKey,
+				"secretkey": filestore.SecretKey,
+			}
+
+			_, err = kubeClient.Clientset.CoreV1().Secrets(installationName).Update(context.TODO(), existingFilestoreSecret, metav1.UpdateOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to update filestore secret: %w", err)
+			}
+
+			MMFilestore.External.Bucket = filestore.BucketName
+			MMFilestore.External.URL = filestore.BucketURL
+			MMFilestore.External.Secret = model.SecretNameFilestore
+		} else if filestorePatch.FilestoreOption == model.FilestoreOptionInClusterLocal {
+			MMFilestore.Local.StorageSize = filestorePatch.LocalFileStore.StorageSize
+			MMFilestore.Local.Enabled = true
+		} else if filestorePatch.FilestoreOption == model.FilestoreOptionInClusterExternal {
+			MMFilestore.ExternalVolume.VolumeClaimName = filestorePatch.LocalExternalFileStore.VolumeClaimName
+		}
+	}
+
+	if patchRequest.License != nil {
+		existingLicenseSecretName := model.GetLicenseSecretName(installation)
+
+		// A secret for this already exists, so delete it
+		if existingLicenseSecretName != "" {
+			err := kubeClient.Clientset.CoreV1().Secrets(installationName).Delete(c.Ctx, existingLicenseSecretName, metav1.DeleteOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete existing license secret: %w", err)
+			}
+		}
+
+		licenseSecret := model.NewMattermostLicenseSecret(installationName, *patchRequest.License)
+		_, err = kubeClient.Clientset.CoreV1().Secrets(installationName).Create(context.TODO(), licenseSecret, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update license secret: %w", err)
+		}
+
+		setEnv := false
+		for i, envVar := range installation.Spec.MattermostEnv {
+			if envVar.Name == model.MMENVLicense {
+				setEnv = true
+				installation.Spec.MattermostEnv[i] = v1.EnvVar{Name: model.MMENVLicense, ValueFrom: &v1.EnvVarSource{
+					SecretKeyRef: &v1.SecretKeySelector{Key: "license", LocalObjectReference: v1.LocalObjectReference{Name: licenseSecret.ObjectMeta.Name}, Optional: aws.Bool(true)}, // Add comma to separate items
+				}}
+			}
+		}
+
+		if !setEnv {
+			installation.Spec.MattermostEnv = append(installation.Spec.MattermostEnv, v1.EnvVar{Name: model.MMENVLicense, ValueFrom: &v1.EnvVarSource{
+				SecretKeyRef: &v1.SecretKeySelector{Key: "license", LocalObjectReference: v1.LocalObjectReference{Name: licenseSecret.ObjectMeta.Name}, Optional: aws.Bool(true)}, // Add comma to separate items
+			}})
+		}
+	}
+
+	database := installation.Spec.Database
+	if patchRequest.DatabasePatch != nil {
+		databaseSecret, err := kubeClient.Clientset.CoreV1().Secrets(installationName).Get(c.Ctx, model.SecretNameDatabase, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get database secret: %w", err)
+		}
+
+		existingStringData := databaseSecret.Data
+
+		if patchRequest.DatabasePatch.ConnectionString != "" {
+			existingStringData["DB_CONNECTION_CHECK_URL"] = []byte(patchRequest.DatabasePatch.ConnectionString)
+			existingStringData["DB_CONNECTION_STRING"] = []byte(patchRequest.DatabasePatch.ConnectionString)
+		}
+
+		if patchRequest.DatabasePatch.ReplicaConnectionString != "" {
+			existingStringData["MM_SQLSETTINGS_DATASOURCEREPLICAS"] = []byte(patchRequest.DatabasePatch.ReplicaConnectionString)
+		}
+
+		updatedSecret, err := kubeClient.Clientset.CoreV1().Secrets(installationName).Update(c.Ctx, databaseSecret, metav1.UpdateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update database secret: %w", err)
+		}
+
+		database.External.Secret = updatedSecret.ObjectMeta.Name
+	}
+
+	if patchRequest.CNPGDatabasePatch != nil {
+		err = PatchCNPGDatabase(c, kubeClient, installationName, patchRequest.CNPGDatabasePatch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Update environment variables if provided
+	if len(patchRequest.MattermostEnv) > 0 {
+		logger.FromContext(c.Ctx).Infof("Updating environment variables, count: %d", len(patchRequest.MattermostEnv))
+		installation.Spec.MattermostEnv = patchRequest.MattermostEnv
+	}
+
+	installation.Spec.Version = patchRequest.Version
+	installation.Spec.Image = patchRequest.Image
+	installation.Spec.FileStore = MMFilestore
+	installation.Spec.Database = database
+
+	installation, err = kubeClient.MattermostClientsetV1Beta.MattermostV1beta1().Mattermosts(installationName).Update(context.TODO(), installation, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update installation: %w", err)
+	}
+
+	return installation, nil
+}
+
+// DeleteMattermostInstallation deletes the Mattermost custom resource and its namespace.
+func DeleteMattermostInstallation(c *Context, clusterName string, installationName string) error {
+	if installationName == "" {
+		return errors.New("installation name must be provided")
+	}
+
+	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
+	if err != nil {
+		return fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	// Delete the CRD
+	err = kubeClient.MattermostClientsetV1Beta.MattermostV1beta1().Mattermosts(installationName).Delete(context.TODO(), installationName, metav1.DeleteOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to delete CRD: %w", err)
+	}
+
+	// Delete the namespace
+	err = kubeClient.Clientset.CoreV1().Namespaces().Delete(context.TODO(), installationName, metav1.DeleteOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to delete namespace: %w", err)
+	}
+
+	return nil
+}
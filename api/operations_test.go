@@ -0,0 +1,91 @@
+package api_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNginxValues(t *testing.T) {
+	newContext := func(name string, provider providers.CloudProvider) *api.Context {
+		return &api.Context{Ctx: context.Background(), CloudProviderName: name, CloudProvider: provider}
+	}
+
+	service := func(t *testing.T, values map[string]interface{}) map[string]interface{} {
+		controller, ok := values["controller"].(map[string]interface{})
+		require.True(t, ok)
+		service, ok := controller["service"].(map[string]interface{})
+		require.True(t, ok)
+		return service
+	}
+
+	t.Run("Defaults", func(t *testing.T) {
+		values, err := api.NginxValues(newContext("aws", providers.GetAWSProvider(nil)), nil)
+		require.NoError(t, err)
+
+		controller := values["controller"].(map[string]interface{})
+		assert.Equal(t, map[string]interface{}{"use-forwarded-headers": "true"}, controller["config"])
+		assert.Equal(t, false, service(t, values)["enableHttps"])
+		assert.NotContains(t, service(t, values)["annotations"], "service.beta.kubernetes.io/aws-load-balancer-type")
+	})
+
+	t.Run("NLB", func(t *testing.T) {
+		values, err := api.NginxValues(newContext("aws", providers.GetAWSProvider(nil)), &model.DeployNginxRequest{LoadBalancerType: model.NginxLoadBalancerNLB})
+		require.NoError(t, err)
+
+		assert.Equal(t, "nlb", service(t, values)["annotations"].(map[string]interface{})["service.beta.kubernetes.io/aws-load-balancer-type"])
+	})
+
+	t.Run("ACM", func(t *testing.T) {
+		values, err := api.NginxValues(newContext("aws", providers.GetAWSProvider(nil)), &model.DeployNginxRequest{
+			TLSMode:        model.NginxTLSModeACM,
+			CertificateARN: "arn:aws:acm:us-east-1:123456789012:certificate/test",
+		})
+		require.NoError(t, err)
+
+		assert.NotContains(t, service(t, values), "enableHttps")
+		assert.Equal(t, "arn:aws:acm:us-east-1:123456789012:certificate/test", service(t, values)["annotations"].(map[string]interface{})["service.beta.kubernetes.io/aws-load-balancer-ssl-cert"])
+		assert.Equal(t, map[string]interface{}{"http": "http", "https": "http"}, service(t, values)["targetPorts"])
+	})
+
+	t.Run("ACMWithoutCertificate", func(t *testing.T) {
+		_, err := api.NginxValues(newContext("aws", providers.GetAWSProvider(nil)), &model.DeployNginxRequest{TLSMode: model.NginxTLSModeACM})
+		require.Error(t, err)
+	})
+
+	t.Run("UnsupportedLoadBalancerType", func(t *testing.T) {
+		_, err := api.NginxValues(newContext("gcp", providers.NewGCPProvider(nil)), &model.DeployNginxRequest{LoadBalancerType: model.NginxLoadBalancerCLB})
+		require.Error(t, err)
+
+		_, err = api.NginxValues(newContext("custom", providers.GetCustomProvider(nil)), &model.DeployNginxRequest{LoadBalancerType: model.NginxLoadBalancerInternal})
+		require.Error(t, err)
+	})
+
+	t.Run("Overrides", func(t *testing.T) {
+		values, err := api.NginxValues(newContext("gcp", providers.NewGCPProvider(nil)), &model.DeployNginxRequest{
+			TLSMode:            model.NginxTLSModeCertManager,
+			LoadBalancerType:   model.NginxLoadBalancerInternal,
+			ServiceAnnotations: map[string]string{"external-dns.alpha.kubernetes.io/hostname": "mattermost.example.com"},
+			Values: map[string]interface{}{
+				"controller": map[string]interface{}{
+					"replicaCount": 2,
+					"service":      map[string]interface{}{"externalTrafficPolicy": "Local"},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]interface{}{
+			"networking.gke.io/load-balancer-type":      "Internal",
+			"external-dns.alpha.kubernetes.io/hostname": "mattermost.example.com",
+		}, service(t, values)["annotations"])
+		assert.Equal(t, "Local", service(t, values)["externalTrafficPolicy"])
+		assert.Equal(t, 2, values["controller"].(map[string]interface{})["replicaCount"])
+		assert.Contains(t, values["controller"], "config")
+	})
+}
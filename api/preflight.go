@@ -0,0 +1,319 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudnative-pg/cloudnative-pg/pkg/versions"
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// preflightNamespace is where probe jobs run, since the namespace of the installation isn't created until the
+// installation is.
+const preflightNamespace = "default"
+
+// preflightTimeout bounds each probe job, including pulling its image.
+const preflightTimeout = 2 * time.Minute
+
+// preflightObjectKey is the object that the filestore probe writes and then removes, to check write access.
+const preflightObjectKey = ".mcnb-preflight"
+
+// mcImage is the MinIO client at the tag of MinioChart, so that it is mirrored along with the chart's images.
+const mcImage = "quay.io/minio/mc:RELEASE.2024-04-18T16-45-29Z"
+
+// preflightProbe is a check that runs its steps in order in a job. Credentials in env are stored in a secret rather
+// than in the job, and are passed to every step.
+type preflightProbe struct {
+	name  string
+	env   map[string]string
+	steps []v1.Container
+	// skipped explains why the check can't be run before the installation is created.
+	skipped string
+}
+
+// ValidateMattermostInstallation tests the database and S3 credentials of a create installation request before it
+// is created, with a probe job for each connection.
+func ValidateMattermostInstallation(c *Context, clusterName string, create *model.CreateMattermostWorkspaceRequest) (*model.InstallationValidation, error) {
+	kubeClient, err := c.CloudProvider.KubeClient(c.Ctx, clusterName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	return RunPreflightChecks(c, kubeClient, create)
+}
+
+// RunPreflightChecks runs the probes of a create installation request one after another, and removes their jobs
+// and secrets. Connections to resources that the installation creates itself, or whose credentials are in existing
+// secrets, are skipped.
+func RunPreflightChecks(c *Context, kubeClient *model.KubeClient, create *model.CreateMattermostWorkspaceRequest) (*model.InstallationValidation, error) {
+	checks := []model.PreflightCheck{}
+	for _, probe := range preflightProbes(c, create) {
+		if probe.skipped != "" {
+			checks = append(checks, model.PreflightCheck{Name: probe.name, Status: model.PreflightCheckSkipped, Message: probe.skipped})
+			continue
+		}
+
+		check, err := runPreflightProbe(c, kubeClient, probe)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, *check)
+	}
+
+	return model.NewInstallationValidation(checks), nil
+}
+
+// preflightProbes returns the database and filestore checks of a create installation request.
+func preflightProbes(c *Context, create *model.CreateMattermostWorkspaceRequest) []preflightProbe {
+	probes := []preflightProbe{}
+
+	switch {
+	case create.DBConnectionOption != model.DatabaseOptionExisting:
+		probes = append(probes, preflightProbe{name: model.PreflightCheckDatabase, skipped: "The database is created along with the installation"})
+	case create.ExistingDBSecretName != "" || create.ExistingDBConnection == nil:
+		probes = append(probes, preflightProbe{name: model.PreflightCheckDatabase, skipped: fmt.Sprintf("Connection strings in existing secret %q aren't checked", create.ExistingDBSecretName)})
+	default:
+		probes = append(probes, postgresProbe(c, model.PreflightCheckDatabase, create.ExistingDBConnection.ConnectionString))
+		if create.ExistingDBConnection.ReplicaConnectionString != "" {
+			probes = append(probes, postgresProbe(c, model.PreflightCheckDatabaseReplicas, create.ExistingDBConnection.ReplicaConnectionString))
+		}
+	}
+
+	switch {
+	case create.FilestoreOption != model.FilestoreOptionExistingS3:
+		probes = append(probes, preflightProbe{name: model.PreflightCheckFilestore, skipped: fmt.Sprintf("The %s filestore has no credentials to check", create.FilestoreOption)})
+	case create.FilestoreSecretName != "" || create.S3Filestore == nil:
+		probes = append(probes, preflightProbe{name: model.PreflightCheckFilestore, skipped: fmt.Sprintf("Keys in existing secret %q aren't checked", create.FilestoreSecretName)})
+	case create.S3Filestore.UseServiceAccount:
+		probes = append(probes, preflightProbe{name: model.PreflightCheckFilestore, skipped: "Service account access can only be used by the installation's pods"})
+	default:
+		probes = append(probes, s3Probe(c, create.S3Filestore))
+	}
+
+	return probes
+}
+
+// postgresProbe connects to a PostgreSQL connection string with psql, from the PostgreSQL image that CNPG uses.
+func postgresProbe(c *Context, name, connectionString string) preflightProbe {
+	return preflightProbe{
+		name: name,
+		env: map[string]string{
+			"CONNECTION_STRING": connectionString,
+			"PGCONNECT_TIMEOUT": "10",
+		},
+		steps: []v1.Container{{
+			Name:  "psql",
+			Image: preflightImage(c, versions.DefaultImageName),
+			Args:  []string{"psql", "$(CONNECTION_STRING)", "--no-psqlrc", "--tuples-only", "--command", "SELECT 1"},
+		}},
+	}
+}
+
+// s3Probe writes an object to the bucket and removes it again, with the MinIO client and the supplied keys. The
+// endpoint uses TLS, as the Mattermost operator does for external filestores.
+func s3Probe(c *Context, filestore *model.S3Filestore) preflightProbe {
+	endpoint := url.URL{Scheme: "https", User: url.UserPassword(filestore.AccessKey, filestore.SecretKey), Host: filestore.BucketURL}
+	object := fmt.Sprintf("target/%s/%s", filestore.BucketName, preflightObjectKey)
+	image := preflightImage(c, mcImage)
+
+	return preflightProbe{
+		name: model.PreflightCheckFilestore,
+		// mc reads the endpoint and keys of the target alias from MC_HOST_target
+		env: map[string]string{"MC_HOST_target": endpoint.String()},
+		steps: []v1.Container{
+			// /etc/hostname is mounted into every container, so it serves as a small object to upload
+			{Name: "write", Image: image, Args: []string{"--quiet", "cp", "/etc/hostname", object}},
+			{Name: "remove", Image: image, Args: []string{"--quiet", "rm", object}},
+		},
+	}
+}
+
+// preflightImage returns the image, moved to the mirror registry if one is set.
+func preflightImage(c *Context, image string) string {
+	if registry := mirrorConfig(c).ImageRegistry; registry != "" {
+		return MirrorImage(registry, image)
+	}
+	return image
+}
+
+// runPreflightProbe runs a probe as a job and waits for it to finish. A failed probe reports the termination message
+// of its failed step, which falls back to the end of the step's logs.
+func runPreflightProbe(c *Context, kubeClient *model.KubeClient, probe preflightProbe) (*model.PreflightCheck, error) {
+	suffix, err := model.NewRandomAlphanumeric(6)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate job name: %w", err)
+	}
+	name := strings.ToLower(fmt.Sprintf("mcnb-preflight-%s-%s", probe.name, suffix))
+
+	secrets := kubeClient.Clientset.CoreV1().Secrets(preflightNamespace)
+	jobs := kubeClient.Clientset.BatchV1().Jobs(preflightNamespace)
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: preflightNamespace},
+		Type:       v1.SecretTypeOpaque,
+		StringData: probe.env,
+	}
+	_, err = secrets.Create(c.Ctx, secret, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s probe secret: %w", probe.name, err)
+	}
+	defer func() {
+		err := secrets.Delete(c.Ctx, name, metav1.DeleteOptions{})
+		if err != nil {
+			logger.FromContext(c.Ctx).WithError(err).Warnf("Failed to delete %s probe secret", probe.name)
+		}
+	}()
+
+	keys := []string{}
+	for key := range probe.env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env := []v1.EnvVar{}
+	for _, key := range keys {
+		env = append(env, v1.EnvVar{Name: key, ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: name}, Key: key},
+		}})
+	}
+	steps := []v1.Container{}
+	for _, step := range probe.steps {
+		step.Env = env
+		step.TerminationMessagePolicy = v1.TerminationMessageFallbackToLogsOnError
+		steps = append(steps, step)
+	}
+
+	backoffLimit := int32(0)
+	activeDeadlineSeconds := int64(preflightTimeout.Seconds())
+	ttlSecondsAfterFinished := int32(300)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: preflightNamespace},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			ActiveDeadlineSeconds:   &activeDeadlineSeconds,
+			TTLSecondsAfterFinished: &ttlSecondsAfterFinished,
+			Template: v1.PodTemplateSpec{
+				Spec: v1.PodSpec{
+					RestartPolicy:  v1.RestartPolicyNever,
+					InitContainers: steps[:len(steps)-1],
+					Containers:     steps[len(steps)-1:],
+				},
+			},
+		},
+	}
+
+	logger.FromContext(c.Ctx).Infof("Running %s probe", probe.name)
+	_, err = jobs.Create(c.Ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s probe job: %w", probe.name, err)
+	}
+	defer func() {
+		propagation := metav1.DeletePropagationBackground
+		err := jobs.Delete(c.Ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil {
+			logger.FromContext(c.Ctx).WithError(err).Warnf("Failed to delete %s probe job", probe.name)
+		}
+	}()
+
+	// The job fails itself at its deadline, so the wait only times out if the job controller doesn't act on it
+	obj, err := WaitForObject(c, fmt.Sprintf("%s probe", probe.name), preflightTimeout+30*time.Second, namedListWatch(c.Ctx, jobs, name), &batchv1.Job{}, func(obj runtime.Object) (bool, string, error) {
+		finished, failed, _ := jobFinished(obj.(*batchv1.Job))
+		if !finished {
+			return false, "running", nil
+		}
+		if failed {
+			return true, "failed", nil
+		}
+		return true, "passed", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, failed, message := jobFinished(obj.(*batchv1.Job))
+	if !failed {
+		return &model.PreflightCheck{Name: probe.name, Status: model.PreflightCheckPassed}, nil
+	}
+
+	if stepMessage := failedStepMessage(c, kubeClient, name); stepMessage != "" {
+		message = stepMessage
+	}
+	return &model.PreflightCheck{Name: probe.name, Status: model.PreflightCheckFailed, Message: message}, nil
+}
+
+// jobFinished reports whether a job completed or failed, and the message of its failed condition.
+func jobFinished(job *batchv1.Job) (finished bool, failed bool, message string) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != v1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, false, ""
+		case batchv1.JobFailed:
+			return true, true, condition.Message
+		}
+	}
+	return false, false, ""
+}
+
+// failedStepMessage returns why a step of a job's pod failed, or why it couldn't start, such as an image that
+// can't be pulled. It is empty when the pod is gone or no step failed.
+func failedStepMessage(c *Context, kubeClient *model.KubeClient, jobName string) string {
+	pods, err := kubeClient.Clientset.CoreV1().Pods(preflightNamespace).List(c.Ctx, metav1.ListOptions{LabelSelector: "job-name=" + jobName})
+	if err != nil {
+		logger.FromContext(c.Ctx).WithError(err).Warn("Failed to list probe pods")
+		return ""
+	}
+
+	for _, pod := range pods.Items {
+		statuses := [][]v1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses}
+		for _, status := range slices.Concat(statuses...) {
+			if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+				return fmt.Sprintf("%s: %s", status.Name, strings.TrimSpace(terminated.Message))
+			}
+			if waiting := status.State.Waiting; waiting != nil && waiting.Reason != "" && waiting.Reason != "PodInitializing" {
+				return strings.TrimSpace(fmt.Sprintf("%s: %s %s", status.Name, waiting.Reason, waiting.Message))
+			}
+		}
+	}
+
+	return ""
+}
+
+func handleValidateMattermostInstallation(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName := vars["name"]
+	if clusterName == "" || clusterName == "undefined" {
+		c.SetInvalidParam("name")
+		return
+	}
+
+	create, err := model.NewCreateMattermostWorkspaceRequestFromReader(r.Body)
+	if err != nil {
+		c.Err = model.NewInvalidRequestError("Failed to parse create mattermost workspace request").Wrap(err)
+		return
+	}
+
+	c.Err = validateCreateMattermostWorkspaceRequest(c, create)
+	if c.Err != nil {
+		return
+	}
+
+	// Each probe can take minutes, so together they outlast the server's write timeout
+	job := &model.Job{Type: model.JobTypeValidateInstallation, ClusterName: clusterName, Target: create.InstallationName}
+	startJob(c, w, job, func(c *Context) (interface{}, error) {
+		return ValidateMattermostInstallation(c, clusterName, create)
+	})
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+)
+
+func initProviders(apiRouter *mux.Router, context *Context) {
+	addContext := func(handler contextHandlerFunc) *contextHandler {
+		return newContextHandler(context, handler)
+	}
+
+	apiRouter.Handle("/providers", addContext(handleListProviders)).Methods(http.MethodGet)
+	apiRouter.Handle("/providers/{providerName:[A-Za-z0-9_-]+}", addContext(handleGetProvider)).Methods(http.MethodGet)
+}
+
+func handleListProviders(c *Context, w http.ResponseWriter, r *http.Request) {
+	infos := providers.ListProviders()
+	for i := range infos {
+		infos[i] = configuredProviderInfo(c, infos[i])
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(infos)
+}
+
+// handleGetProvider returns the capabilities document of a single provider.
+func handleGetProvider(c *Context, w http.ResponseWriter, r *http.Request) {
+	info, err := providers.GetProviderInfo(mux.Vars(r)["providerName"])
+	if err != nil {
+		c.SetError(err, "Failed to get provider")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(configuredProviderInfo(c, info))
+}
+
+// configuredProviderInfo returns the description of a provider with the capabilities of its instance, since some
+// depend on its settings, such as the cluster tool of the local provider.
+func configuredProviderInfo(c *Context, info providers.ProviderInfo) providers.ProviderInfo {
+	provider, err := providers.GetCloudProvider(info.Name, c.BootstrapperState.Credentials)
+	if err == nil {
+		info.Capabilities = providers.CapabilitiesOf(provider)
+	}
+
+	return info
+}
+
+// newNotImplementedError is returned for operations that the provider of the request doesn't support, which
+// clients can find out ahead of time from its capabilities.
+func newNotImplementedError(c *Context, operation string) *model.AppError {
+	return model.NewAppError(model.ErrorCodeNotImplemented, http.StatusNotImplemented, fmt.Sprintf("The %s provider does not support %s", c.CloudProviderName, operation))
+}
@@ -0,0 +1,113 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviders(t *testing.T) {
+	_, router := newTestRouter(t)
+
+	t.Run("List", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/providers", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var list []providers.ProviderInfo
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+
+		names := []string{}
+		for _, provider := range list {
+			names = append(names, provider.Name)
+		}
+		assert.Equal(t, []string{"aws", "azure", "custom", "gcp", "local"}, names)
+		assert.True(t, list[0].Capabilities.Roles)
+		assert.False(t, list[2].Capabilities.CreateCluster)
+	})
+
+	t.Run("UnknownProvider", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/nope/clusters", nil))
+		require.Equal(t, http.StatusNotFound, w.Code)
+
+		var response model.ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, model.ErrorCodeNotFound, response.Error.Code)
+		assert.Contains(t, response.Error.Message, "nope")
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/providers/custom", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var info providers.ProviderInfo
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&info))
+		assert.Equal(t, "custom", info.Name)
+		assert.False(t, info.Capabilities.CreateCluster)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/providers/nope", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("UnsupportedOperation", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/custom/cluster", strings.NewReader(`{"clusterName": "test"}`)))
+		require.Equal(t, http.StatusNotImplemented, w.Code)
+
+		var response model.ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, model.ErrorCodeNotImplemented, response.Error.Code)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/custom/roles", nil))
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/custom/cluster/test", nil))
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/custom/cluster/test/nodegroups/ng-1", nil))
+		assert.Equal(t, http.StatusNotImplemented, w.Code)
+	})
+
+	t.Run("UpgradeClusterRequiresVersion", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/aws/cluster/test/upgrade", strings.NewReader(`{}`)))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response model.ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Contains(t, response.Error.Message, "kubernetesVersion")
+	})
+
+	t.Run("InvalidNodegroupUpdate", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/v1/aws/cluster/test/nodegroups/ng-1", strings.NewReader(`{"scalingConfig": {"minSize": 3, "maxSize": 1}}`)))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response model.ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Contains(t, response.Error.Details, "scalingConfig")
+	})
+
+	t.Run("UnknownProviderInState", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/v1/state", strings.NewReader(`{"provider": "nope"}`)))
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		var response model.ErrorResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, model.ErrorCodeInvalidRequest, response.Error.Code)
+	})
+}
@@ -0,0 +1,385 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	helmclient "github.com/mittwald/go-helm-client"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/repo"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// releaseTimeout bounds upgrades and rollbacks of releases that aren't catalog add-ons, which have their own timeout.
+const releaseTimeout = 300 * time.Second
+
+// ListReleaseHistory returns the revisions of a helm release, newest first.
+func ListReleaseHistory(c *Context, clusterName, namespace, releaseName string) ([]model.ReleaseRevision, error) {
+	history, err := releaseHistory(c, clusterName, namespace, releaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := []model.ReleaseRevision{}
+	for _, rel := range history {
+		revisions = append(revisions, releaseRevision(rel))
+	}
+
+	return revisions, nil
+}
+
+// DiffReleaseValues compares the user-supplied values of two revisions of a helm release. A zero to compares the
+// latest revision, and a zero from compares against the revision before to.
+func DiffReleaseValues(c *Context, clusterName, namespace, releaseName string, from, to int) (*model.ReleaseValuesDiff, error) {
+	history, err := releaseHistory(c, clusterName, namespace, releaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffReleaseHistory(history, releaseName, from, to)
+}
+
+// diffReleaseHistory compares the values of two revisions in the history of a release, sorted newest first, the way
+// DiffReleaseValues describes.
+func diffReleaseHistory(history []*release.Release, releaseName string, from, to int) (*model.ReleaseValuesDiff, error) {
+	var toRelease, fromRelease *release.Release
+	for _, rel := range history {
+		if to == 0 || rel.Version == to {
+			toRelease = rel
+			break
+		}
+	}
+	if toRelease == nil {
+		return nil, model.NewNotFoundError(fmt.Sprintf("Release %s has no revision %d", releaseName, to))
+	}
+
+	for _, rel := range history {
+		if (from == 0 && rel.Version < toRelease.Version) || (from != 0 && rel.Version == from) {
+			fromRelease = rel
+			break
+		}
+	}
+	if fromRelease == nil {
+		if from != 0 {
+			return nil, model.NewNotFoundError(fmt.Sprintf("Release %s has no revision %d", releaseName, from))
+		}
+		return nil, model.NewInvalidRequestError(fmt.Sprintf("Release %s has no revision before %d to compare with", releaseName, toRelease.Version))
+	}
+
+	return &model.ReleaseValuesDiff{
+		From:    fromRelease.Version,
+		To:      toRelease.Version,
+		Changes: model.DiffValues(fromRelease.Config, toRelease.Config),
+	}, nil
+}
+
+// UpgradeRelease upgrades a helm release to a new revision with the values of the request, keeping the chart of the
+// current revision unless the request selects another version.
+func UpgradeRelease(c *Context, clusterName, namespace, releaseName string, request *model.UpgradeReleaseRequest) (*model.ReleaseRevision, error) {
+	c.Ctx = logger.WithField(c.Ctx, "action", "upgrade-"+releaseName)
+	c.Ctx = logger.WithNamespace(c.Ctx, namespace)
+	c.Ctx = logger.WithClusterName(c.Ctx, clusterName)
+
+	helmClient, err := releaseHelmClient(c, clusterName, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := helmClient.GetRelease(releaseName)
+	if err != nil {
+		return nil, releaseError(err, "get", releaseName)
+	}
+
+	values := current.Config
+	if request.ResetValues {
+		values = nil
+	}
+	values = mergeValues(values, request.Values)
+
+	addon, isAddon := addonForRelease(namespace, releaseName)
+	timeout := releaseTimeout
+	if isAddon && addon.Timeout > 0 {
+		timeout = addon.Timeout
+	}
+
+	upgrade := action.NewUpgrade(helmClient.ActionConfig)
+	chart := current.Chart
+	if request.Version != "" && request.Version != current.Chart.Metadata.Version {
+		if !isAddon {
+			return nil, model.NewInvalidRequestError(fmt.Sprintf("The chart version of %s can't be changed because it isn't a catalog add-on", releaseName))
+		}
+
+		addon.Version = request.Version
+		chartName, addRepo, err := addonChartName(c, addon)
+		if err != nil {
+			return nil, err
+		}
+		if addRepo {
+			err = helmClient.AddOrUpdateChartRepo(repo.Entry{Name: addon.RepoName, URL: addon.RepoURL})
+			if err != nil {
+				return nil, fmt.Errorf("failed to add or update chart repo: %w", err)
+			}
+		}
+
+		// The chart path options of the upgrade action carry the registry client needed for OCI charts
+		upgrade.Version = request.Version
+		chart, _, err = helmClient.GetChart(chartName, &upgrade.ChartPathOptions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chart %s version %s: %w", addon.Chart, request.Version, err)
+		}
+	}
+
+	upgrade.Namespace = namespace
+	upgrade.Wait = true
+	upgrade.Timeout = timeout
+	upgrade.CleanupOnFail = true
+	upgraded, err := upgrade.RunWithContext(c.Ctx, releaseName, chart, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upgrade %s: %w", releaseName, err)
+	}
+	logger.FromContext(c.Ctx).Infof("Upgraded %s to revision %d", releaseName, upgraded.Version)
+
+	if isAddon {
+		recordAddonVersion(c, clusterName, addon, upgraded)
+	}
+
+	revision := releaseRevision(upgraded)
+	return &revision, nil
+}
+
+// RollbackRelease rolls a helm release back to a previous revision, or to the one before the current revision when
+// revision is zero. Helm records the rollback as a new revision.
+func RollbackRelease(c *Context, clusterName, namespace, releaseName string, revision int) (*model.ReleaseRevision, error) {
+	c.Ctx = logger.WithField(c.Ctx, "action", "rollback-"+releaseName)
+	c.Ctx = logger.WithNamespace(c.Ctx, namespace)
+	c.Ctx = logger.WithClusterName(c.Ctx, clusterName)
+
+	helmClient, err := releaseHelmClient(c, clusterName, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	addon, isAddon := addonForRelease(namespace, releaseName)
+	timeout := releaseTimeout
+	if isAddon && addon.Timeout > 0 {
+		timeout = addon.Timeout
+	}
+
+	rollback := action.NewRollback(helmClient.ActionConfig)
+	rollback.Version = revision
+	rollback.Wait = true
+	rollback.Timeout = timeout
+	rollback.CleanupOnFail = true
+	err = rollback.Run(releaseName)
+	if err != nil {
+		return nil, releaseError(err, "roll back", releaseName)
+	}
+
+	current, err := helmClient.GetRelease(releaseName)
+	if err != nil {
+		return nil, releaseError(err, "get", releaseName)
+	}
+	logger.FromContext(c.Ctx).Infof("Rolled back %s, now at revision %d", releaseName, current.Version)
+
+	if isAddon {
+		recordAddonVersion(c, clusterName, addon, current)
+	}
+
+	result := releaseRevision(current)
+	return &result, nil
+}
+
+func releaseHistory(c *Context, clusterName, namespace, releaseName string) ([]*release.Release, error) {
+	helmClient, err := releaseHelmClient(c, clusterName, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := helmClient.ListReleaseHistory(releaseName, 0)
+	if err != nil {
+		return nil, releaseError(err, "get the history of", releaseName)
+	}
+
+	sortReleaseHistory(history)
+	return history, nil
+}
+
+// sortReleaseHistory sorts the revisions of a release newest first, since helm returns them in storage order.
+func sortReleaseHistory(history []*release.Release) {
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].Version > history[j].Version
+	})
+}
+
+// releaseHelmClient returns a helm client whose action configuration can be used for the helm actions that the
+// client doesn't wrap, such as rolling back to a given revision.
+func releaseHelmClient(c *Context, clusterName, namespace string) (*helmclient.HelmClient, error) {
+	client, err := c.CloudProvider.HelmClient(c.Ctx, clusterName, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate helm client: %w", err)
+	}
+
+	helmClient, ok := client.(*helmclient.HelmClient)
+	if !ok {
+		return nil, fmt.Errorf("unexpected helm client type %T", client)
+	}
+
+	return helmClient, nil
+}
+
+// releaseError turns helm's error for a missing release into a not found error.
+func releaseError(err error, verb, releaseName string) error {
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return model.NewNotFoundError(fmt.Sprintf("Release %s not found", releaseName)).Wrap(err)
+	}
+	return fmt.Errorf("failed to %s release %s: %w", verb, releaseName, err)
+}
+
+func releaseRevision(rel *release.Release) model.ReleaseRevision {
+	revision := model.ReleaseRevision{Revision: rel.Version}
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		revision.Chart = rel.Chart.Metadata.Name
+		revision.ChartVersion = rel.Chart.Metadata.Version
+		revision.AppVersion = rel.Chart.Metadata.AppVersion
+	}
+	if rel.Info != nil {
+		revision.Status = rel.Info.Status.String()
+		revision.Description = rel.Info.Description
+		revision.Updated = rel.Info.LastDeployed.Time
+	}
+
+	return revision
+}
+
+// addonForRelease returns the catalog add-on that is installed as the given release.
+func addonForRelease(namespace, releaseName string) (Addon, bool) {
+	for _, addon := range Addons {
+		if addon.Namespace == namespace && addon.ReleaseName == releaseName {
+			return addon, true
+		}
+	}
+
+	return Addon{}, false
+}
+
+func recordAddonVersion(c *Context, clusterName string, addon Addon, rel *release.Release) {
+	if rel.Chart == nil || rel.Chart.Metadata == nil {
+		return
+	}
+
+	err := UpdateStateAddonVersion(c.BootstrapperState, clusterName, addon.Name, rel.Chart.Metadata.Version)
+	if err != nil {
+		logger.FromContext(c.Ctx).WithError(err).Warn("Failed to record add-on version in state")
+	}
+}
+
+func handleGetReleaseHistory(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName := vars["name"]
+	if clusterName == "" || clusterName == "undefined" {
+		c.SetInvalidParam("name")
+		return
+	}
+
+	revisions, err := ListReleaseHistory(c, clusterName, vars["namespace"], vars["release"])
+	if err != nil {
+		c.SetError(err, "Failed to get release history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(revisions)
+}
+
+func handleGetReleaseValuesDiff(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName := vars["name"]
+	if clusterName == "" || clusterName == "undefined" {
+		c.SetInvalidParam("name")
+		return
+	}
+
+	revisions := map[string]int{}
+	for _, param := range []string{"from", "to"} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		revision, err := strconv.Atoi(value)
+		if err != nil || revision < 1 {
+			c.SetInvalidParam(param)
+			return
+		}
+		revisions[param] = revision
+	}
+
+	diff, err := DiffReleaseValues(c, clusterName, vars["namespace"], vars["release"], revisions["from"], revisions["to"])
+	if err != nil {
+		c.SetError(err, "Failed to diff release values")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(diff)
+}
+
+func handleUpgradeRelease(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName := vars["name"]
+	if clusterName == "" || clusterName == "undefined" {
+		c.SetInvalidParam("name")
+		return
+	}
+
+	request, err := model.NewUpgradeReleaseRequestFromReader(r.Body)
+	if err != nil {
+		c.Err = model.NewInvalidRequestError("Failed to parse upgrade release request").Wrap(err)
+		return
+	}
+	defer r.Body.Close()
+
+	namespace, releaseName := vars["namespace"], vars["release"]
+	job := &model.Job{Type: model.JobTypeUpgradeRelease, ClusterName: clusterName, Target: namespace + "/" + releaseName}
+	startJob(c, w, job, func(c *Context) (interface{}, error) {
+		return UpgradeRelease(c, clusterName, namespace, releaseName, request)
+	})
+}
+
+func handleRollbackRelease(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterName := vars["name"]
+	if clusterName == "" || clusterName == "undefined" {
+		c.SetInvalidParam("name")
+		return
+	}
+
+	request, err := model.NewRollbackReleaseRequestFromReader(r.Body)
+	if err != nil {
+		c.Err = model.NewInvalidRequestError("Failed to parse rollback release request").Wrap(err)
+		return
+	}
+	defer r.Body.Close()
+
+	err = request.IsValid()
+	if err != nil {
+		c.Err = model.NewInvalidRequestError("Invalid rollback release request").Wrap(err)
+		return
+	}
+
+	namespace, releaseName := vars["namespace"], vars["release"]
+	job := &model.Job{Type: model.JobTypeRollbackRelease, ClusterName: clusterName, Target: namespace + "/" + releaseName}
+	startJob(c, w, job, func(c *Context) (interface{}, error) {
+		return RollbackRelease(c, clusterName, namespace, releaseName, request.Revision)
+	})
+}
@@ -0,0 +1,105 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestReleaseEndpoints(t *testing.T) {
+	_, router := newTestRouter(t)
+
+	t.Run("InvalidRevision", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/custom/cluster/test/releases/cnpg-system/cnpg-system/diff?from=abc", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/custom/cluster/test/releases/cnpg-system/cnpg-system/rollback", strings.NewReader(`{"revision": -1}`)))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestDiffReleaseHistory(t *testing.T) {
+	memory := func(value string) map[string]interface{} {
+		return map[string]interface{}{"limits": map[string]interface{}{"memory": value}}
+	}
+
+	// Helm returns the history in storage order
+	history := []*release.Release{
+		{Name: "cnpg", Version: 2, Config: map[string]interface{}{"replicaCount": 2, "resources": memory("1Gi")}},
+		{Name: "cnpg", Version: 1, Config: map[string]interface{}{"replicaCount": 1}},
+		{Name: "cnpg", Version: 3, Config: map[string]interface{}{"replicaCount": 2, "resources": memory("2Gi")}},
+	}
+	api.SortReleaseHistory(history)
+
+	versions := []int{}
+	for _, rel := range history {
+		versions = append(versions, rel.Version)
+	}
+	require.Equal(t, []int{3, 2, 1}, versions)
+
+	t.Run("LatestAgainstPrevious", func(t *testing.T) {
+		diff, err := api.DiffReleaseHistory(history, "cnpg", 0, 0)
+		require.NoError(t, err)
+
+		assert.Equal(t, &model.ReleaseValuesDiff{
+			From:    2,
+			To:      3,
+			Changes: []model.ValueChange{{Path: "resources.limits.memory", From: "1Gi", To: "2Gi"}},
+		}, diff)
+	})
+
+	t.Run("RevisionAgainstPrevious", func(t *testing.T) {
+		diff, err := api.DiffReleaseHistory(history, "cnpg", 0, 2)
+		require.NoError(t, err)
+
+		assert.Equal(t, &model.ReleaseValuesDiff{
+			From: 1,
+			To:   2,
+			Changes: []model.ValueChange{
+				{Path: "replicaCount", From: 1, To: 2},
+				{Path: "resources.limits.memory", To: "1Gi"},
+			},
+		}, diff)
+	})
+
+	t.Run("OlderRevision", func(t *testing.T) {
+		diff, err := api.DiffReleaseHistory(history, "cnpg", 3, 1)
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, diff.From)
+		assert.Equal(t, 1, diff.To)
+		assert.Equal(t, []model.ValueChange{
+			{Path: "replicaCount", From: 2, To: 1},
+			{Path: "resources.limits.memory", From: "2Gi"},
+		}, diff.Changes)
+	})
+
+	t.Run("NoPreviousRevision", func(t *testing.T) {
+		_, err := api.DiffReleaseHistory(history, "cnpg", 0, 1)
+
+		var appErr *model.AppError
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, model.ErrorCodeInvalidRequest, appErr.Code)
+	})
+
+	t.Run("MissingRevision", func(t *testing.T) {
+		var appErr *model.AppError
+
+		_, err := api.DiffReleaseHistory(history, "cnpg", 0, 4)
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, model.ErrorCodeNotFound, appErr.Code)
+
+		_, err = api.DiffReleaseHistory(history, "cnpg", 5, 0)
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, model.ErrorCodeNotFound, appErr.Code)
+	})
+}
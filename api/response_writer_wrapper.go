@@ -54,7 +54,13 @@ func (rw *ResponseWriterWrapper) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if rw.hijacker == nil {
 		return nil, nil, errors.New("Hijacker interface not supported by the wrapped ResponseWriter")
 	}
-	return rw.hijacker.Hijack()
+	conn, buf, err := rw.hijacker.Hijack()
+	if err == nil {
+		// The connection now belongs to the caller, so no status code can be written anymore
+		rw.statusCode = http.StatusSwitchingProtocols
+		rw.statusCodeWritten = true
+	}
+	return conn, buf, err
 }
 
 // Flush flushes the response writer.
@@ -5,9 +5,11 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/gorilla/mux"
 	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
 )
 
 func initState(apiRouter *mux.Router, context *Context) {
@@ -42,7 +44,7 @@ func handleCheckState(c *Context, w http.ResponseWriter, r *http.Request) {
 	// Check if a state file exists
 	exists, err := CheckStateExists(c.BootstrapperState.StateFilePath)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		c.SetError(err, "Failed to check state file")
 		return
 	}
 
@@ -66,28 +68,45 @@ func handleCheckState(c *Context, w http.ResponseWriter, r *http.Request) {
 }
 
 func handlePatchState(c *Context, w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
 	var newState BootstrapperState
 	err := json.NewDecoder(r.Body).Decode(&newState)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		c.Err = model.NewInvalidRequestError("Failed to parse state patch").Wrap(err)
 		return
 	}
 
-	state := c.BootstrapperState
+	if newState.Provider != "" && !providers.IsRegistered(newState.Provider) {
+		c.SetInvalidParam("provider")
+		return
+	}
 
-	state = state.Merge(newState)
+	if newState.Mirror != nil {
+		err = newState.Mirror.IsValid()
+		if err != nil {
+			c.Err = model.NewInvalidRequestError("Invalid mirror settings").Wrap(err)
+			return
+		}
+	}
 
-	err = SetState("", state)
+	// Merge against the file rather than the state loaded at startup, which misses changes made since, such as
+	// the versions of add-ons that jobs deployed
+	state, err := updateState(c.BootstrapperState.StateFilePath, func(state *BootstrapperState) {
+		*state = state.Merge(newState)
+	})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		c.SetError(err, "Failed to save state")
 		return
 	}
-	json.NewEncoder(w).Encode(state)
+	c.setState(state)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(c.BootstrapperState)
 }
 
+// stateLock serializes updates of the state file, and of the state of the server's context.
+var stateLock sync.Mutex
+
 const stateFileName = "state.json"
 const stateFileDir = ".mcnb" // Mattermost CloudNative Bootstrapper
 
@@ -179,52 +198,69 @@ func SetState(stateFilePath string, state BootstrapperState) error {
 }
 
 func UpdateStateCredentials(existingState BootstrapperState, credentials *model.Credentials) error {
-	state, err := GetState(existingState.StateFilePath)
-	if err != nil {
-		return err
-	}
-
-	state.Credentials = credentials
-
-	err = SetState(existingState.StateFilePath, state)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	_, err := updateState(existingState.StateFilePath, func(state *BootstrapperState) {
+		state.Credentials = credentials
+	})
+	return err
 }
 
 // UpdateStateCredentialsAndProvider updates credentials and provider in state
 func UpdateStateCredentialsAndProvider(existingState BootstrapperState, credentials *model.Credentials, provider string) error {
-	state, err := GetState(existingState.StateFilePath)
-	if err != nil {
-		return err
-	}
-
-	state.Credentials = credentials
-	state.Provider = provider
-
-	err = SetState(existingState.StateFilePath, state)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	_, err := updateState(existingState.StateFilePath, func(state *BootstrapperState) {
+		state.Credentials = credentials
+		state.Provider = provider
+	})
+	return err
 }
 
 // UpdateStateClusterName updates the cluster name in state
 func UpdateStateClusterName(existingState BootstrapperState, clusterName string) error {
-	state, err := GetState(existingState.StateFilePath)
+	_, err := updateState(existingState.StateFilePath, func(state *BootstrapperState) {
+		state.ClusterName = clusterName
+	})
+	return err
+}
+
+// UpdateStateAddonVersion records the chart version of an add-on deployed to a cluster. An empty version forgets
+// the add-on.
+func UpdateStateAddonVersion(existingState BootstrapperState, clusterName string, addon string, version string) error {
+	_, err := updateState(existingState.StateFilePath, func(state *BootstrapperState) {
+		if version == "" {
+			delete(state.Addons[clusterName], addon)
+			if len(state.Addons[clusterName]) == 0 {
+				delete(state.Addons, clusterName)
+			}
+			return
+		}
+
+		if state.Addons == nil {
+			state.Addons = map[string]map[string]string{}
+		}
+		if state.Addons[clusterName] == nil {
+			state.Addons[clusterName] = map[string]string{}
+		}
+		state.Addons[clusterName][addon] = version
+	})
+	return err
+}
+
+// updateState applies update to the state file and returns the saved state. Updates are serialized, and each one
+// reads the file first, so that concurrent requests and jobs don't overwrite each other's changes.
+func updateState(stateFilePath string, update func(state *BootstrapperState)) (BootstrapperState, error) {
+	stateLock.Lock()
+	defer stateLock.Unlock()
+
+	state, err := GetState(stateFilePath)
 	if err != nil {
-		return err
+		return state, err
 	}
 
-	state.ClusterName = clusterName
+	update(&state)
 
-	err = SetState(existingState.StateFilePath, state)
+	err = SetState(stateFilePath, state)
 	if err != nil {
-		return err
+		return state, err
 	}
 
-	return nil
+	return state, nil
 }
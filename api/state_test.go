@@ -1,8 +1,14 @@
 package api_test
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
@@ -76,3 +82,79 @@ func TestStateFunctions(t *testing.T) {
 		assert.Equal(t, newState, readState)
 	})
 }
+
+func TestUpdateStateAddonVersion(t *testing.T) {
+	state := api.BootstrapperState{StateFilePath: filepath.Join(t.TempDir(), "state.json")}
+	require.NoError(t, api.InitState(state.StateFilePath))
+
+	require.NoError(t, api.UpdateStateAddonVersion(state, "cluster-1", "cnpg", "0.20.1"))
+	require.NoError(t, api.UpdateStateAddonVersion(state, "cluster-1", "ingress-nginx", "4.10.1"))
+	require.NoError(t, api.UpdateStateAddonVersion(state, "cluster-2", "cnpg", "0.21.0"))
+
+	saved, err := api.GetState(state.StateFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{
+		"cluster-1": {"cnpg": "0.20.1", "ingress-nginx": "4.10.1"},
+		"cluster-2": {"cnpg": "0.21.0"},
+	}, saved.Addons)
+
+	require.NoError(t, api.UpdateStateAddonVersion(state, "cluster-2", "cnpg", ""))
+
+	saved, err = api.GetState(state.StateFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{
+		"cluster-1": {"cnpg": "0.20.1", "ingress-nginx": "4.10.1"},
+	}, saved.Addons)
+}
+
+func TestUpdateStateConcurrently(t *testing.T) {
+	state := api.BootstrapperState{StateFilePath: filepath.Join(t.TempDir(), "state.json")}
+	require.NoError(t, api.InitState(state.StateFilePath))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(t, api.UpdateStateAddonVersion(state, "cluster-1", fmt.Sprintf("addon-%d", i), "1.0.0"))
+		}(i)
+	}
+	wg.Wait()
+
+	saved, err := api.GetState(state.StateFilePath)
+	require.NoError(t, err)
+	assert.Len(t, saved.Addons["cluster-1"], 20)
+}
+
+func TestPatchState(t *testing.T) {
+	c, router := newTestRouter(t)
+
+	// Recorded after the server loaded the state, as a job would
+	require.NoError(t, api.UpdateStateAddonVersion(c.BootstrapperState, "cluster-1", "cnpg", "0.20.1"))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/v1/state", strings.NewReader(`{"clusterName": "cluster-1"}`)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	saved, err := api.GetState(c.BootstrapperState.StateFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, "cluster-1", saved.ClusterName)
+	assert.Equal(t, map[string]map[string]string{"cluster-1": {"cnpg": "0.20.1"}}, saved.Addons)
+
+	t.Run("mirror applies to later requests", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, "/api/v1/state", strings.NewReader(`{"mirror": {"imageRegistry": "registry.example.com"}}`)))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/state/hydrate", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var hydrated api.BootstrapperState
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&hydrated))
+		require.NotNil(t, hydrated.Mirror)
+		assert.Equal(t, "registry.example.com", hydrated.Mirror.ImageRegistry)
+		assert.Equal(t, "cluster-1", hydrated.ClusterName)
+		assert.Equal(t, c.BootstrapperState.StateFilePath, hydrated.StateFilePath)
+	})
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+)
+
+// WaitCondition checks an object that is being waited on. It reports whether the object is ready, and otherwise
+// describes its current state, such as "2 of 3 instances ready".
+type WaitCondition func(obj runtime.Object) (ready bool, state string, err error)
+
+// PollCondition checks something that can't be watched, such as a cloud provider resource.
+type PollCondition func() (ready bool, state string, err error)
+
+// Backoff is the interval between polls, which starts at Initial and doubles up to Max.
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+// listWatcher is the List and Watch methods shared by typed and dynamic Kubernetes clients.
+type listWatcher[L runtime.Object] interface {
+	List(ctx context.Context, opts metav1.ListOptions) (L, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+}
+
+// namedListWatch restricts a client to the object with the given name.
+func namedListWatch[L runtime.Object](ctx context.Context, client listWatcher[L], name string) cache.ListerWatcher {
+	selector := fields.OneTermEqualSelector("metadata.name", name).String()
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = selector
+			return client.List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+			return client.Watch(ctx, options)
+		},
+	}
+}
+
+// waitReporter logs each change in the state of what is being waited on, which jobs record as steps.
+type waitReporter struct {
+	c           *Context
+	description string
+	state       string
+	start       time.Time
+}
+
+func newWaitReporter(c *Context, description string) *waitReporter {
+	logger.FromContext(c.Ctx).Infof("Waiting for %s", description)
+	return &waitReporter{c: c, description: description, state: "not found", start: time.Now()}
+}
+
+func (r *waitReporter) report(state string) {
+	if state == "" || state == r.state {
+		return
+	}
+	r.state = state
+	logger.FromContext(r.c.Ctx).Infof("Waiting for %s: %s", r.description, state)
+}
+
+func (r *waitReporter) done() {
+	logger.FromContext(r.c.Ctx).Infof("Finished waiting for %s after %s", r.description, time.Since(r.start).Round(time.Second))
+}
+
+// timeoutError is returned when the wait timed out, with the last state that was seen.
+func (r *waitReporter) timeoutError(timeout time.Duration) *model.AppError {
+	return model.NewAppError(model.ErrorCodeTimeout, http.StatusGatewayTimeout, fmt.Sprintf("Timed out after %s waiting for %s", timeout, r.description)).WithDetails("last state: " + r.state)
+}
+
+// WaitForObject watches the object listed by lw until condition reports it ready, and returns it. The object
+// doesn't need to exist yet. Watches that drop are resumed, and each change in the state of the object is logged.
+func WaitForObject(c *Context, description string, timeout time.Duration, lw cache.ListerWatcher, objType runtime.Object, condition WaitCondition) (runtime.Object, error) {
+	ctx, cancel := context.WithTimeout(c.Ctx, timeout)
+	defer cancel()
+
+	reporter := newWaitReporter(c, description)
+	event, err := watchtools.UntilWithSync(ctx, lw, objType, nil, func(event watch.Event) (bool, error) {
+		if event.Type == watch.Deleted {
+			reporter.report("deleted")
+			return false, nil
+		}
+
+		ready, state, err := condition(event.Object)
+		if err != nil {
+			return false, err
+		}
+		reporter.report(state)
+		return ready, nil
+	})
+	if err != nil {
+		if c.Ctx.Err() == nil && ctx.Err() == context.DeadlineExceeded {
+			return nil, reporter.timeoutError(timeout)
+		}
+		return nil, fmt.Errorf("failed waiting for %s: %w", description, err)
+	}
+
+	reporter.done()
+	return event.Object, nil
+}
+
+// PollUntil checks condition with backoff until it reports ready, for things that can't be watched. Each change in
+// state is logged.
+func PollUntil(c *Context, description string, timeout time.Duration, backoff Backoff, condition PollCondition) error {
+	reporter := newWaitReporter(c, description)
+	deadline := time.Now().Add(timeout)
+	interval := backoff.Initial
+	for {
+		ready, state, err := condition()
+		if err != nil {
+			return err
+		}
+		reporter.report(state)
+		if ready {
+			reporter.done()
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return reporter.timeoutError(timeout)
+		}
+
+		select {
+		case <-c.Ctx.Done():
+			return c.Ctx.Err()
+		case <-time.After(min(interval, remaining)):
+		}
+
+		interval *= 2
+		if interval > backoff.Max {
+			interval = backoff.Max
+		}
+	}
+}
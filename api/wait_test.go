@@ -0,0 +1,102 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestWaitForObject(t *testing.T) {
+	c := newTestContext(t)
+
+	clientset := fake.NewSimpleClientset()
+	secrets := clientset.CoreV1().Secrets("test")
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return secrets.List(context.Background(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return secrets.Watch(context.Background(), options)
+		},
+	}
+	hasKey := func(obj runtime.Object) (bool, string, error) {
+		if _, ok := obj.(*v1.Secret).Data["uri"]; ok {
+			return true, "ready", nil
+		}
+		return false, "missing uri", nil
+	}
+
+	t.Run("ready after changes", func(t *testing.T) {
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "test"}}
+			_, _ = secrets.Create(context.Background(), secret, metav1.CreateOptions{})
+
+			time.Sleep(100 * time.Millisecond)
+			secret.Data = map[string][]byte{"uri": []byte("postgresql://app")}
+			_, _ = secrets.Update(context.Background(), secret, metav1.UpdateOptions{})
+		}()
+
+		obj, err := api.WaitForObject(c, "secret app", 10*time.Second, lw, &v1.Secret{}, hasKey)
+		require.NoError(t, err)
+		assert.Equal(t, "postgresql://app", string(obj.(*v1.Secret).Data["uri"]))
+	})
+
+	t.Run("timeout reports the last state", func(t *testing.T) {
+		_, err := secrets.Create(context.Background(), &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "test"}}, metav1.CreateOptions{})
+		require.NoError(t, err)
+
+		_, err = api.WaitForObject(c, "secret other", 200*time.Millisecond, lw, &v1.Secret{}, func(obj runtime.Object) (bool, string, error) {
+			return false, "missing uri", nil
+		})
+		var appErr *model.AppError
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, model.ErrorCodeTimeout, appErr.Code)
+		assert.Equal(t, "last state: missing uri", appErr.Details)
+	})
+}
+
+func TestPollUntil(t *testing.T) {
+	c := newTestContext(t)
+
+	backoff := api.Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond}
+
+	t.Run("ready", func(t *testing.T) {
+		polls := 0
+		err := api.PollUntil(c, "cluster", time.Second, backoff, func() (bool, string, error) {
+			polls++
+			return polls == 3, "creating", nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, polls)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		err := api.PollUntil(c, "cluster", time.Second, backoff, func() (bool, string, error) {
+			return false, "", errors.New("cluster failed")
+		})
+		assert.EqualError(t, err, "cluster failed")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		err := api.PollUntil(c, "cluster", 20*time.Millisecond, backoff, func() (bool, string, error) {
+			return false, "creating", nil
+		})
+		var appErr *model.AppError
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, model.ErrorCodeTimeout, appErr.Code)
+		assert.Equal(t, "last state: creating", appErr.Details)
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
+	"github.com/spf13/cobra"
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Download the add-on charts for installing into clusters without internet access",
+	Long: `Download the chart archive of each add-on's pinned version into a directory. Copy the directory to the
+air-gapped network and pass it with --chart-directory, or push the archives to an OCI registry with helm push
+and pass the registry with --chart-registry.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, _ := cmd.Flags().GetString("dir")
+		selected, _ := cmd.Flags().GetStringSlice("operator")
+		all, _ := cmd.Flags().GetBool("all")
+
+		switch {
+		case all:
+			selected = api.AddonNames()
+		case len(selected) == 0:
+			selected = api.RequiredAddonNames()
+		}
+
+		// Dependencies are bundled too, since installing an add-on installs them first
+		addons := []api.Addon{}
+		bundled := map[string]bool{}
+		for _, name := range selected {
+			if _, ok := api.GetAddon(name); !ok {
+				return fmt.Errorf("unknown operator %q, expected one of %v", name, api.AddonNames())
+			}
+
+			order, err := api.AddonInstallOrder(name)
+			if err != nil {
+				return err
+			}
+			for _, addon := range order {
+				if !bundled[addon.Name] {
+					bundled[addon.Name] = true
+					addons = append(addons, addon)
+				}
+			}
+		}
+
+		// MinIO is deployed per installation rather than as an add-on, but air-gapped clusters need its chart too
+		if all {
+			addons = append(addons, api.MinioChart)
+		}
+
+		// Providers install their charts before the add-ons that need them, such as the EBS CSI driver before CNPG
+		addons = append(addons, api.ProviderCharts...)
+
+		c := &api.Context{Ctx: cmd.Context()}
+		archives, err := api.BundleAddons(c, dir, addons)
+		if err != nil {
+			return err
+		}
+
+		rows := [][]string{}
+		for i, addon := range addons {
+			rows = append(rows, []string{addon.Name, addon.Version, archives[i]})
+		}
+
+		return printResult(cmd, archives, []string{"NAME", "VERSION", "ARCHIVE"}, rows)
+	},
+}
+
+func init() {
+	bundleCmd.Flags().String("dir", "mcnb-bundle", "Directory to download the chart archives into")
+	bundleCmd.Flags().StringSlice("operator", nil, fmt.Sprintf("Add-ons to bundle, one or more of %v. Defaults to %v", api.AddonNames(), api.RequiredAddonNames()))
+	bundleCmd.Flags().Bool("all", false, "Bundle every add-on in the catalog, and the MinIO chart for InClusterMinio filestores")
+}
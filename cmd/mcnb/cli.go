@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// newCLIContext builds an API context from the state file and selects the cloud provider from the --provider flag,
+// falling back to the provider saved in state.
+func newCLIContext(cmd *cobra.Command) (*api.Context, error) {
+	stateFilePath, _ := cmd.Flags().GetString("state-file-path")
+	telemetryDisabled, _ := cmd.Flags().GetBool("disable-telemetry")
+	providerName, _ := cmd.Flags().GetString("provider")
+
+	c, err := api.NewContext(cmd.Context(), stateFilePath, telemetryDisabled)
+	if err != nil {
+		return nil, err
+	}
+
+	err = applyMirrorFlags(cmd, c)
+	if err != nil {
+		return nil, err
+	}
+
+	if providerName == "" {
+		providerName = c.BootstrapperState.Provider
+	}
+	if providerName == "" {
+		return nil, errors.New("no cloud provider selected, pass --provider or run 'mcnb credentials set'")
+	}
+
+	provider, err := providers.GetCloudProvider(providerName, c.BootstrapperState.Credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	c.CloudProviderName = providerName
+	c.CloudProvider = provider
+
+	return c, nil
+}
+
+// applyMirrorFlags overrides the mirror settings saved in state with the --chart-registry, --chart-directory and
+// --image-registry flags, for this run only.
+func applyMirrorFlags(cmd *cobra.Command, c *api.Context) error {
+	mirror := model.MirrorConfig{}
+	if c.BootstrapperState.Mirror != nil {
+		mirror = *c.BootstrapperState.Mirror
+	}
+
+	flags := map[string]*string{
+		"chart-registry":  &mirror.ChartRegistry,
+		"chart-directory": &mirror.ChartDirectory,
+		"image-registry":  &mirror.ImageRegistry,
+	}
+	changed := false
+	for name, field := range flags {
+		if cmd.Flags().Changed(name) {
+			*field, _ = cmd.Flags().GetString(name)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	// A chart source from the flags replaces the one in state, since only one can be set
+	if cmd.Flags().Changed("chart-registry") && !cmd.Flags().Changed("chart-directory") {
+		mirror.ChartDirectory = ""
+	}
+	if cmd.Flags().Changed("chart-directory") && !cmd.Flags().Changed("chart-registry") {
+		mirror.ChartRegistry = ""
+	}
+
+	err := mirror.IsValid()
+	if err != nil {
+		return err
+	}
+
+	c.BootstrapperState.Mirror = &mirror
+	return nil
+}
+
+// clusterNameFromFlags returns the --cluster flag, falling back to the cluster name saved in state.
+func clusterNameFromFlags(cmd *cobra.Command, c *api.Context) (string, error) {
+	clusterName, _ := cmd.Flags().GetString("cluster")
+	if clusterName == "" {
+		clusterName = c.BootstrapperState.ClusterName
+	}
+	if clusterName == "" {
+		return "", errors.New("no cluster selected, pass --cluster")
+	}
+
+	return clusterName, nil
+}
+
+// readSpecFile decodes a YAML or JSON file into out, honoring the json tags of the model types.
+func readSpecFile(path string, out interface{}) error {
+	if path == "" {
+		return errors.New("a spec file must be provided with -f")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return yaml.Unmarshal(data, out)
+}
+
+// printResult writes result as indented JSON when --output=json, otherwise as a table of the given rows.
+func printResult(cmd *cobra.Command, result interface{}, header []string, rows [][]string) error {
+	output, _ := cmd.Flags().GetString("output")
+
+	switch output {
+	case outputJSON:
+		encoder := json.NewEncoder(cmd.OutOrStdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	case outputTable, "":
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+		fmt.Fprintln(w, strings.Join(header, "\t"))
+		for _, row := range rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unsupported output format: %s", output)
+	}
+}
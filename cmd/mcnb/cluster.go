@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var clusterCmd = &cobra.Command{
+	Use:   "cluster",
+	Short: "Inspect and create Kubernetes clusters",
+}
+
+var clusterListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the clusters available to the current credentials",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		region, _ := cmd.Flags().GetString("region")
+		clusters, err := c.CloudProvider.ListClusters(c.Ctx, region)
+		if err != nil {
+			return fmt.Errorf("failed to list clusters: %w", err)
+		}
+
+		rows := [][]string{}
+		for _, cluster := range clusters {
+			rows = append(rows, []string{aws.StringValue(cluster)})
+		}
+
+		return printResult(cmd, clusters, []string{"NAME"}, rows)
+	},
+}
+
+var clusterGetCmd = &cobra.Command{
+	Use:   "get [name]",
+	Short: "Describe a cluster and its nodegroups",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 1 {
+			cmd.Flags().Set("cluster", args[0])
+		}
+		clusterName, err := clusterNameFromFlags(cmd, c)
+		if err != nil {
+			return err
+		}
+
+		cluster, err := c.CloudProvider.GetCluster(c.Ctx, clusterName)
+		if err != nil {
+			return fmt.Errorf("failed to describe cluster: %w", err)
+		}
+
+		err = api.UpdateStateClusterName(c.BootstrapperState, clusterName)
+		if err != nil {
+			return fmt.Errorf("failed to update cluster name in state: %w", err)
+		}
+
+		if cluster.ClusterNodegroups == nil {
+			cluster.ClusterNodegroups, err = c.CloudProvider.GetNodegroups(c.Ctx, clusterName)
+			if err != nil {
+				return fmt.Errorf("failed to list nodegroups: %w", err)
+			}
+		}
+
+		rows := [][]string{}
+		for _, nodegroup := range cluster.ClusterNodegroups {
+			rows = append(rows, []string{
+				clusterName,
+				aws.StringValue(cluster.Version),
+				string(cluster.Status),
+				aws.StringValue(nodegroup.NodegroupName),
+				strings.Join(aws.StringValueSlice(nodegroup.InstanceTypes), ","),
+				string(nodegroup.Status),
+			})
+		}
+		if len(rows) == 0 {
+			rows = append(rows, []string{clusterName, aws.StringValue(cluster.Version), string(cluster.Status), "", "", ""})
+		}
+
+		return printResult(cmd, cluster, []string{"CLUSTER", "VERSION", "STATUS", "NODEGROUP", "INSTANCE TYPES", "NODEGROUP STATUS"}, rows)
+	},
+}
+
+var clusterCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a cluster from a CreateClusterRequest spec file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		file, _ := cmd.Flags().GetString("file")
+		create := &model.CreateClusterRequest{}
+		if err := readSpecFile(file, create); err != nil {
+			return fmt.Errorf("failed to read cluster spec: %w", err)
+		}
+
+		clusterCreator, ok := c.CloudProvider.(providers.ClusterCreator)
+		if !ok {
+			return fmt.Errorf("the %s provider does not support creating clusters", c.CloudProviderName)
+		}
+
+		cluster, err := clusterCreator.CreateCluster(c.Ctx, create)
+		if err != nil {
+			return fmt.Errorf("failed to create cluster: %w", err)
+		}
+
+		return printResult(cmd, cluster, []string{"CLUSTER", "STATUS"}, [][]string{{aws.StringValue(cluster.Name), string(cluster.Status)}})
+	},
+}
+
+var clusterDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a cluster, for providers that support it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		clusterDeleter, ok := c.CloudProvider.(providers.ClusterDeleter)
+		if !ok {
+			return fmt.Errorf("the %s provider does not support deleting clusters", c.CloudProviderName)
+		}
+
+		err = clusterDeleter.DeleteCluster(c.Ctx, args[0])
+		if err != nil {
+			return fmt.Errorf("failed to delete cluster: %w", err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Cluster %s deleted\n", args[0])
+		return nil
+	},
+}
+
+var clusterUpgradeCmd = &cobra.Command{
+	Use:   "upgrade <name> <kubernetes-version>",
+	Short: "Upgrade a cluster and its nodegroups to a Kubernetes version, for providers that support it",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		clusterUpgrader, ok := c.CloudProvider.(providers.ClusterUpgrader)
+		if !ok {
+			return fmt.Errorf("the %s provider does not support upgrading clusters", c.CloudProviderName)
+		}
+
+		cluster, err := clusterUpgrader.UpgradeCluster(c.Ctx, args[0], args[1])
+		if err != nil {
+			return fmt.Errorf("failed to upgrade cluster: %w", err)
+		}
+
+		return printResult(cmd, cluster, []string{"CLUSTER", "VERSION", "STATUS"}, [][]string{{aws.StringValue(cluster.Name), aws.StringValue(cluster.Version), string(cluster.Status)}})
+	},
+}
+
+var clusterKubeconfigCmd = &cobra.Command{
+	Use:   "kubeconfig [name]",
+	Short: "Print a kubeconfig for the cluster",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		if len(args) == 1 {
+			cmd.Flags().Set("cluster", args[0])
+		}
+		clusterName, err := clusterNameFromFlags(cmd, c)
+		if err != nil {
+			return err
+		}
+
+		config, err := c.CloudProvider.GetKubeConfig(c.Ctx, clusterName)
+		if err != nil {
+			return fmt.Errorf("failed to get kubeconfig: %w", err)
+		}
+
+		rawConfig, err := config.RawConfig()
+		if err != nil {
+			return fmt.Errorf("failed to get raw config: %w", err)
+		}
+
+		kubeconfigBytes, err := clientcmd.Write(rawConfig)
+		if err != nil {
+			return fmt.Errorf("failed to write kubeconfig: %w", err)
+		}
+
+		_, err = cmd.OutOrStdout().Write(kubeconfigBytes)
+		return err
+	},
+}
+
+func init() {
+	clusterListCmd.Flags().String("region", "", "Region to list clusters in")
+	clusterCreateCmd.Flags().StringP("file", "f", "", "Path to a YAML or JSON CreateClusterRequest spec")
+
+	clusterCmd.PersistentFlags().String("cluster", "", "Cluster name. Defaults to the cluster saved in state")
+	clusterCmd.AddCommand(clusterListCmd)
+	clusterCmd.AddCommand(clusterGetCmd)
+	clusterCmd.AddCommand(clusterCreateCmd)
+	clusterCmd.AddCommand(clusterDeleteCmd)
+	clusterCmd.AddCommand(clusterUpgradeCmd)
+	clusterCmd.AddCommand(clusterKubeconfigCmd)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/spf13/cobra"
+)
+
+var credentialsCmd = &cobra.Command{
+	Use:   "credentials",
+	Short: "Manage the cloud provider credentials used by the bootstrapper",
+}
+
+var credentialsSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Validate and save cloud provider credentials to the state file",
+	Long: `Validate and save cloud provider credentials to the state file.
+
+Credentials are read from the file passed with -f. When no file is given, the aws
+provider reads AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN and
+AWS_REGION, the custom provider reads the kubeconfig file in KUBECONFIG, the gcp
+provider reads the service account key file in GOOGLE_APPLICATION_CREDENTIALS,
+GOOGLE_CLOUD_PROJECT and GOOGLE_CLOUD_REGION, and the azure provider reads
+AZURE_TENANT_ID, AZURE_CLIENT_ID, AZURE_CLIENT_SECRET, AZURE_SUBSCRIPTION_ID,
+AZURE_RESOURCE_GROUP and AZURE_LOCATION.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		providerName, _ := cmd.Flags().GetString("provider")
+		if providerName == "" {
+			return errors.New("--provider is required")
+		}
+
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		credentials := &model.Credentials{}
+		file, _ := cmd.Flags().GetString("file")
+		if file != "" {
+			if err := readSpecFile(file, credentials); err != nil {
+				return fmt.Errorf("failed to read credentials file: %w", err)
+			}
+		} else {
+			credentials = credentialsFromEnv(providerName)
+		}
+
+		err = c.CloudProvider.SetCredentials(c.Ctx, credentials)
+		if err != nil {
+			return fmt.Errorf("failed to set credentials: %w", err)
+		}
+
+		_, err = c.CloudProvider.ValidateCredentials(c.Ctx, credentials)
+		if err != nil {
+			return fmt.Errorf("failed to validate credentials: %w", err)
+		}
+
+		err = api.UpdateStateCredentialsAndProvider(c.BootstrapperState, credentials, providerName)
+		if err != nil {
+			return fmt.Errorf("failed to update state credentials: %w", err)
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Credentials for %s validated and saved\n", providerName)
+		return nil
+	},
+}
+
+func credentialsFromEnv(providerName string) *model.Credentials {
+	switch providerName {
+	case "aws":
+		return &model.Credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			Region:          os.Getenv("AWS_REGION"),
+		}
+	case "custom":
+		return &model.Credentials{
+			Kubecfg:     os.Getenv("KUBECONFIG"),
+			KubecfgType: "file",
+		}
+	case "gcp":
+		credentials := &model.Credentials{
+			ProjectID: os.Getenv("GOOGLE_CLOUD_PROJECT"),
+			Region:    os.Getenv("GOOGLE_CLOUD_REGION"),
+		}
+		if key, err := os.ReadFile(os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")); err == nil {
+			credentials.ServiceAccountJSON = string(key)
+		}
+		return credentials
+	case "azure":
+		return &model.Credentials{
+			TenantID:       os.Getenv("AZURE_TENANT_ID"),
+			ClientID:       os.Getenv("AZURE_CLIENT_ID"),
+			ClientSecret:   os.Getenv("AZURE_CLIENT_SECRET"),
+			SubscriptionID: os.Getenv("AZURE_SUBSCRIPTION_ID"),
+			ResourceGroup:  os.Getenv("AZURE_RESOURCE_GROUP"),
+			Region:         os.Getenv("AZURE_LOCATION"),
+		}
+	default:
+		return &model.Credentials{}
+	}
+}
+
+func init() {
+	credentialsSetCmd.Flags().StringP("file", "f", "", "Path to a YAML or JSON credentials file")
+	credentialsCmd.AddCommand(credentialsSetCmd)
+}
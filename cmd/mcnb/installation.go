@@ -0,0 +1,385 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	mmv1beta1 "github.com/mattermost/mattermost-operator/apis/mattermost/v1beta1"
+	"github.com/spf13/cobra"
+)
+
+var installationCmd = &cobra.Command{
+	Use:   "installation",
+	Short: "Manage Mattermost installations",
+}
+
+var installationListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the Mattermost installations on the cluster",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		clusterName, err := clusterNameFromFlags(cmd, c)
+		if err != nil {
+			return err
+		}
+
+		installations, err := api.ListMattermostInstallations(c, clusterName)
+		if err != nil {
+			return err
+		}
+
+		return printInstallations(cmd, installations, installations)
+	},
+}
+
+var installationCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a Mattermost installation from a CreateMattermostWorkspaceRequest spec file",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		clusterName, err := clusterNameFromFlags(cmd, c)
+		if err != nil {
+			return err
+		}
+
+		create, err := readInstallationSpec(cmd)
+		if err != nil {
+			return err
+		}
+
+		installation, err := api.CreateMattermostInstallation(c, clusterName, create)
+		if err != nil {
+			return err
+		}
+
+		return printInstallations(cmd, installation, []mmv1beta1.Mattermost{*installation})
+	},
+}
+
+var installationValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Test the database and S3 credentials of an installation spec file before creating it",
+	Long: `Run a probe job in the cluster for each database connection string and S3 filestore of the spec, and report
+whether each connection works. Connections to resources that the installation would create are skipped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		clusterName, err := clusterNameFromFlags(cmd, c)
+		if err != nil {
+			return err
+		}
+
+		create, err := readInstallationSpec(cmd)
+		if err != nil {
+			return err
+		}
+
+		validation, err := api.ValidateMattermostInstallation(c, clusterName, create)
+		if err != nil {
+			return err
+		}
+
+		rows := [][]string{}
+		for _, check := range validation.Checks {
+			rows = append(rows, []string{check.Name, check.Status, check.Message})
+		}
+		err = printResult(cmd, validation, []string{"CHECK", "STATUS", "MESSAGE"}, rows)
+		if err != nil {
+			return err
+		}
+
+		if !validation.Valid {
+			return errors.New("installation spec failed pre-flight checks")
+		}
+		return nil
+	},
+}
+
+var installationPatchCmd = &cobra.Command{
+	Use:   "patch <installation>",
+	Short: "Patch a Mattermost installation from a PatchMattermostWorkspaceRequest spec file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		clusterName, err := clusterNameFromFlags(cmd, c)
+		if err != nil {
+			return err
+		}
+
+		file, _ := cmd.Flags().GetString("file")
+		patch := &model.PatchMattermostWorkspaceRequest{}
+		if err := readSpecFile(file, patch); err != nil {
+			return fmt.Errorf("failed to read patch spec: %w", err)
+		}
+
+		if patch.CNPGDatabasePatch != nil {
+			if err := patch.CNPGDatabasePatch.IsValidPatch(); err != nil {
+				return fmt.Errorf("invalid CNPG database patch: %w", err)
+			}
+		}
+
+		if !patch.IsValid() {
+			return fmt.Errorf("invalid patch spec, version validation failed for version: %s", patch.Version)
+		}
+
+		installation, err := api.PatchMattermostInstallation(c, clusterName, args[0], patch)
+		if err != nil {
+			return err
+		}
+
+		return printInstallations(cmd, installation, []mmv1beta1.Mattermost{*installation})
+	},
+}
+
+var installationDeleteCmd = &cobra.Command{
+	Use:   "delete <installation>",
+	Short: "Delete a Mattermost installation and its namespace",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		clusterName, err := clusterNameFromFlags(cmd, c)
+		if err != nil {
+			return err
+		}
+
+		err = api.DeleteMattermostInstallation(c, clusterName, args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "%s deleted\n", args[0])
+		return nil
+	},
+}
+
+var installationBackupsCmd = &cobra.Command{
+	Use:   "backups <installation>",
+	Short: "List the database backups of a Mattermost installation",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		clusterName, err := clusterNameFromFlags(cmd, c)
+		if err != nil {
+			return err
+		}
+
+		backups, err := api.ListDatabaseBackups(c, clusterName, args[0])
+		if err != nil {
+			return err
+		}
+
+		return printBackups(cmd, backups, backups)
+	},
+}
+
+var installationBackupCmd = &cobra.Command{
+	Use:   "backup <installation>",
+	Short: "Start a backup of the database of a Mattermost installation",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		clusterName, err := clusterNameFromFlags(cmd, c)
+		if err != nil {
+			return err
+		}
+
+		backup, err := api.CreateDatabaseBackup(c, clusterName, args[0])
+		if err != nil {
+			return err
+		}
+
+		return printBackups(cmd, backup, []model.DatabaseBackup{*backup})
+	},
+}
+
+var installationRestoreCmd = &cobra.Command{
+	Use:   "restore <installation>",
+	Short: "Restore the database of a Mattermost installation into a new CNPG cluster",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		clusterName, err := clusterNameFromFlags(cmd, c)
+		if err != nil {
+			return err
+		}
+
+		request := &model.RestoreDatabaseRequest{}
+		request.ClusterName, _ = cmd.Flags().GetString("cnpg-cluster")
+		targetTime, _ := cmd.Flags().GetString("target-time")
+		if targetTime != "" {
+			parsed, err := time.Parse(time.RFC3339, targetTime)
+			if err != nil {
+				return fmt.Errorf("invalid target time: %w", err)
+			}
+			request.TargetTime = &parsed
+		}
+
+		if err := request.IsValid(); err != nil {
+			return err
+		}
+
+		restore, err := api.RestoreDatabase(c, clusterName, args[0], request)
+		if err != nil {
+			return err
+		}
+
+		return printResult(cmd, restore, []string{"CLUSTER", "NAMESPACE"}, [][]string{{restore.ClusterName, restore.Namespace}})
+	},
+}
+
+var installationRDSCmd = &cobra.Command{
+	Use:   "rds <installation>",
+	Short: "Create an Aurora or RDS PostgreSQL database and store its connection strings in the installation's database secret",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		clusterName, err := clusterNameFromFlags(cmd, c)
+		if err != nil {
+			return err
+		}
+
+		create := &model.CreateManagedDatabaseRequest{InstallationName: args[0]}
+		create.Engine, _ = cmd.Flags().GetString("engine")
+		create.InstanceClass, _ = cmd.Flags().GetString("instance-class")
+		create.Replicas, _ = cmd.Flags().GetInt("replicas")
+		create.MultiAZ, _ = cmd.Flags().GetBool("multi-az")
+		if err := create.IsValid(); err != nil {
+			return err
+		}
+
+		database, err := api.CreateManagedDatabase(c, clusterName, create)
+		if err != nil {
+			return err
+		}
+
+		return printResult(cmd, database, []string{"IDENTIFIER", "ENGINE", "ENDPOINT"}, [][]string{{database.Identifier, database.Engine, database.Endpoint}})
+	},
+}
+
+// readInstallationSpec reads the CreateMattermostWorkspaceRequest spec file of the file flag and checks it.
+func readInstallationSpec(cmd *cobra.Command) (*model.CreateMattermostWorkspaceRequest, error) {
+	file, _ := cmd.Flags().GetString("file")
+	create := &model.CreateMattermostWorkspaceRequest{}
+	if err := readSpecFile(file, create); err != nil {
+		return nil, fmt.Errorf("failed to read installation spec: %w", err)
+	}
+
+	if create.CNPGDatabase != nil {
+		if err := create.CNPGDatabase.IsValid(); err != nil {
+			return nil, fmt.Errorf("invalid CNPG database config: %w", err)
+		}
+	}
+
+	if create.RDSDatabase != nil {
+		if err := create.RDSDatabase.IsValid(); err != nil {
+			return nil, fmt.Errorf("invalid RDS database config: %w", err)
+		}
+	}
+
+	if create.AWSS3Filestore != nil {
+		if err := create.AWSS3Filestore.IsValid(); err != nil {
+			return nil, fmt.Errorf("invalid AWS S3 filestore config: %w", err)
+		}
+	}
+
+	if create.MinioFilestore != nil {
+		if err := create.MinioFilestore.IsValid(); err != nil {
+			return nil, fmt.Errorf("invalid MinIO filestore config: %w", err)
+		}
+	}
+
+	if !create.IsValid() {
+		return nil, errors.New("invalid installation spec")
+	}
+
+	return create, nil
+}
+
+func printBackups(cmd *cobra.Command, result interface{}, backups []model.DatabaseBackup) error {
+	rows := [][]string{}
+	for _, backup := range backups {
+		started := ""
+		if backup.StartedAt != nil {
+			started = backup.StartedAt.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{backup.Name, backup.ClusterName, backup.Phase, started, backup.Error})
+	}
+
+	return printResult(cmd, result, []string{"NAME", "CLUSTER", "PHASE", "STARTED", "ERROR"}, rows)
+}
+
+func printInstallations(cmd *cobra.Command, result interface{}, installations []mmv1beta1.Mattermost) error {
+	rows := [][]string{}
+	for _, installation := range installations {
+		rows = append(rows, []string{
+			installation.Name,
+			installation.Namespace,
+			installation.Spec.Version,
+			string(installation.Status.State),
+			installation.Status.Endpoint,
+		})
+	}
+
+	return printResult(cmd, result, []string{"NAME", "NAMESPACE", "VERSION", "STATE", "ENDPOINT"}, rows)
+}
+
+func init() {
+	installationCreateCmd.Flags().StringP("file", "f", "", "Path to a YAML or JSON installation spec")
+	installationValidateCmd.Flags().StringP("file", "f", "", "Path to a YAML or JSON installation spec")
+	installationPatchCmd.Flags().StringP("file", "f", "", "Path to a YAML or JSON patch spec")
+	installationRestoreCmd.Flags().String("target-time", "", "RFC 3339 time to recover to. Defaults to the latest archived WAL")
+	installationRDSCmd.Flags().String("engine", model.ManagedDatabaseEngineAurora, "Database engine, aurora-postgresql or postgres")
+	installationRDSCmd.Flags().String("instance-class", "", "Instance class. Defaults to db.r6g.large for Aurora and db.t4g.medium for RDS")
+	installationRDSCmd.Flags().Int("replicas", 0, "Number of Aurora readers")
+	installationRDSCmd.Flags().Bool("multi-az", false, "Add a standby in another availability zone to an RDS instance")
+	installationRestoreCmd.Flags().String("cnpg-cluster", "", "Name of the new CNPG cluster. Defaults to the installation's cluster name with a timestamp")
+
+	installationCmd.PersistentFlags().String("cluster", "", "Cluster name. Defaults to the cluster saved in state")
+	installationCmd.AddCommand(installationListCmd)
+	installationCmd.AddCommand(installationCreateCmd)
+	installationCmd.AddCommand(installationValidateCmd)
+	installationCmd.AddCommand(installationPatchCmd)
+	installationCmd.AddCommand(installationDeleteCmd)
+	installationCmd.AddCommand(installationBackupsCmd)
+	installationCmd.AddCommand(installationBackupCmd)
+	installationCmd.AddCommand(installationRestoreCmd)
+	installationCmd.AddCommand(installationRDSCmd)
+}
@@ -21,9 +21,21 @@ var rootCmd = &cobra.Command{
 }
 
 func init() {
-	serverCmd.PersistentFlags().String("state-file-path", api.DefaultStateFilePath(), "Path to the state file. Defaults to ~/.mcnb/state.json")
-	serverCmd.PersistentFlags().Bool("disable-telemetry", false, "Disable telemetry")
+	rootCmd.PersistentFlags().String("state-file-path", api.DefaultStateFilePath(), "Path to the state file. Defaults to ~/.mcnb/state.json")
+	rootCmd.PersistentFlags().Bool("disable-telemetry", false, "Disable telemetry")
+	rootCmd.PersistentFlags().String("provider", "", "Cloud provider to use (aws, custom, gcp, azure, local). Defaults to the provider saved in state")
+	rootCmd.PersistentFlags().StringP("output", "o", outputTable, "Output format for command results: table or json")
+	rootCmd.PersistentFlags().String("chart-registry", "", "OCI registry to install add-on charts from instead of their public repositories, such as oci://registry.example.com/charts")
+	rootCmd.PersistentFlags().String("chart-directory", "", "Directory of chart archives created by 'mcnb bundle' to install add-ons from")
+	rootCmd.PersistentFlags().String("image-registry", "", "Registry to pull add-on and installation images from instead of their public registries")
 	rootCmd.AddCommand(serverCmd)
+	rootCmd.AddCommand(credentialsCmd)
+	rootCmd.AddCommand(clusterCmd)
+	rootCmd.AddCommand(operatorsCmd)
+	rootCmd.AddCommand(installationCmd)
+	rootCmd.AddCommand(planCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(bundleCmd)
 }
 
 func main() {
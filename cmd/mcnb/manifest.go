@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/spf13/cobra"
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show the changes needed to converge the cluster to a bootstrap manifest",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, manifest, err := loadManifest(cmd)
+		if err != nil {
+			return err
+		}
+
+		plan, _, err := api.PlanManifest(c, manifest)
+		if err != nil {
+			return err
+		}
+
+		return printPlan(cmd, plan)
+	},
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Converge the cluster to a bootstrap manifest, changing only what differs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, manifest, err := loadManifest(cmd)
+		if err != nil {
+			return err
+		}
+
+		plan, observed, err := api.PlanManifest(c, manifest)
+		if err != nil {
+			return err
+		}
+
+		err = printPlan(cmd, plan)
+		if err != nil {
+			return err
+		}
+
+		if !plan.HasChanges() {
+			fmt.Fprintln(cmd.ErrOrStderr(), "No changes, the cluster matches the manifest")
+			return nil
+		}
+
+		err = api.ApplyPlan(c, manifest, plan, observed)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(cmd.ErrOrStderr(), "Apply complete")
+		return nil
+	},
+}
+
+// loadManifest reads the manifest passed with -f and builds a context for its provider, applying the credentials
+// the manifest references. Without a credentials file or environment, the credentials saved in state are kept, moved
+// to the manifest's region when it has one.
+func loadManifest(cmd *cobra.Command) (*api.Context, *model.BootstrapManifest, error) {
+	file, _ := cmd.Flags().GetString("file")
+	manifest := &model.BootstrapManifest{}
+	if err := readSpecFile(file, manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	if provider, _ := cmd.Flags().GetString("provider"); provider == "" {
+		cmd.Flags().Set("provider", manifest.Provider)
+	}
+
+	c, err := newCLIContext(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var credentials *model.Credentials
+	switch {
+	case manifest.Credentials != nil && manifest.Credentials.File != "":
+		credentials = &model.Credentials{}
+		if err := readSpecFile(manifest.Credentials.File, credentials); err != nil {
+			return nil, nil, fmt.Errorf("failed to read credentials file: %w", err)
+		}
+	case manifest.Credentials != nil && manifest.Credentials.FromEnv:
+		credentials = credentialsFromEnv(manifest.Provider)
+	case manifest.Cluster.Region != "" && c.BootstrapperState.Credentials != nil:
+		stateCredentials := *c.BootstrapperState.Credentials
+		credentials = &stateCredentials
+	default:
+		return c, manifest, nil
+	}
+
+	if manifest.Cluster.Region != "" {
+		credentials.Region = manifest.Cluster.Region
+	}
+
+	err = c.CloudProvider.SetCredentials(c.Ctx, credentials)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to set credentials: %w", err)
+	}
+
+	return c, manifest, nil
+}
+
+func printPlan(cmd *cobra.Command, plan *model.Plan) error {
+	rows := [][]string{}
+	for _, change := range plan.Changes {
+		rows = append(rows, []string{change.Resource, change.Name, string(change.Action), change.Reason})
+	}
+
+	return printResult(cmd, plan, []string{"RESOURCE", "NAME", "ACTION", "REASON"}, rows)
+}
+
+func init() {
+	planCmd.Flags().StringP("file", "f", "", "Path to a YAML or JSON bootstrap manifest")
+	applyCmd.Flags().StringP("file", "f", "", "Path to a YAML or JSON bootstrap manifest")
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/api"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/spf13/cobra"
+)
+
+var operatorsCmd = &cobra.Command{
+	Use:   "operators",
+	Short: "Deploy and remove the operators required by Mattermost installations",
+}
+
+var operatorsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the helm releases deployed to the cluster",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		clusterName, err := clusterNameFromFlags(cmd, c)
+		if err != nil {
+			return err
+		}
+
+		releases, err := api.ListInstalledReleases(c, clusterName)
+		if err != nil {
+			return err
+		}
+
+		rows := [][]string{}
+		for _, release := range releases {
+			rows = append(rows, []string{release.Name, release.Namespace, release.Version, release.Status})
+		}
+
+		return printResult(cmd, releases, []string{"NAME", "NAMESPACE", "VERSION", "STATUS"}, rows)
+	},
+}
+
+var operatorsDeployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Deploy operators and other add-ons to the cluster",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		versions, _ := cmd.Flags().GetStringToString("version")
+		for name := range versions {
+			if _, ok := api.GetAddon(name); !ok {
+				return fmt.Errorf("unknown operator %q in --version, expected one of %v", name, api.AddonNames())
+			}
+		}
+
+		return runOperatorAction(cmd, "deployed", func(c *api.Context, clusterName string, addon api.Addon) error {
+			return api.InstallAddon(c, clusterName, addon, &model.InstallAddonRequest{Version: versions[addon.Name]})
+		})
+	},
+}
+
+var operatorsDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Remove operators and other add-ons from the cluster",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runOperatorAction(cmd, "deleted", api.UninstallAddon)
+	},
+}
+
+var operatorsPreflightCmd = &cobra.Command{
+	Use:   "preflight",
+	Short: "Check that the cluster is ready for operators and an installation of the given size",
+	Long: `Inspect the Kubernetes version against the versions the operators support, the default storage class, the
+capacity of the nodes, load balancer support, ingress classes, conflicting CRDs and the permissions of the
+credentials. Failed checks make the command exit with an error.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newCLIContext(cmd)
+		if err != nil {
+			return err
+		}
+
+		clusterName, err := clusterNameFromFlags(cmd, c)
+		if err != nil {
+			return err
+		}
+
+		selected, _ := cmd.Flags().GetStringSlice("operator")
+		size, _ := cmd.Flags().GetString("size")
+		report, err := api.ClusterPreflight(c, clusterName, size, selected)
+		if err != nil {
+			return err
+		}
+
+		rows := [][]string{}
+		for _, check := range report.Checks {
+			rows = append(rows, []string{check.Name, check.Status, check.Message})
+		}
+		err = printResult(cmd, report, []string{"CHECK", "STATUS", "MESSAGE"}, rows)
+		if err != nil {
+			return err
+		}
+
+		if report.Status == model.PreflightCheckFailed {
+			return errors.New("cluster failed pre-flight checks")
+		}
+		return nil
+	},
+}
+
+var operatorsCatalogCmd = &cobra.Command{
+	Use:   "catalog",
+	Short: "List the add-ons that can be deployed",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		rows := [][]string{}
+		for _, addon := range api.Addons {
+			rows = append(rows, []string{addon.Name, addon.RepoURL, addon.Chart, addon.Version, addon.Namespace, fmt.Sprint(addon.Required), addon.Description})
+		}
+
+		return printResult(cmd, api.Addons, []string{"NAME", "REPOSITORY", "CHART", "VERSION", "NAMESPACE", "REQUIRED", "DESCRIPTION"}, rows)
+	},
+}
+
+var operatorsVersionsCmd = &cobra.Command{
+	Use:   "versions <operator>",
+	Short: "List the chart versions of an add-on, newest first",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addon, ok := api.GetAddon(args[0])
+		if !ok {
+			return fmt.Errorf("unknown operator %q, expected one of %v", args[0], api.AddonNames())
+		}
+
+		c := &api.Context{Ctx: cmd.Context()}
+		versions, err := api.ListAddonVersions(c, addon)
+		if err != nil {
+			return err
+		}
+
+		rows := [][]string{}
+		for _, version := range versions {
+			pinned := ""
+			if version.Pinned {
+				pinned = "*"
+			}
+			rows = append(rows, []string{version.Version, version.AppVersion, version.Created.Format(time.DateOnly), pinned})
+		}
+
+		return printResult(cmd, versions, []string{"VERSION", "APP VERSION", "CREATED", "PINNED"}, rows)
+	},
+}
+
+var operatorsHistoryCmd = &cobra.Command{
+	Use:   "history <release>",
+	Short: "List the revisions of a helm release, newest first",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, clusterName, namespace, err := releaseFromFlags(cmd, args[0])
+		if err != nil {
+			return err
+		}
+
+		revisions, err := api.ListReleaseHistory(c, clusterName, namespace, args[0])
+		if err != nil {
+			return err
+		}
+
+		rows := [][]string{}
+		for _, revision := range revisions {
+			rows = append(rows, []string{fmt.Sprint(revision.Revision), revision.Updated.Format(time.DateTime), revision.Status, revision.ChartVersion, revision.AppVersion, revision.Description})
+		}
+
+		return printResult(cmd, revisions, []string{"REVISION", "UPDATED", "STATUS", "CHART VERSION", "APP VERSION", "DESCRIPTION"}, rows)
+	},
+}
+
+var operatorsRollbackCmd = &cobra.Command{
+	Use:   "rollback <release>",
+	Short: "Roll a helm release back to a previous revision",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, clusterName, namespace, err := releaseFromFlags(cmd, args[0])
+		if err != nil {
+			return err
+		}
+
+		revision, _ := cmd.Flags().GetInt("revision")
+		current, err := api.RollbackRelease(c, clusterName, namespace, args[0], revision)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "%s rolled back, now at revision %d with chart version %s\n", args[0], current.Revision, current.ChartVersion)
+		return nil
+	},
+}
+
+// releaseFromFlags returns the context, cluster and namespace for a release command. The namespace defaults to the
+// one of the catalog add-on with the same release name.
+func releaseFromFlags(cmd *cobra.Command, releaseName string) (*api.Context, string, string, error) {
+	c, err := newCLIContext(cmd)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	clusterName, err := clusterNameFromFlags(cmd, c)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	namespace, _ := cmd.Flags().GetString("namespace")
+	if namespace == "" {
+		for _, addon := range api.Addons {
+			if addon.ReleaseName == releaseName {
+				namespace = addon.Namespace
+				break
+			}
+		}
+	}
+	if namespace == "" {
+		return nil, "", "", fmt.Errorf("--namespace is required for %s, which isn't a catalog add-on", releaseName)
+	}
+
+	return c, clusterName, namespace, nil
+}
+
+func runOperatorAction(cmd *cobra.Command, verb string, action func(c *api.Context, clusterName string, addon api.Addon) error) error {
+	c, err := newCLIContext(cmd)
+	if err != nil {
+		return err
+	}
+
+	clusterName, err := clusterNameFromFlags(cmd, c)
+	if err != nil {
+		return err
+	}
+
+	selected, _ := cmd.Flags().GetStringSlice("operator")
+	if len(selected) == 0 {
+		selected = api.RequiredAddonNames()
+	}
+
+	addons := []api.Addon{}
+	for _, name := range selected {
+		addon, ok := api.GetAddon(name)
+		if !ok {
+			return fmt.Errorf("unknown operator %q, expected one of %v", name, api.AddonNames())
+		}
+		addons = append(addons, addon)
+	}
+
+	for _, addon := range addons {
+		err = action(c, clusterName, addon)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n", addon.Name, verb)
+	}
+
+	return nil
+}
+
+func init() {
+	operatorsDeployCmd.Flags().StringSlice("operator", nil, fmt.Sprintf("Add-ons to deploy, one or more of %v. Defaults to %v", api.AddonNames(), api.RequiredAddonNames()))
+	operatorsDeployCmd.Flags().StringToString("version", nil, "Chart versions to deploy instead of the pinned ones, for example cnpg=0.21.0")
+	operatorsPreflightCmd.Flags().StringSlice("operator", nil, fmt.Sprintf("Add-ons to check for, one or more of %v. Defaults to %v", api.AddonNames(), api.RequiredAddonNames()))
+	operatorsPreflightCmd.Flags().String("size", "100users", "Size of the Mattermost installation to check node capacity for, such as 1000users")
+	operatorsDeleteCmd.Flags().StringSlice("operator", nil, fmt.Sprintf("Add-ons to delete, one or more of %v. Defaults to %v", api.AddonNames(), api.RequiredAddonNames()))
+
+	operatorsHistoryCmd.Flags().String("namespace", "", "Namespace of the release. Defaults to the namespace of the add-on with that release name")
+	operatorsRollbackCmd.Flags().String("namespace", "", "Namespace of the release. Defaults to the namespace of the add-on with that release name")
+	operatorsRollbackCmd.Flags().Int("revision", 0, "Revision to roll back to. Defaults to the previous revision")
+
+	operatorsCmd.PersistentFlags().String("cluster", "", "Cluster name. Defaults to the cluster saved in state")
+	operatorsCmd.AddCommand(operatorsListCmd)
+	operatorsCmd.AddCommand(operatorsDeployCmd)
+	operatorsCmd.AddCommand(operatorsDeleteCmd)
+	operatorsCmd.AddCommand(operatorsPreflightCmd)
+	operatorsCmd.AddCommand(operatorsCatalogCmd)
+	operatorsCmd.AddCommand(operatorsVersionsCmd)
+	operatorsCmd.AddCommand(operatorsHistoryCmd)
+	operatorsCmd.AddCommand(operatorsRollbackCmd)
+}
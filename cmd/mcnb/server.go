@@ -18,6 +18,9 @@ import (
 
 const defaultLocalServerAPI = "http://localhost:8070"
 
+// jobWorkers is the number of long-running operations, such as cluster creation, that run at the same time.
+const jobWorkers = 4
+
 var serverCmd = &cobra.Command{
 	Use:   "server",
 	Short: "Run the Mattermost CloudNative Bootstrapper server",
@@ -37,6 +40,16 @@ var serverCmd = &cobra.Command{
 			return err
 		}
 
+		err = applyMirrorFlags(cmd, apiContext)
+		if err != nil {
+			return err
+		}
+
+		apiContext.Jobs, err = api.NewJobManager(ctx, api.DefaultJobsDir(stateFilePath), jobWorkers)
+		if err != nil {
+			return err
+		}
+
 		api.Register(r, apiContext)
 
 		srv := &http.Server{
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// HookPhase is when a hook runs relative to the installation of an add-on.
+type HookPhase string
+
+const (
+	HookPhasePreInstall  HookPhase = "pre-install"
+	HookPhasePostInstall HookPhase = "post-install"
+)
+
+// InstallAddonRequest configures an add-on from the catalog. Version selects a chart version other than the
+// pinned one, and Values are helm values merged over the add-on's defaults.
+type InstallAddonRequest struct {
+	Version string                 `json:"version,omitempty"`
+	Values  map[string]interface{} `json:"values,omitempty"`
+}
+
+// AddonVersion is a version of an add-on's chart, as listed in its repository. Pinned is set for the version the
+// bootstrapper installs by default.
+type AddonVersion struct {
+	Version    string    `json:"version"`
+	AppVersion string    `json:"appVersion"`
+	Created    time.Time `json:"created"`
+	Pinned     bool      `json:"pinned"`
+}
+
+// NewInstallAddonRequestFromReader decodes an InstallAddonRequest. An empty body is an empty request.
+func NewInstallAddonRequestFromReader(reader io.Reader) (*InstallAddonRequest, error) {
+	var installAddonRequest InstallAddonRequest
+	err := json.NewDecoder(reader).Decode(&installAddonRequest)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &installAddonRequest, nil
+}
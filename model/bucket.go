@@ -0,0 +1,99 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// BucketAccessIAMUser gives Mattermost the access keys of an IAM user that can only use the bucket.
+	BucketAccessIAMUser = "iam-user"
+	// BucketAccessIRSA gives the Mattermost service account an IAM role that can only use the bucket, through the
+	// OIDC provider of the cluster.
+	BucketAccessIRSA = "irsa"
+)
+
+// DefaultNoncurrentVersionExpirationDays is how long overwritten and deleted files are kept in a new bucket.
+const DefaultNoncurrentVersionExpirationDays = 30
+
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// CreateBucketRequest provisions an object storage bucket for the files of an installation, along with the
+// credentials Mattermost uses to reach it. Empty fields take the defaults of WithDefaults.
+type CreateBucketRequest struct {
+	// InstallationName is the installation that uses the bucket. It is set from the installation when the bucket is
+	// created along with it.
+	InstallationName string `json:"installationName,omitempty"`
+	BucketName       string `json:"bucketName,omitempty"`
+	Access           string `json:"access,omitempty"`
+	// NoncurrentVersionExpirationDays is how long overwritten and deleted files are kept before they are removed.
+	NoncurrentVersionExpirationDays int `json:"noncurrentVersionExpirationDays,omitempty"`
+
+	// AccessKeyID and SecretAccessKey are an access key of the bucket's IAM user from an earlier attempt, such as the
+	// one in the installation's filestore secret. It is reused if the user still has it.
+	AccessKeyID     string `json:"-"`
+	SecretAccessKey string `json:"-"`
+}
+
+// Bucket is a bucket provisioned by a provider. The secret access key is only stored in the installation's
+// filestore secret.
+type Bucket struct {
+	Name            string `json:"name"`
+	URL             string `json:"url"`
+	Region          string `json:"region"`
+	Access          string `json:"access"`
+	RoleARN         string `json:"roleArn,omitempty"`
+	UserName        string `json:"userName,omitempty"`
+	AccessKeyID     string `json:"-"`
+	SecretAccessKey string `json:"-"`
+}
+
+// WithDefaults returns the request with a bucket name derived from the cluster and installation names, IAM user
+// access and the default expiration of old versions, unless they are set.
+func (r *CreateBucketRequest) WithDefaults(clusterName string) CreateBucketRequest {
+	request := CreateBucketRequest{}
+	if r != nil {
+		request = *r
+	}
+
+	if request.BucketName == "" {
+		request.BucketName = strings.ToLower(strings.ReplaceAll(fmt.Sprintf("%s-%s-filestore", clusterName, request.InstallationName), "_", "-"))
+	}
+	if request.Access == "" {
+		request.Access = BucketAccessIAMUser
+	}
+	if request.NoncurrentVersionExpirationDays == 0 {
+		request.NoncurrentVersionExpirationDays = DefaultNoncurrentVersionExpirationDays
+	}
+
+	return request
+}
+
+// IsValid checks the bucket name, access and expiration.
+func (r *CreateBucketRequest) IsValid() error {
+	switch r.Access {
+	case "", BucketAccessIAMUser, BucketAccessIRSA:
+	default:
+		return fmt.Errorf("unknown access %q, expected %s or %s", r.Access, BucketAccessIAMUser, BucketAccessIRSA)
+	}
+	if r.BucketName != "" && !bucketNamePattern.MatchString(r.BucketName) {
+		return fmt.Errorf("bucketName %q must have 3 to 63 lowercase letters, digits, dots and hyphens", r.BucketName)
+	}
+	if r.NoncurrentVersionExpirationDays < 0 {
+		return fmt.Errorf("noncurrentVersionExpirationDays must not be negative")
+	}
+
+	return nil
+}
+
+// S3Filestore returns the filestore config for an installation that uses the bucket.
+func (b *Bucket) S3Filestore() *S3Filestore {
+	return &S3Filestore{
+		BucketURL:         b.URL,
+		BucketName:        b.Name,
+		AccessKey:         b.AccessKeyID,
+		SecretKey:         b.SecretAccessKey,
+		UseServiceAccount: b.Access == BucketAccessIRSA,
+	}
+}
@@ -0,0 +1,126 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateBucketRequestWithDefaults(t *testing.T) {
+	t.Run("nil request", func(t *testing.T) {
+		var create *model.CreateBucketRequest
+		withDefaults := create.WithDefaults("cluster")
+		assert.Equal(t, model.BucketAccessIAMUser, withDefaults.Access)
+		assert.Equal(t, model.DefaultNoncurrentVersionExpirationDays, withDefaults.NoncurrentVersionExpirationDays)
+	})
+
+	t.Run("bucket name from the cluster and installation", func(t *testing.T) {
+		create := &model.CreateBucketRequest{InstallationName: "My_Installation", Access: model.BucketAccessIRSA}
+		withDefaults := create.WithDefaults("Cluster")
+		assert.Equal(t, "cluster-my-installation-filestore", withDefaults.BucketName)
+		assert.Equal(t, model.BucketAccessIRSA, withDefaults.Access)
+		assert.NoError(t, withDefaults.IsValid())
+	})
+}
+
+func TestCreateBucketRequestIsValid(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		create model.CreateBucketRequest
+		valid  bool
+	}{
+		{"empty", model.CreateBucketRequest{}, true},
+		{"irsa", model.CreateBucketRequest{BucketName: "mattermost.files", Access: model.BucketAccessIRSA}, true},
+		{"unknown access", model.CreateBucketRequest{Access: "public"}, false},
+		{"uppercase bucket", model.CreateBucketRequest{BucketName: "Mattermost"}, false},
+		{"short bucket", model.CreateBucketRequest{BucketName: "mm"}, false},
+		{"negative expiration", model.CreateBucketRequest{NoncurrentVersionExpirationDays: -1}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.valid, tc.create.IsValid() == nil)
+		})
+	}
+}
+
+func TestAWSS3Filestore(t *testing.T) {
+	create := &model.CreateMattermostWorkspaceRequest{FilestoreOption: model.FilestoreOptionAWSS3}
+
+	t.Run("access keys", func(t *testing.T) {
+		bucket := &model.Bucket{Name: "files", URL: "s3.us-east-1.amazonaws.com", Access: model.BucketAccessIAMUser, AccessKeyID: "id", SecretAccessKey: "secret"}
+		create.S3Filestore = bucket.S3Filestore()
+
+		secret := create.GetMMOperatorFilestoreSecret("mm")
+		require.NotNil(t, secret)
+		assert.Equal(t, map[string]string{"accesskey": "id", "secretkey": "secret"}, secret.StringData)
+
+		filestore := create.GetMMOperatorFilestore("mm", secret)
+		require.NotNil(t, filestore.External)
+		assert.Equal(t, "files", filestore.External.Bucket)
+		assert.Equal(t, model.SecretNameFilestore, filestore.External.Secret)
+		assert.False(t, filestore.External.UseServiceAccount)
+	})
+
+	t.Run("service account", func(t *testing.T) {
+		bucket := &model.Bucket{Name: "files", URL: "s3.us-east-1.amazonaws.com", Access: model.BucketAccessIRSA, RoleARN: "arn:aws:iam::123456789012:role/files"}
+		create.S3Filestore = bucket.S3Filestore()
+		assert.True(t, create.S3Filestore.IsValid())
+
+		assert.Nil(t, create.GetMMOperatorFilestoreSecret("mm"))
+
+		filestore := create.GetMMOperatorFilestore("mm", nil)
+		require.NotNil(t, filestore.External)
+		assert.True(t, filestore.External.UseServiceAccount)
+		assert.Empty(t, filestore.External.Secret)
+	})
+}
+
+func TestMinioFilestoreWithDefaults(t *testing.T) {
+	var config *model.MinioFilestore
+	withDefaults := config.WithDefaults()
+	assert.Equal(t, 1, withDefaults.Replicas)
+	assert.Equal(t, "50Gi", withDefaults.StorageSize)
+	assert.Equal(t, "mattermost", withDefaults.BucketName)
+	assert.NoError(t, withDefaults.IsValid())
+
+	config = &model.MinioFilestore{Replicas: 4, StorageSize: "100Gi"}
+	withDefaults = config.WithDefaults()
+	assert.Equal(t, 4, withDefaults.Replicas)
+	assert.Equal(t, "100Gi", withDefaults.StorageSize)
+}
+
+func TestMinioFilestoreIsValid(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config model.MinioFilestore
+		valid  bool
+	}{
+		{"empty", model.MinioFilestore{}, true},
+		{"distributed", model.MinioFilestore{Replicas: 4, StorageSize: "10Gi", BucketName: "files"}, true},
+		{"two replicas", model.MinioFilestore{Replicas: 2}, false},
+		{"negative replicas", model.MinioFilestore{Replicas: -1}, false},
+		{"invalid storage size", model.MinioFilestore{StorageSize: "lots"}, false},
+		{"uppercase bucket", model.MinioFilestore{BucketName: "Files"}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.valid, tc.config.IsValid() == nil)
+		})
+	}
+}
+
+func TestInClusterMinioFilestore(t *testing.T) {
+	create := &model.CreateMattermostWorkspaceRequest{
+		FilestoreOption: model.FilestoreOptionInClusterMinio,
+		S3Filestore:     &model.S3Filestore{BucketURL: "minio.mm.svc.cluster.local:9000", BucketName: "mattermost", AccessKey: "id", SecretKey: "secret"},
+	}
+
+	// The secret is created along with MinIO, which reads the root credentials from it
+	assert.Nil(t, create.GetMMOperatorFilestoreSecret("mm"))
+
+	filestore := create.GetMMOperatorFilestore("mm", nil)
+	require.NotNil(t, filestore.External)
+	assert.Equal(t, "minio.mm.svc.cluster.local:9000", filestore.External.URL)
+	assert.Equal(t, "mattermost", filestore.External.Bucket)
+	assert.Equal(t, model.SecretNameFilestore, filestore.External.Secret)
+}
@@ -2,6 +2,8 @@ package model
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"time"
 )
@@ -50,6 +52,7 @@ type ClusterNodegroup struct {
 	UpdatedAt       *time.Time         `json:"UpdatedAt,omitempty"`
 	SubnetIds       []*string          `json:"subnetIds"`
 	NodeRole        *string            `json:"NodeRole"`
+	Taints          []*NodegroupTaint  `json:"Taints,omitempty"`
 }
 
 // TODO: Change AWSCredentials to Credentials
@@ -60,6 +63,19 @@ type Credentials struct {
 	Region          string `json:"region"`
 	Kubecfg         string `json:"kubeconfig"`
 	KubecfgType     string `json:"kubeconfigType"`
+	// ServiceAccountJSON is the key file of a GCP service account. Region is used as the GKE location.
+	ServiceAccountJSON string `json:"serviceAccountJson,omitempty"`
+	// ProjectID is the GCP project. When empty, the project of the service account is used.
+	ProjectID string `json:"projectId,omitempty"`
+	// TenantID, ClientID and ClientSecret identify an Azure service principal. Region is used as the AKS location.
+	TenantID     string `json:"tenantId,omitempty"`
+	ClientID     string `json:"clientId,omitempty"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+	// SubscriptionID and ResourceGroup scope the AKS clusters the bootstrapper lists and creates.
+	SubscriptionID string `json:"subscriptionId,omitempty"`
+	ResourceGroup  string `json:"resourceGroup,omitempty"`
+	// ClusterTool selects kind or k3d for the local provider. When empty, whichever is installed is used.
+	ClusterTool string `json:"clusterTool,omitempty"`
 }
 
 type UpdateRegionRequest struct {
@@ -81,6 +97,10 @@ type CreateClusterRequest struct {
 	SubnetIDs         []*string `json:"subnetIds"`
 }
 
+type UpgradeClusterRequest struct {
+	KubernetesVersion string `json:"kubernetesVersion"`
+}
+
 type PolicyDocument struct {
 	Statement []StatementEntry
 }
@@ -114,6 +134,66 @@ type CreateNodegroupRequest struct {
 type ScalingConfig struct {
 	MinSize int64 `json:"minSize"`
 	MaxSize int64 `json:"maxSize"`
+	// DesiredSize is the number of nodes to run. When nil, the provider picks a size within MinSize and MaxSize.
+	DesiredSize *int64 `json:"desiredSize,omitempty"`
+}
+
+const (
+	TaintEffectNoSchedule       = "NO_SCHEDULE"
+	TaintEffectNoExecute        = "NO_EXECUTE"
+	TaintEffectPreferNoSchedule = "PREFER_NO_SCHEDULE"
+)
+
+// NodegroupTaint is a Kubernetes taint applied to every node in a nodegroup.
+type NodegroupTaint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
+// UpdateNodegroupRequest changes an existing nodegroup. Fields that are nil or empty are left as they are, while
+// Labels and Taints replace the nodegroup's current ones, so an empty list removes them all.
+type UpdateNodegroupRequest struct {
+	ScalingConfig  *ScalingConfig    `json:"scalingConfig,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Taints         []NodegroupTaint  `json:"taints,omitempty"`
+	ReleaseVersion string            `json:"releaseVersion,omitempty"`
+}
+
+// HasConfigChanges reports whether the request changes the scaling, labels or taints of the nodegroup, as opposed
+// to only its release version.
+func (u *UpdateNodegroupRequest) HasConfigChanges() bool {
+	return u.ScalingConfig != nil || u.Labels != nil || u.Taints != nil
+}
+
+// IsValid checks that the request changes something, and that the changes are consistent.
+func (u *UpdateNodegroupRequest) IsValid() error {
+	if !u.HasConfigChanges() && u.ReleaseVersion == "" {
+		return errors.New("the request doesn't change anything")
+	}
+
+	if u.ScalingConfig != nil {
+		scaling := u.ScalingConfig
+		if scaling.MinSize < 0 || scaling.MaxSize < 1 || scaling.MinSize > scaling.MaxSize {
+			return errors.New("scalingConfig must have 0 <= minSize <= maxSize and maxSize >= 1")
+		}
+		if scaling.DesiredSize != nil && (*scaling.DesiredSize < scaling.MinSize || *scaling.DesiredSize > scaling.MaxSize) {
+			return errors.New("scalingConfig.desiredSize must be between minSize and maxSize")
+		}
+	}
+
+	for _, taint := range u.Taints {
+		if taint.Key == "" {
+			return errors.New("every taint must have a key")
+		}
+		switch taint.Effect {
+		case TaintEffectNoSchedule, TaintEffectNoExecute, TaintEffectPreferNoSchedule:
+		default:
+			return fmt.Errorf("taint %s has an invalid effect %q", taint.Key, taint.Effect)
+		}
+	}
+
+	return nil
 }
 
 type InstalledReleases struct {
@@ -132,6 +212,15 @@ func NewCreateNodeGroupRequestFromReader(reader io.Reader) (*CreateNodegroupRequ
 	return &createNodeGroupRequest, nil
 }
 
+func NewUpdateNodegroupRequestFromReader(reader io.Reader) (*UpdateNodegroupRequest, error) {
+	var updateNodegroupRequest UpdateNodegroupRequest
+	err := json.NewDecoder(reader).Decode(&updateNodegroupRequest)
+	if err != nil {
+		return nil, err
+	}
+	return &updateNodegroupRequest, nil
+}
+
 func NewCreateClusterRequestFromReader(reader io.Reader) (*CreateClusterRequest, error) {
 	var createClusterRequest CreateClusterRequest
 	err := json.NewDecoder(reader).Decode(&createClusterRequest)
@@ -140,3 +229,12 @@ func NewCreateClusterRequestFromReader(reader io.Reader) (*CreateClusterRequest,
 	}
 	return &createClusterRequest, nil
 }
+
+func NewUpgradeClusterRequestFromReader(reader io.Reader) (*UpgradeClusterRequest, error) {
+	var upgradeClusterRequest UpgradeClusterRequest
+	err := json.NewDecoder(reader).Decode(&upgradeClusterRequest)
+	if err != nil {
+		return nil, err
+	}
+	return &upgradeClusterRequest, nil
+}
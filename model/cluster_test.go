@@ -0,0 +1,39 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateNodegroupRequestIsValid(t *testing.T) {
+	desiredSize := func(size int64) *int64 {
+		return &size
+	}
+
+	for _, tc := range []struct {
+		name    string
+		request model.UpdateNodegroupRequest
+		valid   bool
+	}{
+		{"Empty", model.UpdateNodegroupRequest{}, false},
+		{"ReleaseVersion", model.UpdateNodegroupRequest{ReleaseVersion: "1.29.3-20240506"}, true},
+		{"Scaling", model.UpdateNodegroupRequest{ScalingConfig: &model.ScalingConfig{MinSize: 1, MaxSize: 3, DesiredSize: desiredSize(2)}}, true},
+		{"ScalingMinAboveMax", model.UpdateNodegroupRequest{ScalingConfig: &model.ScalingConfig{MinSize: 3, MaxSize: 1}}, false},
+		{"ScalingDesiredOutOfBounds", model.UpdateNodegroupRequest{ScalingConfig: &model.ScalingConfig{MinSize: 1, MaxSize: 3, DesiredSize: desiredSize(4)}}, false},
+		{"RemoveAllLabels", model.UpdateNodegroupRequest{Labels: map[string]string{}}, true},
+		{"Taint", model.UpdateNodegroupRequest{Taints: []model.NodegroupTaint{{Key: "dedicated", Value: "mattermost", Effect: model.TaintEffectNoSchedule}}}, true},
+		{"TaintWithoutKey", model.UpdateNodegroupRequest{Taints: []model.NodegroupTaint{{Effect: model.TaintEffectNoSchedule}}}, false},
+		{"TaintWithInvalidEffect", model.UpdateNodegroupRequest{Taints: []model.NodegroupTaint{{Key: "dedicated", Effect: "NoSchedule"}}}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.request.IsValid()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	CNPGPoolModeSession     = "session"
+	CNPGPoolModeTransaction = "transaction"
+)
+
+// cnpgMaxInstances bounds the instances of a CNPG cluster: a primary and up to nine replicas.
+const cnpgMaxInstances = 10
+
+var postgresMajorVersionPattern = regexp.MustCompile(`^\d{2}$`)
+
+// CNPGDatabaseConfig sizes the CloudNativePG cluster created for an installation. Empty fields take the defaults for
+// the size of the installation, see CNPGDefaultsForSize.
+type CNPGDatabaseConfig struct {
+	// Instances is the number of PostgreSQL instances: a primary, and replicas that take over if it fails.
+	Instances    int    `json:"instances,omitempty"`
+	StorageSize  string `json:"storageSize,omitempty"`
+	StorageClass string `json:"storageClass,omitempty"`
+	// Resources are the requests and limits of each PostgreSQL instance.
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
+	// PostgresVersion is a major PostgreSQL version, such as 16. It defaults to the operator's default image.
+	PostgresVersion string `json:"postgresVersion,omitempty"`
+	// Parameters are set in postgresql.conf.
+	Parameters map[string]string `json:"parameters,omitempty"`
+	Pooler     *CNPGPoolerConfig `json:"pooler,omitempty"`
+	Backup     *CNPGBackupConfig `json:"backup,omitempty"`
+}
+
+// CNPGPoolerConfig puts PgBouncer between the installation and the primary.
+type CNPGPoolerConfig struct {
+	Enabled   bool   `json:"enabled"`
+	Instances int    `json:"instances,omitempty"`
+	PoolMode  string `json:"poolMode,omitempty"`
+	// Parameters are PgBouncer settings, such as max_client_conn.
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// CNPGBackupConfig archives the WAL of a CNPG cluster and takes scheduled base backups to S3 or S3-compatible object
+// storage, so that the database can be restored to any point in time within the retention policy.
+type CNPGBackupConfig struct {
+	// DestinationPath is the bucket and path to back up to, such as s3://backups/mattermost.
+	DestinationPath string `json:"destinationPath"`
+	// EndpointURL is set for S3-compatible storage, such as MinIO.
+	EndpointURL string `json:"endpointURL,omitempty"`
+	Region      string `json:"region,omitempty"`
+	// AccessKeyID and SecretAccessKey are stored in a secret. Without them the IAM role of the pods is used.
+	AccessKeyID     string `json:"accessKeyId,omitempty"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+	// Schedule is a cron schedule with seconds, such as "0 0 0 * * *" for daily at midnight.
+	Schedule string `json:"schedule,omitempty"`
+	// RetentionPolicy is how long backups are kept, such as 30d, 4w or 6m.
+	RetentionPolicy string `json:"retentionPolicy,omitempty"`
+}
+
+const (
+	CNPGDefaultBackupSchedule        = "0 0 0 * * *"
+	CNPGDefaultBackupRetentionPolicy = "30d"
+)
+
+var backupRetentionPolicyPattern = regexp.MustCompile(`^[1-9][0-9]*[dwm]$`)
+
+// WithDefaults returns the config with a daily schedule and a 30 day retention policy unless they are set.
+func (c *CNPGBackupConfig) WithDefaults() CNPGBackupConfig {
+	config := *c
+	if config.Schedule == "" {
+		config.Schedule = CNPGDefaultBackupSchedule
+	}
+	if config.RetentionPolicy == "" {
+		config.RetentionPolicy = CNPGDefaultBackupRetentionPolicy
+	}
+	return config
+}
+
+// IsValid checks the destination, credentials, schedule and retention policy.
+func (c *CNPGBackupConfig) IsValid() error {
+	if !strings.HasPrefix(c.DestinationPath, "s3://") {
+		return fmt.Errorf("backup destinationPath must start with s3://, got %q", c.DestinationPath)
+	}
+	if (c.AccessKeyID == "") != (c.SecretAccessKey == "") {
+		return errors.New("backup accessKeyId and secretAccessKey must be set together")
+	}
+	if c.Schedule != "" && len(strings.Fields(c.Schedule)) != 6 {
+		return fmt.Errorf("backup schedule must have six fields, starting with seconds, got %q", c.Schedule)
+	}
+	if c.RetentionPolicy != "" && !backupRetentionPolicyPattern.MatchString(c.RetentionPolicy) {
+		return fmt.Errorf("backup retentionPolicy must be a number of days, weeks or months such as 30d, got %q", c.RetentionPolicy)
+	}
+
+	return nil
+}
+
+// DatabaseBackup is a backup of the CNPG cluster of an installation.
+type DatabaseBackup struct {
+	Name        string     `json:"name"`
+	ClusterName string     `json:"clusterName"`
+	Phase       string     `json:"phase"`
+	Method      string     `json:"method"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	StoppedAt   *time.Time `json:"stoppedAt,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// RestoreDatabaseRequest restores the database of an installation into a new CNPG cluster. TargetTime is the point
+// in time to recover to, and the latest archived WAL is replayed when it is empty. ClusterName defaults to the name of
+// the installation's cluster with a timestamp.
+type RestoreDatabaseRequest struct {
+	TargetTime  *time.Time `json:"targetTime,omitempty"`
+	ClusterName string     `json:"clusterName,omitempty"`
+}
+
+// IsValid checks that the target time isn't in the future.
+func (r *RestoreDatabaseRequest) IsValid() error {
+	if r.TargetTime != nil && r.TargetTime.After(time.Now()) {
+		return errors.New("targetTime must not be in the future")
+	}
+	return nil
+}
+
+// DatabaseRestore is the result of restoring a database.
+type DatabaseRestore struct {
+	ClusterName string     `json:"clusterName"`
+	Namespace   string     `json:"namespace"`
+	TargetTime  *time.Time `json:"targetTime,omitempty"`
+}
+
+// CNPGDefaultsForSize returns the database sizing for a Mattermost installation size, such as 5000users. Larger
+// installations get a highly available cluster with a connection pooler. Unknown sizes get the smallest sizing.
+func CNPGDefaultsForSize(size string) CNPGDatabaseConfig {
+	resources := func(cpu, memory string) *v1.ResourceRequirements {
+		return &v1.ResourceRequirements{
+			Requests: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse(cpu),
+				v1.ResourceMemory: resource.MustParse(memory),
+			},
+			Limits: v1.ResourceList{
+				v1.ResourceMemory: resource.MustParse(memory),
+			},
+		}
+	}
+	pooler := func(instances int) *CNPGPoolerConfig {
+		return &CNPGPoolerConfig{Enabled: true, Instances: instances, PoolMode: CNPGPoolModeSession}
+	}
+
+	switch size {
+	case "1000users":
+		return CNPGDatabaseConfig{Instances: 2, StorageSize: "10Gi", Resources: resources("500m", "1Gi")}
+	case "5000users":
+		return CNPGDatabaseConfig{Instances: 3, StorageSize: "50Gi", Resources: resources("1", "4Gi"), Pooler: pooler(2)}
+	case "10000users":
+		return CNPGDatabaseConfig{Instances: 3, StorageSize: "100Gi", Resources: resources("2", "8Gi"), Pooler: pooler(2)}
+	case "25000users":
+		return CNPGDatabaseConfig{Instances: 3, StorageSize: "200Gi", Resources: resources("4", "16Gi"), Pooler: pooler(3)}
+	default:
+		return CNPGDatabaseConfig{Instances: 1, StorageSize: "5Gi", Resources: resources("250m", "512Mi")}
+	}
+}
+
+// WithDefaults returns the config with its empty fields set to the defaults for the installation size.
+func (c *CNPGDatabaseConfig) WithDefaults(size string) CNPGDatabaseConfig {
+	defaults := CNPGDefaultsForSize(size)
+	if c == nil {
+		return defaults
+	}
+
+	config := *c
+	if config.Instances == 0 {
+		config.Instances = defaults.Instances
+	}
+	if config.StorageSize == "" {
+		config.StorageSize = defaults.StorageSize
+	}
+	if config.Resources == nil {
+		config.Resources = defaults.Resources
+	}
+	if config.Pooler == nil {
+		config.Pooler = defaults.Pooler
+	}
+	if config.Pooler != nil && config.Pooler.Enabled {
+		pooler := *config.Pooler
+		if pooler.Instances == 0 {
+			pooler.Instances = 2
+		}
+		if pooler.PoolMode == "" {
+			pooler.PoolMode = CNPGPoolModeSession
+		}
+		config.Pooler = &pooler
+	}
+	if config.Backup != nil {
+		backup := config.Backup.WithDefaults()
+		config.Backup = &backup
+	}
+
+	return config
+}
+
+// IsValid checks the instance counts, storage size, PostgreSQL version, pool mode and backups.
+func (c *CNPGDatabaseConfig) IsValid() error {
+	if c.Instances < 0 || c.Instances > cnpgMaxInstances {
+		return fmt.Errorf("instances must be between 1 and %d", cnpgMaxInstances)
+	}
+	if c.StorageSize != "" {
+		if _, err := resource.ParseQuantity(c.StorageSize); err != nil {
+			return fmt.Errorf("invalid storageSize %q: %w", c.StorageSize, err)
+		}
+	}
+	if c.PostgresVersion != "" && !postgresMajorVersionPattern.MatchString(c.PostgresVersion) {
+		return fmt.Errorf("postgresVersion must be a major version such as 16, got %q", c.PostgresVersion)
+	}
+	if c.Pooler != nil {
+		if c.Pooler.Instances < 0 || c.Pooler.Instances > cnpgMaxInstances {
+			return fmt.Errorf("pooler instances must be between 1 and %d", cnpgMaxInstances)
+		}
+		switch c.Pooler.PoolMode {
+		case "", CNPGPoolModeSession, CNPGPoolModeTransaction:
+		default:
+			return fmt.Errorf("unknown pooler poolMode %q, expected session or transaction", c.Pooler.PoolMode)
+		}
+	}
+	if c.Backup != nil {
+		err := c.Backup.IsValid()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsValidPatch checks a config used to resize an existing cluster. The storage class and PostgreSQL version are
+// fixed when the cluster is created.
+func (c *CNPGDatabaseConfig) IsValidPatch() error {
+	if c.StorageClass != "" {
+		return errors.New("the storageClass of an existing database can't be changed")
+	}
+	if c.PostgresVersion != "" {
+		return errors.New("the postgresVersion of an existing database can't be changed")
+	}
+
+	return c.IsValid()
+}
+
+// NewRestoreDatabaseRequestFromReader decodes a RestoreDatabaseRequest. An empty body is an empty request.
+func NewRestoreDatabaseRequestFromReader(reader io.Reader) (*RestoreDatabaseRequest, error) {
+	var restoreDatabaseRequest RestoreDatabaseRequest
+	err := json.NewDecoder(reader).Decode(&restoreDatabaseRequest)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &restoreDatabaseRequest, nil
+}
+
+// NewCNPGDatabaseConfigFromReader decodes a CNPGDatabaseConfig. An empty body is an empty config.
+func NewCNPGDatabaseConfigFromReader(reader io.Reader) (*CNPGDatabaseConfig, error) {
+	var cnpgDatabaseConfig CNPGDatabaseConfig
+	err := json.NewDecoder(reader).Decode(&cnpgDatabaseConfig)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &cnpgDatabaseConfig, nil
+}
@@ -0,0 +1,92 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCNPGDatabaseConfigWithDefaults(t *testing.T) {
+	t.Run("nil config", func(t *testing.T) {
+		var config *model.CNPGDatabaseConfig
+		assert.Equal(t, model.CNPGDefaultsForSize("5000users"), config.WithDefaults("5000users"))
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		config := &model.CNPGDatabaseConfig{Instances: 5, StorageClass: "gp3"}
+		withDefaults := config.WithDefaults("10000users")
+		assert.Equal(t, 5, withDefaults.Instances)
+		assert.Equal(t, "gp3", withDefaults.StorageClass)
+		assert.Equal(t, "100Gi", withDefaults.StorageSize)
+		require.NotNil(t, withDefaults.Pooler)
+		assert.True(t, withDefaults.Pooler.Enabled)
+	})
+
+	t.Run("pooler enabled for a small size", func(t *testing.T) {
+		config := &model.CNPGDatabaseConfig{Pooler: &model.CNPGPoolerConfig{Enabled: true}}
+		withDefaults := config.WithDefaults("100users")
+		assert.Equal(t, 1, withDefaults.Instances)
+		assert.Equal(t, &model.CNPGPoolerConfig{Enabled: true, Instances: 2, PoolMode: model.CNPGPoolModeSession}, withDefaults.Pooler)
+		assert.Equal(t, 0, config.Pooler.Instances)
+	})
+
+	t.Run("pooler disabled for a large size", func(t *testing.T) {
+		config := &model.CNPGDatabaseConfig{Pooler: &model.CNPGPoolerConfig{Enabled: false}}
+		assert.False(t, config.WithDefaults("25000users").Pooler.Enabled)
+	})
+}
+
+func TestCNPGDatabaseConfigIsValid(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config model.CNPGDatabaseConfig
+		valid  bool
+		patch  bool
+	}{
+		{"empty", model.CNPGDatabaseConfig{}, true, true},
+		{"full", model.CNPGDatabaseConfig{Instances: 3, StorageSize: "50Gi", PostgresVersion: "16", Pooler: &model.CNPGPoolerConfig{Enabled: true, PoolMode: model.CNPGPoolModeTransaction}}, true, false},
+		{"too many instances", model.CNPGDatabaseConfig{Instances: 11}, false, false},
+		{"invalid storage size", model.CNPGDatabaseConfig{StorageSize: "lots"}, false, false},
+		{"minor postgres version", model.CNPGDatabaseConfig{PostgresVersion: "16.1"}, false, false},
+		{"unknown pool mode", model.CNPGDatabaseConfig{Pooler: &model.CNPGPoolerConfig{PoolMode: "statement"}}, false, false},
+		{"storage class", model.CNPGDatabaseConfig{StorageClass: "gp3"}, true, false},
+		{"resize", model.CNPGDatabaseConfig{Instances: 3, StorageSize: "20Gi"}, true, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.valid, tc.config.IsValid() == nil)
+			assert.Equal(t, tc.patch, tc.config.IsValidPatch() == nil)
+		})
+	}
+}
+
+func TestCNPGBackupConfig(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		config := &model.CNPGDatabaseConfig{Backup: &model.CNPGBackupConfig{DestinationPath: "s3://backups", RetentionPolicy: "4w"}}
+		backup := config.WithDefaults("100users").Backup
+		require.NotNil(t, backup)
+		assert.Equal(t, model.CNPGDefaultBackupSchedule, backup.Schedule)
+		assert.Equal(t, "4w", backup.RetentionPolicy)
+	})
+
+	for _, tc := range []struct {
+		name   string
+		config model.CNPGBackupConfig
+		valid  bool
+	}{
+		{"iam role", model.CNPGBackupConfig{DestinationPath: "s3://backups/mattermost"}, true},
+		{"keys", model.CNPGBackupConfig{DestinationPath: "s3://backups", EndpointURL: "http://minio:9000", AccessKeyID: "id", SecretAccessKey: "secret"}, true},
+		{"not s3", model.CNPGBackupConfig{DestinationPath: "gs://backups"}, false},
+		{"missing secret key", model.CNPGBackupConfig{DestinationPath: "s3://backups", AccessKeyID: "id"}, false},
+		{"five field schedule", model.CNPGBackupConfig{DestinationPath: "s3://backups", Schedule: "0 0 * * *"}, false},
+		{"invalid retention", model.CNPGBackupConfig{DestinationPath: "s3://backups", RetentionPolicy: "30 days"}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.valid, tc.config.IsValid() == nil)
+
+			database := model.CNPGDatabaseConfig{Backup: &tc.config}
+			assert.Equal(t, tc.valid, database.IsValidPatch() == nil)
+		})
+	}
+}
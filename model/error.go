@@ -0,0 +1,99 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type ErrorCode string
+
+const (
+	ErrorCodeInvalidRequest     ErrorCode = "invalid_request"
+	ErrorCodeInvalidCredentials ErrorCode = "invalid_credentials"
+	ErrorCodeForbidden          ErrorCode = "forbidden"
+	ErrorCodeNotFound           ErrorCode = "not_found"
+	ErrorCodeAlreadyExists      ErrorCode = "already_exists"
+	ErrorCodeConflict           ErrorCode = "conflict"
+	ErrorCodeLimitExceeded      ErrorCode = "limit_exceeded"
+	ErrorCodeThrottled          ErrorCode = "throttled"
+	ErrorCodeTimeout            ErrorCode = "timeout"
+	ErrorCodeUnavailable        ErrorCode = "unavailable"
+	ErrorCodeNotImplemented     ErrorCode = "not_implemented"
+	ErrorCodeInternal           ErrorCode = "internal_error"
+)
+
+// AppError is an error that carries enough information to build an API response, so that clients can tell
+// apart failures such as bad credentials, missing resources and unreachable services.
+type AppError struct {
+	Code       ErrorCode `json:"code"`
+	StatusCode int       `json:"statusCode"`
+	Message    string    `json:"message"`
+	Details    string    `json:"details,omitempty"`
+	Retryable  bool      `json:"retryable"`
+
+	cause error
+}
+
+// ErrorResponse is the JSON envelope every API error is returned in.
+type ErrorResponse struct {
+	Error *AppError `json:"error"`
+}
+
+func NewAppError(code ErrorCode, statusCode int, message string) *AppError {
+	return &AppError{
+		Code:       code,
+		StatusCode: statusCode,
+		Message:    message,
+		Retryable:  code == ErrorCodeThrottled || code == ErrorCodeTimeout || code == ErrorCodeUnavailable,
+	}
+}
+
+func NewInvalidRequestError(message string) *AppError {
+	return NewAppError(ErrorCodeInvalidRequest, http.StatusBadRequest, message)
+}
+
+func NewInvalidParamError(param string) *AppError {
+	return NewInvalidRequestError(fmt.Sprintf("Invalid or missing parameter: %s", param))
+}
+
+func NewNotFoundError(message string) *AppError {
+	return NewAppError(ErrorCodeNotFound, http.StatusNotFound, message)
+}
+
+func NewInternalError(message string) *AppError {
+	return NewAppError(ErrorCodeInternal, http.StatusInternalServerError, message)
+}
+
+func (e *AppError) Error() string {
+	if e.Details != "" {
+		return fmt.Sprintf("%s: %s", e.Message, e.Details)
+	}
+
+	return e.Message
+}
+
+func (e *AppError) Unwrap() error {
+	return e.cause
+}
+
+// Wrap records err as the cause of the AppError, and as its details when none are set.
+func (e *AppError) Wrap(err error) *AppError {
+	e.cause = err
+	if e.Details == "" && err != nil {
+		e.Details = err.Error()
+	}
+
+	return e
+}
+
+func (e *AppError) WithDetails(details string) *AppError {
+	e.Details = details
+	return e
+}
+
+// ToJSON writes the AppError wrapped in an ErrorResponse.
+func (e *AppError) ToJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(ErrorResponse{Error: e})
+}
@@ -0,0 +1,68 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// NginxTLSModeNone serves plain HTTP.
+	NginxTLSModeNone = "none"
+	// NginxTLSModeACM terminates TLS at an AWS load balancer with an ACM certificate.
+	NginxTLSModeACM = "acm"
+	// NginxTLSModeCertManager terminates TLS in ingress-nginx, with certificates issued by cert-manager.
+	NginxTLSModeCertManager = "cert-manager"
+)
+
+const (
+	NginxLoadBalancerNLB      = "nlb"
+	NginxLoadBalancerCLB      = "clb"
+	NginxLoadBalancerInternal = "internal"
+)
+
+// DeployNginxRequest configures the ingress-nginx release. Empty fields use the provider's defaults.
+type DeployNginxRequest struct {
+	TLSMode string `json:"tlsMode,omitempty"`
+	// CertificateARN is the ACM certificate to serve when TLSMode is acm.
+	CertificateARN   string `json:"certificateArn,omitempty"`
+	LoadBalancerType string `json:"loadBalancerType,omitempty"`
+	// ServiceAnnotations are added to the controller service, after the provider's own annotations.
+	ServiceAnnotations map[string]string `json:"serviceAnnotations,omitempty"`
+	// Values are helm values merged over everything else, for settings the request has no field for.
+	Values map[string]interface{} `json:"values,omitempty"`
+	// Version selects a chart version other than the pinned one.
+	Version string `json:"version,omitempty"`
+}
+
+// IsValid checks that the TLS mode and load balancer type are known, and that ACM has a certificate.
+func (r *DeployNginxRequest) IsValid() error {
+	switch r.TLSMode {
+	case "", NginxTLSModeNone, NginxTLSModeCertManager:
+	case NginxTLSModeACM:
+		if r.CertificateARN == "" {
+			return errors.New("certificateArn must be set when tlsMode is acm")
+		}
+	default:
+		return fmt.Errorf("unknown tlsMode %q, expected one of none, acm or cert-manager", r.TLSMode)
+	}
+
+	switch r.LoadBalancerType {
+	case "", NginxLoadBalancerNLB, NginxLoadBalancerCLB, NginxLoadBalancerInternal:
+	default:
+		return fmt.Errorf("unknown loadBalancerType %q, expected one of nlb, clb or internal", r.LoadBalancerType)
+	}
+
+	return nil
+}
+
+// NewDeployNginxRequestFromReader decodes a DeployNginxRequest. An empty body is an empty request.
+func NewDeployNginxRequestFromReader(reader io.Reader) (*DeployNginxRequest, error) {
+	var deployNginxRequest DeployNginxRequest
+	err := json.NewDecoder(reader).Decode(&deployNginxRequest)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &deployNginxRequest, nil
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "queued"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusSucceeded JobStatus = "succeeded"
+	JobStatusFailed    JobStatus = "failed"
+)
+
+const (
+	JobTypeDeployOperator       = "deploy-operator"
+	JobTypeInstallAddon         = "install-addon"
+	JobTypeCreateCluster        = "create-cluster"
+	JobTypeDeleteCluster        = "delete-cluster"
+	JobTypeUpgradeCluster       = "upgrade-cluster"
+	JobTypeUpdateNodegroup      = "update-nodegroup"
+	JobTypeDeleteNodegroup      = "delete-nodegroup"
+	JobTypeUpgradeRelease       = "upgrade-release"
+	JobTypeRollbackRelease      = "rollback-release"
+	JobTypeCreateInstallation   = "create-installation"
+	JobTypeRestoreDatabase      = "restore-database"
+	JobTypeCreateDatabase       = "create-database"
+	JobTypeValidateInstallation = "validate-installation"
+)
+
+// Job tracks a long-running operation that runs in the background after the API request that started it returns.
+type Job struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Provider    string          `json:"provider"`
+	ClusterName string          `json:"clusterName,omitempty"`
+	Target      string          `json:"target,omitempty"`
+	Status      JobStatus       `json:"status"`
+	Steps       []JobStep       `json:"steps"`
+	Error       string          `json:"error,omitempty"`
+	ErrorCode   ErrorCode       `json:"errorCode,omitempty"`
+	Result      json.RawMessage `json:"result,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
+// JobStep is a single entry in a job's progress log.
+type JobStep struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+}
+
+// IsFinished reports whether the job has reached a terminal status.
+func (j *Job) IsFinished() bool {
+	return j.Status == JobStatusSucceeded || j.Status == JobStatusFailed
+}
+
+// Copy returns a deep copy of the job that is safe to hand to other goroutines.
+func (j *Job) Copy() *Job {
+	job := *j
+	job.Steps = append([]JobStep{}, j.Steps...)
+	job.Result = append(json.RawMessage{}, j.Result...)
+	if len(j.Result) == 0 {
+		job.Result = nil
+	}
+	return &job
+}
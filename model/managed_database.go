@@ -0,0 +1,125 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+const (
+	ManagedDatabaseEngineAurora   = "aurora-postgresql"
+	ManagedDatabaseEnginePostgres = "postgres"
+)
+
+// managedDatabaseMaxReplicas is the number of Aurora replicas a cluster can have.
+const managedDatabaseMaxReplicas = 15
+
+var managedDatabaseIdentifierPattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,62}$`)
+
+// CreateManagedDatabaseRequest provisions a managed PostgreSQL database, such as Aurora or RDS, in the network of a
+// cluster. Empty fields take the defaults of WithDefaults.
+type CreateManagedDatabaseRequest struct {
+	// InstallationName is the installation whose database secret gets the connection strings. It is set from the
+	// installation when the database is created along with it.
+	InstallationName string `json:"installationName,omitempty"`
+	// Identifier names the database and the resources created for it.
+	Identifier    string `json:"identifier,omitempty"`
+	Engine        string `json:"engine,omitempty"`
+	EngineVersion string `json:"engineVersion,omitempty"`
+	InstanceClass string `json:"instanceClass,omitempty"`
+	// Replicas is the number of Aurora reader instances. RDS PostgreSQL uses MultiAZ for a standby instead.
+	Replicas int  `json:"replicas,omitempty"`
+	MultiAZ  bool `json:"multiAZ,omitempty"`
+	// AllocatedStorage is the storage of an RDS PostgreSQL instance in GiB. Aurora storage grows on its own.
+	AllocatedStorage int    `json:"allocatedStorage,omitempty"`
+	DatabaseName     string `json:"databaseName,omitempty"`
+	Username         string `json:"username,omitempty"`
+
+	// MasterPassword is the password of the master user. It is stored in the installation's namespace before the
+	// database is created, so that a retry resumes with the same password.
+	MasterPassword string `json:"-"`
+}
+
+// ManagedDatabase is a database provisioned by a provider. Its connection strings include the password, so they
+// aren't returned by the API and only end up in the installation's database secret.
+type ManagedDatabase struct {
+	Identifier             string `json:"identifier"`
+	Engine                 string `json:"engine"`
+	EngineVersion          string `json:"engineVersion"`
+	Endpoint               string `json:"endpoint"`
+	ReaderEndpoint         string `json:"readerEndpoint,omitempty"`
+	Port                   int    `json:"port"`
+	DatabaseName           string `json:"databaseName"`
+	Username               string `json:"username"`
+	SecurityGroupID        string `json:"securityGroupId,omitempty"`
+	ConnectionString       string `json:"-"`
+	ReaderConnectionString string `json:"-"`
+}
+
+// WithDefaults returns the request with an Aurora cluster, a mattermost database and an identifier derived from the
+// cluster and installation names, unless they are set.
+func (r *CreateManagedDatabaseRequest) WithDefaults(clusterName string) CreateManagedDatabaseRequest {
+	request := CreateManagedDatabaseRequest{}
+	if r != nil {
+		request = *r
+	}
+
+	if request.Identifier == "" {
+		request.Identifier = fmt.Sprintf("%s-%s", clusterName, request.InstallationName)
+	}
+	if request.Engine == "" {
+		request.Engine = ManagedDatabaseEngineAurora
+	}
+	if request.InstanceClass == "" {
+		request.InstanceClass = "db.r6g.large"
+		if request.Engine == ManagedDatabaseEnginePostgres {
+			request.InstanceClass = "db.t4g.medium"
+		}
+	}
+	if request.Engine == ManagedDatabaseEnginePostgres && request.AllocatedStorage == 0 {
+		request.AllocatedStorage = 20
+	}
+	if request.DatabaseName == "" {
+		request.DatabaseName = "mattermost"
+	}
+	if request.Username == "" {
+		request.Username = "mmuser"
+	}
+
+	return request
+}
+
+// IsValid checks the engine, replica count, storage and identifier.
+func (r *CreateManagedDatabaseRequest) IsValid() error {
+	switch r.Engine {
+	case "", ManagedDatabaseEngineAurora, ManagedDatabaseEnginePostgres:
+	default:
+		return fmt.Errorf("unknown engine %q, expected %s or %s", r.Engine, ManagedDatabaseEngineAurora, ManagedDatabaseEnginePostgres)
+	}
+	if r.Replicas < 0 || r.Replicas > managedDatabaseMaxReplicas {
+		return fmt.Errorf("replicas must be between 0 and %d", managedDatabaseMaxReplicas)
+	}
+	if r.Replicas > 0 && r.Engine == ManagedDatabaseEnginePostgres {
+		return errors.New("replicas are only supported by aurora-postgresql, use multiAZ for a standby")
+	}
+	if r.AllocatedStorage < 0 || (r.AllocatedStorage > 0 && r.AllocatedStorage < 20) {
+		return errors.New("allocatedStorage must be at least 20 GiB")
+	}
+	if r.Identifier != "" && !managedDatabaseIdentifierPattern.MatchString(r.Identifier) {
+		return fmt.Errorf("identifier %q must start with a letter and have at most 63 lowercase letters, digits and hyphens", r.Identifier)
+	}
+
+	return nil
+}
+
+// NewCreateManagedDatabaseRequestFromReader decodes a CreateManagedDatabaseRequest.
+func NewCreateManagedDatabaseRequestFromReader(reader io.Reader) (*CreateManagedDatabaseRequest, error) {
+	var createManagedDatabaseRequest CreateManagedDatabaseRequest
+	err := json.NewDecoder(reader).Decode(&createManagedDatabaseRequest)
+	if err != nil {
+		return nil, err
+	}
+	return &createManagedDatabaseRequest, nil
+}
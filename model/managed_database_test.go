@@ -0,0 +1,79 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateManagedDatabaseRequestWithDefaults(t *testing.T) {
+	t.Run("nil request", func(t *testing.T) {
+		var create *model.CreateManagedDatabaseRequest
+		assert.Equal(t, model.CreateManagedDatabaseRequest{
+			Identifier:    "cluster-",
+			Engine:        model.ManagedDatabaseEngineAurora,
+			InstanceClass: "db.r6g.large",
+			DatabaseName:  "mattermost",
+			Username:      "mmuser",
+		}, create.WithDefaults("cluster"))
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		create := &model.CreateManagedDatabaseRequest{InstallationName: "mm", Engine: model.ManagedDatabaseEnginePostgres, MultiAZ: true}
+		withDefaults := create.WithDefaults("cluster")
+		assert.Equal(t, "cluster-mm", withDefaults.Identifier)
+		assert.Equal(t, "db.t4g.medium", withDefaults.InstanceClass)
+		assert.Equal(t, 20, withDefaults.AllocatedStorage)
+		assert.True(t, withDefaults.MultiAZ)
+		assert.Empty(t, create.Identifier)
+	})
+
+	t.Run("overrides", func(t *testing.T) {
+		create := &model.CreateManagedDatabaseRequest{Identifier: "db", InstanceClass: "db.r7g.xlarge", Replicas: 2, DatabaseName: "chat"}
+		withDefaults := create.WithDefaults("cluster")
+		assert.Equal(t, "db", withDefaults.Identifier)
+		assert.Equal(t, "db.r7g.xlarge", withDefaults.InstanceClass)
+		assert.Equal(t, 2, withDefaults.Replicas)
+		assert.Equal(t, "chat", withDefaults.DatabaseName)
+		assert.Zero(t, withDefaults.AllocatedStorage)
+	})
+}
+
+func TestCreateManagedDatabaseRequestIsValid(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		create model.CreateManagedDatabaseRequest
+		valid  bool
+	}{
+		{"empty", model.CreateManagedDatabaseRequest{}, true},
+		{"aurora replicas", model.CreateManagedDatabaseRequest{Engine: model.ManagedDatabaseEngineAurora, Replicas: 2}, true},
+		{"postgres multi az", model.CreateManagedDatabaseRequest{Engine: model.ManagedDatabaseEnginePostgres, MultiAZ: true, AllocatedStorage: 100}, true},
+		{"unknown engine", model.CreateManagedDatabaseRequest{Engine: "mysql"}, false},
+		{"postgres replicas", model.CreateManagedDatabaseRequest{Engine: model.ManagedDatabaseEnginePostgres, Replicas: 1}, false},
+		{"too many replicas", model.CreateManagedDatabaseRequest{Replicas: 16}, false},
+		{"too little storage", model.CreateManagedDatabaseRequest{AllocatedStorage: 10}, false},
+		{"uppercase identifier", model.CreateManagedDatabaseRequest{Identifier: "Mattermost"}, false},
+		{"long identifier", model.CreateManagedDatabaseRequest{Identifier: "a" + strings.Repeat("b", 63)}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.valid, tc.create.IsValid() == nil)
+		})
+	}
+}
+
+func TestCreateMattermostWorkspaceRequestRDS(t *testing.T) {
+	create := &model.CreateMattermostWorkspaceRequest{
+		InstallationName:    "mm",
+		FullDomainName:      "mm.example.com",
+		FilestoreOption:     model.FilestoreOptionExistingS3,
+		FilestoreSecretName: "filestore",
+		DBConnectionOption:  model.DatabaseOptionCreateForMeRDS,
+	}
+	require.True(t, create.IsValid())
+
+	create.RDSDatabase = &model.CreateManagedDatabaseRequest{Engine: "mysql"}
+	assert.False(t, create.IsValid())
+}
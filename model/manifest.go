@@ -0,0 +1,177 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// BootstrapManifest declaratively describes a bootstrapped environment: the provider and cluster to use, the
+// nodegroups and operators the cluster should have, and the Mattermost installations to run on it.
+type BootstrapManifest struct {
+	Provider      string                             `json:"provider"`
+	Credentials   *ManifestCredentials               `json:"credentials,omitempty"`
+	Cluster       ManifestCluster                    `json:"cluster"`
+	Operators     []string                           `json:"operators"`
+	Installations []CreateMattermostWorkspaceRequest `json:"installations"`
+}
+
+// ManifestCredentials references where credentials come from, so that secrets are never stored in the manifest.
+// When both are empty, the credentials already saved in state are used.
+type ManifestCredentials struct {
+	File    string `json:"file,omitempty"`
+	FromEnv bool   `json:"fromEnv,omitempty"`
+}
+
+type ManifestCluster struct {
+	Name   string `json:"name"`
+	Region string `json:"region,omitempty"`
+	// Create is used to create the cluster when it does not exist yet. When nil, the cluster must already exist.
+	Create     *CreateClusterRequest    `json:"create,omitempty"`
+	Nodegroups []CreateNodegroupRequest `json:"nodegroups,omitempty"`
+}
+
+// ObservedState is what currently exists for the resources a manifest describes.
+type ObservedState struct {
+	ClusterExists bool
+	Nodegroups    []string
+	Releases      []InstalledReleases
+	Installations []ObservedInstallation
+}
+
+type ObservedInstallation struct {
+	Name    string
+	Version string
+	Image   string
+}
+
+type PlanAction string
+
+const (
+	PlanActionCreate PlanAction = "create"
+	PlanActionUpdate PlanAction = "update"
+	PlanActionNone   PlanAction = "none"
+)
+
+const (
+	PlanResourceCluster      = "cluster"
+	PlanResourceNodegroup    = "nodegroup"
+	PlanResourceOperator     = "operator"
+	PlanResourceInstallation = "installation"
+)
+
+// PlanChange is a single difference between a manifest and the observed state.
+type PlanChange struct {
+	Resource string     `json:"resource"`
+	Name     string     `json:"name"`
+	Action   PlanAction `json:"action"`
+	Reason   string     `json:"reason,omitempty"`
+}
+
+type Plan struct {
+	Changes []PlanChange `json:"changes"`
+}
+
+// HasChanges reports whether applying the plan would change anything.
+func (p *Plan) HasChanges() bool {
+	for _, change := range p.Changes {
+		if change.Action != PlanActionNone {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InstallationNamespace returns the namespace, and Mattermost resource name, used for an installation.
+func InstallationNamespace(installationName string) string {
+	return "mm-installation-" + installationName
+}
+
+// IsValid checks that the manifest is complete enough to plan against.
+func (m *BootstrapManifest) IsValid() error {
+	if m.Provider == "" {
+		return errors.New("provider must be set")
+	}
+
+	if m.Cluster.Name == "" {
+		return errors.New("cluster.name must be set")
+	}
+
+	for _, nodegroup := range m.Cluster.Nodegroups {
+		if nodegroup.NodegroupName == "" {
+			return errors.New("every nodegroup must have a nodeGroupName")
+		}
+	}
+
+	for i := range m.Installations {
+		if !m.Installations[i].IsValid() {
+			return fmt.Errorf("installation %q is invalid", m.Installations[i].InstallationName)
+		}
+	}
+
+	return nil
+}
+
+// DiffManifest compares a manifest with the observed state. operatorReleases maps every operator name the
+// manifest may reference to the helm release name it is deployed as.
+func DiffManifest(manifest *BootstrapManifest, observed *ObservedState, operatorReleases map[string]string) (*Plan, error) {
+	plan := &Plan{Changes: []PlanChange{}}
+
+	if observed.ClusterExists {
+		plan.Changes = append(plan.Changes, PlanChange{Resource: PlanResourceCluster, Name: manifest.Cluster.Name, Action: PlanActionNone})
+	} else {
+		if manifest.Cluster.Create == nil {
+			return nil, fmt.Errorf("cluster %s does not exist and the manifest has no cluster.create section", manifest.Cluster.Name)
+		}
+		plan.Changes = append(plan.Changes, PlanChange{Resource: PlanResourceCluster, Name: manifest.Cluster.Name, Action: PlanActionCreate, Reason: "cluster does not exist"})
+	}
+
+	existingNodegroups := map[string]bool{}
+	for _, name := range observed.Nodegroups {
+		existingNodegroups[name] = true
+	}
+	for _, nodegroup := range manifest.Cluster.Nodegroups {
+		change := PlanChange{Resource: PlanResourceNodegroup, Name: nodegroup.NodegroupName, Action: PlanActionNone}
+		if !existingNodegroups[nodegroup.NodegroupName] {
+			change.Action = PlanActionCreate
+			change.Reason = "nodegroup does not exist"
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	deployedReleases := map[string]bool{}
+	for _, release := range observed.Releases {
+		deployedReleases[release.Name] = true
+	}
+	for _, operator := range manifest.Operators {
+		releaseName, ok := operatorReleases[operator]
+		if !ok {
+			return nil, fmt.Errorf("unknown operator %q", operator)
+		}
+		change := PlanChange{Resource: PlanResourceOperator, Name: operator, Action: PlanActionNone}
+		if !deployedReleases[releaseName] {
+			change.Action = PlanActionCreate
+			change.Reason = fmt.Sprintf("release %s is not deployed", releaseName)
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	existingInstallations := map[string]ObservedInstallation{}
+	for _, installation := range observed.Installations {
+		existingInstallations[installation.Name] = installation
+	}
+	for _, installation := range manifest.Installations {
+		change := PlanChange{Resource: PlanResourceInstallation, Name: installation.InstallationName, Action: PlanActionNone}
+		existing, ok := existingInstallations[InstallationNamespace(installation.InstallationName)]
+		if !ok {
+			change.Action = PlanActionCreate
+			change.Reason = "installation does not exist"
+		} else if installation.Version != "" && existing.Version != installation.Version {
+			change.Action = PlanActionUpdate
+			change.Reason = fmt.Sprintf("version %s differs from desired %s", existing.Version, installation.Version)
+		}
+		plan.Changes = append(plan.Changes, change)
+	}
+
+	return plan, nil
+}
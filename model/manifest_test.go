@@ -0,0 +1,98 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffManifest(t *testing.T) {
+	operatorReleases := map[string]string{
+		"mattermost-operator": "mattermost-operator",
+		"ingress-nginx":       "ingress-nginx",
+		"cnpg":                "cnpg-system",
+	}
+
+	newManifest := func() *model.BootstrapManifest {
+		return &model.BootstrapManifest{
+			Provider: "aws",
+			Cluster: model.ManifestCluster{
+				Name:       "test-cluster",
+				Create:     &model.CreateClusterRequest{},
+				Nodegroups: []model.CreateNodegroupRequest{{NodegroupName: "ng-1"}},
+			},
+			Operators: []string{"mattermost-operator", "cnpg"},
+			Installations: []model.CreateMattermostWorkspaceRequest{
+				{InstallationName: "example", Version: "9.11.0"},
+			},
+		}
+	}
+
+	t.Run("NewCluster", func(t *testing.T) {
+		plan, err := model.DiffManifest(newManifest(), &model.ObservedState{}, operatorReleases)
+		require.NoError(t, err)
+		assert.True(t, plan.HasChanges())
+
+		for _, change := range plan.Changes {
+			assert.Equal(t, model.PlanActionCreate, change.Action, "%s %s", change.Resource, change.Name)
+		}
+		assert.Len(t, plan.Changes, 5)
+	})
+
+	t.Run("MissingClusterWithoutCreate", func(t *testing.T) {
+		manifest := newManifest()
+		manifest.Cluster.Create = nil
+
+		_, err := model.DiffManifest(manifest, &model.ObservedState{}, operatorReleases)
+		require.Error(t, err)
+	})
+
+	t.Run("Converged", func(t *testing.T) {
+		observed := &model.ObservedState{
+			ClusterExists: true,
+			Nodegroups:    []string{"ng-1"},
+			Releases: []model.InstalledReleases{
+				{Name: "mattermost-operator"},
+				{Name: "cnpg-system"},
+			},
+			Installations: []model.ObservedInstallation{{Name: "mm-installation-example", Version: "9.11.0"}},
+		}
+
+		plan, err := model.DiffManifest(newManifest(), observed, operatorReleases)
+		require.NoError(t, err)
+		assert.False(t, plan.HasChanges())
+	})
+
+	t.Run("OnlyDifferences", func(t *testing.T) {
+		observed := &model.ObservedState{
+			ClusterExists: true,
+			Nodegroups:    []string{"ng-1"},
+			Releases:      []model.InstalledReleases{{Name: "mattermost-operator"}},
+			Installations: []model.ObservedInstallation{{Name: "mm-installation-example", Version: "9.10.0"}},
+		}
+
+		plan, err := model.DiffManifest(newManifest(), observed, operatorReleases)
+		require.NoError(t, err)
+
+		changed := map[string]model.PlanAction{}
+		for _, change := range plan.Changes {
+			if change.Action != model.PlanActionNone {
+				changed[change.Resource+"/"+change.Name] = change.Action
+			}
+		}
+		assert.Equal(t, map[string]model.PlanAction{
+			"operator/cnpg":        model.PlanActionCreate,
+			"installation/example": model.PlanActionUpdate,
+		}, changed)
+	})
+
+	t.Run("UnknownOperator", func(t *testing.T) {
+		manifest := newManifest()
+		manifest.Operators = append(manifest.Operators, "unknown")
+
+		_, err := model.DiffManifest(manifest, &model.ObservedState{ClusterExists: true}, operatorReleases)
+		require.Error(t, err)
+	})
+}
@@ -3,12 +3,14 @@ package model
 import (
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"regexp"
 
 	mmv1beta1 "github.com/mattermost/mattermost-operator/apis/mattermost/v1beta1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -17,17 +19,20 @@ const (
 	FilestoreOptionExistingS3        = "ExistingS3"
 	FilestoreOptionAWSS3             = "AWSS3"
 	FilestoreOptionInClusterExternal = "InClusterExternal"
+	FilestoreOptionInClusterMinio    = "InClusterMinio"
 )
 
 const (
-	DatabaseOptionCreateForMe = "CreateForMeCNPG"
-	DatabaseOptionExisting    = "Existing"
+	DatabaseOptionCreateForMe    = "CreateForMeCNPG"
+	DatabaseOptionCreateForMeRDS = "CreateForMeRDS"
+	DatabaseOptionExisting       = "Existing"
 )
 
 const (
 	SecretNameFilestore         = "filestore"
 	SecretNameDatabase          = "database"
 	SecretNameMattermostLicense = "mattermost-license"
+	SecretNameManagedDatabase   = "managed-database"
 )
 
 const (
@@ -48,6 +53,16 @@ type CreateMattermostWorkspaceRequest struct {
 	FilestoreSecretName    string                  `json:"filestoreSecretName"`
 	LocalFileStore         *LocalFileStore         `json:"localFilestoreConfig"`
 	LocalExternalFileStore *LocalExternalFileStore `json:"localExternalFilestoreConfig"`
+	CNPGDatabase           *CNPGDatabaseConfig     `json:"cnpgDatabaseConfig,omitempty"` // Sizing for the CreateForMeCNPG database
+
+	// RDSDatabase is the engine and sizing of the CreateForMeRDS database. Its installation name is always that of
+	// the installation.
+	RDSDatabase *CreateManagedDatabaseRequest `json:"rdsDatabaseConfig,omitempty"`
+	// AWSS3Filestore is the bucket name, access and lifecycle of the AWSS3 filestore. Its installation name is always
+	// that of the installation.
+	AWSS3Filestore *CreateBucketRequest `json:"awsS3FilestoreConfig,omitempty"`
+	// MinioFilestore is the sizing of the InClusterMinio filestore.
+	MinioFilestore *MinioFilestore `json:"minioFilestoreConfig,omitempty"`
 }
 
 type ExistingDBConnection struct {
@@ -64,12 +79,62 @@ type S3Filestore struct {
 	BucketName string `json:"bucket"`
 	AccessKey  string `json:"accessKeyId"`
 	SecretKey  string `json:"accessKeySecret"`
+	// UseServiceAccount authenticates with the IAM role of the installation's service account instead of keys.
+	UseServiceAccount bool `json:"useServiceAccount,omitempty"`
 }
 
 type LocalExternalFileStore struct {
 	VolumeClaimName string `json:"volumeClaimName,omitempty"`
 }
 
+// MinioFilestore is a MinIO deployment in the namespace of an installation, for clusters without object storage.
+// Empty fields take the defaults of WithDefaults.
+type MinioFilestore struct {
+	// Replicas is 1 for a standalone server, or at least 4 for a distributed one with erasure coding.
+	Replicas     int    `json:"replicas,omitempty"`
+	StorageSize  string `json:"storageSize,omitempty"`
+	StorageClass string `json:"storageClass,omitempty"`
+	BucketName   string `json:"bucketName,omitempty"`
+}
+
+// WithDefaults returns the config with a standalone server, 50Gi of storage and a mattermost bucket, unless they
+// are set.
+func (m *MinioFilestore) WithDefaults() MinioFilestore {
+	config := MinioFilestore{}
+	if m != nil {
+		config = *m
+	}
+
+	if config.Replicas == 0 {
+		config.Replicas = 1
+	}
+	if config.StorageSize == "" {
+		config.StorageSize = "50Gi"
+	}
+	if config.BucketName == "" {
+		config.BucketName = "mattermost"
+	}
+
+	return config
+}
+
+// IsValid checks the replica count, storage size and bucket name.
+func (m *MinioFilestore) IsValid() error {
+	if m.Replicas < 0 || (m.Replicas > 1 && m.Replicas < 4) {
+		return errors.New("replicas must be 1 for a standalone server, or at least 4 for a distributed one")
+	}
+	if m.StorageSize != "" {
+		if _, err := resource.ParseQuantity(m.StorageSize); err != nil {
+			return fmt.Errorf("invalid storageSize %q: %w", m.StorageSize, err)
+		}
+	}
+	if m.BucketName != "" && !bucketNamePattern.MatchString(m.BucketName) {
+		return fmt.Errorf("bucketName %q must have 3 to 63 lowercase letters, digits, dots and hyphens", m.BucketName)
+	}
+
+	return nil
+}
+
 type InstallationSecrets struct {
 	DatabaseSecret  *v1.Secret `json:"databaseSecret"`
 	FilestoreSecret *v1.Secret `json:"filestoreSecret"`
@@ -152,11 +217,11 @@ func (e *ExistingDBConnection) IsValid() bool {
 }
 
 func (s *S3Filestore) IsValid() bool {
-	if s.AccessKey == "" {
+	if !s.UseServiceAccount && s.AccessKey == "" {
 		return false
 	}
 
-	if s.SecretKey == "" {
+	if !s.UseServiceAccount && s.SecretKey == "" {
 		return false
 	}
 
@@ -175,7 +240,10 @@ func (c *CreateMattermostWorkspaceRequest) GetMMOperatorFilestoreSecret(namespac
 	if c.FilestoreSecretName != "" {
 		return nil
 	}
-	if c.FilestoreOption == FilestoreOptionExistingS3 {
+	if c.FilestoreOption == FilestoreOptionExistingS3 || c.FilestoreOption == FilestoreOptionAWSS3 {
+		if c.S3Filestore == nil || c.S3Filestore.UseServiceAccount {
+			return nil
+		}
 		return &v1.Secret{
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      SecretNameFilestore,
@@ -188,23 +256,26 @@ func (c *CreateMattermostWorkspaceRequest) GetMMOperatorFilestoreSecret(namespac
 			},
 		}
 	}
-	// TODO - Add support for other non-local options
 
 	return nil
 }
 
 func (c *CreateMattermostWorkspaceRequest) GetMMOperatorFilestore(namespaceName string, secret *v1.Secret) mmv1beta1.FileStore {
 	filestore := mmv1beta1.FileStore{}
-	if c.FilestoreOption == FilestoreOptionExistingS3 {
+	if c.FilestoreOption == FilestoreOptionExistingS3 || c.FilestoreOption == FilestoreOptionAWSS3 || c.FilestoreOption == FilestoreOptionInClusterMinio {
 		filestore.External = &mmv1beta1.ExternalFileStore{}
 		if c.S3Filestore != nil {
 			filestore.External.URL = c.S3Filestore.BucketURL
 			filestore.External.Bucket = c.S3Filestore.BucketName
+			filestore.External.UseServiceAccount = c.S3Filestore.UseServiceAccount
 		}
 		if secret != nil {
 			filestore.External.Secret = secret.Name
 		} else if c.FilestoreSecretName != "" {
 			filestore.External.Secret = c.FilestoreSecretName
+		} else if c.FilestoreOption == FilestoreOptionInClusterMinio {
+			// The MinIO credentials are generated into the filestore secret when MinIO is deployed
+			filestore.External.Secret = SecretNameFilestore
 		}
 	} else if c.FilestoreOption == FilestoreOptionInClusterLocal {
 		filestore.Local = &mmv1beta1.LocalFileStore{
@@ -217,8 +288,6 @@ func (c *CreateMattermostWorkspaceRequest) GetMMOperatorFilestore(namespaceName
 		}
 	}
 
-	// TODO - Add support for other non-local options
-
 	return filestore
 }
 
@@ -233,6 +302,9 @@ type PatchMattermostWorkspaceRequest struct {
 	FilestorePatch *PatchMattermostFilestoreRequest `json:"fileStorePatch"`
 	DatabasePatch  *PatchMattermostDatabaseRequest  `json:"databasePatch"`
 	MattermostEnv  []v1.EnvVar                      `json:"mattermostEnv"`
+	// CNPGDatabasePatch resizes the database of an installation created with the CreateForMeCNPG option. Only the
+	// fields that are set are changed.
+	CNPGDatabasePatch *CNPGDatabaseConfig `json:"cnpgDatabasePatch,omitempty"`
 }
 
 type PatchMattermostFilestoreRequest struct {
@@ -306,6 +378,14 @@ func (c *CreateMattermostWorkspaceRequest) IsValid() bool {
 		return false
 	}
 
+	if c.FilestoreOption == FilestoreOptionAWSS3 && c.AWSS3Filestore != nil && c.AWSS3Filestore.IsValid() != nil {
+		return false
+	}
+
+	if c.FilestoreOption == FilestoreOptionInClusterMinio && c.MinioFilestore != nil && c.MinioFilestore.IsValid() != nil {
+		return false
+	}
+
 	if c.FilestoreOption == FilestoreOptionInClusterLocal && !c.LocalFileStore.IsValid() {
 		return false
 	}
@@ -314,7 +394,9 @@ func (c *CreateMattermostWorkspaceRequest) IsValid() bool {
 		return false
 	}
 
-	if c.DBConnectionOption != DatabaseOptionCreateForMe && c.DBConnectionOption != DatabaseOptionExisting {
+	switch c.DBConnectionOption {
+	case DatabaseOptionCreateForMe, DatabaseOptionCreateForMeRDS, DatabaseOptionExisting:
+	default:
 		return false
 	}
 
@@ -322,6 +404,14 @@ func (c *CreateMattermostWorkspaceRequest) IsValid() bool {
 		return false
 	}
 
+	if c.CNPGDatabase != nil && c.CNPGDatabase.IsValid() != nil {
+		return false
+	}
+
+	if c.RDSDatabase != nil && c.RDSDatabase.IsValid() != nil {
+		return false
+	}
+
 	return true
 }
 
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+// MirrorConfig points the bootstrapper at internal mirrors, for clusters without internet access. Charts come from
+// either an OCI registry or a directory of chart archives created by mcnb bundle, instead of the public chart
+// repositories, and images are pulled from ImageRegistry.
+type MirrorConfig struct {
+	// ChartRegistry is an OCI registry path holding the add-on charts, such as oci://registry.example.com/charts.
+	ChartRegistry string `json:"chartRegistry,omitempty"`
+	// ChartDirectory holds archives named <chart>-<version>.tgz.
+	ChartDirectory string `json:"chartDirectory,omitempty"`
+	// ImageRegistry replaces the registry of the images deployed by add-ons and installations, such as
+	// registry.example.com/mirror.
+	ImageRegistry string `json:"imageRegistry,omitempty"`
+}
+
+// IsValid checks that at most one chart source is set, and that the registries are in the expected format.
+func (m *MirrorConfig) IsValid() error {
+	if m.ChartRegistry != "" && m.ChartDirectory != "" {
+		return errors.New("only one of chartRegistry and chartDirectory can be set")
+	}
+	if m.ChartRegistry != "" && !strings.HasPrefix(m.ChartRegistry, "oci://") {
+		return errors.New("chartRegistry must start with oci://")
+	}
+	if strings.Contains(m.ImageRegistry, "://") {
+		return errors.New("imageRegistry must be a registry host and optional path, without a scheme")
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package model
+
+const (
+	PreflightCheckPassed  = "passed"
+	PreflightCheckFailed  = "failed"
+	PreflightCheckSkipped = "skipped"
+	// PreflightCheckWarning is a problem that may not stop the add-ons from deploying, such as an untested
+	// Kubernetes version.
+	PreflightCheckWarning = "warning"
+)
+
+const (
+	PreflightCheckDatabase         = "database"
+	PreflightCheckDatabaseReplicas = "databaseReplicas"
+	PreflightCheckFilestore        = "filestore"
+)
+
+const (
+	PreflightCheckKubernetesVersion   = "kubernetesVersion"
+	PreflightCheckDefaultStorageClass = "defaultStorageClass"
+	PreflightCheckNodeCapacity        = "nodeCapacity"
+	PreflightCheckLoadBalancer        = "loadBalancer"
+	PreflightCheckIngressClasses      = "ingressClasses"
+	PreflightCheckCRDs                = "crds"
+	PreflightCheckPermissions         = "permissions"
+)
+
+// PreflightCheck is the result of testing one of the connections of an installation before it is created, or one
+// of the requirements of the add-ons on a cluster.
+type PreflightCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// Message explains the status, such as the error of the client that was run or what a warning is about.
+	Message string `json:"message,omitempty"`
+}
+
+// InstallationValidation is the result of the pre-flight checks of a create installation request.
+type InstallationValidation struct {
+	// Valid is set when no check failed. Skipped checks don't make a request invalid.
+	Valid  bool             `json:"valid"`
+	Checks []PreflightCheck `json:"checks"`
+}
+
+// NewInstallationValidation returns the validation of the checks, which is valid unless one of them failed.
+func NewInstallationValidation(checks []PreflightCheck) *InstallationValidation {
+	validation := &InstallationValidation{Valid: true, Checks: checks}
+	for _, check := range checks {
+		if check.Status == PreflightCheckFailed {
+			validation.Valid = false
+		}
+	}
+
+	return validation
+}
+
+// ClusterPreflightReport is the result of inspecting a cluster before add-ons are deployed into it.
+type ClusterPreflightReport struct {
+	// Status is the worst status of the checks: failed, warning or passed.
+	Status string           `json:"status"`
+	Checks []PreflightCheck `json:"checks"`
+}
+
+// NewClusterPreflightReport returns the report of the checks, with the status of the worst of them.
+func NewClusterPreflightReport(checks []PreflightCheck) *ClusterPreflightReport {
+	report := &ClusterPreflightReport{Status: PreflightCheckPassed, Checks: checks}
+	for _, check := range checks {
+		switch {
+		case check.Status == PreflightCheckFailed:
+			report.Status = PreflightCheckFailed
+		case check.Status == PreflightCheckWarning && report.Status == PreflightCheckPassed:
+			report.Status = PreflightCheckWarning
+		}
+	}
+
+	return report
+}
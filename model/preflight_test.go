@@ -0,0 +1,29 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewInstallationValidation(t *testing.T) {
+	passed := model.PreflightCheck{Name: model.PreflightCheckDatabase, Status: model.PreflightCheckPassed}
+	skipped := model.PreflightCheck{Name: model.PreflightCheckFilestore, Status: model.PreflightCheckSkipped, Message: "no credentials"}
+	failed := model.PreflightCheck{Name: model.PreflightCheckDatabaseReplicas, Status: model.PreflightCheckFailed, Message: "timeout"}
+
+	assert.True(t, model.NewInstallationValidation(nil).Valid)
+	assert.True(t, model.NewInstallationValidation([]model.PreflightCheck{passed, skipped}).Valid)
+	assert.False(t, model.NewInstallationValidation([]model.PreflightCheck{passed, failed, skipped}).Valid)
+}
+
+func TestNewClusterPreflightReport(t *testing.T) {
+	passed := model.PreflightCheck{Name: model.PreflightCheckKubernetesVersion, Status: model.PreflightCheckPassed}
+	warning := model.PreflightCheck{Name: model.PreflightCheckLoadBalancer, Status: model.PreflightCheckWarning}
+	failed := model.PreflightCheck{Name: model.PreflightCheckDefaultStorageClass, Status: model.PreflightCheckFailed}
+
+	assert.Equal(t, model.PreflightCheckPassed, model.NewClusterPreflightReport([]model.PreflightCheck{passed}).Status)
+	assert.Equal(t, model.PreflightCheckWarning, model.NewClusterPreflightReport([]model.PreflightCheck{passed, warning}).Status)
+	assert.Equal(t, model.PreflightCheckFailed, model.NewClusterPreflightReport([]model.PreflightCheck{failed, warning}).Status)
+	assert.Equal(t, model.PreflightCheckFailed, model.NewClusterPreflightReport([]model.PreflightCheck{warning, failed, passed}).Status)
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// NewRandomAlphanumeric returns a random string of letters and digits for passwords and keys, which is safe to use
+// in URLs, connection strings and environment variables without escaping.
+func NewRandomAlphanumeric(length int) (string, error) {
+	result := make([]byte, length)
+	for i := range result {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphanumeric))))
+		if err != nil {
+			return "", err
+		}
+		result[i] = alphanumeric[n.Int64()]
+	}
+
+	return string(result), nil
+}
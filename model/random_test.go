@@ -0,0 +1,20 @@
+package model_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRandomAlphanumeric(t *testing.T) {
+	first, err := model.NewRandomAlphanumeric(32)
+	require.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^[a-zA-Z0-9]{32}$`), first)
+
+	second, err := model.NewRandomAlphanumeric(32)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// ReleaseRevision is one revision in the history of a helm release.
+type ReleaseRevision struct {
+	Revision     int       `json:"revision"`
+	Chart        string    `json:"chart"`
+	ChartVersion string    `json:"chartVersion"`
+	AppVersion   string    `json:"appVersion"`
+	Status       string    `json:"status"`
+	Description  string    `json:"description"`
+	Updated      time.Time `json:"updated"`
+}
+
+// UpgradeReleaseRequest upgrades a helm release. Values are merged over the values of the current revision, or
+// replace them when ResetValues is set. Version selects another chart version, which is only possible for releases
+// of catalog add-ons since the chart repository of other releases isn't known.
+type UpgradeReleaseRequest struct {
+	Version     string                 `json:"version,omitempty"`
+	Values      map[string]interface{} `json:"values,omitempty"`
+	ResetValues bool                   `json:"resetValues,omitempty"`
+}
+
+// RollbackReleaseRequest rolls a helm release back to Revision, or to the previous revision when it is zero.
+type RollbackReleaseRequest struct {
+	Revision int `json:"revision,omitempty"`
+}
+
+// IsValid checks that the revision isn't negative.
+func (r *RollbackReleaseRequest) IsValid() error {
+	if r.Revision < 0 {
+		return errors.New("revision must not be negative")
+	}
+	return nil
+}
+
+// ValueChange is a helm value that differs between two revisions. Path is the dotted path of the value, and From
+// or To is nil when the value was added or removed.
+type ValueChange struct {
+	Path string      `json:"path"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// ReleaseValuesDiff lists the user-supplied values that changed between two revisions of a release.
+type ReleaseValuesDiff struct {
+	From    int           `json:"from"`
+	To      int           `json:"to"`
+	Changes []ValueChange `json:"changes"`
+}
+
+// DiffValues compares two sets of helm values. Nested maps are compared key by key, and any other value, lists
+// included, is compared as a whole. Changes are sorted by path.
+func DiffValues(from, to map[string]interface{}) []ValueChange {
+	changes := []ValueChange{}
+	diffValues("", from, to, &changes)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func diffValues(prefix string, from, to map[string]interface{}, changes *[]ValueChange) {
+	keys := map[string]bool{}
+	for key := range from {
+		keys[key] = true
+	}
+	for key := range to {
+		keys[key] = true
+	}
+
+	for key := range keys {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		fromValue, inFrom := from[key]
+		toValue, inTo := to[key]
+		fromMap, fromIsMap := fromValue.(map[string]interface{})
+		toMap, toIsMap := toValue.(map[string]interface{})
+		switch {
+		case fromIsMap && toIsMap:
+			diffValues(path, fromMap, toMap, changes)
+		case fromIsMap && !inTo:
+			diffValues(path, fromMap, nil, changes)
+		case toIsMap && !inFrom:
+			diffValues(path, nil, toMap, changes)
+		case !reflect.DeepEqual(fromValue, toValue) || inFrom != inTo:
+			*changes = append(*changes, ValueChange{Path: path, From: fromValue, To: toValue})
+		}
+	}
+}
+
+// NewUpgradeReleaseRequestFromReader decodes an UpgradeReleaseRequest. An empty body is an empty request.
+func NewUpgradeReleaseRequestFromReader(reader io.Reader) (*UpgradeReleaseRequest, error) {
+	var upgradeReleaseRequest UpgradeReleaseRequest
+	err := json.NewDecoder(reader).Decode(&upgradeReleaseRequest)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &upgradeReleaseRequest, nil
+}
+
+// NewRollbackReleaseRequestFromReader decodes a RollbackReleaseRequest. An empty body is an empty request.
+func NewRollbackReleaseRequestFromReader(reader io.Reader) (*RollbackReleaseRequest, error) {
+	var rollbackReleaseRequest RollbackReleaseRequest
+	err := json.NewDecoder(reader).Decode(&rollbackReleaseRequest)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &rollbackReleaseRequest, nil
+}
@@ -0,0 +1,33 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffValues(t *testing.T) {
+	from := map[string]interface{}{
+		"replicaCount": 1,
+		"image":        map[string]interface{}{"tag": "v1.0.0", "pullPolicy": "IfNotPresent"},
+		"args":         []interface{}{"--verbose"},
+		"removed":      map[string]interface{}{"enabled": true},
+	}
+	to := map[string]interface{}{
+		"replicaCount": 2,
+		"image":        map[string]interface{}{"tag": "v1.1.0", "pullPolicy": "IfNotPresent"},
+		"args":         []interface{}{"--verbose"},
+		"added":        "value",
+	}
+
+	assert.Equal(t, []model.ValueChange{
+		{Path: "added", To: "value"},
+		{Path: "image.tag", From: "v1.0.0", To: "v1.1.0"},
+		{Path: "removed.enabled", From: true},
+		{Path: "replicaCount", From: 1, To: 2},
+	}, model.DiffValues(from, to))
+
+	assert.Empty(t, model.DiffValues(from, from))
+	assert.Empty(t, model.DiffValues(nil, nil))
+}
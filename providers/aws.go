@@ -3,26 +3,33 @@ package providers
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"net/url"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
 	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
 	mmclientv1beta1 "github.com/mattermost/mattermost-operator/pkg/client/v1beta1/clientset/versioned"
 	helmclient "github.com/mittwald/go-helm-client"
-	"helm.sh/helm/v3/pkg/repo"
 	apixclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -31,6 +38,13 @@ import (
 	"sigs.k8s.io/aws-iam-authenticator/pkg/token"
 )
 
+// eksUpdatePollInterval is how often the status of a cluster or nodegroup upgrade is checked.
+const eksUpdatePollInterval = 30 * time.Second
+
+// eksUpdateTimeout bounds how long a single cluster or nodegroup update is waited on. Nodegroup upgrades replace every
+// node, so they can take far longer than control plane updates.
+const eksUpdateTimeout = 90 * time.Minute
+
 type AWSProvider struct {
 	Credentials     *model.Credentials
 	credentialsLock *sync.Mutex
@@ -43,6 +57,13 @@ type EKSClient struct {
 	once   *sync.Once
 }
 
+func init() {
+	Register(ProviderInfo{
+		Name:        "aws",
+		DisplayName: "Amazon EKS",
+	}, GetAWSProvider)
+}
+
 var awsProviderInstance *AWSProvider
 var awsClientOnce sync.Once
 
@@ -150,7 +171,7 @@ func (a *AWSProvider) ValidateCredentials(c context.Context, creds *model.Creden
 	stsClient := sts.New(sess)
 	_, err = stsClient.GetCallerIdentity(&sts.GetCallerIdentityInput{})
 	if err != nil {
-		return false, fmt.Errorf("failed to validate credentials: %v", err)
+		return false, NewProviderError(err, "AWS rejected the credentials")
 	}
 
 	// Create an EKS client to check for EKS-specific permissions
@@ -158,7 +179,7 @@ func (a *AWSProvider) ValidateCredentials(c context.Context, creds *model.Creden
 	_, err = eksClient.ListClusters(&eks.ListClustersInput{})
 	if err != nil {
 		// If the credentials are valid but do not have EKS permissions, handle accordingly
-		return false, fmt.Errorf("credentials valid but lack EKS permissions: %v", err)
+		return false, NewProviderError(err, "Credentials are valid but lack EKS permissions")
 	}
 
 	// If no errors, credentials are valid and have EKS permissions
@@ -191,7 +212,7 @@ func (a *AWSProvider) ListRoles(c context.Context) ([]*model.SupportedRolesRespo
 		return !lastPage
 	})
 	if err != nil {
-		log.Fatalf("Error listing roles: %v", err)
+		return nil, NewProviderError(err, "Failed to list IAM roles")
 	}
 
 	return eksSupportedRolesToSupportedRoleResponse(eksSupportedRoles), nil
@@ -225,31 +246,8 @@ func (a *AWSProvider) CreateCluster(c context.Context, create *model.CreateClust
 
 	result, err := eksClient.CreateCluster(input)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case eks.ErrCodeResourceInUseException:
-				logger.FromContext(c).Error(eks.ErrCodeResourceInUseException, aerr.Error())
-			case eks.ErrCodeResourceLimitExceededException:
-				logger.FromContext(c).Error(eks.ErrCodeResourceLimitExceededException, aerr.Error())
-			case eks.ErrCodeInvalidParameterException:
-				logger.FromContext(c).Error(eks.ErrCodeInvalidParameterException, aerr.Error())
-			case eks.ErrCodeClientException:
-				logger.FromContext(c).Error(eks.ErrCodeClientException, aerr.Error())
-			case eks.ErrCodeServerException:
-				logger.FromContext(c).Error(eks.ErrCodeServerException, aerr.Error())
-			case eks.ErrCodeServiceUnavailableException:
-				logger.FromContext(c).Error(eks.ErrCodeServiceUnavailableException, aerr.Error())
-			case eks.ErrCodeUnsupportedAvailabilityZoneException:
-				logger.FromContext(c).Error(eks.ErrCodeUnsupportedAvailabilityZoneException, aerr.Error())
-			default:
-				logger.FromContext(c).Error(aerr.Error())
-			}
-		} else {
-			// Print the error, cast err to awserr.Error to get the Code and
-			// Message from an error.
-			logger.FromContext(c).Error(err.Error())
-		}
-		return nil, errors.New("received an unknown error while creating cluster")
+		logger.FromContext(c).WithError(err).Error("Failed to create EKS cluster")
+		return nil, NewProviderError(err, "Failed to create EKS cluster")
 	}
 	return aWSClusterToCluster(result.Cluster), nil
 }
@@ -303,6 +301,11 @@ func (a *AWSProvider) CreateNodegroup(c context.Context, name string, create *mo
 
 	instanceTypes := []*string{&create.InstanceType}
 
+	desiredSize := aws.Int64(2)
+	if create.ScalingConfig.DesiredSize != nil {
+		desiredSize = create.ScalingConfig.DesiredSize
+	}
+
 	input := &eks.CreateNodegroupInput{
 		ClusterName:   aws.String(name),
 		NodegroupName: aws.String(create.NodegroupName),
@@ -310,7 +313,7 @@ func (a *AWSProvider) CreateNodegroup(c context.Context, name string, create *mo
 		ScalingConfig: &eks.NodegroupScalingConfig{
 			MaxSize:     aws.Int64(create.ScalingConfig.MaxSize),
 			MinSize:     aws.Int64(create.ScalingConfig.MinSize),
-			DesiredSize: aws.Int64(2),
+			DesiredSize: desiredSize,
 		},
 		NodeRole: aws.String(create.RoleARN),
 		Subnets:  aws.StringSlice(create.SubnetIDs),
@@ -318,35 +321,269 @@ func (a *AWSProvider) CreateNodegroup(c context.Context, name string, create *mo
 
 	result, err := eksClient.CreateNodegroup(input)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case eks.ErrCodeResourceInUseException:
-				logger.FromContext(c).Error(eks.ErrCodeResourceInUseException, aerr.Error())
-			case eks.ErrCodeResourceLimitExceededException:
-				logger.FromContext(c).Error(eks.ErrCodeResourceLimitExceededException, aerr.Error())
-			case eks.ErrCodeInvalidParameterException:
-				logger.FromContext(c).Error(eks.ErrCodeInvalidParameterException, aerr.Error())
-			case eks.ErrCodeClientException:
-				logger.FromContext(c).Error(eks.ErrCodeClientException, aerr.Error())
-			case eks.ErrCodeServerException:
-				logger.FromContext(c).Error(eks.ErrCodeServerException, aerr.Error())
-			case eks.ErrCodeServiceUnavailableException:
-				logger.FromContext(c).Error(eks.ErrCodeServiceUnavailableException, aerr.Error())
-			case eks.ErrCodeUnsupportedAvailabilityZoneException:
-				logger.FromContext(c).Error(eks.ErrCodeUnsupportedAvailabilityZoneException, aerr.Error())
-			default:
-				logger.FromContext(c).Error(aerr.Error())
-			}
-		} else {
-			// Print the error, cast err to awserr.Error to get
-			logger.FromContext(c).Error(err.Error())
+		logger.FromContext(c).WithError(err).Error("Failed to create EKS nodegroup")
+		return nil, NewProviderError(err, "Failed to create EKS nodegroup")
+	}
+
+	return awsNodegroupToNodegroup(result.Nodegroup), nil
+}
+
+// UpdateNodegroup changes the scaling, labels, taints and release version of an EKS nodegroup. EKS runs one update
+// at a time per nodegroup, so the configuration changes are applied and waited for before the release version.
+func (a *AWSProvider) UpdateNodegroup(c context.Context, clusterName string, nodegroupName string, update *model.UpdateNodegroupRequest) (*model.ClusterNodegroup, error) {
+	eksClient := a.NewEKSClient().Client
+
+	describeInput := &eks.DescribeNodegroupInput{
+		ClusterName:   aws.String(clusterName),
+		NodegroupName: aws.String(nodegroupName),
+	}
+
+	current, err := eksClient.DescribeNodegroupWithContext(c, describeInput)
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to describe EKS nodegroup")
+	}
+
+	input := &eks.UpdateNodegroupConfigInput{
+		ClusterName:   aws.String(clusterName),
+		NodegroupName: aws.String(nodegroupName),
+	}
+	if update.ScalingConfig != nil {
+		input.ScalingConfig = awsScalingConfigUpdate(current.Nodegroup.ScalingConfig, update.ScalingConfig)
+	}
+	if update.Labels != nil {
+		input.Labels = awsLabelsUpdate(current.Nodegroup.Labels, update.Labels)
+	}
+	if update.Taints != nil {
+		input.Taints = awsTaintsUpdate(current.Nodegroup.Taints, update.Taints)
+	}
+
+	if input.ScalingConfig != nil || input.Labels != nil || input.Taints != nil {
+		logger.FromContext(c).Infof("Updating configuration of nodegroup %s", nodegroupName)
+		result, err := eksClient.UpdateNodegroupConfigWithContext(c, input)
+		if err != nil {
+			logger.FromContext(c).WithError(err).Error("Failed to update EKS nodegroup")
+			return nil, NewProviderError(err, "Failed to update EKS nodegroup")
+		}
+
+		err = waitForEKSUpdate(c, eksClient, clusterName, aws.String(nodegroupName), result.Update.Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if update.ReleaseVersion != "" && update.ReleaseVersion != aws.StringValue(current.Nodegroup.ReleaseVersion) {
+		logger.FromContext(c).Infof("Updating nodegroup %s to release version %s", nodegroupName, update.ReleaseVersion)
+		result, err := eksClient.UpdateNodegroupVersionWithContext(c, &eks.UpdateNodegroupVersionInput{
+			ClusterName:    aws.String(clusterName),
+			NodegroupName:  aws.String(nodegroupName),
+			ReleaseVersion: aws.String(update.ReleaseVersion),
+		})
+		if err != nil {
+			logger.FromContext(c).WithError(err).Error("Failed to update EKS nodegroup version")
+			return nil, NewProviderError(err, "Failed to update EKS nodegroup version")
+		}
+
+		err = waitForEKSUpdate(c, eksClient, clusterName, aws.String(nodegroupName), result.Update.Id)
+		if err != nil {
+			return nil, err
 		}
-		return nil, errors.New("error while creating nodegroup")
+	}
+
+	result, err := eksClient.DescribeNodegroupWithContext(c, describeInput)
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to describe EKS nodegroup")
 	}
 
 	return awsNodegroupToNodegroup(result.Nodegroup), nil
 }
 
+// DeleteNodegroup drains and deletes an EKS nodegroup, returning once it is gone.
+func (a *AWSProvider) DeleteNodegroup(c context.Context, clusterName string, nodegroupName string) error {
+	eksClient := a.NewEKSClient().Client
+
+	logger.FromContext(c).Infof("Draining and deleting nodegroup %s", nodegroupName)
+	_, err := eksClient.DeleteNodegroupWithContext(c, &eks.DeleteNodegroupInput{
+		ClusterName:   aws.String(clusterName),
+		NodegroupName: aws.String(nodegroupName),
+	})
+	if err != nil {
+		logger.FromContext(c).WithError(err).Error("Failed to delete EKS nodegroup")
+		return NewProviderError(err, "Failed to delete EKS nodegroup")
+	}
+
+	err = eksClient.WaitUntilNodegroupDeletedWithContext(c, &eks.DescribeNodegroupInput{
+		ClusterName:   aws.String(clusterName),
+		NodegroupName: aws.String(nodegroupName),
+	})
+	if err != nil {
+		return NewProviderError(err, "Failed waiting for EKS nodegroup to be deleted")
+	}
+	logger.FromContext(c).Infof("Nodegroup %s deleted", nodegroupName)
+
+	return nil
+}
+
+// DeleteCluster deletes the nodegroups of an EKS cluster and then the cluster itself, returning once it is gone.
+// EKS drains the nodes of managed nodegroups before terminating them.
+func (a *AWSProvider) DeleteCluster(c context.Context, name string) error {
+	eksClient := a.NewEKSClient().Client
+
+	nodegroups := []*string{}
+	err := eksClient.ListNodegroupsPagesWithContext(c, &eks.ListNodegroupsInput{ClusterName: aws.String(name)}, func(page *eks.ListNodegroupsOutput, lastPage bool) bool {
+		nodegroups = append(nodegroups, page.Nodegroups...)
+		return !lastPage
+	})
+	if err != nil {
+		return NewProviderError(err, "Failed to list EKS nodegroups")
+	}
+
+	for _, nodegroup := range nodegroups {
+		logger.FromContext(c).Infof("Draining and deleting nodegroup %s", aws.StringValue(nodegroup))
+		_, err = eksClient.DeleteNodegroupWithContext(c, &eks.DeleteNodegroupInput{
+			ClusterName:   aws.String(name),
+			NodegroupName: nodegroup,
+		})
+		if err != nil && errorCode(err) != model.ErrorCodeNotFound {
+			return NewProviderError(err, "Failed to delete EKS nodegroup")
+		}
+	}
+
+	for _, nodegroup := range nodegroups {
+		err = eksClient.WaitUntilNodegroupDeletedWithContext(c, &eks.DescribeNodegroupInput{
+			ClusterName:   aws.String(name),
+			NodegroupName: nodegroup,
+		})
+		if err != nil {
+			return NewProviderError(err, "Failed waiting for EKS nodegroup to be deleted")
+		}
+		logger.FromContext(c).Infof("Nodegroup %s deleted", aws.StringValue(nodegroup))
+	}
+
+	logger.FromContext(c).Infof("Deleting cluster %s", name)
+	_, err = eksClient.DeleteClusterWithContext(c, &eks.DeleteClusterInput{Name: aws.String(name)})
+	if err != nil {
+		logger.FromContext(c).WithError(err).Error("Failed to delete EKS cluster")
+		return NewProviderError(err, "Failed to delete EKS cluster")
+	}
+
+	err = eksClient.WaitUntilClusterDeletedWithContext(c, &eks.DescribeClusterInput{Name: aws.String(name)})
+	if err != nil {
+		return NewProviderError(err, "Failed waiting for EKS cluster to be deleted")
+	}
+	logger.FromContext(c).Infof("Cluster %s deleted", name)
+
+	return nil
+}
+
+// UpgradeCluster upgrades the control plane of an EKS cluster to the given Kubernetes version, and then each of its
+// nodegroups to match. It returns once every update has completed.
+func (a *AWSProvider) UpgradeCluster(c context.Context, name string, version string) (*model.Cluster, error) {
+	if version == "" {
+		return nil, model.NewInvalidParamError("kubernetesVersion")
+	}
+
+	eksClient := a.NewEKSClient().Client
+
+	logger.FromContext(c).Infof("Upgrading control plane of cluster %s to %s", name, version)
+	result, err := eksClient.UpdateClusterVersionWithContext(c, &eks.UpdateClusterVersionInput{
+		Name:    aws.String(name),
+		Version: aws.String(version),
+	})
+	if err != nil {
+		logger.FromContext(c).WithError(err).Error("Failed to upgrade EKS cluster")
+		return nil, NewProviderError(err, "Failed to upgrade EKS cluster")
+	}
+
+	err = waitForEKSUpdate(c, eksClient, name, nil, result.Update.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	nodegroups := []*string{}
+	err = eksClient.ListNodegroupsPagesWithContext(c, &eks.ListNodegroupsInput{ClusterName: aws.String(name)}, func(page *eks.ListNodegroupsOutput, lastPage bool) bool {
+		nodegroups = append(nodegroups, page.Nodegroups...)
+		return !lastPage
+	})
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to list EKS nodegroups")
+	}
+
+	for _, nodegroup := range nodegroups {
+		logger.FromContext(c).Infof("Upgrading nodegroup %s to %s", aws.StringValue(nodegroup), version)
+		// Without a version, the nodegroup is upgraded to the version of the control plane
+		result, err := eksClient.UpdateNodegroupVersionWithContext(c, &eks.UpdateNodegroupVersionInput{
+			ClusterName:   aws.String(name),
+			NodegroupName: nodegroup,
+		})
+		if err != nil {
+			return nil, NewProviderError(err, "Failed to upgrade EKS nodegroup")
+		}
+
+		err = waitForEKSUpdate(c, eksClient, name, nodegroup, result.Update.Id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return a.GetCluster(c, name)
+}
+
+// WaitForCluster waits for an EKS cluster to become active, for example after it has been created.
+func (a *AWSProvider) WaitForCluster(c context.Context, name string) (*model.Cluster, error) {
+	eksClient := a.NewEKSClient().Client
+
+	err := eksClient.WaitUntilClusterActiveWithContext(c, &eks.DescribeClusterInput{Name: aws.String(name)})
+	if err != nil {
+		cluster, describeErr := a.GetCluster(c, name)
+		if describeErr == nil && cluster.Status == model.ClusterStatusFailed {
+			return cluster, model.NewInternalError(fmt.Sprintf("EKS cluster %s failed", name))
+		}
+
+		return nil, NewProviderError(err, "Failed waiting for EKS cluster to become active")
+	}
+
+	return a.GetCluster(c, name)
+}
+
+// waitForEKSUpdate polls an update of a cluster, or of one of its nodegroups, until it has completed or
+// eksUpdateTimeout has passed.
+func waitForEKSUpdate(c context.Context, eksClient *eks.EKS, clusterName string, nodegroupName *string, updateID *string) error {
+	c, cancel := context.WithTimeout(c, eksUpdateTimeout)
+	defer cancel()
+
+	for {
+		result, err := eksClient.DescribeUpdateWithContext(c, &eks.DescribeUpdateInput{
+			Name:          aws.String(clusterName),
+			NodegroupName: nodegroupName,
+			UpdateId:      updateID,
+		})
+		if err != nil {
+			if c.Err() != nil {
+				return NewProviderError(c.Err(), "Timed out waiting for EKS update")
+			}
+			return NewProviderError(err, "Failed to describe EKS update")
+		}
+
+		switch aws.StringValue(result.Update.Status) {
+		case eks.UpdateStatusSuccessful:
+			return nil
+		case eks.UpdateStatusFailed, eks.UpdateStatusCancelled:
+			details := []string{}
+			for _, updateErr := range result.Update.Errors {
+				details = append(details, aws.StringValue(updateErr.ErrorMessage))
+			}
+			return model.NewInternalError(fmt.Sprintf("EKS update %s %s", aws.StringValue(updateID), strings.ToLower(aws.StringValue(result.Update.Status)))).WithDetails(strings.Join(details, "; "))
+		}
+
+		logger.FromContext(c).Infof("Waiting for update %s, current status %s", aws.StringValue(updateID), aws.StringValue(result.Update.Status))
+		select {
+		case <-c.Done():
+			return NewProviderError(c.Err(), "Timed out waiting for EKS update")
+		case <-time.After(eksUpdatePollInterval):
+		}
+	}
+}
+
 func (a *AWSProvider) GetKubeRestConfig(c context.Context, clusterName string) (*rest.Config, error) {
 	eksClient := a.NewEKSClient().Client
 
@@ -498,41 +735,139 @@ func (a *AWSProvider) HelmClient(c context.Context, clusterName string, namespac
 	return k8sClient.GetHelmClient(c, namespace)
 }
 
-func (a *AWSProvider) HelmFileStorePre(c context.Context, clusterName string, namespace string) error {
-	helmClient, err := a.HelmClient(c, clusterName, namespace)
+// ebsCSIDriverPresent reports whether the EBS CSI driver is already installed on the cluster, for example as an EKS
+// add-on.
+func (a *AWSProvider) ebsCSIDriverPresent(c context.Context, clusterName string) (bool, error) {
+	kubeClient, err := a.KubeClient(c, clusterName)
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	_, err = kubeClient.Clientset.StorageV1().CSIDrivers().Get(c, "ebs.csi.aws.com", metav1.GetOptions{})
+	if err == nil {
+		return true, nil
 	}
+	if !apiErrors.IsNotFound(err) {
+		return false, NewProviderError(err, "Failed to check for the EBS CSI driver")
+	}
+
+	return false, nil
+}
 
-	chartRepo := repo.Entry{
-		Name: "aws-ebs-csi-driver",
-		URL:  "https://kubernetes-sigs.github.io/aws-ebs-csi-driver/",
+// AddonHooks tags the cluster's subnets before ingress-nginx is installed, so that its load balancer can be placed
+// in them, and installs the EBS CSI driver before CNPG and MinIO, which need volumes, unless it is already present.
+func (a *AWSProvider) AddonHooks(addon string, phase model.HookPhase) []Hook {
+	if phase != model.HookPhasePreInstall {
+		return nil
+	}
+
+	switch addon {
+	case "ingress-nginx":
+		return []Hook{{Name: "tag-subnets", Run: a.tagClusterSubnets}}
+	case "cnpg", "minio":
+		return []Hook{{Name: "aws-ebs-csi-driver", Chart: "aws-ebs-csi-driver", Run: func(c context.Context, clusterName string) error {
+			present, err := a.ebsCSIDriverPresent(c, clusterName)
+			if err != nil {
+				return err
+			}
+			if present {
+				logger.FromContext(c).Info("EBS CSI driver is already installed")
+				return ErrSkipChart
+			}
+			return nil
+		}}}
 	}
 
-	err = helmClient.AddOrUpdateChartRepo(chartRepo)
+	return nil
+}
+
+// tagClusterSubnets adds the kubernetes.io/cluster/<name> tag to the subnets of the cluster, which the AWS cloud
+// provider uses to find the subnets for load balancers. Subnets that are already tagged keep their value.
+func (a *AWSProvider) tagClusterSubnets(c context.Context, clusterName string) error {
+	eksClient := a.NewEKSClient().Client
+	result, err := eksClient.DescribeClusterWithContext(c, &eks.DescribeClusterInput{
+		Name: aws.String(clusterName),
+	})
 	if err != nil {
-		return errors.New("failed to add or update chart repo for aws-ebs-csi-driver")
+		return NewProviderError(err, "Failed to describe cluster")
+	}
+	if result.Cluster.ResourcesVpcConfig == nil || len(result.Cluster.ResourcesVpcConfig.SubnetIds) == 0 {
+		return nil
 	}
 
-	chartSpec := helmclient.ChartSpec{
-		ReleaseName:     "aws-ebs-csi-driver",
-		ChartName:       "aws-ebs-csi-driver/aws-ebs-csi-driver",
-		Namespace:       "kube-system",
-		UpgradeCRDs:     true,
-		Wait:            true,
-		Timeout:         300 * time.Second,
-		CreateNamespace: true,
-		CleanupOnFail:   true,
+	sess, err := session.NewSession(eksClient.Config.Copy())
+	if err != nil {
+		return NewProviderError(err, "Failed to create AWS session")
 	}
+	ec2Client := ec2.New(sess)
 
-	// Install a chart release.
-	if _, err := helmClient.InstallOrUpgradeChart(context.Background(), &chartSpec, nil); err != nil {
-		return errors.New("Failed to install aws-ebs-csi-driver")
+	tagKey := "kubernetes.io/cluster/" + clusterName
+	subnets, err := ec2Client.DescribeSubnetsWithContext(c, &ec2.DescribeSubnetsInput{
+		SubnetIds: result.Cluster.ResourcesVpcConfig.SubnetIds,
+	})
+	if err != nil {
+		return NewProviderError(err, "Failed to describe cluster subnets")
 	}
 
+	untagged := []*string{}
+	for _, subnet := range subnets.Subnets {
+		tagged := false
+		for _, tag := range subnet.Tags {
+			if aws.StringValue(tag.Key) == tagKey {
+				tagged = true
+				break
+			}
+		}
+		if !tagged {
+			untagged = append(untagged, subnet.SubnetId)
+		}
+	}
+	if len(untagged) == 0 {
+		logger.FromContext(c).Infof("Subnets are already tagged with %s", tagKey)
+		return nil
+	}
+
+	_, err = ec2Client.CreateTagsWithContext(c, &ec2.CreateTagsInput{
+		Resources: untagged,
+		Tags:      []*ec2.Tag{{Key: aws.String(tagKey), Value: aws.String("shared")}},
+	})
+	if err != nil {
+		return NewProviderError(err, "Failed to tag cluster subnets")
+	}
+	logger.FromContext(c).Infof("Tagged %d subnets with %s", len(untagged), tagKey)
+
 	return nil
 }
 
+// NginxValues exposes ingress-nginx through a classic load balancer unless another load balancer type is requested,
+// so that deploying it again doesn't replace the load balancer of an existing cluster. With ACM, the load balancer
+// terminates TLS and forwards HTTPS traffic to the controller's HTTP port.
+func (a *AWSProvider) NginxValues(c context.Context, request *model.DeployNginxRequest) (map[string]interface{}, error) {
+	annotations := map[string]interface{}{}
+	switch request.LoadBalancerType {
+	case model.NginxLoadBalancerNLB:
+		annotations["service.beta.kubernetes.io/aws-load-balancer-type"] = "nlb"
+	case model.NginxLoadBalancerInternal:
+		annotations["service.beta.kubernetes.io/aws-load-balancer-type"] = "nlb"
+		annotations["service.beta.kubernetes.io/aws-load-balancer-internal"] = "true"
+	case "", model.NginxLoadBalancerCLB:
+		// A classic load balancer is what services get without a type annotation
+	}
+
+	service := map[string]interface{}{"annotations": annotations}
+	if request.TLSMode == model.NginxTLSModeACM {
+		annotations["service.beta.kubernetes.io/aws-load-balancer-backend-protocol"] = "tcp"
+		annotations["service.beta.kubernetes.io/aws-load-balancer-ssl-ports"] = "https"
+		annotations["service.beta.kubernetes.io/aws-load-balancer-ssl-cert"] = request.CertificateARN
+		service["targetPorts"] = map[string]interface{}{
+			"http":  "http",
+			"https": "http",
+		}
+	}
+
+	return nginxServiceValues(service), nil
+}
+
 func eksSupportedRolesToSupportedRoleResponse(roles []*iam.Role) []*model.SupportedRolesResponse {
 	var supportedRoles []*model.SupportedRolesResponse
 	for _, role := range roles {
@@ -582,5 +917,673 @@ func awsNodegroupToNodegroup(awsNodegroup *eks.Nodegroup) *model.ClusterNodegrou
 		nodegroup.Status = model.ClusterStatus(*awsNodegroup.Status)
 	}
 
+	if awsNodegroup.ScalingConfig != nil {
+		nodegroup.ScalingConfig = map[string]*string{
+			"minSize":     aws.String(fmt.Sprint(aws.Int64Value(awsNodegroup.ScalingConfig.MinSize))),
+			"maxSize":     aws.String(fmt.Sprint(aws.Int64Value(awsNodegroup.ScalingConfig.MaxSize))),
+			"desiredSize": aws.String(fmt.Sprint(aws.Int64Value(awsNodegroup.ScalingConfig.DesiredSize))),
+		}
+	}
+
+	for _, taint := range awsNodegroup.Taints {
+		nodegroup.Taints = append(nodegroup.Taints, &model.NodegroupTaint{
+			Key:    aws.StringValue(taint.Key),
+			Value:  aws.StringValue(taint.Value),
+			Effect: aws.StringValue(taint.Effect),
+		})
+	}
+
 	return nodegroup
 }
+
+// awsScalingConfigUpdate converts the requested scaling of a nodegroup. Without a desired size, the current one is
+// kept, moved within the new bounds if needed.
+func awsScalingConfigUpdate(current *eks.NodegroupScalingConfig, scaling *model.ScalingConfig) *eks.NodegroupScalingConfig {
+	desiredSize := scaling.MinSize
+	if scaling.DesiredSize != nil {
+		desiredSize = *scaling.DesiredSize
+	} else if current != nil && current.DesiredSize != nil {
+		desiredSize = min(max(*current.DesiredSize, scaling.MinSize), scaling.MaxSize)
+	}
+
+	return &eks.NodegroupScalingConfig{
+		MinSize:     aws.Int64(scaling.MinSize),
+		MaxSize:     aws.Int64(scaling.MaxSize),
+		DesiredSize: aws.Int64(desiredSize),
+	}
+}
+
+// awsLabelsUpdate returns the changes that turn the current labels of a nodegroup into the requested ones, or nil
+// if they already match.
+func awsLabelsUpdate(current map[string]*string, labels map[string]string) *eks.UpdateLabelsPayload {
+	payload := &eks.UpdateLabelsPayload{}
+	for key, value := range labels {
+		if currentValue, ok := current[key]; !ok || aws.StringValue(currentValue) != value {
+			if payload.AddOrUpdateLabels == nil {
+				payload.AddOrUpdateLabels = map[string]*string{}
+			}
+			payload.AddOrUpdateLabels[key] = aws.String(value)
+		}
+	}
+	for key := range current {
+		if _, ok := labels[key]; !ok {
+			payload.RemoveLabels = append(payload.RemoveLabels, aws.String(key))
+		}
+	}
+
+	if payload.AddOrUpdateLabels == nil && payload.RemoveLabels == nil {
+		return nil
+	}
+
+	return payload
+}
+
+// awsTaintsUpdate returns the changes that turn the current taints of a nodegroup into the requested ones, or nil if
+// they already match. Taints are identified by their key and effect.
+func awsTaintsUpdate(current []*eks.Taint, taints []model.NodegroupTaint) *eks.UpdateTaintsPayload {
+	payload := &eks.UpdateTaintsPayload{}
+	currentValues := map[string]string{}
+	for _, taint := range current {
+		currentValues[aws.StringValue(taint.Key)+":"+aws.StringValue(taint.Effect)] = aws.StringValue(taint.Value)
+	}
+
+	requested := map[string]bool{}
+	for _, taint := range taints {
+		id := taint.Key + ":" + taint.Effect
+		requested[id] = true
+		if value, ok := currentValues[id]; !ok || value != taint.Value {
+			payload.AddOrUpdateTaints = append(payload.AddOrUpdateTaints, &eks.Taint{
+				Key:    aws.String(taint.Key),
+				Value:  aws.String(taint.Value),
+				Effect: aws.String(taint.Effect),
+			})
+		}
+	}
+	for _, taint := range current {
+		if !requested[aws.StringValue(taint.Key)+":"+aws.StringValue(taint.Effect)] {
+			payload.RemoveTaints = append(payload.RemoveTaints, taint)
+		}
+	}
+
+	if payload.AddOrUpdateTaints == nil && payload.RemoveTaints == nil {
+		return nil
+	}
+
+	return payload
+}
+
+// CreateDatabase creates an Aurora PostgreSQL cluster, or an RDS PostgreSQL instance, in the VPC of an EKS cluster.
+// The database gets a subnet group of the cluster's subnets and a security group that only allows PostgreSQL
+// connections from the cluster. A database that already exists is waited on rather than created again, so an
+// interrupted creation can be resumed with the same master password. It returns once the database is available.
+func (a *AWSProvider) CreateDatabase(c context.Context, clusterName string, create *model.CreateManagedDatabaseRequest) (*model.ManagedDatabase, error) {
+	config := create.WithDefaults(clusterName)
+	if err := config.IsValid(); err != nil {
+		return nil, model.NewInvalidRequestError("Invalid managed database request").Wrap(err)
+	}
+	// The caller stores the password first, since it can't be read back from a database that was left behind
+	if config.MasterPassword == "" {
+		return nil, model.NewInvalidParamError("masterPassword")
+	}
+
+	eksClient := a.NewEKSClient().Client
+	cluster, err := eksClient.DescribeClusterWithContext(c, &eks.DescribeClusterInput{Name: aws.String(clusterName)})
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to describe cluster")
+	}
+	vpcConfig := cluster.Cluster.ResourcesVpcConfig
+	if vpcConfig == nil || len(vpcConfig.SubnetIds) == 0 {
+		return nil, model.NewInvalidRequestError(fmt.Sprintf("Cluster %s has no subnets to create a database in", clusterName))
+	}
+
+	sess, err := session.NewSession(eksClient.Config.Copy())
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to create AWS session")
+	}
+	rdsClient := rds.New(sess)
+
+	subnetGroupName := config.Identifier + "-subnets"
+	logger.FromContext(c).Infof("Creating DB subnet group %s", subnetGroupName)
+	_, err = rdsClient.CreateDBSubnetGroupWithContext(c, &rds.CreateDBSubnetGroupInput{
+		DBSubnetGroupName:        aws.String(subnetGroupName),
+		DBSubnetGroupDescription: aws.String(fmt.Sprintf("Subnets of EKS cluster %s", clusterName)),
+		SubnetIds:                vpcConfig.SubnetIds,
+		Tags:                     rdsTags(clusterName),
+	})
+	if err != nil && errorCode(err) != model.ErrorCodeAlreadyExists {
+		return nil, NewProviderError(err, "Failed to create DB subnet group")
+	}
+
+	securityGroupID, err := databaseSecurityGroup(c, ec2.New(sess), config.Identifier+"-db", clusterName, vpcConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	password := config.MasterPassword
+	var database *model.ManagedDatabase
+	if config.Engine == model.ManagedDatabaseEngineAurora {
+		database, err = createAuroraCluster(c, rdsClient, clusterName, &config, subnetGroupName, securityGroupID, password)
+	} else {
+		database, err = createRDSInstance(c, rdsClient, clusterName, &config, subnetGroupName, securityGroupID, password)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	database.SecurityGroupID = securityGroupID
+	database.ConnectionString = postgresConnectionString(config.Username, password, database.Endpoint, database.Port, config.DatabaseName)
+	if database.ReaderEndpoint != "" {
+		database.ReaderConnectionString = postgresConnectionString(config.Username, password, database.ReaderEndpoint, database.Port, config.DatabaseName)
+	}
+
+	return database, nil
+}
+
+// createAuroraCluster creates an Aurora PostgreSQL cluster with a writer and the requested number of readers, and
+// waits for all of them to become available.
+func createAuroraCluster(c context.Context, rdsClient *rds.RDS, clusterName string, config *model.CreateManagedDatabaseRequest, subnetGroupName, securityGroupID, password string) (*model.ManagedDatabase, error) {
+	logger.FromContext(c).Infof("Creating Aurora PostgreSQL cluster %s", config.Identifier)
+	_, err := rdsClient.CreateDBClusterWithContext(c, &rds.CreateDBClusterInput{
+		DBClusterIdentifier: aws.String(config.Identifier),
+		Engine:              aws.String(config.Engine),
+		EngineVersion:       stringPtr(config.EngineVersion),
+		DatabaseName:        aws.String(config.DatabaseName),
+		MasterUsername:      aws.String(config.Username),
+		MasterUserPassword:  aws.String(password),
+		DBSubnetGroupName:   aws.String(subnetGroupName),
+		VpcSecurityGroupIds: []*string{aws.String(securityGroupID)},
+		StorageEncrypted:    aws.Bool(true),
+		Tags:                rdsTags(clusterName),
+	})
+	if errorCode(err) == model.ErrorCodeAlreadyExists {
+		logger.FromContext(c).Infof("Aurora cluster %s already exists, resuming", config.Identifier)
+	} else if err != nil {
+		logger.FromContext(c).WithError(err).Error("Failed to create Aurora cluster")
+		return nil, NewProviderError(err, "Failed to create Aurora cluster")
+	}
+
+	instances := []*string{}
+	for i := 0; i <= config.Replicas; i++ {
+		instanceIdentifier := fmt.Sprintf("%s-%d", config.Identifier, i)
+		logger.FromContext(c).Infof("Creating Aurora instance %s", instanceIdentifier)
+		_, err = rdsClient.CreateDBInstanceWithContext(c, &rds.CreateDBInstanceInput{
+			DBInstanceIdentifier: aws.String(instanceIdentifier),
+			DBClusterIdentifier:  aws.String(config.Identifier),
+			DBInstanceClass:      aws.String(config.InstanceClass),
+			Engine:               aws.String(config.Engine),
+			Tags:                 rdsTags(clusterName),
+		})
+		if errorCode(err) == model.ErrorCodeAlreadyExists {
+			logger.FromContext(c).Infof("Aurora instance %s already exists, resuming", instanceIdentifier)
+		} else if err != nil {
+			logger.FromContext(c).WithError(err).Error("Failed to create Aurora instance")
+			return nil, NewProviderError(err, "Failed to create Aurora instance")
+		}
+		instances = append(instances, aws.String(instanceIdentifier))
+	}
+
+	logger.FromContext(c).Infof("Waiting for Aurora cluster %s to become available", config.Identifier)
+	err = rdsClient.WaitUntilDBClusterAvailableWithContext(c, &rds.DescribeDBClustersInput{DBClusterIdentifier: aws.String(config.Identifier)}, databaseWaiterOptions...)
+	if err != nil {
+		return nil, NewProviderError(err, "Failed waiting for Aurora cluster to become available")
+	}
+	for _, instance := range instances {
+		logger.FromContext(c).Infof("Waiting for Aurora instance %s to become available", aws.StringValue(instance))
+		err = rdsClient.WaitUntilDBInstanceAvailableWithContext(c, &rds.DescribeDBInstancesInput{DBInstanceIdentifier: instance}, databaseWaiterOptions...)
+		if err != nil {
+			return nil, NewProviderError(err, "Failed waiting for Aurora instance to become available")
+		}
+	}
+
+	described, err := rdsClient.DescribeDBClustersWithContext(c, &rds.DescribeDBClustersInput{DBClusterIdentifier: aws.String(config.Identifier)})
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to describe Aurora cluster")
+	}
+	if len(described.DBClusters) == 0 {
+		return nil, model.NewInternalError(fmt.Sprintf("Aurora cluster %s not found after creation", config.Identifier))
+	}
+	dbCluster := described.DBClusters[0]
+	logger.FromContext(c).Infof("Aurora cluster %s is available", config.Identifier)
+
+	database := &model.ManagedDatabase{
+		Identifier:    config.Identifier,
+		Engine:        config.Engine,
+		EngineVersion: aws.StringValue(dbCluster.EngineVersion),
+		Endpoint:      aws.StringValue(dbCluster.Endpoint),
+		Port:          int(aws.Int64Value(dbCluster.Port)),
+		DatabaseName:  config.DatabaseName,
+		Username:      config.Username,
+	}
+	// Without readers, the reader endpoint resolves to the writer
+	if config.Replicas > 0 {
+		database.ReaderEndpoint = aws.StringValue(dbCluster.ReaderEndpoint)
+	}
+
+	return database, nil
+}
+
+// createRDSInstance creates an RDS PostgreSQL instance, with a standby in another availability zone when MultiAZ
+// is set, and waits for it to become available.
+func createRDSInstance(c context.Context, rdsClient *rds.RDS, clusterName string, config *model.CreateManagedDatabaseRequest, subnetGroupName, securityGroupID, password string) (*model.ManagedDatabase, error) {
+	logger.FromContext(c).Infof("Creating RDS PostgreSQL instance %s", config.Identifier)
+	_, err := rdsClient.CreateDBInstanceWithContext(c, &rds.CreateDBInstanceInput{
+		DBInstanceIdentifier: aws.String(config.Identifier),
+		DBInstanceClass:      aws.String(config.InstanceClass),
+		Engine:               aws.String(config.Engine),
+		EngineVersion:        stringPtr(config.EngineVersion),
+		DBName:               aws.String(config.DatabaseName),
+		MasterUsername:       aws.String(config.Username),
+		MasterUserPassword:   aws.String(password),
+		AllocatedStorage:     aws.Int64(int64(config.AllocatedStorage)),
+		StorageType:          aws.String("gp3"),
+		StorageEncrypted:     aws.Bool(true),
+		MultiAZ:              aws.Bool(config.MultiAZ),
+		PubliclyAccessible:   aws.Bool(false),
+		DBSubnetGroupName:    aws.String(subnetGroupName),
+		VpcSecurityGroupIds:  []*string{aws.String(securityGroupID)},
+		Tags:                 rdsTags(clusterName),
+	})
+	if errorCode(err) == model.ErrorCodeAlreadyExists {
+		logger.FromContext(c).Infof("RDS instance %s already exists, resuming", config.Identifier)
+	} else if err != nil {
+		logger.FromContext(c).WithError(err).Error("Failed to create RDS instance")
+		return nil, NewProviderError(err, "Failed to create RDS instance")
+	}
+
+	logger.FromContext(c).Infof("Waiting for RDS instance %s to become available", config.Identifier)
+	input := &rds.DescribeDBInstancesInput{DBInstanceIdentifier: aws.String(config.Identifier)}
+	err = rdsClient.WaitUntilDBInstanceAvailableWithContext(c, input, databaseWaiterOptions...)
+	if err != nil {
+		return nil, NewProviderError(err, "Failed waiting for RDS instance to become available")
+	}
+
+	described, err := rdsClient.DescribeDBInstancesWithContext(c, input)
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to describe RDS instance")
+	}
+	if len(described.DBInstances) == 0 || described.DBInstances[0].Endpoint == nil {
+		return nil, model.NewInternalError(fmt.Sprintf("RDS instance %s has no endpoint", config.Identifier))
+	}
+	instance := described.DBInstances[0]
+	logger.FromContext(c).Infof("RDS instance %s is available", config.Identifier)
+
+	return &model.ManagedDatabase{
+		Identifier:    config.Identifier,
+		Engine:        config.Engine,
+		EngineVersion: aws.StringValue(instance.EngineVersion),
+		Endpoint:      aws.StringValue(instance.Endpoint.Address),
+		Port:          int(aws.Int64Value(instance.Endpoint.Port)),
+		DatabaseName:  config.DatabaseName,
+		Username:      config.Username,
+	}, nil
+}
+
+// databaseWaiterOptions poll every 30 seconds for up to an hour, since a new database takes longer than the default
+// waiter allows.
+var databaseWaiterOptions = []request.WaiterOption{
+	request.WithWaiterDelay(request.ConstantWaiterDelay(30 * time.Second)),
+	request.WithWaiterMaxAttempts(120),
+}
+
+// databaseSecurityGroup creates a security group in the cluster's VPC that allows PostgreSQL connections from the
+// cluster security group, which EKS attaches to the control plane and managed nodes. An existing group with the same
+// name is reused.
+func databaseSecurityGroup(c context.Context, ec2Client *ec2.EC2, name string, clusterName string, vpcConfig *eks.VpcConfigResponse) (string, error) {
+	existing, err := ec2Client.DescribeSecurityGroupsWithContext(c, &ec2.DescribeSecurityGroupsInput{
+		Filters: []*ec2.Filter{
+			{Name: aws.String("vpc-id"), Values: []*string{vpcConfig.VpcId}},
+			{Name: aws.String("group-name"), Values: []*string{aws.String(name)}},
+		},
+	})
+	if err != nil {
+		return "", NewProviderError(err, "Failed to describe security groups")
+	}
+	if len(existing.SecurityGroups) > 0 {
+		logger.FromContext(c).Infof("Using existing security group %s", name)
+		return aws.StringValue(existing.SecurityGroups[0].GroupId), nil
+	}
+
+	logger.FromContext(c).Infof("Creating security group %s", name)
+	group, err := ec2Client.CreateSecurityGroupWithContext(c, &ec2.CreateSecurityGroupInput{
+		GroupName:   aws.String(name),
+		Description: aws.String(fmt.Sprintf("PostgreSQL access from EKS cluster %s", clusterName)),
+		VpcId:       vpcConfig.VpcId,
+		TagSpecifications: []*ec2.TagSpecification{{
+			ResourceType: aws.String(ec2.ResourceTypeSecurityGroup),
+			Tags:         []*ec2.Tag{{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")}},
+		}},
+	})
+	if err != nil {
+		return "", NewProviderError(err, "Failed to create database security group")
+	}
+
+	source := vpcConfig.ClusterSecurityGroupId
+	if source == nil && len(vpcConfig.SecurityGroupIds) > 0 {
+		source = vpcConfig.SecurityGroupIds[0]
+	}
+	if source == nil {
+		return "", model.NewInternalError(fmt.Sprintf("Cluster %s has no security group to allow database access from", clusterName))
+	}
+
+	_, err = ec2Client.AuthorizeSecurityGroupIngressWithContext(c, &ec2.AuthorizeSecurityGroupIngressInput{
+		GroupId: group.GroupId,
+		IpPermissions: []*ec2.IpPermission{{
+			IpProtocol:       aws.String("tcp"),
+			FromPort:         aws.Int64(5432),
+			ToPort:           aws.Int64(5432),
+			UserIdGroupPairs: []*ec2.UserIdGroupPair{{GroupId: source}},
+		}},
+	})
+	if err != nil && errorCode(err) != model.ErrorCodeAlreadyExists {
+		return "", NewProviderError(err, "Failed to allow database access from the cluster")
+	}
+
+	return aws.StringValue(group.GroupId), nil
+}
+
+func rdsTags(clusterName string) []*rds.Tag {
+	return []*rds.Tag{{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")}}
+}
+
+// postgresConnectionString builds a connection string in the format Mattermost expects, requiring TLS since RDS
+// supports it by default.
+func postgresConnectionString(username, password, host string, port int, database string) string {
+	connection := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(username, password),
+		Host:     fmt.Sprintf("%s:%d", host, port),
+		Path:     database,
+		RawQuery: "connect_timeout=10&sslmode=require",
+	}
+	return connection.String()
+}
+
+// CreateBucket creates an encrypted, versioned S3 bucket that blocks public access, with lifecycle rules that
+// remove old versions and incomplete uploads. Mattermost gets either the access keys of an IAM user, or an IAM role
+// for the installation's service account, that can only use the bucket. An existing bucket owned by the account
+// is reused.
+func (a *AWSProvider) CreateBucket(c context.Context, clusterName string, create *model.CreateBucketRequest) (*model.Bucket, error) {
+	config := create.WithDefaults(clusterName)
+	if err := config.IsValid(); err != nil {
+		return nil, model.NewInvalidRequestError("Invalid bucket request").Wrap(err)
+	}
+
+	eksClient := a.NewEKSClient().Client
+	sess, err := session.NewSession(eksClient.Config.Copy())
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to create AWS session")
+	}
+	region := aws.StringValue(eksClient.Config.Region)
+
+	err = createS3Bucket(c, s3.New(sess), clusterName, region, config)
+	if err != nil {
+		return nil, err
+	}
+
+	bucket := &model.Bucket{
+		Name:   config.BucketName,
+		URL:    fmt.Sprintf("s3.%s.amazonaws.com", region),
+		Region: region,
+		Access: config.Access,
+	}
+
+	iamClient := iam.New(sess)
+	policy, err := json.Marshal(bucketPolicyDocument(config.BucketName))
+	if err != nil {
+		return nil, model.NewInternalError("Failed to build bucket policy").Wrap(err)
+	}
+
+	if config.Access == model.BucketAccessIRSA {
+		trustPolicy, err := serviceAccountTrustPolicy(c, eksClient, sess, clusterName, model.InstallationNamespace(config.InstallationName))
+		if err != nil {
+			return nil, err
+		}
+
+		logger.FromContext(c).Infof("Creating IAM role %s", config.BucketName)
+		role, err := iamClient.CreateRoleWithContext(c, &iam.CreateRoleInput{
+			RoleName:                 aws.String(config.BucketName),
+			AssumeRolePolicyDocument: aws.String(trustPolicy),
+			Description:              aws.String(fmt.Sprintf("Access to the %s bucket from EKS cluster %s", config.BucketName, clusterName)),
+		})
+		if errorCode(err) == model.ErrorCodeAlreadyExists {
+			_, err = iamClient.UpdateAssumeRolePolicyWithContext(c, &iam.UpdateAssumeRolePolicyInput{
+				RoleName:       aws.String(config.BucketName),
+				PolicyDocument: aws.String(trustPolicy),
+			})
+			if err == nil {
+				var existing *iam.GetRoleOutput
+				existing, err = iamClient.GetRoleWithContext(c, &iam.GetRoleInput{RoleName: aws.String(config.BucketName)})
+				if err == nil {
+					role = &iam.CreateRoleOutput{Role: existing.Role}
+				}
+			}
+		}
+		if err != nil {
+			return nil, NewProviderError(err, "Failed to create IAM role for the bucket")
+		}
+
+		_, err = iamClient.PutRolePolicyWithContext(c, &iam.PutRolePolicyInput{
+			RoleName:       aws.String(config.BucketName),
+			PolicyName:     aws.String("filestore"),
+			PolicyDocument: aws.String(string(policy)),
+		})
+		if err != nil {
+			return nil, NewProviderError(err, "Failed to attach the bucket policy to the IAM role")
+		}
+		bucket.RoleARN = aws.StringValue(role.Role.Arn)
+
+		return bucket, nil
+	}
+
+	logger.FromContext(c).Infof("Creating IAM user %s", config.BucketName)
+	_, err = iamClient.CreateUserWithContext(c, &iam.CreateUserInput{UserName: aws.String(config.BucketName)})
+	if err != nil && errorCode(err) != model.ErrorCodeAlreadyExists {
+		return nil, NewProviderError(err, "Failed to create IAM user for the bucket")
+	}
+
+	_, err = iamClient.PutUserPolicyWithContext(c, &iam.PutUserPolicyInput{
+		UserName:       aws.String(config.BucketName),
+		PolicyName:     aws.String("filestore"),
+		PolicyDocument: aws.String(string(policy)),
+	})
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to attach the bucket policy to the IAM user")
+	}
+
+	bucket.UserName = config.BucketName
+	bucket.AccessKeyID, bucket.SecretAccessKey, err = bucketUserAccessKey(c, iamClient, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return bucket, nil
+}
+
+// bucketUserAccessKey returns the access key of a bucket's IAM user. The key of the request is reused while the user
+// still has it. Any other key is deleted, since its secret is only returned when it is created and has been lost, so
+// that retries don't run into the limit of two keys per user.
+func bucketUserAccessKey(c context.Context, iamClient *iam.IAM, config model.CreateBucketRequest) (string, string, error) {
+	keys, err := iamClient.ListAccessKeysWithContext(c, &iam.ListAccessKeysInput{UserName: aws.String(config.BucketName)})
+	if err != nil {
+		return "", "", NewProviderError(err, "Failed to list access keys of the bucket's IAM user")
+	}
+
+	reuse := false
+	for _, key := range keys.AccessKeyMetadata {
+		keyID := aws.StringValue(key.AccessKeyId)
+		if keyID == config.AccessKeyID && config.SecretAccessKey != "" {
+			reuse = true
+			continue
+		}
+
+		logger.FromContext(c).Infof("Deleting stale access key %s of IAM user %s", keyID, config.BucketName)
+		_, err = iamClient.DeleteAccessKeyWithContext(c, &iam.DeleteAccessKeyInput{UserName: aws.String(config.BucketName), AccessKeyId: key.AccessKeyId})
+		if err != nil {
+			return "", "", NewProviderError(err, "Failed to delete stale access key of the bucket's IAM user")
+		}
+	}
+	if reuse {
+		logger.FromContext(c).Infof("Reusing access key %s of IAM user %s", config.AccessKeyID, config.BucketName)
+		return config.AccessKeyID, config.SecretAccessKey, nil
+	}
+
+	accessKey, err := iamClient.CreateAccessKeyWithContext(c, &iam.CreateAccessKeyInput{UserName: aws.String(config.BucketName)})
+	if err != nil {
+		return "", "", NewProviderError(err, "Failed to create access key for the bucket")
+	}
+
+	return aws.StringValue(accessKey.AccessKey.AccessKeyId), aws.StringValue(accessKey.AccessKey.SecretAccessKey), nil
+}
+
+// createS3Bucket creates the bucket, or reuses one the account already owns, and applies the encryption, public
+// access, versioning and lifecycle defaults to it.
+func createS3Bucket(c context.Context, s3Client *s3.S3, clusterName, region string, config model.CreateBucketRequest) error {
+	bucketName := aws.String(config.BucketName)
+
+	input := &s3.CreateBucketInput{Bucket: bucketName}
+	// us-east-1 is the default location, which can't be set explicitly
+	if region != "us-east-1" {
+		input.CreateBucketConfiguration = &s3.CreateBucketConfiguration{LocationConstraint: aws.String(region)}
+	}
+	logger.FromContext(c).Infof("Creating S3 bucket %s", config.BucketName)
+	_, err := s3Client.CreateBucketWithContext(c, input)
+	if err != nil {
+		var aerr awserr.Error
+		if !errors.As(err, &aerr) || aerr.Code() != s3.ErrCodeBucketAlreadyOwnedByYou {
+			return NewProviderError(err, "Failed to create S3 bucket")
+		}
+		logger.FromContext(c).Infof("Using existing S3 bucket %s", config.BucketName)
+	}
+
+	_, err = s3Client.PutPublicAccessBlockWithContext(c, &s3.PutPublicAccessBlockInput{
+		Bucket: bucketName,
+		PublicAccessBlockConfiguration: &s3.PublicAccessBlockConfiguration{
+			BlockPublicAcls:       aws.Bool(true),
+			BlockPublicPolicy:     aws.Bool(true),
+			IgnorePublicAcls:      aws.Bool(true),
+			RestrictPublicBuckets: aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return NewProviderError(err, "Failed to block public access to the S3 bucket")
+	}
+
+	_, err = s3Client.PutBucketEncryptionWithContext(c, &s3.PutBucketEncryptionInput{
+		Bucket: bucketName,
+		ServerSideEncryptionConfiguration: &s3.ServerSideEncryptionConfiguration{
+			Rules: []*s3.ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: &s3.ServerSideEncryptionByDefault{SSEAlgorithm: aws.String(s3.ServerSideEncryptionAes256)},
+			}},
+		},
+	})
+	if err != nil {
+		return NewProviderError(err, "Failed to enable S3 bucket encryption")
+	}
+
+	_, err = s3Client.PutBucketVersioningWithContext(c, &s3.PutBucketVersioningInput{
+		Bucket:                  bucketName,
+		VersioningConfiguration: &s3.VersioningConfiguration{Status: aws.String(s3.BucketVersioningStatusEnabled)},
+	})
+	if err != nil {
+		return NewProviderError(err, "Failed to enable S3 bucket versioning")
+	}
+
+	_, err = s3Client.PutBucketLifecycleConfigurationWithContext(c, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket: bucketName,
+		LifecycleConfiguration: &s3.BucketLifecycleConfiguration{
+			Rules: []*s3.LifecycleRule{{
+				ID:     aws.String("filestore"),
+				Status: aws.String(s3.ExpirationStatusEnabled),
+				Filter: &s3.LifecycleRuleFilter{Prefix: aws.String("")},
+				NoncurrentVersionExpiration: &s3.NoncurrentVersionExpiration{
+					NoncurrentDays: aws.Int64(int64(config.NoncurrentVersionExpirationDays)),
+				},
+				AbortIncompleteMultipartUpload: &s3.AbortIncompleteMultipartUpload{DaysAfterInitiation: aws.Int64(7)},
+			}},
+		},
+	})
+	if err != nil {
+		return NewProviderError(err, "Failed to set S3 bucket lifecycle rules")
+	}
+
+	_, err = s3Client.PutBucketTaggingWithContext(c, &s3.PutBucketTaggingInput{
+		Bucket: bucketName,
+		Tagging: &s3.Tagging{TagSet: []*s3.Tag{
+			{Key: aws.String("kubernetes.io/cluster/" + clusterName), Value: aws.String("owned")},
+			{Key: aws.String("installation"), Value: aws.String(config.InstallationName)},
+		}},
+	})
+	if err != nil {
+		return NewProviderError(err, "Failed to tag S3 bucket")
+	}
+
+	return nil
+}
+
+// serviceAccountTrustPolicy returns a trust policy that lets the service account with the given name in the
+// namespace of the same name assume a role through the IAM OIDC provider of the cluster. The OIDC provider has to
+// exist already.
+func serviceAccountTrustPolicy(c context.Context, eksClient *eks.EKS, sess *session.Session, clusterName, serviceAccount string) (string, error) {
+	cluster, err := eksClient.DescribeClusterWithContext(c, &eks.DescribeClusterInput{Name: aws.String(clusterName)})
+	if err != nil {
+		return "", NewProviderError(err, "Failed to describe cluster")
+	}
+	if cluster.Cluster.Identity == nil || cluster.Cluster.Identity.Oidc == nil {
+		return "", model.NewInvalidRequestError(fmt.Sprintf("Cluster %s has no OIDC issuer", clusterName))
+	}
+	issuer := strings.TrimPrefix(aws.StringValue(cluster.Cluster.Identity.Oidc.Issuer), "https://")
+
+	oidcProviders, err := iam.New(sess).ListOpenIDConnectProvidersWithContext(c, &iam.ListOpenIDConnectProvidersInput{})
+	if err != nil {
+		return "", NewProviderError(err, "Failed to list IAM OIDC providers")
+	}
+	providerARN := ""
+	for _, provider := range oidcProviders.OpenIDConnectProviderList {
+		if strings.HasSuffix(aws.StringValue(provider.Arn), ":oidc-provider/"+issuer) {
+			providerARN = aws.StringValue(provider.Arn)
+			break
+		}
+	}
+	if providerARN == "" {
+		return "", model.NewInvalidRequestError(fmt.Sprintf("Cluster %s has no IAM OIDC provider, associate one or use %s access", clusterName, model.BucketAccessIAMUser))
+	}
+
+	policy, err := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{{
+			"Effect":    "Allow",
+			"Principal": map[string]string{"Federated": providerARN},
+			"Action":    "sts:AssumeRoleWithWebIdentity",
+			"Condition": map[string]interface{}{
+				"StringEquals": map[string]string{
+					issuer + ":sub": fmt.Sprintf("system:serviceaccount:%s:%s", serviceAccount, serviceAccount),
+					issuer + ":aud": "sts.amazonaws.com",
+				},
+			},
+		}},
+	})
+	if err != nil {
+		return "", model.NewInternalError("Failed to build trust policy").Wrap(err)
+	}
+
+	return string(policy), nil
+}
+
+// bucketPolicyDocument allows listing and using the objects of one bucket, which is all Mattermost needs.
+func bucketPolicyDocument(bucketName string) map[string]interface{} {
+	return map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Effect":   "Allow",
+				"Action":   []string{"s3:ListBucket", "s3:GetBucketLocation", "s3:ListBucketMultipartUploads"},
+				"Resource": "arn:aws:s3:::" + bucketName,
+			},
+			{
+				"Effect":   "Allow",
+				"Action":   []string{"s3:GetObject", "s3:PutObject", "s3:DeleteObject", "s3:AbortMultipartUpload", "s3:ListMultipartUploadParts"},
+				"Resource": "arn:aws:s3:::" + bucketName + "/*",
+			},
+		},
+	}
+}
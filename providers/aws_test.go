@@ -0,0 +1,162 @@
+package providers_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAWSScalingConfigUpdate(t *testing.T) {
+	current := &eks.NodegroupScalingConfig{MinSize: aws.Int64(1), MaxSize: aws.Int64(5), DesiredSize: aws.Int64(3)}
+
+	tests := []struct {
+		name     string
+		current  *eks.NodegroupScalingConfig
+		scaling  model.ScalingConfig
+		expected *eks.NodegroupScalingConfig
+	}{
+		{
+			name:     "unchanged",
+			current:  current,
+			scaling:  model.ScalingConfig{MinSize: 1, MaxSize: 5},
+			expected: current,
+		},
+		{
+			name:     "explicit desired size",
+			current:  current,
+			scaling:  model.ScalingConfig{MinSize: 1, MaxSize: 5, DesiredSize: aws.Int64(5)},
+			expected: &eks.NodegroupScalingConfig{MinSize: aws.Int64(1), MaxSize: aws.Int64(5), DesiredSize: aws.Int64(5)},
+		},
+		{
+			name:     "desired size raised to the new minimum",
+			current:  current,
+			scaling:  model.ScalingConfig{MinSize: 4, MaxSize: 6},
+			expected: &eks.NodegroupScalingConfig{MinSize: aws.Int64(4), MaxSize: aws.Int64(6), DesiredSize: aws.Int64(4)},
+		},
+		{
+			name:     "desired size lowered to the new maximum",
+			current:  current,
+			scaling:  model.ScalingConfig{MinSize: 1, MaxSize: 2},
+			expected: &eks.NodegroupScalingConfig{MinSize: aws.Int64(1), MaxSize: aws.Int64(2), DesiredSize: aws.Int64(2)},
+		},
+		{
+			name:     "unknown current size starts at the minimum",
+			scaling:  model.ScalingConfig{MinSize: 2, MaxSize: 4},
+			expected: &eks.NodegroupScalingConfig{MinSize: aws.Int64(2), MaxSize: aws.Int64(4), DesiredSize: aws.Int64(2)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, providers.AWSScalingConfigUpdate(test.current, &test.scaling))
+		})
+	}
+}
+
+func TestAWSLabelsUpdate(t *testing.T) {
+	current := map[string]*string{"team": aws.String("chat"), "tier": aws.String("app")}
+
+	tests := []struct {
+		name           string
+		labels         map[string]string
+		expectedAdd    map[string]*string
+		expectedRemove []string
+		unchanged      bool
+	}{
+		{
+			name:      "unchanged",
+			labels:    map[string]string{"team": "chat", "tier": "app"},
+			unchanged: true,
+		},
+		{
+			name:        "added and updated",
+			labels:      map[string]string{"team": "chat", "tier": "db", "zone": "a"},
+			expectedAdd: map[string]*string{"tier": aws.String("db"), "zone": aws.String("a")},
+		},
+		{
+			name:           "removed",
+			labels:         map[string]string{"team": "chat"},
+			expectedRemove: []string{"tier"},
+		},
+		{
+			name:           "all removed",
+			labels:         map[string]string{},
+			expectedRemove: []string{"team", "tier"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payload := providers.AWSLabelsUpdate(current, test.labels)
+			if test.unchanged {
+				assert.Nil(t, payload)
+				return
+			}
+
+			require.NotNil(t, payload)
+			assert.Equal(t, test.expectedAdd, payload.AddOrUpdateLabels)
+			assert.ElementsMatch(t, test.expectedRemove, aws.StringValueSlice(payload.RemoveLabels))
+		})
+	}
+}
+
+func TestAWSTaintsUpdate(t *testing.T) {
+	dedicated := &eks.Taint{Key: aws.String("dedicated"), Value: aws.String("mattermost"), Effect: aws.String(eks.TaintEffectNoSchedule)}
+	current := []*eks.Taint{dedicated}
+
+	tests := []struct {
+		name           string
+		taints         []model.NodegroupTaint
+		expectedAdd    []*eks.Taint
+		expectedRemove []*eks.Taint
+		unchanged      bool
+	}{
+		{
+			name:      "unchanged",
+			taints:    []model.NodegroupTaint{{Key: "dedicated", Value: "mattermost", Effect: eks.TaintEffectNoSchedule}},
+			unchanged: true,
+		},
+		{
+			name: "added",
+			taints: []model.NodegroupTaint{
+				{Key: "dedicated", Value: "mattermost", Effect: eks.TaintEffectNoSchedule},
+				{Key: "spot", Effect: eks.TaintEffectPreferNoSchedule},
+			},
+			expectedAdd: []*eks.Taint{{Key: aws.String("spot"), Value: aws.String(""), Effect: aws.String(eks.TaintEffectPreferNoSchedule)}},
+		},
+		{
+			name:        "value updated",
+			taints:      []model.NodegroupTaint{{Key: "dedicated", Value: "database", Effect: eks.TaintEffectNoSchedule}},
+			expectedAdd: []*eks.Taint{{Key: aws.String("dedicated"), Value: aws.String("database"), Effect: aws.String(eks.TaintEffectNoSchedule)}},
+		},
+		{
+			name:           "effect changed replaces the taint",
+			taints:         []model.NodegroupTaint{{Key: "dedicated", Value: "mattermost", Effect: eks.TaintEffectNoExecute}},
+			expectedAdd:    []*eks.Taint{{Key: aws.String("dedicated"), Value: aws.String("mattermost"), Effect: aws.String(eks.TaintEffectNoExecute)}},
+			expectedRemove: []*eks.Taint{dedicated},
+		},
+		{
+			name:           "removed",
+			expectedRemove: []*eks.Taint{dedicated},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			payload := providers.AWSTaintsUpdate(current, test.taints)
+			if test.unchanged {
+				assert.Nil(t, payload)
+				return
+			}
+
+			require.NotNil(t, payload)
+			assert.Equal(t, test.expectedAdd, payload.AddOrUpdateTaints)
+			assert.Equal(t, test.expectedRemove, payload.RemoveTaints)
+		})
+	}
+}
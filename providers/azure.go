@@ -0,0 +1,727 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	helmclient "github.com/mittwald/go-helm-client"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	armDefaultEndpoint        = "https://management.azure.com/"
+	azureDefaultLoginEndpoint = "https://login.microsoftonline.com/"
+	azureManagementScope      = "https://management.azure.com/.default"
+	aksAPIVersion             = "2024-02-01"
+	armResourceGroupVersion   = "2021-04-01"
+	aksDefaultSystemPoolSize  = "Standard_D2s_v3"
+)
+
+// AzureProvider manages AKS clusters through the Azure Resource Manager REST API, authenticating with a service
+// principal.
+type AzureProvider struct {
+	Credentials     *model.Credentials
+	credentialsLock *sync.Mutex
+	// Endpoint is the base URL of the Azure Resource Manager API, and LoginEndpoint the base URL of Azure AD.
+	// Both can be pointed at a fake server for testing.
+	Endpoint      string
+	LoginEndpoint string
+	tokenSource   oauth2.TokenSource
+}
+
+// aksManagedCluster is the subset of the AKS ManagedCluster resource used by the bootstrapper.
+type aksManagedCluster struct {
+	ID         string                       `json:"id,omitempty"`
+	Name       string                       `json:"name,omitempty"`
+	Location   string                       `json:"location"`
+	Tags       map[string]string            `json:"tags,omitempty"`
+	Identity   *aksIdentity                 `json:"identity,omitempty"`
+	Properties *aksManagedClusterProperties `json:"properties"`
+}
+
+type aksIdentity struct {
+	Type string `json:"type"`
+}
+
+type aksManagedClusterProperties struct {
+	ProvisioningState        string                    `json:"provisioningState,omitempty"`
+	KubernetesVersion        string                    `json:"kubernetesVersion,omitempty"`
+	CurrentKubernetesVersion string                    `json:"currentKubernetesVersion,omitempty"`
+	DNSPrefix                string                    `json:"dnsPrefix,omitempty"`
+	FQDN                     string                    `json:"fqdn,omitempty"`
+	AgentPoolProfiles        []*aksAgentPoolProperties `json:"agentPoolProfiles,omitempty"`
+	StorageProfile           *aksStorageProfile        `json:"storageProfile,omitempty"`
+}
+
+type aksStorageProfile struct {
+	DiskCSIDriver *aksEnabled `json:"diskCSIDriver,omitempty"`
+}
+
+type aksEnabled struct {
+	Enabled bool `json:"enabled"`
+}
+
+// aksAgentPool is an AKS AgentPool resource. Agent pools embedded in a managed cluster only have the properties,
+// with the name set.
+type aksAgentPool struct {
+	ID         string                  `json:"id,omitempty"`
+	Name       string                  `json:"name,omitempty"`
+	Properties *aksAgentPoolProperties `json:"properties"`
+}
+
+type aksAgentPoolProperties struct {
+	Name                string            `json:"name,omitempty"`
+	Count               int64             `json:"count,omitempty"`
+	VMSize              string            `json:"vmSize,omitempty"`
+	OSSKU               string            `json:"osSKU,omitempty"`
+	Mode                string            `json:"mode,omitempty"`
+	OrchestratorVersion string            `json:"orchestratorVersion,omitempty"`
+	EnableAutoScaling   bool              `json:"enableAutoScaling,omitempty"`
+	MinCount            int64             `json:"minCount,omitempty"`
+	MaxCount            int64             `json:"maxCount,omitempty"`
+	NodeLabels          map[string]string `json:"nodeLabels,omitempty"`
+	Tags                map[string]string `json:"tags,omitempty"`
+	VnetSubnetID        string            `json:"vnetSubnetID,omitempty"`
+	ProvisioningState   string            `json:"provisioningState,omitempty"`
+}
+
+// azureAPIError is the error body returned by the Azure Resource Manager API.
+type azureAPIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *azureAPIError) Error() string {
+	return fmt.Sprintf("Azure API returned %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func (e *azureAPIError) HTTPStatusCode() int {
+	return e.StatusCode
+}
+
+func init() {
+	Register(ProviderInfo{
+		Name:        "azure",
+		DisplayName: "Azure AKS",
+	}, GetAzureProvider)
+}
+
+var azureProviderInstance *AzureProvider
+var azureProviderInstanceOnce sync.Once
+
+func GetAzureProvider(credentials *model.Credentials) *AzureProvider {
+	azureProviderInstanceOnce.Do(func() {
+		azureProviderInstance = NewAzureProvider(credentials)
+	})
+
+	return azureProviderInstance
+}
+
+// NewAzureProvider returns an AzureProvider for the given credentials. Invalid credentials are reported when the
+// provider is first used, or by ValidateCredentials.
+func NewAzureProvider(credentials *model.Credentials) *AzureProvider {
+	p := &AzureProvider{
+		credentialsLock: &sync.Mutex{},
+		Endpoint:        armDefaultEndpoint,
+		LoginEndpoint:   azureDefaultLoginEndpoint,
+	}
+
+	if credentials == nil || credentials.ClientSecret == "" {
+		credentials = azureCredentialsFromEnv()
+	}
+	_ = p.SetCredentials(context.Background(), credentials)
+
+	return p
+}
+
+// azureCredentialsFromEnv reads the service principal from the same environment variables as the Azure SDKs.
+func azureCredentialsFromEnv() *model.Credentials {
+	return &model.Credentials{
+		TenantID:       os.Getenv("AZURE_TENANT_ID"),
+		ClientID:       os.Getenv("AZURE_CLIENT_ID"),
+		ClientSecret:   os.Getenv("AZURE_CLIENT_SECRET"),
+		SubscriptionID: os.Getenv("AZURE_SUBSCRIPTION_ID"),
+		ResourceGroup:  os.Getenv("AZURE_RESOURCE_GROUP"),
+		Region:         os.Getenv("AZURE_LOCATION"),
+	}
+}
+
+func validateAzureCredentials(credentials *model.Credentials) error {
+	if credentials == nil || credentials.TenantID == "" || credentials.ClientID == "" || credentials.ClientSecret == "" {
+		return model.NewAppError(model.ErrorCodeInvalidCredentials, http.StatusUnauthorized, "Azure credentials must include a tenant ID, client ID and client secret")
+	}
+
+	if credentials.SubscriptionID == "" || credentials.ResourceGroup == "" {
+		return model.NewInvalidRequestError("Azure credentials must include a subscription ID and resource group")
+	}
+
+	return nil
+}
+
+func (p *AzureProvider) newTokenSource(credentials *model.Credentials) oauth2.TokenSource {
+	config := &clientcredentials.Config{
+		ClientID:     credentials.ClientID,
+		ClientSecret: credentials.ClientSecret,
+		TokenURL:     strings.TrimSuffix(p.LoginEndpoint, "/") + "/" + credentials.TenantID + "/oauth2/v2.0/token",
+		Scopes:       []string{azureManagementScope},
+		AuthStyle:    oauth2.AuthStyleInParams,
+	}
+
+	return config.TokenSource(context.Background())
+}
+
+func (p *AzureProvider) GetAzureCredentials() *model.Credentials {
+	p.credentialsLock.Lock()
+	defer p.credentialsLock.Unlock()
+	return p.Credentials
+}
+
+func (p *AzureProvider) SetCredentials(c context.Context, credentials *model.Credentials) error {
+	p.credentialsLock.Lock()
+	defer p.credentialsLock.Unlock()
+
+	p.Credentials = credentials
+	// The token source is created on first use, so that LoginEndpoint can still be changed
+	p.tokenSource = nil
+
+	return validateAzureCredentials(credentials)
+}
+
+func (p *AzureProvider) SetRegion(c context.Context, region string) error {
+	p.credentialsLock.Lock()
+	defer p.credentialsLock.Unlock()
+
+	if p.Credentials == nil {
+		p.Credentials = &model.Credentials{}
+	}
+	p.Credentials.Region = region
+
+	return nil
+}
+
+func (p *AzureProvider) ValidateCredentials(c context.Context, creds *model.Credentials) (bool, error) {
+	err := validateAzureCredentials(creds)
+	if err != nil {
+		return false, err
+	}
+
+	tokenSource := p.newTokenSource(creds)
+	_, err = tokenSource.Token()
+	if err != nil {
+		return false, model.NewAppError(model.ErrorCodeInvalidCredentials, http.StatusUnauthorized, "Azure AD rejected the service principal").Wrap(err)
+	}
+
+	path := fmt.Sprintf("subscriptions/%s/resourcegroups/%s", creds.SubscriptionID, creds.ResourceGroup)
+	err = p.do(c, tokenSource, http.MethodGet, path, armResourceGroupVersion, nil, nil)
+	if err != nil {
+		return false, NewProviderError(err, "Credentials are valid but the resource group can't be accessed")
+	}
+
+	return true, nil
+}
+
+// ListClusters lists the AKS clusters in the configured resource group. Resource groups can hold clusters in
+// several locations, so the region is only used as a filter.
+func (p *AzureProvider) ListClusters(c context.Context, region string) ([]*string, error) {
+	clustersPath, err := p.clustersPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Value []*aksManagedCluster `json:"value"`
+	}
+	err = p.do(c, p.getTokenSource(), http.MethodGet, clustersPath, aksAPIVersion, nil, &list)
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to list AKS clusters")
+	}
+
+	clusters := []*string{}
+	for _, cluster := range list.Value {
+		if region != "" && !strings.EqualFold(cluster.Location, region) {
+			continue
+		}
+		name := cluster.Name
+		clusters = append(clusters, &name)
+	}
+
+	return clusters, nil
+}
+
+func (p *AzureProvider) CreateCluster(c context.Context, create *model.CreateClusterRequest) (*model.Cluster, error) {
+	if create.ClusterName == nil || *create.ClusterName == "" {
+		return nil, model.NewInvalidParamError("clusterName")
+	}
+
+	credentials := p.GetAzureCredentials()
+	if credentials == nil || credentials.Region == "" {
+		return nil, model.NewInvalidRequestError("An AKS location must be set")
+	}
+
+	// Creating a managed cluster is a PUT, which would silently update an existing cluster with the same name
+	_, err := p.getAKSCluster(c, *create.ClusterName)
+	if err == nil {
+		return nil, model.NewAppError(model.ErrorCodeAlreadyExists, http.StatusConflict, fmt.Sprintf("AKS cluster %s already exists", *create.ClusterName))
+	}
+	var appErr *model.AppError
+	if !errors.As(err, &appErr) || appErr.Code != model.ErrorCodeNotFound {
+		return nil, err
+	}
+
+	systemPool := &aksAgentPoolProperties{
+		Name:   "system",
+		Count:  1,
+		VMSize: aksDefaultSystemPoolSize,
+		Mode:   "System",
+	}
+	if len(create.SubnetIDs) > 0 && create.SubnetIDs[0] != nil {
+		systemPool.VnetSubnetID = *create.SubnetIDs[0]
+	}
+
+	cluster := &aksManagedCluster{
+		Name:     *create.ClusterName,
+		Location: credentials.Region,
+		Identity: &aksIdentity{Type: "SystemAssigned"},
+		Properties: &aksManagedClusterProperties{
+			DNSPrefix:         *create.ClusterName,
+			AgentPoolProfiles: []*aksAgentPoolProperties{systemPool},
+			StorageProfile: &aksStorageProfile{
+				DiskCSIDriver: &aksEnabled{Enabled: true},
+			},
+		},
+	}
+	if create.KubernetesVersion != nil {
+		cluster.Properties.KubernetesVersion = *create.KubernetesVersion
+	}
+
+	clusterPath, err := p.clusterPath(*create.ClusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	var result aksManagedCluster
+	err = p.do(c, p.getTokenSource(), http.MethodPut, clusterPath, aksAPIVersion, cluster, &result)
+	if err != nil {
+		logger.FromContext(c).WithError(err).Error("Failed to create AKS cluster")
+		return nil, NewProviderError(err, "Failed to create AKS cluster")
+	}
+
+	return aksClusterToCluster(&result), nil
+}
+
+func (p *AzureProvider) GetCluster(c context.Context, name string) (*model.Cluster, error) {
+	cluster, err := p.getAKSCluster(c, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return aksClusterToCluster(cluster), nil
+}
+
+func (p *AzureProvider) GetNodegroups(c context.Context, clusterName string) ([]*model.ClusterNodegroup, error) {
+	clusterPath, err := p.clusterPath(clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Value []*aksAgentPool `json:"value"`
+	}
+	err = p.do(c, p.getTokenSource(), http.MethodGet, clusterPath+"/agentPools", aksAPIVersion, nil, &list)
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to list AKS agent pools")
+	}
+
+	nodegroups := []*model.ClusterNodegroup{}
+	for _, agentPool := range list.Value {
+		if agentPool.Properties == nil {
+			continue
+		}
+		agentPool.Properties.Name = agentPool.Name
+		nodegroups = append(nodegroups, aksAgentPoolToNodegroup(clusterName, agentPool.Properties))
+	}
+
+	return nodegroups, nil
+}
+
+// CreateNodegroup adds a user agent pool to the cluster. AKS agent pool names must be lowercase alphanumeric.
+func (p *AzureProvider) CreateNodegroup(c context.Context, name string, create *model.CreateNodegroupRequest) (*model.ClusterNodegroup, error) {
+	if create.NodegroupName == "" {
+		return nil, model.NewInvalidParamError("nodeGroupName")
+	}
+
+	clusterPath, err := p.clusterPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	count := create.ScalingConfig.MinSize
+	if count < 1 {
+		count = 1
+	}
+
+	properties := &aksAgentPoolProperties{
+		Count:               count,
+		VMSize:              create.InstanceType,
+		OSSKU:               create.AMIType,
+		Mode:                "User",
+		OrchestratorVersion: create.ReleaseVersion,
+		EnableAutoScaling:   create.ScalingConfig.MaxSize > 0,
+		MinCount:            create.ScalingConfig.MinSize,
+		MaxCount:            create.ScalingConfig.MaxSize,
+		NodeLabels:          create.Labels,
+		Tags:                create.Tags,
+	}
+	if len(create.SubnetIDs) > 0 {
+		properties.VnetSubnetID = create.SubnetIDs[0]
+	}
+
+	// Creating an agent pool is a PUT too, which would silently update an existing pool with the same name
+	path := clusterPath + "/agentPools/" + create.NodegroupName
+	err = p.do(c, p.getTokenSource(), http.MethodGet, path, aksAPIVersion, nil, nil)
+	if err == nil {
+		return nil, model.NewAppError(model.ErrorCodeAlreadyExists, http.StatusConflict, fmt.Sprintf("AKS agent pool %s already exists", create.NodegroupName))
+	}
+	err = NewProviderError(err, "Failed to describe AKS agent pool")
+	var appErr *model.AppError
+	if !errors.As(err, &appErr) || appErr.Code != model.ErrorCodeNotFound {
+		return nil, err
+	}
+
+	var result aksAgentPool
+	err = p.do(c, p.getTokenSource(), http.MethodPut, path, aksAPIVersion, &aksAgentPool{Properties: properties}, &result)
+	if err != nil {
+		logger.FromContext(c).WithError(err).Error("Failed to create AKS agent pool")
+		return nil, NewProviderError(err, "Failed to create AKS agent pool")
+	}
+
+	if result.Properties == nil {
+		result.Properties = properties
+	}
+	result.Properties.Name = create.NodegroupName
+
+	return aksAgentPoolToNodegroup(name, result.Properties), nil
+}
+
+func (p *AzureProvider) GetKubeRestConfig(c context.Context, clusterName string) (*rest.Config, error) {
+	kubeConfig, err := p.GetKubeConfig(c, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	return kubeConfig.ClientConfig()
+}
+
+// GetKubeConfig returns the admin kubeconfig of the cluster, which uses a client certificate rather than Azure AD.
+func (p *AzureProvider) GetKubeConfig(c context.Context, clusterName string) (clientcmd.ClientConfig, error) {
+	clusterPath, err := p.clusterPath(clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	var credentials struct {
+		Kubeconfigs []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"kubeconfigs"`
+	}
+	err = p.do(c, p.getTokenSource(), http.MethodPost, clusterPath+"/listClusterAdminCredential", aksAPIVersion, nil, &credentials)
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to get AKS cluster admin credentials")
+	}
+
+	if len(credentials.Kubeconfigs) == 0 {
+		return nil, model.NewAppError(model.ErrorCodeConflict, http.StatusConflict, fmt.Sprintf("AKS cluster %s has no admin credentials yet", clusterName))
+	}
+
+	kubeconfig, err := base64.StdEncoding.DecodeString(credentials.Kubeconfigs[0].Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode AKS kubeconfig: %w", err)
+	}
+
+	return clientcmd.NewClientConfigFromBytes(kubeconfig)
+}
+
+func (p *AzureProvider) KubeClient(c context.Context, clusterName string) (*model.KubeClient, error) {
+	config, err := p.GetKubeRestConfig(c, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	return newKubeClient(config)
+}
+
+func (p *AzureProvider) HelmClient(c context.Context, clusterName string, namespace string) (helmclient.Client, error) {
+	k8sClient, err := p.KubeClient(c, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	return k8sClient.GetHelmClient(c, namespace)
+}
+
+// enableDiskCSIDriver makes sure the Azure Disk CSI driver is enabled on the cluster. AKS manages the driver itself,
+// so unlike on EKS there is no chart to install.
+func (p *AzureProvider) enableDiskCSIDriver(c context.Context, clusterName string) error {
+	clusterPath, err := p.clusterPath(clusterName)
+	if err != nil {
+		return err
+	}
+
+	// Updating a managed cluster replaces it, so the full resource is sent back rather than aksManagedCluster,
+	// which would drop the fields the bootstrapper doesn't know about.
+	var cluster map[string]interface{}
+	err = p.do(c, p.getTokenSource(), http.MethodGet, clusterPath, aksAPIVersion, nil, &cluster)
+	if err != nil {
+		return NewProviderError(err, "Failed to describe AKS cluster")
+	}
+
+	properties, _ := cluster["properties"].(map[string]interface{})
+	if properties == nil {
+		properties = map[string]interface{}{}
+		cluster["properties"] = properties
+	}
+	storageProfile, _ := properties["storageProfile"].(map[string]interface{})
+	if storageProfile == nil {
+		storageProfile = map[string]interface{}{}
+		properties["storageProfile"] = storageProfile
+	}
+	diskCSIDriver, _ := storageProfile["diskCSIDriver"].(map[string]interface{})
+	if enabled, _ := diskCSIDriver["enabled"].(bool); enabled {
+		logger.FromContext(c).Info("Azure Disk CSI driver is already enabled")
+		return nil
+	}
+
+	logger.FromContext(c).Info("Enabling the Azure Disk CSI driver")
+	storageProfile["diskCSIDriver"] = map[string]interface{}{"enabled": true}
+	err = p.do(c, p.getTokenSource(), http.MethodPut, clusterPath, aksAPIVersion, cluster, nil)
+	if err != nil {
+		return NewProviderError(err, "Failed to enable the Azure Disk CSI driver")
+	}
+
+	return nil
+}
+
+// AddonHooks enables the Azure Disk CSI driver before CNPG or MinIO is installed.
+func (p *AzureProvider) AddonHooks(addon string, phase model.HookPhase) []Hook {
+	if (addon == "cnpg" || addon == "minio") && phase == model.HookPhasePreInstall {
+		return []Hook{{Name: "azure-disk-csi-driver", Run: p.enableDiskCSIDriver}}
+	}
+
+	return nil
+}
+
+// NginxValues exposes ingress-nginx through the cluster's standard load balancer, or an internal one if requested.
+func (p *AzureProvider) NginxValues(c context.Context, request *model.DeployNginxRequest) (map[string]interface{}, error) {
+	err := checkNginxRequest("AKS", request, model.NginxLoadBalancerNLB, model.NginxLoadBalancerInternal)
+	if err != nil {
+		return nil, err
+	}
+
+	annotations := map[string]interface{}{
+		// The default probe requests / on the HTTP port, which ingress-nginx answers with a 404
+		"service.beta.kubernetes.io/azure-load-balancer-health-probe-request-path": "/healthz",
+	}
+	if request.LoadBalancerType == model.NginxLoadBalancerInternal {
+		annotations["service.beta.kubernetes.io/azure-load-balancer-internal"] = "true"
+	}
+
+	return nginxServiceValues(map[string]interface{}{"annotations": annotations}), nil
+}
+
+func (p *AzureProvider) getTokenSource() oauth2.TokenSource {
+	p.credentialsLock.Lock()
+	defer p.credentialsLock.Unlock()
+
+	if p.tokenSource == nil && validateAzureCredentials(p.Credentials) == nil {
+		p.tokenSource = p.newTokenSource(p.Credentials)
+	}
+
+	return p.tokenSource
+}
+
+func (p *AzureProvider) clustersPath() (string, error) {
+	credentials := p.GetAzureCredentials()
+	err := validateAzureCredentials(credentials)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerService/managedClusters", credentials.SubscriptionID, credentials.ResourceGroup), nil
+}
+
+func (p *AzureProvider) clusterPath(clusterName string) (string, error) {
+	clustersPath, err := p.clustersPath()
+	if err != nil {
+		return "", err
+	}
+
+	return clustersPath + "/" + clusterName, nil
+}
+
+func (p *AzureProvider) getAKSCluster(c context.Context, clusterName string) (*aksManagedCluster, error) {
+	clusterPath, err := p.clusterPath(clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	var cluster aksManagedCluster
+	err = p.do(c, p.getTokenSource(), http.MethodGet, clusterPath, aksAPIVersion, nil, &cluster)
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to describe AKS cluster")
+	}
+
+	return &cluster, nil
+}
+
+// do sends a request to the Azure Resource Manager API and decodes the JSON response into out, when set.
+func (p *AzureProvider) do(c context.Context, tokenSource oauth2.TokenSource, method, path, apiVersion string, body, out interface{}) error {
+	if tokenSource == nil {
+		return model.NewAppError(model.ErrorCodeInvalidCredentials, http.StatusUnauthorized, "No Azure service principal has been set")
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	url := strings.TrimSuffix(p.Endpoint, "/") + "/" + path + "?api-version=" + apiVersion
+	request, err := http.NewRequestWithContext(c, method, url, reader)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{
+		Transport: &oauth2.Transport{Source: tokenSource},
+		Timeout:   60 * time.Second,
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		apiErr := &azureAPIError{StatusCode: response.StatusCode, Code: http.StatusText(response.StatusCode)}
+		var errorBody struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.NewDecoder(response.Body).Decode(&errorBody) == nil {
+			apiErr.Message = errorBody.Error.Message
+			if errorBody.Error.Code != "" {
+				apiErr.Code = errorBody.Error.Code
+			}
+		}
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(response.Body).Decode(out)
+}
+
+func aksStatusToClusterStatus(provisioningState string) model.ClusterStatus {
+	switch provisioningState {
+	case "Creating":
+		return model.ClusterStatusCreating
+	case "Succeeded":
+		return model.ClusterStatusActive
+	case "Updating", "Upgrading", "Scaling", "Starting", "Stopping":
+		return model.ClusterStatusUpdating
+	case "Deleting":
+		return model.ClusterStatusDeleting
+	case "Failed", "Canceled":
+		return model.ClusterStatusFailed
+	default:
+		return model.ClusterStatus(provisioningState)
+	}
+}
+
+func aksClusterToCluster(aksCluster *aksManagedCluster) *model.Cluster {
+	cluster := &model.Cluster{
+		Name:              stringPtr(aksCluster.Name),
+		Id:                stringPtr(aksCluster.ID),
+		Arn:               stringPtr(aksCluster.ID),
+		Tags:              stringMapPtr(aksCluster.Tags),
+		ClusterNodegroups: []*model.ClusterNodegroup{},
+	}
+
+	properties := aksCluster.Properties
+	if properties == nil {
+		return cluster
+	}
+
+	cluster.Status = aksStatusToClusterStatus(properties.ProvisioningState)
+	cluster.Version = stringPtr(properties.CurrentKubernetesVersion)
+	if cluster.Version == nil {
+		cluster.Version = stringPtr(properties.KubernetesVersion)
+	}
+	if properties.FQDN != "" {
+		cluster.Endpoint = stringPtr("https://" + properties.FQDN)
+	}
+
+	for _, agentPool := range properties.AgentPoolProfiles {
+		cluster.ClusterNodegroups = append(cluster.ClusterNodegroups, aksAgentPoolToNodegroup(aksCluster.Name, agentPool))
+	}
+
+	return cluster
+}
+
+func aksAgentPoolToNodegroup(clusterName string, agentPool *aksAgentPoolProperties) *model.ClusterNodegroup {
+	nodegroup := &model.ClusterNodegroup{
+		ClusterName:    stringPtr(clusterName),
+		NodegroupName:  stringPtr(agentPool.Name),
+		AmiType:        stringPtr(agentPool.OSSKU),
+		ReleaseVersion: stringPtr(agentPool.OrchestratorVersion),
+		Status:         aksStatusToClusterStatus(agentPool.ProvisioningState),
+		Labels:         stringMapPtr(agentPool.NodeLabels),
+		Tags:           stringMapPtr(agentPool.Tags),
+		SubnetIds:      []*string{},
+	}
+
+	if agentPool.VMSize != "" {
+		nodegroup.InstanceTypes = []*string{stringPtr(agentPool.VMSize)}
+	}
+	if agentPool.VnetSubnetID != "" {
+		nodegroup.SubnetIds = append(nodegroup.SubnetIds, stringPtr(agentPool.VnetSubnetID))
+	}
+
+	if agentPool.EnableAutoScaling {
+		nodegroup.ScalingConfig = map[string]*string{
+			"minSize": stringPtr(fmt.Sprint(agentPool.MinCount)),
+			"maxSize": stringPtr(fmt.Sprint(agentPool.MaxCount)),
+		}
+	} else {
+		nodegroup.ScalingConfig = map[string]*string{
+			"desiredSize": stringPtr(fmt.Sprint(agentPool.Count)),
+		}
+	}
+
+	return nodegroup
+}
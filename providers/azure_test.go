@@ -0,0 +1,293 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAKSKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test-cluster
+  cluster:
+    server: https://test-cluster.hcp.eastus.azmk8s.io:443
+contexts:
+- name: test-cluster-admin
+  context:
+    cluster: test-cluster
+    user: clusterAdmin
+current-context: test-cluster-admin
+users:
+- name: clusterAdmin
+  user:
+    token: admin-token
+`
+
+// fakeARM is a minimal in-memory implementation of the AKS resource provider and the Azure AD token endpoint.
+type fakeARM struct {
+	lock       sync.Mutex
+	clusters   map[string]map[string]interface{}
+	agentPools map[string][]map[string]interface{}
+	requests   []string
+}
+
+func newFakeARM() *fakeARM {
+	return &fakeARM{
+		clusters:   map[string]map[string]interface{}{},
+		agentPools: map[string][]map[string]interface{}{},
+	}
+}
+
+func writeARMError(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"code": code, "message": code}})
+}
+
+func (f *fakeARM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.URL.Path == "/test-tenant/oauth2/v2.0/token" {
+		r.ParseForm()
+		if r.Form.Get("client_secret") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "fake-token", "token_type": "Bearer", "expires_in": 3600})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer fake-token" {
+		writeARMError(w, http.StatusUnauthorized, "AuthenticationFailed")
+		return
+	}
+
+	if r.URL.Path == "/subscriptions/test-subscription/resourcegroups/test-rg" {
+		json.NewEncoder(w).Encode(map[string]string{"name": "test-rg"})
+		return
+	}
+
+	// Paths look like /subscriptions/{sub}/resourceGroups/{rg}/providers/Microsoft.ContainerService/managedClusters[/{name}[/...]]
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) < 7 || parts[6] != "managedClusters" || r.URL.Query().Get("api-version") == "" {
+		writeARMError(w, http.StatusNotFound, "InvalidResourceType")
+		return
+	}
+
+	switch {
+	case len(parts) == 7 && r.Method == http.MethodGet:
+		clusters := []interface{}{}
+		for _, cluster := range f.clusters {
+			clusters = append(clusters, cluster)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"value": clusters})
+	case len(parts) == 8 && r.Method == http.MethodGet:
+		cluster, ok := f.clusters[parts[7]]
+		if !ok {
+			writeARMError(w, http.StatusNotFound, "ResourceNotFound")
+			return
+		}
+		json.NewEncoder(w).Encode(cluster)
+	case len(parts) == 8 && r.Method == http.MethodPut:
+		var cluster map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&cluster)
+		cluster["name"] = parts[7]
+		cluster["id"] = r.URL.Path
+		cluster["properties"].(map[string]interface{})["provisioningState"] = "Creating"
+		f.clusters[parts[7]] = cluster
+		json.NewEncoder(w).Encode(cluster)
+	case len(parts) == 9 && parts[8] == "listClusterAdminCredential" && r.Method == http.MethodPost:
+		if _, ok := f.clusters[parts[7]]; !ok {
+			writeARMError(w, http.StatusNotFound, "ResourceNotFound")
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"kubeconfigs": []map[string]string{
+			{"name": "clusterAdmin", "value": base64.StdEncoding.EncodeToString([]byte(testAKSKubeconfig))},
+		}})
+	case len(parts) == 9 && parts[8] == "agentPools" && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{"value": f.agentPools[parts[7]]})
+	case len(parts) == 10 && parts[8] == "agentPools" && r.Method == http.MethodGet:
+		for _, agentPool := range f.agentPools[parts[7]] {
+			if agentPool["name"] == parts[9] {
+				json.NewEncoder(w).Encode(agentPool)
+				return
+			}
+		}
+		writeARMError(w, http.StatusNotFound, "ResourceNotFound")
+	case len(parts) == 10 && parts[8] == "agentPools" && r.Method == http.MethodPut:
+		var agentPool map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&agentPool)
+		agentPool["name"] = parts[9]
+		agentPool["properties"].(map[string]interface{})["provisioningState"] = "Creating"
+		f.agentPools[parts[7]] = append(f.agentPools[parts[7]], agentPool)
+		json.NewEncoder(w).Encode(agentPool)
+	default:
+		writeARMError(w, http.StatusNotFound, "NotFound")
+	}
+}
+
+func (f *fakeARM) takeRequests() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	requests := f.requests
+	f.requests = nil
+	return requests
+}
+
+func TestAzureProvider(t *testing.T) {
+	fake := newFakeARM()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	credentials := &model.Credentials{
+		TenantID:       "test-tenant",
+		ClientID:       "client",
+		ClientSecret:   "secret",
+		SubscriptionID: "test-subscription",
+		ResourceGroup:  "test-rg",
+		Region:         "eastus",
+	}
+
+	provider := providers.NewAzureProvider(credentials)
+	provider.Endpoint = server.URL + "/"
+	provider.LoginEndpoint = server.URL + "/"
+	ctx := context.Background()
+	clustersPath := "/subscriptions/test-subscription/resourceGroups/test-rg/providers/Microsoft.ContainerService/managedClusters"
+
+	t.Run("ValidateCredentials", func(t *testing.T) {
+		valid, err := provider.ValidateCredentials(ctx, credentials)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("InvalidCredentials", func(t *testing.T) {
+		invalid := *credentials
+		invalid.ClientSecret = "wrong"
+		valid, err := provider.ValidateCredentials(ctx, &invalid)
+		assert.False(t, valid)
+
+		var appErr *model.AppError
+		require.True(t, errors.As(err, &appErr))
+		assert.Equal(t, model.ErrorCodeInvalidCredentials, appErr.Code)
+
+		_, err = provider.ValidateCredentials(ctx, &model.Credentials{TenantID: "test-tenant"})
+		require.True(t, errors.As(err, &appErr))
+		assert.Equal(t, model.ErrorCodeInvalidCredentials, appErr.Code)
+	})
+
+	t.Run("CreateCluster", func(t *testing.T) {
+		cluster, err := provider.CreateCluster(ctx, &model.CreateClusterRequest{
+			ClusterName:       aws.String("test-cluster"),
+			KubernetesVersion: aws.String("1.29"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "test-cluster", *cluster.Name)
+		assert.Equal(t, model.ClusterStatusCreating, cluster.Status)
+		assert.Equal(t, "1.29", *cluster.Version)
+		require.Len(t, cluster.ClusterNodegroups, 1)
+		assert.Equal(t, "system", *cluster.ClusterNodegroups[0].NodegroupName)
+
+		_, err = provider.CreateCluster(ctx, &model.CreateClusterRequest{ClusterName: aws.String("test-cluster")})
+		var appErr *model.AppError
+		require.True(t, errors.As(err, &appErr))
+		assert.Equal(t, model.ErrorCodeAlreadyExists, appErr.Code)
+	})
+
+	t.Run("ListClusters", func(t *testing.T) {
+		clusters, err := provider.ListClusters(ctx, "")
+		require.NoError(t, err)
+		require.Len(t, clusters, 1)
+		assert.Equal(t, "test-cluster", *clusters[0])
+
+		clusters, err = provider.ListClusters(ctx, "westeurope")
+		require.NoError(t, err)
+		assert.Empty(t, clusters)
+	})
+
+	t.Run("GetClusterNotFound", func(t *testing.T) {
+		_, err := provider.GetCluster(ctx, "missing")
+		var appErr *model.AppError
+		require.True(t, errors.As(err, &appErr))
+		assert.Equal(t, model.ErrorCodeNotFound, appErr.Code)
+	})
+
+	t.Run("Nodegroups", func(t *testing.T) {
+		nodegroup, err := provider.CreateNodegroup(ctx, "test-cluster", &model.CreateNodegroupRequest{
+			NodegroupName: "workers",
+			InstanceType:  "Standard_D4s_v3",
+			ScalingConfig: model.ScalingConfig{MinSize: 2, MaxSize: 4},
+			Labels:        map[string]string{"role": "mattermost"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "workers", *nodegroup.NodegroupName)
+		assert.Equal(t, model.ClusterStatusCreating, nodegroup.Status)
+
+		nodegroups, err := provider.GetNodegroups(ctx, "test-cluster")
+		require.NoError(t, err)
+		require.Len(t, nodegroups, 1)
+		assert.Equal(t, "workers", *nodegroups[0].NodegroupName)
+		assert.Equal(t, "Standard_D4s_v3", *nodegroups[0].InstanceTypes[0])
+		assert.Equal(t, "mattermost", *nodegroups[0].Labels["role"])
+		assert.Equal(t, "4", *nodegroups[0].ScalingConfig["maxSize"])
+
+		_, err = provider.CreateNodegroup(ctx, "test-cluster", &model.CreateNodegroupRequest{NodegroupName: "workers", InstanceType: "Standard_D2s_v3"})
+		var appErr *model.AppError
+		require.True(t, errors.As(err, &appErr))
+		assert.Equal(t, model.ErrorCodeAlreadyExists, appErr.Code)
+		assert.Equal(t, http.StatusConflict, appErr.StatusCode)
+	})
+
+	t.Run("GetKubeRestConfig", func(t *testing.T) {
+		config, err := provider.GetKubeRestConfig(ctx, "test-cluster")
+		require.NoError(t, err)
+		assert.Equal(t, "https://test-cluster.hcp.eastus.azmk8s.io:443", config.Host)
+		assert.Equal(t, "admin-token", config.BearerToken)
+	})
+
+	t.Run("StorageDriverHook", func(t *testing.T) {
+		hooks := provider.AddonHooks("cnpg", model.HookPhasePreInstall)
+		require.Len(t, hooks, 1)
+		assert.Equal(t, "azure-disk-csi-driver", hooks[0].Name)
+
+		// Clusters created by the bootstrapper already have the driver enabled
+		fake.takeRequests()
+		err := hooks[0].Run(ctx, "test-cluster")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"GET " + clustersPath + "/test-cluster"}, fake.takeRequests())
+
+		fake.lock.Lock()
+		fake.clusters["test-cluster"]["properties"].(map[string]interface{})["storageProfile"] = map[string]interface{}{
+			"diskCSIDriver": map[string]interface{}{"enabled": false},
+		}
+		fake.clusters["test-cluster"]["properties"].(map[string]interface{})["nodeResourceGroup"] = "MC_test"
+		fake.lock.Unlock()
+
+		err = hooks[0].Run(ctx, "test-cluster")
+		require.NoError(t, err)
+		assert.Contains(t, fake.takeRequests(), "PUT "+clustersPath+"/test-cluster")
+
+		fake.lock.Lock()
+		properties := fake.clusters["test-cluster"]["properties"].(map[string]interface{})
+		fake.lock.Unlock()
+		assert.Equal(t, true, properties["storageProfile"].(map[string]interface{})["diskCSIDriver"].(map[string]interface{})["enabled"])
+		assert.Equal(t, "MC_test", properties["nodeResourceGroup"], "fields unknown to the provider are kept")
+	})
+}
@@ -2,28 +2,236 @@ package providers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
 
 	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	mmclientv1beta1 "github.com/mattermost/mattermost-operator/pkg/client/v1beta1/clientset/versioned"
 	helmclient "github.com/mittwald/go-helm-client"
+	apixclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
-// TODO - consider some sort of AppError struct that can return a status code and a message + some other details, to aid in
-// error handling and response generation in the API handlers, and to make it easier to handle errors in the UI
+// CloudProvider is implemented by every supported provider. Implementations should return a *model.AppError,
+// for example through NewProviderError, so that API callers can tell why an operation failed.
+//
+// Operations that only some providers support are split into the capability interfaces below. Callers check for
+// them with a type assertion, and Capabilities reports them to clients.
 type CloudProvider interface {
 	SetCredentials(c context.Context, credentials *model.Credentials) error
-	SetRegion(c context.Context, region string) error
 	ValidateCredentials(c context.Context, creds *model.Credentials) (bool, error)
-	ListRoles(c context.Context) ([]*model.SupportedRolesResponse, error)
 	ListClusters(c context.Context, region string) ([]*string, error)
-	CreateCluster(c context.Context, create *model.CreateClusterRequest) (*model.Cluster, error)
 	GetCluster(c context.Context, name string) (*model.Cluster, error)
 	GetNodegroups(c context.Context, clusterName string) ([]*model.ClusterNodegroup, error)
-	CreateNodegroup(c context.Context, name string, create *model.CreateNodegroupRequest) (*model.ClusterNodegroup, error)
 	GetKubeRestConfig(c context.Context, clusterName string) (*rest.Config, error)
 	GetKubeConfig(c context.Context, clusterName string) (clientcmd.ClientConfig, error)
 	KubeClient(c context.Context, clusterName string) (*model.KubeClient, error)
 	HelmClient(c context.Context, clusterName string, namespace string) (helmclient.Client, error)
-	HelmFileStorePre(c context.Context, clusterName string, namespace string) error
+}
+
+// ClusterCreator is implemented by providers that can create clusters.
+type ClusterCreator interface {
+	CreateCluster(c context.Context, create *model.CreateClusterRequest) (*model.Cluster, error)
+}
+
+// NodegroupManager is implemented by providers that can add nodegroups to a cluster.
+type NodegroupManager interface {
+	CreateNodegroup(c context.Context, name string, create *model.CreateNodegroupRequest) (*model.ClusterNodegroup, error)
+}
+
+// NodegroupUpdater is implemented by providers that can change the scaling, labels, taints and release version of
+// a nodegroup. UpdateNodegroup returns once the changes have been applied.
+type NodegroupUpdater interface {
+	UpdateNodegroup(c context.Context, clusterName string, nodegroupName string, update *model.UpdateNodegroupRequest) (*model.ClusterNodegroup, error)
+}
+
+// NodegroupDeleter is implemented by providers that can remove a nodegroup from a cluster. DeleteNodegroup returns
+// once the nodegroup is gone.
+type NodegroupDeleter interface {
+	DeleteNodegroup(c context.Context, clusterName string, nodegroupName string) error
+}
+
+// RoleLister is implemented by providers whose clusters and nodegroups are created with an IAM role.
+type RoleLister interface {
+	ListRoles(c context.Context) ([]*model.SupportedRolesResponse, error)
+}
+
+// RegionSelector is implemented by providers whose clusters live in a region.
+type RegionSelector interface {
+	SetRegion(c context.Context, region string) error
+}
+
+// ClusterDeleter is implemented by providers that can delete clusters. DeleteCluster returns once the cluster is
+// gone.
+type ClusterDeleter interface {
+	DeleteCluster(c context.Context, name string) error
+}
+
+// ClusterUpgrader is implemented by providers that can upgrade the Kubernetes version of a cluster.
+type ClusterUpgrader interface {
+	UpgradeCluster(c context.Context, name string, version string) (*model.Cluster, error)
+}
+
+// ClusterWaiter is implemented by providers that can wait for a cluster to become active.
+type ClusterWaiter interface {
+	WaitForCluster(c context.Context, name string) (*model.Cluster, error)
+}
+
+// IngressDefaulter is implemented by providers that know how ingress-nginx should be exposed on their clusters.
+// NginxValues returns the helm values for the TLS mode and load balancer type of the request.
+type IngressDefaulter interface {
+	NginxValues(c context.Context, request *model.DeployNginxRequest) (map[string]interface{}, error)
+}
+
+// DatabaseProvisioner is implemented by providers that can create a managed PostgreSQL database reachable from a
+// cluster's nodes. CreateDatabase returns once the database is available. The request's MasterPassword must be set,
+// and stored by the caller beforehand.
+type DatabaseProvisioner interface {
+	CreateDatabase(c context.Context, clusterName string, create *model.CreateManagedDatabaseRequest) (*model.ManagedDatabase, error)
+}
+
+// BucketProvisioner is implemented by providers that can create an object storage bucket for the files of an
+// installation, along with credentials that can only use that bucket.
+type BucketProvisioner interface {
+	CreateBucket(c context.Context, clusterName string, create *model.CreateBucketRequest) (*model.Bucket, error)
+}
+
+// Hook is a named step that a provider runs before or after an add-on is installed, such as installing a storage
+// driver or tagging cloud resources.
+type Hook struct {
+	Name string
+	Run  func(c context.Context, clusterName string) error
+	// Chart names one of the bootstrapper's provider charts, which is installed once Run succeeds, from the same
+	// mirror as the add-ons. Run may be nil, or return ErrSkipChart when the cluster doesn't need the chart.
+	Chart string
+}
+
+// ErrSkipChart is returned by the Run of a hook when the chart of the hook doesn't need to be installed.
+var ErrSkipChart = errors.New("chart is not needed")
+
+// AddonHooker is implemented by providers that need to prepare a cluster for an add-on, or finish setting it up
+// afterwards. AddonHooks returns the hooks to run for the named add-on in the given phase, in order.
+type AddonHooker interface {
+	AddonHooks(addon string, phase model.HookPhase) []Hook
+}
+
+// CapabilityLimiter is implemented by providers that only support some of the operations they implement with
+// certain settings. LimitCapabilities turns off the capabilities that the provider's settings don't support, and
+// must accept a nil pointer receiver.
+type CapabilityLimiter interface {
+	LimitCapabilities(capabilities Capabilities) Capabilities
+}
+
+// CapabilitiesOf reports which capability interfaces provider implements, limited by its settings when it is a
+// CapabilityLimiter. provider may be a nil pointer.
+func CapabilitiesOf(provider CloudProvider) Capabilities {
+	_, createCluster := provider.(ClusterCreator)
+	_, deleteCluster := provider.(ClusterDeleter)
+	_, upgradeCluster := provider.(ClusterUpgrader)
+	_, nodegroups := provider.(NodegroupManager)
+	_, updateNodegroup := provider.(NodegroupUpdater)
+	_, deleteNodegroup := provider.(NodegroupDeleter)
+	_, regions := provider.(RegionSelector)
+	_, roles := provider.(RoleLister)
+	_, databases := provider.(DatabaseProvisioner)
+	_, buckets := provider.(BucketProvisioner)
+
+	capabilities := Capabilities{
+		CreateCluster:   createCluster,
+		DeleteCluster:   deleteCluster,
+		UpgradeCluster:  upgradeCluster,
+		Nodegroups:      nodegroups,
+		UpdateNodegroup: updateNodegroup,
+		DeleteNodegroup: deleteNodegroup,
+		Regions:         regions,
+		Roles:           roles,
+		Databases:       databases,
+		Buckets:         buckets,
+	}
+	if limiter, ok := provider.(CapabilityLimiter); ok {
+		capabilities = limiter.LimitCapabilities(capabilities)
+	}
+
+	return capabilities
+}
+
+// nginxServiceValues nests values for the ingress-nginx controller service the way the chart expects them.
+func nginxServiceValues(service map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"controller": map[string]interface{}{
+			"service": service,
+		},
+	}
+}
+
+// checkNginxRequest rejects the ACM TLS mode, which only works with AWS load balancers, and load balancer types
+// other than the given ones. platform names the kind of cluster in the error.
+func checkNginxRequest(platform string, request *model.DeployNginxRequest, loadBalancerTypes ...string) error {
+	if request.TLSMode == model.NginxTLSModeACM {
+		return model.NewInvalidRequestError(fmt.Sprintf("tlsMode acm is not supported on %s", platform))
+	}
+
+	if request.LoadBalancerType != "" && !slices.Contains(loadBalancerTypes, request.LoadBalancerType) {
+		return model.NewInvalidRequestError(fmt.Sprintf("loadBalancerType %s is not supported on %s", request.LoadBalancerType, platform))
+	}
+
+	return nil
+}
+
+// newKubeClient creates the clients the bootstrapper uses to talk to a cluster from its rest config.
+func newKubeClient(config *rest.Config) (*model.KubeClient, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	mattermostV1BetaClientset, err := mmclientv1beta1.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	apixClientset, err := apixclient.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.KubeClient{
+		Config:                    config,
+		Clientset:                 clientset,
+		ApixClientset:             apixClientset,
+		MattermostClientsetV1Beta: mattermostV1BetaClientset,
+		DynamicClient:             dynamicClient,
+	}, nil
+}
+
+// stringPtr returns a pointer to s, or nil when s is empty, for filling the optional fields of model types.
+func stringPtr(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
+
+func stringMapPtr(m map[string]string) map[string]*string {
+	if len(m) == 0 {
+		return nil
+	}
+
+	result := map[string]*string{}
+	for k, v := range m {
+		v := v
+		result[k] = &v
+	}
+
+	return result
 }
@@ -2,8 +2,8 @@ package providers
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -28,6 +28,13 @@ type CustomKubeProvider struct {
 	kubeClient      *model.KubeClient
 }
 
+func init() {
+	Register(ProviderInfo{
+		Name:        "custom",
+		DisplayName: "Custom Kubernetes",
+	}, GetCustomProvider)
+}
+
 var customProviderInstance *CustomKubeProvider
 var customProviderInstanceOnce sync.Once
 
@@ -96,17 +103,17 @@ func (p *CustomKubeProvider) SetCredentials(c context.Context, credentials *mode
 
 func (p *CustomKubeProvider) ValidateCredentials(c context.Context, creds *model.Credentials) (bool, error) {
 	if p.Credentials == nil {
-		return false, errors.New("no credentials set")
+		return false, model.NewAppError(model.ErrorCodeInvalidCredentials, http.StatusUnauthorized, "No kubeconfig has been set")
 	}
 
 	kubeClient, err := p.KubeClient(c, "")
 	if err != nil {
-		return false, fmt.Errorf("Unable to instantiate KubeClient: %w", err)
+		return false, ToAppError(err, "Unable to instantiate KubeClient")
 	}
 
 	_, err = kubeClient.Clientset.Discovery().ServerVersion()
 	if err != nil {
-		return false, fmt.Errorf("Unable to hit discovery endpoint for cluster: %w", err)
+		return false, NewProviderError(err, "Unable to hit discovery endpoint for cluster")
 	}
 
 	return true, nil
@@ -167,11 +174,6 @@ func (p *CustomKubeProvider) HelmClient(c context.Context, clusterName string, n
 	return k8sClient.GetHelmClient(c, namespace)
 }
 
-func (p *CustomKubeProvider) HelmFileStorePre(c context.Context, clusterName string, namespace string) error {
-	// No-op in custom Kubernetes provider
-	return nil
-}
-
 func (p *CustomKubeProvider) GetKubeConfig(c context.Context, clusterName string) (clientcmd.ClientConfig, error) {
 	credentials := p.GetCustomProviderCredentials()
 	// Parse the YAML data into a clientcmdapi.Config object
@@ -259,7 +261,7 @@ func (p *CustomKubeProvider) GetKubeRestConfig(c context.Context, clusterName st
 	}
 
 	if cluster == nil || authInfo == nil {
-		return nil, fmt.Errorf("invalid or incomplete kubeconfig")
+		return nil, model.NewAppError(model.ErrorCodeInvalidCredentials, http.StatusUnauthorized, "Invalid or incomplete kubeconfig")
 	}
 
 	tlsClientConfig := rest.TLSClientConfig{}
@@ -298,19 +300,8 @@ func (p *CustomKubeProvider) ListClusters(c context.Context, region string) ([]*
 	return clusters, nil
 }
 
-// unimplemented methods because custom doesn't support creation of clusters
-
-func (p *CustomKubeProvider) ListRoles(c context.Context) ([]*model.SupportedRolesResponse, error) {
-	return nil, fmt.Errorf("unsupported operation")
-}
-
-func (p *CustomKubeProvider) CreateCluster(c context.Context, create *model.CreateClusterRequest) (*model.Cluster, error) {
-	return nil, fmt.Errorf("unsupported operation")
-}
-
-func (p *CustomKubeProvider) SetRegion(c context.Context, region string) error {
-	return fmt.Errorf("unsupported operation")
-}
+// The custom provider connects to an existing cluster, so it implements none of the capability interfaces such as
+// ClusterCreator.
 
 func (p *CustomKubeProvider) GetNodegroups(c context.Context, clusterName string) ([]*model.ClusterNodegroup, error) {
 	cluster, err := p.GetCluster(c, clusterName)
@@ -321,10 +312,6 @@ func (p *CustomKubeProvider) GetNodegroups(c context.Context, clusterName string
 	return cluster.ClusterNodegroups, nil
 }
 
-func (p *CustomKubeProvider) CreateNodegroup(c context.Context, name string, create *model.CreateNodegroupRequest) (*model.ClusterNodegroup, error) {
-	return nil, fmt.Errorf("unsupported operation")
-}
-
 func kubeNodegroupToClusterNodegroup(node v1.Node) *model.ClusterNodegroup {
 	labels := map[string]*string{}
 	for k, v := range node.Labels {
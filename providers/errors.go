@@ -0,0 +1,175 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// awsErrorCodes maps AWS error codes that clients can act on to application error codes.
+var awsErrorCodes = map[string]model.ErrorCode{
+	"UnrecognizedClientException":          model.ErrorCodeInvalidCredentials,
+	"InvalidClientTokenId":                 model.ErrorCodeInvalidCredentials,
+	"SignatureDoesNotMatch":                model.ErrorCodeInvalidCredentials,
+	"ExpiredToken":                         model.ErrorCodeInvalidCredentials,
+	"ExpiredTokenException":                model.ErrorCodeInvalidCredentials,
+	"AccessDenied":                         model.ErrorCodeForbidden,
+	"AccessDeniedException":                model.ErrorCodeForbidden,
+	"UnauthorizedOperation":                model.ErrorCodeForbidden,
+	"ResourceNotFoundException":            model.ErrorCodeNotFound,
+	"NoSuchEntity":                         model.ErrorCodeNotFound,
+	"NotFoundException":                    model.ErrorCodeNotFound,
+	"ResourceInUseException":               model.ErrorCodeAlreadyExists,
+	"EntityAlreadyExists":                  model.ErrorCodeAlreadyExists,
+	"DBClusterAlreadyExistsFault":          model.ErrorCodeAlreadyExists,
+	"DBInstanceAlreadyExists":              model.ErrorCodeAlreadyExists,
+	"DBSubnetGroupAlreadyExists":           model.ErrorCodeAlreadyExists,
+	"InvalidPermission.Duplicate":          model.ErrorCodeAlreadyExists,
+	"BucketAlreadyExists":                  model.ErrorCodeAlreadyExists,
+	"InvalidParameterException":            model.ErrorCodeInvalidRequest,
+	"InvalidRequestException":              model.ErrorCodeInvalidRequest,
+	"ValidationError":                      model.ErrorCodeInvalidRequest,
+	"InvalidParameterValue":                model.ErrorCodeInvalidRequest,
+	"InvalidParameterCombination":          model.ErrorCodeInvalidRequest,
+	"UnsupportedAvailabilityZoneException": model.ErrorCodeInvalidRequest,
+	"ResourceLimitExceededException":       model.ErrorCodeLimitExceeded,
+	"LimitExceeded":                        model.ErrorCodeLimitExceeded,
+	"DBClusterQuotaExceededFault":          model.ErrorCodeLimitExceeded,
+	"InstanceQuotaExceeded":                model.ErrorCodeLimitExceeded,
+	"StorageQuotaExceeded":                 model.ErrorCodeLimitExceeded,
+	"ServiceUnavailableException":          model.ErrorCodeUnavailable,
+	request.CanceledErrorCode:              model.ErrorCodeTimeout,
+}
+
+var errorCodeStatus = map[model.ErrorCode]int{
+	model.ErrorCodeInvalidRequest:     http.StatusBadRequest,
+	model.ErrorCodeInvalidCredentials: http.StatusUnauthorized,
+	model.ErrorCodeForbidden:          http.StatusForbidden,
+	model.ErrorCodeNotFound:           http.StatusNotFound,
+	model.ErrorCodeAlreadyExists:      http.StatusConflict,
+	model.ErrorCodeConflict:           http.StatusConflict,
+	model.ErrorCodeLimitExceeded:      http.StatusUnprocessableEntity,
+	model.ErrorCodeThrottled:          http.StatusTooManyRequests,
+	model.ErrorCodeTimeout:            http.StatusGatewayTimeout,
+	model.ErrorCodeUnavailable:        http.StatusServiceUnavailable,
+	model.ErrorCodeNotImplemented:     http.StatusNotImplemented,
+	model.ErrorCodeInternal:           http.StatusInternalServerError,
+}
+
+// NewProviderError returns an AppError with the given message whose code is derived from err, so that provider
+// implementations can describe what failed without losing why.
+func NewProviderError(err error, message string) *model.AppError {
+	code := errorCode(err)
+	return model.NewAppError(code, errorCodeStatus[code], message).Wrap(err)
+}
+
+// ToAppError translates any error into an AppError. AppErrors anywhere in the chain are returned as they are,
+// AWS and Kubernetes API errors are mapped to a matching code, and anything else is an internal error.
+func ToAppError(err error, message string) *model.AppError {
+	var appErr *model.AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	return NewProviderError(err, message)
+}
+
+// httpStatusError is implemented by errors from REST APIs, such as GKE, that report an HTTP status code.
+type httpStatusError interface {
+	error
+	HTTPStatusCode() int
+}
+
+func httpStatusErrorCode(statusCode int) model.ErrorCode {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return model.ErrorCodeInvalidRequest
+	case http.StatusUnauthorized:
+		return model.ErrorCodeInvalidCredentials
+	case http.StatusForbidden:
+		return model.ErrorCodeForbidden
+	case http.StatusNotFound:
+		return model.ErrorCodeNotFound
+	case http.StatusConflict:
+		return model.ErrorCodeAlreadyExists
+	case http.StatusTooManyRequests:
+		return model.ErrorCodeThrottled
+	case http.StatusServiceUnavailable:
+		return model.ErrorCodeUnavailable
+	case http.StatusGatewayTimeout:
+		return model.ErrorCodeTimeout
+	default:
+		return model.ErrorCodeInternal
+	}
+}
+
+func newUnsupportedOperationError() *model.AppError {
+	return model.NewAppError(model.ErrorCodeNotImplemented, http.StatusNotImplemented, "Operation is not supported by this provider")
+}
+
+func errorCode(err error) model.ErrorCode {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		if request.IsErrorThrottle(aerr) {
+			return model.ErrorCodeThrottled
+		}
+		if code, ok := awsErrorCodes[aerr.Code()]; ok {
+			return code
+		}
+		if request.IsErrorRetryable(aerr) {
+			return model.ErrorCodeUnavailable
+		}
+	}
+
+	switch {
+	case apiErrors.IsNotFound(err):
+		return model.ErrorCodeNotFound
+	case apiErrors.IsAlreadyExists(err):
+		return model.ErrorCodeAlreadyExists
+	case apiErrors.IsConflict(err):
+		return model.ErrorCodeConflict
+	case apiErrors.IsInvalid(err), apiErrors.IsBadRequest(err):
+		return model.ErrorCodeInvalidRequest
+	case apiErrors.IsUnauthorized(err):
+		return model.ErrorCodeInvalidCredentials
+	case apiErrors.IsForbidden(err):
+		return model.ErrorCodeForbidden
+	case apiErrors.IsTooManyRequests(err):
+		return model.ErrorCodeThrottled
+	case apiErrors.IsTimeout(err), apiErrors.IsServerTimeout(err):
+		return model.ErrorCodeTimeout
+	case apiErrors.IsServiceUnavailable(err):
+		return model.ErrorCodeUnavailable
+	}
+
+	var statusErr httpStatusError
+	if errors.As(err, &statusErr) {
+		return httpStatusErrorCode(statusErr.HTTPStatusCode())
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return model.ErrorCodeTimeout
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return model.ErrorCodeTimeout
+		}
+		return model.ErrorCodeUnavailable
+	}
+
+	// Helm does not return typed errors when a chart repository can't be reached
+	if err != nil && strings.Contains(err.Error(), "cannot be reached") {
+		return model.ErrorCodeUnavailable
+	}
+
+	return model.ErrorCodeInternal
+}
@@ -0,0 +1,53 @@
+package providers_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	"github.com/stretchr/testify/assert"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestToAppError(t *testing.T) {
+	testCases := []struct {
+		name       string
+		err        error
+		code       model.ErrorCode
+		statusCode int
+		retryable  bool
+	}{
+		{"AWSInvalidCredentials", awserr.New("InvalidClientTokenId", "The security token included in the request is invalid", nil), model.ErrorCodeInvalidCredentials, http.StatusUnauthorized, false},
+		{"AWSNotFound", awserr.New("ResourceNotFoundException", "No cluster found for name: test", nil), model.ErrorCodeNotFound, http.StatusNotFound, false},
+		{"AWSThrottled", awserr.New("ThrottlingException", "Rate exceeded", nil), model.ErrorCodeThrottled, http.StatusTooManyRequests, true},
+		{"KubernetesNotFound", apiErrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, "test"), model.ErrorCodeNotFound, http.StatusNotFound, false},
+		{"KubernetesAlreadyExists", apiErrors.NewAlreadyExists(schema.GroupResource{Resource: "namespaces"}, "test"), model.ErrorCodeAlreadyExists, http.StatusConflict, false},
+		{"KubernetesForbidden", apiErrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "test", errors.New("denied")), model.ErrorCodeForbidden, http.StatusForbidden, false},
+		{"WrappedKubernetesError", fmt.Errorf("failed to create namespace: %w", apiErrors.NewAlreadyExists(schema.GroupResource{Resource: "namespaces"}, "test")), model.ErrorCodeAlreadyExists, http.StatusConflict, false},
+		{"HelmRepoUnreachable", errors.New(`looks like "https://example.com" is not a valid chart repository or cannot be reached`), model.ErrorCodeUnavailable, http.StatusServiceUnavailable, true},
+		{"Unknown", errors.New("something went wrong"), model.ErrorCodeInternal, http.StatusInternalServerError, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			appErr := providers.ToAppError(tc.err, "Failed to do something")
+			assert.Equal(t, tc.code, appErr.Code)
+			assert.Equal(t, tc.statusCode, appErr.StatusCode)
+			assert.Equal(t, tc.retryable, appErr.Retryable)
+			assert.Equal(t, "Failed to do something", appErr.Message)
+			assert.Equal(t, tc.err.Error(), appErr.Details)
+			assert.ErrorIs(t, appErr, tc.err)
+		})
+	}
+
+	t.Run("AppErrorIsKept", func(t *testing.T) {
+		original := model.NewNotFoundError("Job not found")
+		appErr := providers.ToAppError(fmt.Errorf("wrapped: %w", original), "Failed to do something")
+		assert.Same(t, original, appErr)
+	})
+}
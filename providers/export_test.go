@@ -0,0 +1,8 @@
+package providers
+
+// Exported for the tests of the providers_test package.
+var (
+	AWSScalingConfigUpdate = awsScalingConfigUpdate
+	AWSLabelsUpdate        = awsLabelsUpdate
+	AWSTaintsUpdate        = awsTaintsUpdate
+)
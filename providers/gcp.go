@@ -0,0 +1,732 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	helmclient "github.com/mittwald/go-helm-client"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	gkeDefaultEndpoint    = "https://container.googleapis.com/v1/"
+	gcpDefaultTokenURL    = "https://oauth2.googleapis.com/token"
+	gcpCloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// GCPProvider manages GKE clusters through the GKE REST API, authenticating with a service account key.
+type GCPProvider struct {
+	Credentials     *model.Credentials
+	credentialsLock *sync.Mutex
+	// Endpoint is the base URL of the GKE API. It can be pointed at a fake server for testing.
+	Endpoint    string
+	tokenSource oauth2.TokenSource
+}
+
+// gcpServiceAccount holds the fields of a service account key file that are needed to authenticate.
+type gcpServiceAccount struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// gkeCluster is the subset of the GKE Cluster resource used by the bootstrapper.
+type gkeCluster struct {
+	Name                  string            `json:"name"`
+	ID                    string            `json:"id,omitempty"`
+	SelfLink              string            `json:"selfLink,omitempty"`
+	Location              string            `json:"location,omitempty"`
+	Status                string            `json:"status,omitempty"`
+	Endpoint              string            `json:"endpoint,omitempty"`
+	InitialClusterVersion string            `json:"initialClusterVersion,omitempty"`
+	CurrentMasterVersion  string            `json:"currentMasterVersion,omitempty"`
+	InitialNodeCount      int64             `json:"initialNodeCount,omitempty"`
+	Network               string            `json:"network,omitempty"`
+	Subnetwork            string            `json:"subnetwork,omitempty"`
+	CreateTime            string            `json:"createTime,omitempty"`
+	ResourceLabels        map[string]string `json:"resourceLabels,omitempty"`
+	MasterAuth            *gkeMasterAuth    `json:"masterAuth,omitempty"`
+	AddonsConfig          *gkeAddonsConfig  `json:"addonsConfig,omitempty"`
+	NodePools             []*gkeNodePool    `json:"nodePools,omitempty"`
+}
+
+type gkeMasterAuth struct {
+	ClusterCaCertificate string `json:"clusterCaCertificate,omitempty"`
+}
+
+type gkeAddonsConfig struct {
+	GcePersistentDiskCsiDriverConfig *gkeAddonEnabled `json:"gcePersistentDiskCsiDriverConfig,omitempty"`
+}
+
+type gkeAddonEnabled struct {
+	Enabled bool `json:"enabled"`
+}
+
+type gkeNodePool struct {
+	Name             string                `json:"name"`
+	Status           string                `json:"status,omitempty"`
+	Version          string                `json:"version,omitempty"`
+	InitialNodeCount int64                 `json:"initialNodeCount,omitempty"`
+	Config           *gkeNodeConfig        `json:"config,omitempty"`
+	Autoscaling      *gkeNodePoolAutoscale `json:"autoscaling,omitempty"`
+	Locations        []string              `json:"locations,omitempty"`
+}
+
+type gkeNodeConfig struct {
+	MachineType    string            `json:"machineType,omitempty"`
+	ImageType      string            `json:"imageType,omitempty"`
+	ServiceAccount string            `json:"serviceAccount,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	ResourceLabels map[string]string `json:"resourceLabels,omitempty"`
+}
+
+type gkeNodePoolAutoscale struct {
+	Enabled      bool  `json:"enabled"`
+	MinNodeCount int64 `json:"minNodeCount,omitempty"`
+	MaxNodeCount int64 `json:"maxNodeCount,omitempty"`
+}
+
+// gcpAPIError is the error body returned by Google Cloud REST APIs.
+type gcpAPIError struct {
+	StatusCode int
+	Status     string
+	Message    string
+}
+
+func (e *gcpAPIError) Error() string {
+	return fmt.Sprintf("GKE API returned %d %s: %s", e.StatusCode, e.Status, e.Message)
+}
+
+func (e *gcpAPIError) HTTPStatusCode() int {
+	return e.StatusCode
+}
+
+func init() {
+	Register(ProviderInfo{
+		Name:        "gcp",
+		DisplayName: "Google GKE",
+	}, GetGCPProvider)
+}
+
+var gcpProviderInstance *GCPProvider
+var gcpProviderInstanceOnce sync.Once
+
+func GetGCPProvider(credentials *model.Credentials) *GCPProvider {
+	gcpProviderInstanceOnce.Do(func() {
+		gcpProviderInstance = NewGCPProvider(credentials)
+	})
+
+	return gcpProviderInstance
+}
+
+// NewGCPProvider returns a GCPProvider for the given credentials. Invalid credentials are reported when the
+// provider is first used, or by ValidateCredentials.
+func NewGCPProvider(credentials *model.Credentials) *GCPProvider {
+	p := &GCPProvider{
+		credentialsLock: &sync.Mutex{},
+		Endpoint:        gkeDefaultEndpoint,
+	}
+
+	if credentials == nil || credentials.ServiceAccountJSON == "" {
+		credentials = gcpCredentialsFromEnv()
+	}
+	err := p.SetCredentials(context.Background(), credentials)
+	if err != nil {
+		// Keep the project and region, so that only the key has to be set later
+		p.Credentials = credentials
+	}
+
+	return p
+}
+
+// gcpCredentialsFromEnv reads the service account key from GOOGLE_APPLICATION_CREDENTIALS, like the gcloud tools do.
+func gcpCredentialsFromEnv() *model.Credentials {
+	credentials := &model.Credentials{
+		ProjectID: os.Getenv("GOOGLE_CLOUD_PROJECT"),
+		Region:    os.Getenv("GOOGLE_CLOUD_REGION"),
+	}
+
+	keyFile := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if keyFile == "" {
+		return credentials
+	}
+
+	key, err := os.ReadFile(keyFile)
+	if err == nil {
+		credentials.ServiceAccountJSON = string(key)
+	}
+
+	return credentials
+}
+
+func newGCPTokenSource(credentials *model.Credentials) (oauth2.TokenSource, *gcpServiceAccount, error) {
+	if credentials == nil || credentials.ServiceAccountJSON == "" {
+		return nil, nil, model.NewAppError(model.ErrorCodeInvalidCredentials, http.StatusUnauthorized, "No GCP service account key has been set")
+	}
+
+	var serviceAccount gcpServiceAccount
+	err := json.Unmarshal([]byte(credentials.ServiceAccountJSON), &serviceAccount)
+	if err != nil {
+		return nil, nil, model.NewAppError(model.ErrorCodeInvalidCredentials, http.StatusUnauthorized, "GCP service account key is not valid JSON").Wrap(err)
+	}
+
+	if serviceAccount.Type != "service_account" || serviceAccount.ClientEmail == "" || serviceAccount.PrivateKey == "" {
+		return nil, nil, model.NewAppError(model.ErrorCodeInvalidCredentials, http.StatusUnauthorized, "GCP credentials must be a service account key file")
+	}
+
+	tokenURL := serviceAccount.TokenURI
+	if tokenURL == "" {
+		tokenURL = gcpDefaultTokenURL
+	}
+
+	config := &jwt.Config{
+		Email:        serviceAccount.ClientEmail,
+		PrivateKey:   []byte(serviceAccount.PrivateKey),
+		PrivateKeyID: serviceAccount.PrivateKeyID,
+		Scopes:       []string{gcpCloudPlatformScope},
+		TokenURL:     tokenURL,
+	}
+
+	return config.TokenSource(context.Background()), &serviceAccount, nil
+}
+
+func (p *GCPProvider) GetGCPCredentials() *model.Credentials {
+	p.credentialsLock.Lock()
+	defer p.credentialsLock.Unlock()
+	return p.Credentials
+}
+
+// SetCredentials replaces the credentials of the provider, unless their service account key can't be used, in which
+// case the current ones are kept.
+func (p *GCPProvider) SetCredentials(c context.Context, credentials *model.Credentials) error {
+	tokenSource, serviceAccount, err := newGCPTokenSource(credentials)
+	if err != nil {
+		return err
+	}
+
+	if credentials.ProjectID == "" {
+		credentials.ProjectID = serviceAccount.ProjectID
+	}
+
+	p.credentialsLock.Lock()
+	defer p.credentialsLock.Unlock()
+
+	p.Credentials = credentials
+	p.tokenSource = tokenSource
+
+	return nil
+}
+
+func (p *GCPProvider) SetRegion(c context.Context, region string) error {
+	p.credentialsLock.Lock()
+	defer p.credentialsLock.Unlock()
+
+	if p.Credentials == nil {
+		p.Credentials = &model.Credentials{}
+	}
+	p.Credentials.Region = region
+
+	return nil
+}
+
+func (p *GCPProvider) ValidateCredentials(c context.Context, creds *model.Credentials) (bool, error) {
+	tokenSource, serviceAccount, err := newGCPTokenSource(creds)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tokenSource.Token()
+	if err != nil {
+		return false, model.NewAppError(model.ErrorCodeInvalidCredentials, http.StatusUnauthorized, "GCP rejected the service account key").Wrap(err)
+	}
+
+	projectID := creds.ProjectID
+	if projectID == "" {
+		projectID = serviceAccount.ProjectID
+	}
+
+	var list struct {
+		Clusters []*gkeCluster `json:"clusters"`
+	}
+	err = p.do(c, tokenSource, http.MethodGet, fmt.Sprintf("projects/%s/locations/-/clusters", projectID), nil, &list)
+	if err != nil {
+		return false, NewProviderError(err, "Credentials are valid but lack GKE permissions")
+	}
+
+	return true, nil
+}
+
+func (p *GCPProvider) ListClusters(c context.Context, region string) ([]*string, error) {
+	if region == "" {
+		region = p.GetGCPCredentials().Region
+	}
+	if region == "" {
+		region = "-"
+	}
+
+	var list struct {
+		Clusters []*gkeCluster `json:"clusters"`
+	}
+	err := p.do(c, p.getTokenSource(), http.MethodGet, p.locationPath(region)+"/clusters", nil, &list)
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to list GKE clusters")
+	}
+
+	clusters := []*string{}
+	for _, cluster := range list.Clusters {
+		name := cluster.Name
+		clusters = append(clusters, &name)
+	}
+
+	return clusters, nil
+}
+
+func (p *GCPProvider) CreateCluster(c context.Context, create *model.CreateClusterRequest) (*model.Cluster, error) {
+	if create.ClusterName == nil || *create.ClusterName == "" {
+		return nil, model.NewInvalidParamError("clusterName")
+	}
+
+	clustersPath, err := p.clustersPath()
+	if err != nil {
+		return nil, err
+	}
+
+	cluster := &gkeCluster{
+		Name:             *create.ClusterName,
+		InitialNodeCount: 1,
+		AddonsConfig: &gkeAddonsConfig{
+			GcePersistentDiskCsiDriverConfig: &gkeAddonEnabled{Enabled: true},
+		},
+	}
+	if create.KubernetesVersion != nil {
+		cluster.InitialClusterVersion = *create.KubernetesVersion
+	}
+	if len(create.SubnetIDs) > 0 && create.SubnetIDs[0] != nil {
+		cluster.Subnetwork = *create.SubnetIDs[0]
+	}
+
+	err = p.do(c, p.getTokenSource(), http.MethodPost, clustersPath, map[string]interface{}{"cluster": cluster}, nil)
+	if err != nil {
+		logger.FromContext(c).WithError(err).Error("Failed to create GKE cluster")
+		return nil, NewProviderError(err, "Failed to create GKE cluster")
+	}
+
+	// The create call returns an operation, but the cluster can be described as soon as it has been accepted
+	result, err := p.GetCluster(c, *create.ClusterName)
+	if err != nil {
+		logger.FromContext(c).WithError(err).Warn("Failed to describe GKE cluster after creating it")
+		return gkeClusterToCluster(cluster), nil
+	}
+
+	return result, nil
+}
+
+func (p *GCPProvider) GetCluster(c context.Context, name string) (*model.Cluster, error) {
+	cluster, err := p.getGKECluster(c, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return gkeClusterToCluster(cluster), nil
+}
+
+func (p *GCPProvider) GetNodegroups(c context.Context, clusterName string) ([]*model.ClusterNodegroup, error) {
+	clusterPath, err := p.clusterPath(clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		NodePools []*gkeNodePool `json:"nodePools"`
+	}
+	err = p.do(c, p.getTokenSource(), http.MethodGet, clusterPath+"/nodePools", nil, &list)
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to list GKE node pools")
+	}
+
+	nodegroups := []*model.ClusterNodegroup{}
+	for _, nodePool := range list.NodePools {
+		nodegroups = append(nodegroups, gkeNodePoolToNodegroup(clusterName, nodePool))
+	}
+
+	return nodegroups, nil
+}
+
+func (p *GCPProvider) CreateNodegroup(c context.Context, name string, create *model.CreateNodegroupRequest) (*model.ClusterNodegroup, error) {
+	clusterPath, err := p.clusterPath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	initialNodeCount := create.ScalingConfig.MinSize
+	if initialNodeCount < 1 {
+		initialNodeCount = 1
+	}
+
+	nodePool := &gkeNodePool{
+		Name:             create.NodegroupName,
+		InitialNodeCount: initialNodeCount,
+		Version:          create.ReleaseVersion,
+		Config: &gkeNodeConfig{
+			MachineType:    create.InstanceType,
+			ImageType:      create.AMIType,
+			ServiceAccount: create.RoleARN,
+			Labels:         create.Labels,
+			ResourceLabels: create.Tags,
+		},
+		Autoscaling: &gkeNodePoolAutoscale{
+			Enabled:      true,
+			MinNodeCount: create.ScalingConfig.MinSize,
+			MaxNodeCount: create.ScalingConfig.MaxSize,
+		},
+	}
+
+	err = p.do(c, p.getTokenSource(), http.MethodPost, clusterPath+"/nodePools", map[string]interface{}{"nodePool": nodePool}, nil)
+	if err != nil {
+		logger.FromContext(c).WithError(err).Error("Failed to create GKE node pool")
+		return nil, NewProviderError(err, "Failed to create GKE node pool")
+	}
+
+	nodePool.Status = "PROVISIONING"
+	return gkeNodePoolToNodegroup(name, nodePool), nil
+}
+
+func (p *GCPProvider) GetKubeRestConfig(c context.Context, clusterName string) (*rest.Config, error) {
+	cluster, err := p.getGKECluster(c, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := gkeClusterCA(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenSource := p.getTokenSource()
+	config := &rest.Config{
+		Host: "https://" + cluster.Endpoint,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: ca,
+		},
+		// Authenticate every request with a fresh access token, since they expire after an hour
+		WrapTransport: func(rt http.RoundTripper) http.RoundTripper {
+			return &oauth2.Transport{Source: tokenSource, Base: rt}
+		},
+	}
+
+	return config, nil
+}
+
+func (p *GCPProvider) GetKubeConfig(c context.Context, clusterName string) (clientcmd.ClientConfig, error) {
+	cluster, err := p.getGKECluster(c, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	ca, err := gkeClusterCA(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := p.getTokenSource().Token()
+	if err != nil {
+		return nil, model.NewAppError(model.ErrorCodeInvalidCredentials, http.StatusUnauthorized, "Failed to get a GCP access token").Wrap(err)
+	}
+
+	config := api.NewConfig()
+	config.Clusters[cluster.Name] = &api.Cluster{
+		Server:                   "https://" + cluster.Endpoint,
+		CertificateAuthorityData: ca,
+	}
+
+	config.AuthInfos[cluster.Name] = &api.AuthInfo{
+		Token: token.AccessToken,
+	}
+
+	config.Contexts[cluster.Name] = &api.Context{
+		Cluster:  cluster.Name,
+		AuthInfo: cluster.Name,
+	}
+
+	config.CurrentContext = cluster.Name
+
+	return clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}), nil
+}
+
+func (p *GCPProvider) KubeClient(c context.Context, clusterName string) (*model.KubeClient, error) {
+	config, err := p.GetKubeRestConfig(c, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	return newKubeClient(config)
+}
+
+func (p *GCPProvider) HelmClient(c context.Context, clusterName string, namespace string) (helmclient.Client, error) {
+	k8sClient, err := p.KubeClient(c, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	return k8sClient.GetHelmClient(c, namespace)
+}
+
+// enablePersistentDiskCSIDriver makes sure the GCE persistent disk CSI driver is enabled on the cluster. GKE ships
+// the driver as a managed add-on, so unlike on EKS there is no chart to install.
+func (p *GCPProvider) enablePersistentDiskCSIDriver(c context.Context, clusterName string) error {
+	cluster, err := p.getGKECluster(c, clusterName)
+	if err != nil {
+		return err
+	}
+
+	if cluster.AddonsConfig != nil && cluster.AddonsConfig.GcePersistentDiskCsiDriverConfig != nil && cluster.AddonsConfig.GcePersistentDiskCsiDriverConfig.Enabled {
+		logger.FromContext(c).Info("GCE persistent disk CSI driver is already enabled")
+		return nil
+	}
+
+	clusterPath, err := p.clusterPath(clusterName)
+	if err != nil {
+		return err
+	}
+
+	logger.FromContext(c).Info("Enabling the GCE persistent disk CSI driver add-on")
+	addons := map[string]interface{}{
+		"addonsConfig": &gkeAddonsConfig{
+			GcePersistentDiskCsiDriverConfig: &gkeAddonEnabled{Enabled: true},
+		},
+	}
+	err = p.do(c, p.getTokenSource(), http.MethodPost, clusterPath+":setAddons", addons, nil)
+	if err != nil {
+		return NewProviderError(err, "Failed to enable the GCE persistent disk CSI driver")
+	}
+
+	return nil
+}
+
+// AddonHooks enables the persistent disk CSI driver before CNPG or MinIO is installed.
+func (p *GCPProvider) AddonHooks(addon string, phase model.HookPhase) []Hook {
+	if (addon == "cnpg" || addon == "minio") && phase == model.HookPhasePreInstall {
+		return []Hook{{Name: "gce-pd-csi-driver", Run: p.enablePersistentDiskCSIDriver}}
+	}
+
+	return nil
+}
+
+// NginxValues exposes ingress-nginx through a passthrough network load balancer, internal to the VPC if requested.
+func (p *GCPProvider) NginxValues(c context.Context, request *model.DeployNginxRequest) (map[string]interface{}, error) {
+	err := checkNginxRequest("GKE", request, model.NginxLoadBalancerNLB, model.NginxLoadBalancerInternal)
+	if err != nil {
+		return nil, err
+	}
+
+	annotations := map[string]interface{}{}
+	if request.LoadBalancerType == model.NginxLoadBalancerInternal {
+		annotations["networking.gke.io/load-balancer-type"] = "Internal"
+	}
+
+	return nginxServiceValues(map[string]interface{}{"annotations": annotations}), nil
+}
+
+func (p *GCPProvider) getTokenSource() oauth2.TokenSource {
+	p.credentialsLock.Lock()
+	defer p.credentialsLock.Unlock()
+	return p.tokenSource
+}
+
+func (p *GCPProvider) locationPath(location string) string {
+	return fmt.Sprintf("projects/%s/locations/%s", p.GetGCPCredentials().ProjectID, location)
+}
+
+// clustersPath returns the resource path of the clusters in the configured location, which may be a region or a
+// zone.
+func (p *GCPProvider) clustersPath() (string, error) {
+	credentials := p.GetGCPCredentials()
+	if credentials == nil || credentials.Region == "" {
+		return "", model.NewInvalidRequestError("A GKE region or zone must be set")
+	}
+
+	return p.locationPath(credentials.Region) + "/clusters", nil
+}
+
+func (p *GCPProvider) clusterPath(clusterName string) (string, error) {
+	clustersPath, err := p.clustersPath()
+	if err != nil {
+		return "", err
+	}
+
+	return clustersPath + "/" + clusterName, nil
+}
+
+func (p *GCPProvider) getGKECluster(c context.Context, clusterName string) (*gkeCluster, error) {
+	clusterPath, err := p.clusterPath(clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	var cluster gkeCluster
+	err = p.do(c, p.getTokenSource(), http.MethodGet, clusterPath, nil, &cluster)
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to describe GKE cluster")
+	}
+
+	return &cluster, nil
+}
+
+// do sends a request to the GKE API and decodes the JSON response into out, when set.
+func (p *GCPProvider) do(c context.Context, tokenSource oauth2.TokenSource, method, path string, body, out interface{}) error {
+	if tokenSource == nil {
+		return model.NewAppError(model.ErrorCodeInvalidCredentials, http.StatusUnauthorized, "No GCP service account key has been set")
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequestWithContext(c, method, strings.TrimSuffix(p.Endpoint, "/")+"/"+path, reader)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{
+		Transport: &oauth2.Transport{Source: tokenSource},
+		Timeout:   60 * time.Second,
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		apiErr := &gcpAPIError{StatusCode: response.StatusCode, Status: http.StatusText(response.StatusCode)}
+		var errorBody struct {
+			Error struct {
+				Message string `json:"message"`
+				Status  string `json:"status"`
+			} `json:"error"`
+		}
+		if json.NewDecoder(response.Body).Decode(&errorBody) == nil {
+			apiErr.Message = errorBody.Error.Message
+			if errorBody.Error.Status != "" {
+				apiErr.Status = errorBody.Error.Status
+			}
+		}
+		return apiErr
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(response.Body).Decode(out)
+}
+
+func gkeClusterCA(cluster *gkeCluster) ([]byte, error) {
+	if cluster.MasterAuth == nil || cluster.Endpoint == "" {
+		return nil, model.NewAppError(model.ErrorCodeConflict, http.StatusConflict, fmt.Sprintf("GKE cluster %s has no endpoint yet", cluster.Name))
+	}
+
+	ca, err := base64.StdEncoding.DecodeString(cluster.MasterAuth.ClusterCaCertificate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode cluster CA certificate: %w", err)
+	}
+
+	return ca, nil
+}
+
+func gkeStatusToClusterStatus(status string) model.ClusterStatus {
+	switch status {
+	case "PROVISIONING":
+		return model.ClusterStatusCreating
+	case "RUNNING":
+		return model.ClusterStatusActive
+	case "RECONCILING":
+		return model.ClusterStatusUpdating
+	case "STOPPING":
+		return model.ClusterStatusDeleting
+	case "ERROR", "DEGRADED":
+		return model.ClusterStatusFailed
+	default:
+		return model.ClusterStatus(status)
+	}
+}
+
+func gkeClusterToCluster(gkeCluster *gkeCluster) *model.Cluster {
+	cluster := &model.Cluster{
+		Name:              stringPtr(gkeCluster.Name),
+		Id:                stringPtr(gkeCluster.ID),
+		Arn:               stringPtr(gkeCluster.SelfLink),
+		Endpoint:          stringPtr(gkeCluster.Endpoint),
+		Version:           stringPtr(gkeCluster.CurrentMasterVersion),
+		Status:            gkeStatusToClusterStatus(gkeCluster.Status),
+		Tags:              stringMapPtr(gkeCluster.ResourceLabels),
+		ClusterNodegroups: []*model.ClusterNodegroup{},
+	}
+
+	if cluster.Version == nil {
+		cluster.Version = stringPtr(gkeCluster.InitialClusterVersion)
+	}
+
+	if createdAt, err := time.Parse(time.RFC3339, gkeCluster.CreateTime); err == nil {
+		cluster.CreatedAt = &createdAt
+	}
+
+	for _, nodePool := range gkeCluster.NodePools {
+		cluster.ClusterNodegroups = append(cluster.ClusterNodegroups, gkeNodePoolToNodegroup(gkeCluster.Name, nodePool))
+	}
+
+	return cluster
+}
+
+func gkeNodePoolToNodegroup(clusterName string, nodePool *gkeNodePool) *model.ClusterNodegroup {
+	nodegroup := &model.ClusterNodegroup{
+		ClusterName:    stringPtr(clusterName),
+		NodegroupName:  stringPtr(nodePool.Name),
+		ReleaseVersion: stringPtr(nodePool.Version),
+		Status:         gkeStatusToClusterStatus(nodePool.Status),
+		SubnetIds:      []*string{},
+	}
+
+	if nodePool.Config != nil {
+		nodegroup.AmiType = stringPtr(nodePool.Config.ImageType)
+		nodegroup.NodeRole = stringPtr(nodePool.Config.ServiceAccount)
+		nodegroup.Labels = stringMapPtr(nodePool.Config.Labels)
+		nodegroup.Tags = stringMapPtr(nodePool.Config.ResourceLabels)
+		if nodePool.Config.MachineType != "" {
+			nodegroup.InstanceTypes = []*string{stringPtr(nodePool.Config.MachineType)}
+		}
+	}
+
+	if nodePool.Autoscaling != nil && nodePool.Autoscaling.Enabled {
+		nodegroup.ScalingConfig = map[string]*string{
+			"minSize": stringPtr(fmt.Sprint(nodePool.Autoscaling.MinNodeCount)),
+			"maxSize": stringPtr(fmt.Sprint(nodePool.Autoscaling.MaxNodeCount)),
+		}
+	}
+
+	return nodegroup
+}
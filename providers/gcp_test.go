@@ -0,0 +1,258 @@
+package providers_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGKE is a minimal in-memory implementation of the GKE API and the Google token endpoint.
+type fakeGKE struct {
+	lock      sync.Mutex
+	clusters  map[string]map[string]interface{}
+	nodePools map[string][]map[string]interface{}
+	requests  []string
+}
+
+func newFakeGKE() *fakeGKE {
+	return &fakeGKE{
+		clusters:  map[string]map[string]interface{}{},
+		nodePools: map[string][]map[string]interface{}{},
+	}
+}
+
+func (f *fakeGKE) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.URL.Path == "/token" {
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "fake-token", "token_type": "Bearer", "expires_in": 3600})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer fake-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"message": "missing token", "status": "UNAUTHENTICATED"}})
+		return
+	}
+
+	// Paths look like /projects/{project}/locations/{location}/clusters[/{name}[/nodePools|:setAddons]]
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) < 5 || parts[0] != "projects" || parts[2] != "locations" || parts[4] != "clusters" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case len(parts) == 5 && r.Method == http.MethodGet:
+		clusters := []interface{}{}
+		for _, cluster := range f.clusters {
+			clusters = append(clusters, cluster)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"clusters": clusters})
+	case len(parts) == 5 && r.Method == http.MethodPost:
+		var body struct {
+			Cluster map[string]interface{} `json:"cluster"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		name := body.Cluster["name"].(string)
+		if _, ok := f.clusters[name]; ok {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"message": "Already exists", "status": "ALREADY_EXISTS"}})
+			return
+		}
+		body.Cluster["status"] = "PROVISIONING"
+		body.Cluster["currentMasterVersion"] = "1.29.1-gke.1"
+		f.clusters[name] = body.Cluster
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": "operation-1", "status": "RUNNING"})
+	case len(parts) == 6 && strings.HasSuffix(parts[5], ":setAddons"):
+		name := strings.TrimSuffix(parts[5], ":setAddons")
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.clusters[name]["addonsConfig"] = body["addonsConfig"]
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": "operation-2"})
+	case len(parts) == 6 && r.Method == http.MethodGet:
+		cluster, ok := f.clusters[parts[5]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"message": "Not found", "status": "NOT_FOUND"}})
+			return
+		}
+		json.NewEncoder(w).Encode(cluster)
+	case len(parts) == 7 && parts[6] == "nodePools" && r.Method == http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{"nodePools": f.nodePools[parts[5]]})
+	case len(parts) == 7 && parts[6] == "nodePools" && r.Method == http.MethodPost:
+		var body struct {
+			NodePool map[string]interface{} `json:"nodePool"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		body.NodePool["status"] = "RUNNING"
+		f.nodePools[parts[5]] = append(f.nodePools[parts[5]], body.NodePool)
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": "operation-3"})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// takeRequests returns the requests received so far and resets the list.
+func (f *fakeGKE) takeRequests() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	requests := f.requests
+	f.requests = nil
+	return requests
+}
+
+func newTestServiceAccountJSON(t *testing.T, tokenURL string) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	serviceAccount, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "test-project",
+		"private_key_id": "key-id",
+		"private_key":    string(keyPEM),
+		"client_email":   "bootstrapper@test-project.iam.gserviceaccount.com",
+		"token_uri":      tokenURL,
+	})
+	require.NoError(t, err)
+
+	return string(serviceAccount)
+}
+
+func TestGCPProvider(t *testing.T) {
+	fake := newFakeGKE()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	credentials := &model.Credentials{
+		ServiceAccountJSON: newTestServiceAccountJSON(t, server.URL+"/token"),
+		Region:             "us-central1",
+	}
+
+	provider := providers.NewGCPProvider(credentials)
+	provider.Endpoint = server.URL + "/"
+	ctx := context.Background()
+
+	t.Run("ValidateCredentials", func(t *testing.T) {
+		valid, err := provider.ValidateCredentials(ctx, credentials)
+		require.NoError(t, err)
+		assert.True(t, valid)
+		assert.Equal(t, "test-project", provider.GetGCPCredentials().ProjectID)
+	})
+
+	t.Run("InvalidCredentials", func(t *testing.T) {
+		valid, err := provider.ValidateCredentials(ctx, &model.Credentials{ServiceAccountJSON: `{"type": "authorized_user"}`})
+		assert.False(t, valid)
+
+		var appErr *model.AppError
+		require.True(t, errors.As(err, &appErr))
+		assert.Equal(t, model.ErrorCodeInvalidCredentials, appErr.Code)
+	})
+
+	t.Run("SetInvalidCredentials", func(t *testing.T) {
+		err := provider.SetCredentials(ctx, &model.Credentials{ServiceAccountJSON: "not json", Region: "europe-west1"})
+		require.Error(t, err)
+		assert.Equal(t, "us-central1", provider.GetGCPCredentials().Region)
+
+		_, err = provider.ListClusters(ctx, "")
+		require.NoError(t, err)
+	})
+
+	t.Run("CreateCluster", func(t *testing.T) {
+		cluster, err := provider.CreateCluster(ctx, &model.CreateClusterRequest{
+			ClusterName:       aws.String("test-cluster"),
+			KubernetesVersion: aws.String("1.29"),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "test-cluster", *cluster.Name)
+		assert.Equal(t, model.ClusterStatusCreating, cluster.Status)
+
+		_, err = provider.CreateCluster(ctx, &model.CreateClusterRequest{ClusterName: aws.String("test-cluster")})
+		var appErr *model.AppError
+		require.True(t, errors.As(err, &appErr))
+		assert.Equal(t, model.ErrorCodeAlreadyExists, appErr.Code)
+	})
+
+	t.Run("ListClusters", func(t *testing.T) {
+		clusters, err := provider.ListClusters(ctx, "")
+		require.NoError(t, err)
+		require.Len(t, clusters, 1)
+		assert.Equal(t, "test-cluster", *clusters[0])
+	})
+
+	t.Run("GetClusterNotFound", func(t *testing.T) {
+		_, err := provider.GetCluster(ctx, "missing")
+		var appErr *model.AppError
+		require.True(t, errors.As(err, &appErr))
+		assert.Equal(t, model.ErrorCodeNotFound, appErr.Code)
+		assert.Equal(t, http.StatusNotFound, appErr.StatusCode)
+	})
+
+	t.Run("Nodegroups", func(t *testing.T) {
+		nodegroup, err := provider.CreateNodegroup(ctx, "test-cluster", &model.CreateNodegroupRequest{
+			NodegroupName: "workers",
+			InstanceType:  "e2-standard-4",
+			ScalingConfig: model.ScalingConfig{MinSize: 2, MaxSize: 4},
+			Labels:        map[string]string{"role": "mattermost"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "workers", *nodegroup.NodegroupName)
+
+		nodegroups, err := provider.GetNodegroups(ctx, "test-cluster")
+		require.NoError(t, err)
+		require.Len(t, nodegroups, 1)
+		assert.Equal(t, model.ClusterStatusActive, nodegroups[0].Status)
+		assert.Equal(t, "e2-standard-4", *nodegroups[0].InstanceTypes[0])
+		assert.Equal(t, "mattermost", *nodegroups[0].Labels["role"])
+		assert.Equal(t, "2", *nodegroups[0].ScalingConfig["minSize"])
+	})
+
+	t.Run("StorageDriverHook", func(t *testing.T) {
+		hooks := provider.AddonHooks("cnpg", model.HookPhasePreInstall)
+		require.Len(t, hooks, 1)
+		assert.Equal(t, "gce-pd-csi-driver", hooks[0].Name)
+
+		fake.lock.Lock()
+		delete(fake.clusters["test-cluster"], "addonsConfig")
+		fake.lock.Unlock()
+		fake.takeRequests()
+
+		err := hooks[0].Run(ctx, "test-cluster")
+		require.NoError(t, err)
+		assert.Contains(t, fake.takeRequests(), "POST /projects/test-project/locations/us-central1/clusters/test-cluster:setAddons")
+
+		// Once the add-on is enabled, nothing else is changed
+		err = hooks[0].Run(ctx, "test-cluster")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"GET /projects/test-project/locations/us-central1/clusters/test-cluster"}, fake.takeRequests())
+	})
+
+	t.Run("GetKubeRestConfigBeforeProvisioned", func(t *testing.T) {
+		_, err := provider.GetKubeRestConfig(ctx, "test-cluster")
+		var appErr *model.AppError
+		require.True(t, errors.As(err, &appErr))
+		assert.Equal(t, model.ErrorCodeConflict, appErr.Code)
+	})
+}
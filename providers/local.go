@@ -0,0 +1,504 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/internal/logger"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	helmclient "github.com/mittwald/go-helm-client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	LocalClusterToolKind = "kind"
+	LocalClusterToolK3d  = "k3d"
+
+	// storageClassDefaultAnnotation marks the StorageClass used by claims that don't name one.
+	storageClassDefaultAnnotation = "storageclass.kubernetes.io/is-default-class"
+)
+
+// CommandRunner runs a command and returns its standard output.
+type CommandRunner func(c context.Context, name string, args ...string) ([]byte, error)
+
+// LocalProvider manages kind or k3d clusters running on the local machine, so that the whole bootstrap can be
+// exercised without cloud credentials.
+type LocalProvider struct {
+	Credentials     *model.Credentials
+	credentialsLock *sync.Mutex
+	// Run executes the kind or k3d commands. It can be replaced for testing.
+	Run CommandRunner
+}
+
+func init() {
+	Register(ProviderInfo{
+		Name:        "local",
+		DisplayName: "Local (kind/k3d)",
+	}, GetLocalProvider)
+}
+
+var localProviderInstance *LocalProvider
+var localProviderInstanceOnce sync.Once
+
+func GetLocalProvider(credentials *model.Credentials) *LocalProvider {
+	localProviderInstanceOnce.Do(func() {
+		localProviderInstance = NewLocalProvider(credentials)
+	})
+
+	return localProviderInstance
+}
+
+func NewLocalProvider(credentials *model.Credentials) *LocalProvider {
+	if credentials == nil {
+		credentials = &model.Credentials{}
+	}
+
+	return &LocalProvider{
+		Credentials:     credentials,
+		credentialsLock: &sync.Mutex{},
+		Run:             runCommand,
+	}
+}
+
+func runCommand(c context.Context, name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(c, name, args...)
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return output, fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+
+	return output, nil
+}
+
+func (p *LocalProvider) GetLocalCredentials() *model.Credentials {
+	p.credentialsLock.Lock()
+	defer p.credentialsLock.Unlock()
+	return p.Credentials
+}
+
+// SetCredentials selects the cluster tool. An unsupported tool is rejected and leaves the current one in place, and
+// nil credentials detect whichever tool is installed.
+func (p *LocalProvider) SetCredentials(c context.Context, credentials *model.Credentials) error {
+	if credentials == nil {
+		credentials = &model.Credentials{}
+	}
+	err := validateLocalClusterTool(credentials.ClusterTool)
+	if err != nil {
+		return err
+	}
+
+	p.credentialsLock.Lock()
+	defer p.credentialsLock.Unlock()
+
+	p.Credentials = credentials
+
+	return nil
+}
+
+func validateLocalClusterTool(tool string) error {
+	switch tool {
+	case "", LocalClusterToolKind, LocalClusterToolK3d:
+		return nil
+	default:
+		return model.NewInvalidRequestError(fmt.Sprintf("Unsupported local cluster tool %s, expected kind or k3d", tool))
+	}
+}
+
+// tool returns the configured cluster tool or, when none is set, whichever of kind and k3d is installed.
+func (p *LocalProvider) tool() (string, error) {
+	credentials := p.GetLocalCredentials()
+	if credentials != nil && credentials.ClusterTool != "" {
+		return credentials.ClusterTool, nil
+	}
+
+	for _, tool := range []string{LocalClusterToolKind, LocalClusterToolK3d} {
+		if _, err := exec.LookPath(tool); err == nil {
+			return tool, nil
+		}
+	}
+
+	return "", model.NewAppError(model.ErrorCodeInvalidCredentials, http.StatusUnauthorized, "Neither kind nor k3d is installed")
+}
+
+// ValidateCredentials checks that the cluster tool is installed and working, since there are no credentials to
+// validate.
+func (p *LocalProvider) ValidateCredentials(c context.Context, creds *model.Credentials) (bool, error) {
+	if creds != nil {
+		if err := validateLocalClusterTool(creds.ClusterTool); err != nil {
+			return false, err
+		}
+	}
+
+	tool, err := p.tool()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = p.Run(c, tool, "version")
+	if err != nil {
+		return false, model.NewAppError(model.ErrorCodeInvalidCredentials, http.StatusUnauthorized, fmt.Sprintf("Unable to run %s", tool)).Wrap(err)
+	}
+
+	return true, nil
+}
+
+func (p *LocalProvider) ListClusters(c context.Context, region string) ([]*string, error) {
+	tool, err := p.tool()
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := []*string{}
+	switch tool {
+	case LocalClusterToolKind:
+		output, err := p.Run(c, tool, "get", "clusters")
+		if err != nil {
+			return nil, NewProviderError(err, "Failed to list kind clusters")
+		}
+
+		scanner := bufio.NewScanner(bytes.NewReader(output))
+		for scanner.Scan() {
+			name := strings.TrimSpace(scanner.Text())
+			if name != "" {
+				clusters = append(clusters, &name)
+			}
+		}
+	case LocalClusterToolK3d:
+		output, err := p.Run(c, tool, "cluster", "list", "-o", "json")
+		if err != nil {
+			return nil, NewProviderError(err, "Failed to list k3d clusters")
+		}
+
+		var list []struct {
+			Name string `json:"name"`
+		}
+		err = json.Unmarshal(output, &list)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse k3d cluster list: %w", err)
+		}
+		for _, cluster := range list {
+			name := cluster.Name
+			clusters = append(clusters, &name)
+		}
+	}
+
+	return clusters, nil
+}
+
+// CreateCluster creates a local cluster and waits for it to be ready. The Kubernetes version selects the node
+// image, so it must be a full version such as 1.29.2.
+func (p *LocalProvider) CreateCluster(c context.Context, create *model.CreateClusterRequest) (*model.Cluster, error) {
+	if create.ClusterName == nil || *create.ClusterName == "" {
+		return nil, model.NewInvalidParamError("clusterName")
+	}
+	name := *create.ClusterName
+
+	exists, err := p.clusterExists(c, name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, model.NewAppError(model.ErrorCodeAlreadyExists, http.StatusConflict, fmt.Sprintf("Local cluster %s already exists", name))
+	}
+
+	tool, err := p.tool()
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	switch tool {
+	case LocalClusterToolKind:
+		args = []string{"create", "cluster", "--name", name, "--wait", "5m"}
+		if create.KubernetesVersion != nil && *create.KubernetesVersion != "" {
+			args = append(args, "--image", "kindest/node:v"+strings.TrimPrefix(*create.KubernetesVersion, "v"))
+		}
+	case LocalClusterToolK3d:
+		args = []string{"cluster", "create", name, "--wait"}
+		if create.KubernetesVersion != nil && *create.KubernetesVersion != "" {
+			args = append(args, "--image", "rancher/k3s:v"+strings.TrimPrefix(*create.KubernetesVersion, "v")+"-k3s1")
+		}
+	}
+
+	logger.FromContext(c).Infof("Creating %s cluster %s", tool, name)
+	_, err = p.Run(c, tool, args...)
+	if err != nil {
+		logger.FromContext(c).WithError(err).Error("Failed to create local cluster")
+		return nil, NewProviderError(err, fmt.Sprintf("Failed to create %s cluster", tool))
+	}
+
+	return &model.Cluster{
+		Name:              &name,
+		Version:           create.KubernetesVersion,
+		Status:            model.ClusterStatusActive,
+		ClusterNodegroups: []*model.ClusterNodegroup{},
+	}, nil
+}
+
+// DeleteCluster deletes a local cluster and its containers.
+func (p *LocalProvider) DeleteCluster(c context.Context, name string) error {
+	exists, err := p.clusterExists(c, name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return model.NewNotFoundError(fmt.Sprintf("Local cluster %s not found", name))
+	}
+
+	tool, err := p.tool()
+	if err != nil {
+		return err
+	}
+
+	var args []string
+	switch tool {
+	case LocalClusterToolKind:
+		args = []string{"delete", "cluster", "--name", name}
+	case LocalClusterToolK3d:
+		args = []string{"cluster", "delete", name}
+	}
+
+	logger.FromContext(c).Infof("Deleting %s cluster %s", tool, name)
+	_, err = p.Run(c, tool, args...)
+	if err != nil {
+		return NewProviderError(err, fmt.Sprintf("Failed to delete %s cluster", tool))
+	}
+
+	return nil
+}
+
+func (p *LocalProvider) clusterExists(c context.Context, name string) (bool, error) {
+	clusters, err := p.ListClusters(c, "")
+	if err != nil {
+		return false, err
+	}
+
+	for _, cluster := range clusters {
+		if *cluster == name {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetCluster describes a local cluster, with each node reported as a nodegroup.
+func (p *LocalProvider) GetCluster(c context.Context, name string) (*model.Cluster, error) {
+	exists, err := p.clusterExists(c, name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, model.NewNotFoundError(fmt.Sprintf("Local cluster %s not found", name))
+	}
+
+	kubeClient, err := p.KubeClient(c, name)
+	if err != nil {
+		return nil, err
+	}
+
+	serverVersion, err := kubeClient.Clientset.Discovery().ServerVersion()
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to get the local cluster version")
+	}
+
+	nodegroups, err := p.GetNodegroups(c, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Cluster{
+		Name:              &name,
+		Version:           &serverVersion.GitVersion,
+		PlatformVersion:   &serverVersion.Platform,
+		Status:            model.ClusterStatusActive,
+		ClusterNodegroups: nodegroups,
+	}, nil
+}
+
+func (p *LocalProvider) GetNodegroups(c context.Context, clusterName string) ([]*model.ClusterNodegroup, error) {
+	kubeClient, err := p.KubeClient(c, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := kubeClient.Clientset.CoreV1().Nodes().List(c, metav1.ListOptions{})
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to list local cluster nodes")
+	}
+
+	nodegroups := []*model.ClusterNodegroup{}
+	for _, node := range nodes.Items {
+		nodegroup := kubeNodegroupToClusterNodegroup(node)
+		nodegroup.ClusterName = &clusterName
+		nodegroups = append(nodegroups, nodegroup)
+	}
+
+	return nodegroups, nil
+}
+
+// LimitCapabilities turns off nodegroups unless the cluster tool is k3d, since kind clusters can't be resized. The
+// capabilities of the registered type, without a tool, are left as they are.
+func (p *LocalProvider) LimitCapabilities(capabilities Capabilities) Capabilities {
+	if p == nil {
+		return capabilities
+	}
+
+	if tool, err := p.tool(); err != nil || tool != LocalClusterToolK3d {
+		capabilities.Nodegroups = false
+	}
+
+	return capabilities
+}
+
+// CreateNodegroup adds agent nodes to a k3d cluster. kind clusters can't be resized after creation.
+func (p *LocalProvider) CreateNodegroup(c context.Context, name string, create *model.CreateNodegroupRequest) (*model.ClusterNodegroup, error) {
+	tool, err := p.tool()
+	if err != nil {
+		return nil, err
+	}
+	if tool != LocalClusterToolK3d {
+		return nil, newUnsupportedOperationError()
+	}
+
+	if create.NodegroupName == "" {
+		return nil, model.NewInvalidParamError("nodeGroupName")
+	}
+
+	replicas := create.ScalingConfig.MinSize
+	if replicas < 1 {
+		replicas = 1
+	}
+
+	args := []string{"node", "create", create.NodegroupName, "--cluster", name, "--role", "agent", "--replicas", fmt.Sprint(replicas), "--wait"}
+	for key, value := range create.Labels {
+		args = append(args, "--k3s-node-label", key+"="+value)
+	}
+
+	_, err = p.Run(c, tool, args...)
+	if err != nil {
+		return nil, NewProviderError(err, "Failed to create k3d nodes")
+	}
+
+	return &model.ClusterNodegroup{
+		ClusterName:   &name,
+		NodegroupName: &create.NodegroupName,
+		Labels:        stringMapPtr(create.Labels),
+		Status:        model.ClusterStatusActive,
+		SubnetIds:     []*string{},
+	}, nil
+}
+
+func (p *LocalProvider) GetKubeConfig(c context.Context, clusterName string) (clientcmd.ClientConfig, error) {
+	tool, err := p.tool()
+	if err != nil {
+		return nil, err
+	}
+
+	var args []string
+	switch tool {
+	case LocalClusterToolKind:
+		args = []string{"get", "kubeconfig", "--name", clusterName}
+	case LocalClusterToolK3d:
+		args = []string{"kubeconfig", "get", clusterName}
+	}
+
+	output, err := p.Run(c, tool, args...)
+	if err != nil {
+		return nil, NewProviderError(err, fmt.Sprintf("Failed to get the kubeconfig of %s cluster %s", tool, clusterName))
+	}
+
+	return clientcmd.NewClientConfigFromBytes(output)
+}
+
+func (p *LocalProvider) GetKubeRestConfig(c context.Context, clusterName string) (*rest.Config, error) {
+	kubeConfig, err := p.GetKubeConfig(c, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	return kubeConfig.ClientConfig()
+}
+
+func (p *LocalProvider) KubeClient(c context.Context, clusterName string) (*model.KubeClient, error) {
+	config, err := p.GetKubeRestConfig(c, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	return newKubeClient(config)
+}
+
+func (p *LocalProvider) HelmClient(c context.Context, clusterName string, namespace string) (helmclient.Client, error) {
+	k8sClient, err := p.KubeClient(c, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	return k8sClient.GetHelmClient(c, namespace)
+}
+
+// checkDefaultStorageClass checks that the cluster has a default StorageClass. Both kind and k3d ship the local-path
+// provisioner, so there is no CSI driver to install.
+func (p *LocalProvider) checkDefaultStorageClass(c context.Context, clusterName string) error {
+	kubeClient, err := p.KubeClient(c, clusterName)
+	if err != nil {
+		return err
+	}
+
+	storageClasses, err := kubeClient.Clientset.StorageV1().StorageClasses().List(c, metav1.ListOptions{})
+	if err != nil {
+		return NewProviderError(err, "Failed to list storage classes")
+	}
+
+	for _, storageClass := range storageClasses.Items {
+		if storageClass.Annotations[storageClassDefaultAnnotation] == "true" {
+			logger.FromContext(c).Infof("Using default storage class %s provisioned by %s", storageClass.Name, storageClass.Provisioner)
+			return nil
+		}
+	}
+
+	return model.NewAppError(model.ErrorCodeConflict, http.StatusConflict, "Local cluster has no default storage class, install the local-path provisioner first")
+}
+
+// AddonHooks checks for a default storage class before CNPG or MinIO is installed.
+func (p *LocalProvider) AddonHooks(addon string, phase model.HookPhase) []Hook {
+	if (addon == "cnpg" || addon == "minio") && phase == model.HookPhasePreInstall {
+		return []Hook{{Name: "default-storage-class", Run: p.checkDefaultStorageClass}}
+	}
+
+	return nil
+}
+
+// NginxValues exposes ingress-nginx on a node port for kind, which has no load balancer implementation. k3d
+// clusters run ServiceLB, so the chart's LoadBalancer service works as is.
+func (p *LocalProvider) NginxValues(c context.Context, request *model.DeployNginxRequest) (map[string]interface{}, error) {
+	err := checkNginxRequest("local clusters", request)
+	if err != nil {
+		return nil, err
+	}
+
+	tool, err := p.tool()
+	if err != nil {
+		return nil, err
+	}
+
+	service := map[string]interface{}{}
+	if tool == LocalClusterToolKind {
+		service["type"] = "NodePort"
+	}
+
+	return nginxServiceValues(service), nil
+}
@@ -0,0 +1,125 @@
+package providers_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClusterTool records the commands it is asked to run and answers the ones used to list clusters and get
+// kubeconfigs.
+type fakeClusterTool struct {
+	clusters []string
+	commands []string
+}
+
+func (f *fakeClusterTool) run(c context.Context, name string, args ...string) ([]byte, error) {
+	command := strings.Join(append([]string{name}, args...), " ")
+	f.commands = append(f.commands, command)
+
+	switch command {
+	case "kind get clusters":
+		return []byte(strings.Join(f.clusters, "\n") + "\n"), nil
+	case "k3d cluster list -o json":
+		var list []string
+		for _, cluster := range f.clusters {
+			list = append(list, `{"name": "`+cluster+`"}`)
+		}
+		return []byte("[" + strings.Join(list, ",") + "]"), nil
+	case "kind get kubeconfig --name test-cluster", "k3d kubeconfig get test-cluster":
+		return []byte(testAKSKubeconfig), nil
+	case "kind version", "k3d version":
+		return []byte("v0.22.0"), nil
+	}
+
+	if len(args) > 2 && (args[0] == "create" || args[1] == "create") {
+		f.clusters = append(f.clusters, "test-cluster")
+	}
+	return nil, nil
+}
+
+func TestLocalProvider(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tool := range []string{providers.LocalClusterToolKind, providers.LocalClusterToolK3d} {
+		t.Run(tool, func(t *testing.T) {
+			fake := &fakeClusterTool{}
+			provider := providers.NewLocalProvider(&model.Credentials{ClusterTool: tool})
+			provider.Run = fake.run
+
+			valid, err := provider.ValidateCredentials(ctx, &model.Credentials{ClusterTool: tool})
+			require.NoError(t, err)
+			assert.True(t, valid)
+
+			cluster, err := provider.CreateCluster(ctx, &model.CreateClusterRequest{
+				ClusterName:       aws.String("test-cluster"),
+				KubernetesVersion: aws.String("1.29.2"),
+			})
+			require.NoError(t, err)
+			assert.Equal(t, "test-cluster", *cluster.Name)
+			assert.Equal(t, model.ClusterStatusActive, cluster.Status)
+
+			_, err = provider.CreateCluster(ctx, &model.CreateClusterRequest{ClusterName: aws.String("test-cluster")})
+			var appErr *model.AppError
+			require.True(t, errors.As(err, &appErr))
+			assert.Equal(t, model.ErrorCodeAlreadyExists, appErr.Code)
+
+			clusters, err := provider.ListClusters(ctx, "")
+			require.NoError(t, err)
+			require.Len(t, clusters, 1)
+			assert.Equal(t, "test-cluster", *clusters[0])
+
+			config, err := provider.GetKubeRestConfig(ctx, "test-cluster")
+			require.NoError(t, err)
+			assert.Equal(t, "https://test-cluster.hcp.eastus.azmk8s.io:443", config.Host)
+
+			require.NoError(t, provider.DeleteCluster(ctx, "test-cluster"))
+
+			err = provider.DeleteCluster(ctx, "missing")
+			require.True(t, errors.As(err, &appErr))
+			assert.Equal(t, model.ErrorCodeNotFound, appErr.Code)
+
+			switch tool {
+			case providers.LocalClusterToolKind:
+				assert.Contains(t, fake.commands, "kind create cluster --name test-cluster --wait 5m --image kindest/node:v1.29.2")
+				assert.Contains(t, fake.commands, "kind delete cluster --name test-cluster")
+			case providers.LocalClusterToolK3d:
+				assert.Contains(t, fake.commands, "k3d cluster create test-cluster --wait --image rancher/k3s:v1.29.2-k3s1")
+				assert.Contains(t, fake.commands, "k3d cluster delete test-cluster")
+			}
+		})
+	}
+
+	t.Run("KindNodegroupsUnsupported", func(t *testing.T) {
+		provider := providers.NewLocalProvider(&model.Credentials{ClusterTool: providers.LocalClusterToolKind})
+		provider.Run = (&fakeClusterTool{}).run
+
+		_, err := provider.CreateNodegroup(ctx, "test-cluster", &model.CreateNodegroupRequest{NodegroupName: "workers"})
+		var appErr *model.AppError
+		require.True(t, errors.As(err, &appErr))
+		assert.Equal(t, model.ErrorCodeNotImplemented, appErr.Code)
+		assert.False(t, providers.CapabilitiesOf(provider).Nodegroups)
+
+		k3d := providers.NewLocalProvider(&model.Credentials{ClusterTool: providers.LocalClusterToolK3d})
+		assert.True(t, providers.CapabilitiesOf(k3d).Nodegroups)
+	})
+
+	t.Run("UnknownTool", func(t *testing.T) {
+		provider := providers.NewLocalProvider(&model.Credentials{ClusterTool: providers.LocalClusterToolK3d})
+		err := provider.SetCredentials(ctx, &model.Credentials{ClusterTool: "minikube"})
+		var appErr *model.AppError
+		require.True(t, errors.As(err, &appErr))
+		assert.Equal(t, model.ErrorCodeInvalidRequest, appErr.Code)
+		assert.Equal(t, providers.LocalClusterToolK3d, provider.GetLocalCredentials().ClusterTool)
+
+		require.NoError(t, provider.SetCredentials(ctx, nil))
+		assert.NotNil(t, provider.GetLocalCredentials())
+	})
+}
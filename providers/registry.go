@@ -0,0 +1,120 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+)
+
+// Factory returns the provider instance to use with the given credentials.
+type Factory func(credentials *model.Credentials) CloudProvider
+
+// Capabilities describes which optional operations a provider supports, so that clients can hide the ones that
+// would fail. See CapabilitiesOf.
+type Capabilities struct {
+	CreateCluster   bool `json:"createCluster"`
+	DeleteCluster   bool `json:"deleteCluster"`
+	UpgradeCluster  bool `json:"upgradeCluster"`
+	Nodegroups      bool `json:"nodegroups"`
+	UpdateNodegroup bool `json:"updateNodegroup"`
+	DeleteNodegroup bool `json:"deleteNodegroup"`
+	Regions         bool `json:"regions"`
+	Roles           bool `json:"roles"`
+	Databases       bool `json:"databases"`
+	Buckets         bool `json:"buckets"`
+}
+
+// ProviderInfo describes a registered provider.
+type ProviderInfo struct {
+	Name         string       `json:"name"`
+	DisplayName  string       `json:"displayName"`
+	Capabilities Capabilities `json:"capabilities"`
+}
+
+type registration struct {
+	info    ProviderInfo
+	factory Factory
+}
+
+var registry = map[string]registration{}
+var registryLock sync.RWMutex
+
+// Register makes a provider available under info.Name, with its capabilities derived from the type returned by
+// factory. Providers register themselves from an init function, so adding one doesn't require changes to the API.
+// Registering the same name twice panics.
+func Register[P CloudProvider](info ProviderInfo, factory func(credentials *model.Credentials) P) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if _, ok := registry[info.Name]; ok {
+		panic(fmt.Sprintf("cloud provider %s is already registered", info.Name))
+	}
+
+	var provider P
+	info.Capabilities = CapabilitiesOf(provider)
+
+	registry[info.Name] = registration{
+		info: info,
+		factory: func(credentials *model.Credentials) CloudProvider {
+			return factory(credentials)
+		},
+	}
+}
+
+// GetProviderInfo returns the description of the provider registered under the given name.
+func GetProviderInfo(name string) (ProviderInfo, error) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	provider, ok := registry[name]
+	if !ok {
+		return ProviderInfo{}, newUnknownProviderError(name)
+	}
+
+	return provider.info, nil
+}
+
+// GetCloudProvider returns the provider registered under the given name, or a not found AppError if there is none.
+func GetCloudProvider(name string, credentials *model.Credentials) (CloudProvider, error) {
+	registryLock.RLock()
+	provider, ok := registry[name]
+	registryLock.RUnlock()
+
+	if !ok {
+		return nil, newUnknownProviderError(name)
+	}
+
+	return provider.factory(credentials), nil
+}
+
+// IsRegistered reports whether a provider is registered under the given name.
+func IsRegistered(name string) bool {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	_, ok := registry[name]
+	return ok
+}
+
+// ListProviders returns the registered providers, sorted by name.
+func ListProviders() []ProviderInfo {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	providers := make([]ProviderInfo, 0, len(registry))
+	for _, provider := range registry {
+		providers = append(providers, provider.info)
+	}
+
+	sort.Slice(providers, func(i, j int) bool {
+		return providers[i].Name < providers[j].Name
+	})
+
+	return providers
+}
+
+func newUnknownProviderError(name string) *model.AppError {
+	return model.NewNotFoundError(fmt.Sprintf("Unknown cloud provider: %s", name))
+}
@@ -0,0 +1,54 @@
+package providers_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/model"
+	"github.com/mattermost/mattermost-cloudnative-bootstrapper/providers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("Registered", func(t *testing.T) {
+		provider, err := providers.GetCloudProvider("local", &model.Credentials{})
+		require.NoError(t, err)
+		assert.IsType(t, &providers.LocalProvider{}, provider)
+		assert.True(t, providers.IsRegistered("local"))
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		provider, err := providers.GetCloudProvider("nope", &model.Credentials{})
+		assert.Nil(t, provider)
+
+		var appErr *model.AppError
+		require.True(t, errors.As(err, &appErr))
+		assert.Equal(t, model.ErrorCodeNotFound, appErr.Code)
+		assert.False(t, providers.IsRegistered("nope"))
+	})
+
+	t.Run("DuplicateRegistrationPanics", func(t *testing.T) {
+		assert.Panics(t, func() {
+			providers.Register(providers.ProviderInfo{Name: "aws"}, providers.GetAWSProvider)
+		})
+	})
+
+	t.Run("Capabilities", func(t *testing.T) {
+		custom, err := providers.GetProviderInfo("custom")
+		require.NoError(t, err)
+		assert.Equal(t, providers.Capabilities{}, custom.Capabilities)
+
+		aws, err := providers.GetProviderInfo("aws")
+		require.NoError(t, err)
+		assert.Equal(t, providers.Capabilities{CreateCluster: true, DeleteCluster: true, UpgradeCluster: true, Nodegroups: true, UpdateNodegroup: true, DeleteNodegroup: true, Regions: true, Roles: true, Databases: true, Buckets: true}, aws.Capabilities)
+
+		gcp, err := providers.GetProviderInfo("gcp")
+		require.NoError(t, err)
+		assert.Equal(t, providers.Capabilities{CreateCluster: true, Nodegroups: true, Regions: true}, gcp.Capabilities)
+
+		local, err := providers.GetProviderInfo("local")
+		require.NoError(t, err)
+		assert.Equal(t, providers.Capabilities{CreateCluster: true, DeleteCluster: true, Nodegroups: true}, local.Capabilities)
+	})
+}
@@ -1,7 +1,7 @@
 import { BaseQueryFn, createApi, FetchArgs, fetchBaseQuery, FetchBaseQueryError, FetchBaseQueryMeta } from '@reduxjs/toolkit/query/react';
-import { CloudCredentials, Namespace, Release, State } from "../types/bootstrapper";
+import { CloudCredentials, DeployNginxRequest, Namespace, Release, State } from "../types/bootstrapper";
 import { RootState } from '../store';
-import { baseUrl, wsBaseUrl } from './client';
+import { baseUrl, runJob, wsBaseUrl } from './client';
 import { Cluster, Nodegroup } from '../types/Cluster';
 import { InstallationLogLine, Pod } from '../types/Installation';
 
@@ -99,22 +99,34 @@ export const bootstrapperApi = createApi({
             providesTags: ['HelmReleases']
         }),
         deployMattermostOperator: builder.mutation<undefined, { cloudProvider: string, clusterName: string }>({
-            query: ({ clusterName, cloudProvider }) => ({
-                url: `/${cloudProvider}/cluster/${clusterName}/deploy_mattermost_operator`,
-                method: 'POST',
-            }),
+            queryFn: async ({ clusterName, cloudProvider }) => {
+                try {
+                    await runJob(`${baseUrl}/api/v1/${cloudProvider}/cluster/${clusterName}/deploy_mattermost_operator`, { method: 'POST' });
+                    return { data: undefined };
+                } catch (e) {
+                    return { error: { status: 'CUSTOM_ERROR', error: (e as Error).message } };
+                }
+            },
         }),
-        deployNginxOperator: builder.mutation<undefined, { cloudProvider: string, clusterName: string }>({
-            query: ({ clusterName, cloudProvider }) => ({
-                url: `/${cloudProvider}/cluster/${clusterName}/deploy_nginx_operator`,
-                method: 'POST',
-            }),
+        deployNginxOperator: builder.mutation<undefined, { cloudProvider: string, clusterName: string, config?: DeployNginxRequest }>({
+            queryFn: async ({ clusterName, cloudProvider, config }) => {
+                try {
+                    await runJob(`${baseUrl}/api/v1/${cloudProvider}/cluster/${clusterName}/deploy_nginx_operator`, { method: 'POST', body: JSON.stringify(config ?? {}) });
+                    return { data: undefined };
+                } catch (e) {
+                    return { error: { status: 'CUSTOM_ERROR', error: (e as Error).message } };
+                }
+            },
         }),
         deployCloudNativePG: builder.mutation<undefined, { cloudProvider: string, clusterName: string }>({
-            query: ({ clusterName, cloudProvider }) => ({
-                url: `/${cloudProvider}/cluster/${clusterName}/deploy_pg_operator`,
-                method: 'POST',
-            }),
+            queryFn: async ({ clusterName, cloudProvider }) => {
+                try {
+                    await runJob(`${baseUrl}/api/v1/${cloudProvider}/cluster/${clusterName}/deploy_pg_operator`, { method: 'POST' });
+                    return { data: undefined };
+                } catch (e) {
+                    return { error: { status: 'CUSTOM_ERROR', error: (e as Error).message } };
+                }
+            },
         }),
         getPodsForInstallation: builder.query<Pod[], { cloudProvider: string, clusterName: string, installationName: string }>({
             query: ({ cloudProvider, clusterName, installationName }) => ({ 
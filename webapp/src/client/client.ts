@@ -1,8 +1,165 @@
-import { CreateClusterRequest, CreateNodegroup } from "../types/Cluster";
+import { Addon, AddonVersion } from "../types/Addon";
+import { ReleaseRevision, ReleaseValuesDiff } from "../types/bootstrapper";
+import { ClusterPreflightReport, CreateClusterRequest, CreateNodegroup } from "../types/Cluster";
+import { CreateManagedDatabaseRequest, CreateMattermostWorkspaceRequest, DatabaseBackup, DatabaseRestore, InstallationValidation, ManagedDatabase } from "../types/Installation";
+import { Job } from "../types/Job";
+import { Provider } from "../types/Provider";
 
 export const baseUrl = process.env.NODE_ENV === 'development' ? 'http://localhost:3000' : 'http://localhost:8070';
 export const wsBaseUrl = process.env.NODE_ENV === 'development' ? 'ws://localhost:8070' : 'ws://localhost:8070';
 
+const jobPollInterval = 2000;
+
+export async function getJob<T = unknown>(id: string): Promise<Job<T>> {
+    const response = await fetch(`${baseUrl}/api/v1/jobs/${id}`);
+    const data = await response.json();
+    return data;
+}
+
+// Long-running operations respond with a queued job, which is polled until it finishes.
+// Resolves with the finished job, or rejects with the job's error if it failed.
+export async function waitForJob<T = unknown>(job: Job<T>): Promise<Job<T>> {
+    while (job.status === 'queued' || job.status === 'running') {
+        await new Promise((resolve) => setTimeout(resolve, jobPollInterval));
+        job = await getJob<T>(job.id);
+    }
+
+    if (job.status === 'failed') {
+        throw new Error(job.error || `Job ${job.id} failed`);
+    }
+
+    return job;
+}
+
+// Starts a long-running operation and waits for the job it queues to finish.
+export async function runJob<T = unknown>(url: string, init: RequestInit = {}): Promise<Job<T>> {
+    const response = await fetch(url, init);
+    if (!response.ok) {
+        // Failed requests respond with an error envelope: { error: { code, message, details, retryable } }
+        const body = await response.json().catch(() => undefined);
+        throw new Error(body?.error?.message || `Request failed with status ${response.status}`);
+    }
+
+    const job = await response.json();
+    return waitForJob<T>(job);
+}
+
+export async function fetchProviders(): Promise<Provider[]> {
+    const response = await fetch(`${baseUrl}/api/v1/providers`);
+    const data = await response.json();
+    return data;
+}
+
+export async function getProvider(name: string): Promise<Provider> {
+    const response = await fetch(`${baseUrl}/api/v1/providers/${name}`);
+    const data = await response.json();
+    return data;
+}
+
+export async function fetchAddons(): Promise<Addon[]> {
+    const response = await fetch(`${baseUrl}/api/v1/addons`);
+    const data = await response.json();
+    return data;
+}
+
+export async function fetchAddonVersions(addon: string): Promise<AddonVersion[]> {
+    const response = await fetch(`${baseUrl}/api/v1/addons/${addon}/versions`);
+    const data = await response.json();
+    return data;
+}
+
+// version defaults to the pinned one, and values are helm values merged over the add-on's defaults.
+export async function installAddon(cloudProvider: string, clusterName: string, addon: string, version?: string, values?: { [key: string]: unknown }) {
+    return runJob(`${baseUrl}/api/v1/${cloudProvider}/cluster/${clusterName}/addons/${addon}`, { method: 'POST', body: JSON.stringify({ version, values }) });
+}
+
+export async function uninstallAddon(cloudProvider: string, clusterName: string, addon: string) {
+    const response = await fetch(`${baseUrl}/api/v1/${cloudProvider}/cluster/${clusterName}/addons/${addon}`, { method: 'DELETE' });
+    if (!response.ok) {
+        const body = await response.json().catch(() => undefined);
+        throw new Error(body?.error?.message || `Request failed with status ${response.status}`);
+    }
+}
+
+export async function fetchReleaseHistory(cloudProvider: string, clusterName: string, namespace: string, release: string): Promise<ReleaseRevision[]> {
+    const response = await fetch(`${baseUrl}/api/v1/${cloudProvider}/cluster/${clusterName}/releases/${namespace}/${release}/history`);
+    const data = await response.json();
+    return data;
+}
+
+// from and to default to the revision before the latest and the latest.
+export async function fetchReleaseValuesDiff(cloudProvider: string, clusterName: string, namespace: string, release: string, from?: number, to?: number): Promise<ReleaseValuesDiff> {
+    const params = new URLSearchParams();
+    if (from) {
+        params.set('from', String(from));
+    }
+    if (to) {
+        params.set('to', String(to));
+    }
+    const response = await fetch(`${baseUrl}/api/v1/${cloudProvider}/cluster/${clusterName}/releases/${namespace}/${release}/diff?${params}`);
+    const data = await response.json();
+    return data;
+}
+
+// values are merged over the current revision's values unless resetValues is set.
+export async function upgradeRelease(cloudProvider: string, clusterName: string, namespace: string, release: string, values?: { [key: string]: unknown }, version?: string, resetValues?: boolean) {
+    return runJob<ReleaseRevision>(`${baseUrl}/api/v1/${cloudProvider}/cluster/${clusterName}/releases/${namespace}/${release}/upgrade`, { method: 'POST', body: JSON.stringify({ version, values, resetValues }) });
+}
+
+// revision defaults to the previous one.
+export async function rollbackRelease(cloudProvider: string, clusterName: string, namespace: string, release: string, revision?: number) {
+    return runJob<ReleaseRevision>(`${baseUrl}/api/v1/${cloudProvider}/cluster/${clusterName}/releases/${namespace}/${release}/rollback`, { method: 'POST', body: JSON.stringify({ revision }) });
+}
+
+export async function fetchDatabaseBackups(cloudProvider: string, clusterName: string, installationName: string): Promise<DatabaseBackup[]> {
+    const response = await fetch(`${baseUrl}/api/v1/${cloudProvider}/cluster/${clusterName}/installation/${installationName}/database/backups`);
+    const data = await response.json();
+    return data;
+}
+
+export async function createDatabaseBackup(cloudProvider: string, clusterName: string, installationName: string): Promise<DatabaseBackup> {
+    const response = await fetch(`${baseUrl}/api/v1/${cloudProvider}/cluster/${clusterName}/installation/${installationName}/database/backups`, { method: 'POST' });
+    if (!response.ok) {
+        const body = await response.json().catch(() => undefined);
+        throw new Error(body?.error?.message || `Request failed with status ${response.status}`);
+    }
+    const data = await response.json();
+    return data;
+}
+
+// targetTime is an RFC 3339 time, and defaults to the latest archived WAL.
+export async function restoreDatabase(cloudProvider: string, clusterName: string, installationName: string, targetTime?: string, cnpgClusterName?: string) {
+    return runJob<DatabaseRestore>(`${baseUrl}/api/v1/${cloudProvider}/cluster/${clusterName}/installation/${installationName}/database/restore`, { method: 'POST', body: JSON.stringify({ targetTime, clusterName: cnpgClusterName }) });
+}
+
+export async function createManagedDatabase(cloudProvider: string, clusterName: string, request: CreateManagedDatabaseRequest) {
+    return runJob<ManagedDatabase>(`${baseUrl}/api/v1/${cloudProvider}/cluster/${clusterName}/rds`, { method: 'POST', body: JSON.stringify(request) });
+}
+
+// Inspects a cluster before the add-ons are deployed. The required add-ons and the 100users size are checked by default.
+export async function fetchClusterPreflight(cloudProvider: string, clusterName: string, size?: string, addons?: string[]): Promise<ClusterPreflightReport> {
+    const params = new URLSearchParams();
+    if (size) {
+        params.set('size', size);
+    }
+    if (addons?.length) {
+        params.set('addons', addons.join(','));
+    }
+    const response = await fetch(`${baseUrl}/api/v1/${cloudProvider}/cluster/${clusterName}/preflight?${params}`);
+    if (!response.ok) {
+        const body = await response.json().catch(() => undefined);
+        throw new Error(body?.error?.message || `Request failed with status ${response.status}`);
+    }
+    const data = await response.json();
+    return data;
+}
+
+// Tests the database and S3 credentials of an installation with probe jobs in the cluster, before it is created.
+export async function validateInstallation(cloudProvider: string, clusterName: string, request: CreateMattermostWorkspaceRequest): Promise<InstallationValidation> {
+    const job = await runJob<InstallationValidation>(`${baseUrl}/api/v1/${cloudProvider}/cluster/${clusterName}/installation/validate`, { method: 'POST', body: JSON.stringify(request) });
+    return job.result as InstallationValidation;
+}
+
 export async function getInstallationByID(id: string) {
     const response = await fetch(`${baseUrl}/api/v1/installation/${id}`);
     const data = await response.json();
@@ -24,15 +181,14 @@ export async function fetchAWSPotentialARNs() {
 }
 
 export async function createEKSCluster(createEKSClusterRequest: CreateClusterRequest) {
-    const response = await fetch(`${baseUrl}/api/v1/aws/cluster`, {
+    const job = await runJob(`${baseUrl}/api/v1/aws/cluster`, {
         method: 'POST',
         headers: {
             'Content-Type': 'application/json'
         },
         body: JSON.stringify(createEKSClusterRequest)
     });
-    const data = await response.json();
-    return data;
+    return job.result;
 }
 
 export async function getEKSCluster(clusterName: string) {
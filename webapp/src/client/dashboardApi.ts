@@ -1,5 +1,5 @@
 import { createApi, fetchBaseQuery } from '@reduxjs/toolkit/query/react';
-import { baseUrl } from './client';
+import { baseUrl, runJob } from './client';
 import { CreateMattermostWorkspaceRequest, Mattermost, MattermostInstallationSecrets, PatchMattermostWorkspaceRequest } from '../types/Installation';
 import { Cluster } from '../types/Cluster';
 
@@ -42,11 +42,18 @@ export const dashboardApi = createApi({
             query: ({ clusterName, cloudProvider, installationName }) => `/${cloudProvider}/cluster/${clusterName}/installation/${installationName}/secrets`,
         }),
         createMattermostWorkspace: builder.mutation<Mattermost, {cloudProvider: string, clusterName: string, workspaceInfo: CreateMattermostWorkspaceRequest}>({
-            query: ({ cloudProvider, clusterName, workspaceInfo }) => ({
-                url: `/${cloudProvider}/cluster/${clusterName}/installation`,
-                method: 'POST',
-                body: workspaceInfo,
-            }),
+            queryFn: async ({ cloudProvider, clusterName, workspaceInfo }) => {
+                try {
+                    const job = await runJob<Mattermost>(`${baseUrl}/api/v1/${cloudProvider}/cluster/${clusterName}/installation`, {
+                        method: 'POST',
+                        headers: { 'Content-Type': 'application/json' },
+                        body: JSON.stringify(workspaceInfo),
+                    });
+                    return { data: job.result as Mattermost };
+                } catch (e) {
+                    return { error: { status: 'CUSTOM_ERROR', error: (e as Error).message } };
+                }
+            },
         }),
     }),
 });
@@ -82,6 +82,12 @@ export default function FilestoreConnection({onChange, cloudProvider, existingFi
                         )}
                     </>
                 )
+            case FilestoreType.InClusterMinio:
+                return (
+                    <>
+                        {!existingFilestore && <div className="filestore-type-descriptor">A standalone MinIO server with 50Gi of storage will be deployed alongside the installation.</div>}
+                    </>
+                )
             case FilestoreType.InClusterExternal:
                 return (
                     <>
@@ -113,6 +119,7 @@ export default function FilestoreConnection({onChange, cloudProvider, existingFi
                 <Option value={FilestoreType.InClusterLocal}>In-Cluster (Local)</Option>
                 <Option value={FilestoreType.ExistingS3}>Use Existing (S3 Compatible)</Option>
                 <Option value={FilestoreType.InClusterExternal}>In-Cluster (External PVC)</Option>
+                <Option value={FilestoreType.InClusterMinio}>In-Cluster (MinIO)</Option>
                 {cloudProvider === 'aws' && <Option value={FilestoreType.AWSS3}>Create For Me (S3)</Option>}
             </Select>
             {getFilestoreConnectionInputs()}
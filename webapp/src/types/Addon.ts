@@ -0,0 +1,23 @@
+// A helm chart from the add-on catalog, from GET /api/v1/addons.
+export interface Addon {
+    name: string;
+    description: string;
+    required: boolean;
+    repoName: string;
+    repoUrl: string;
+    chart: string;
+    releaseName: string;
+    namespace: string;
+    // The pinned chart version installed by default.
+    version: string;
+    values?: { [key: string]: unknown };
+    dependencies?: string[];
+}
+
+// A chart version from GET /api/v1/addons/{name}/versions, newest first.
+export interface AddonVersion {
+    version: string;
+    appVersion: string;
+    created: string;
+    pinned: boolean;
+}
@@ -1,3 +1,5 @@
+import { PreflightCheck } from './Installation';
+
 export type Cluster = {
 	ClientRequestToken?: string;
 	CreatedAt?: Date;
@@ -36,6 +38,21 @@ export type Nodegroup = {
     UpdatedAt?: Date;
     subnetIds: string[];
     NodeRole: string;
+    Taints?: NodegroupTaint[];
+}
+
+export type NodegroupTaint = {
+    key: string;
+    value?: string;
+    effect: "NO_SCHEDULE" | "NO_EXECUTE" | "PREFER_NO_SCHEDULE";
+}
+
+// Fields that are left out are unchanged. labels and taints replace the nodegroup's current ones.
+export type UpdateNodegroup = {
+    scalingConfig?: { minSize: number, maxSize: number, desiredSize?: number };
+    labels?: { [key: string]: string };
+    taints?: NodegroupTaint[];
+    releaseVersion?: string;
 }
 
 export type CreateNodegroup = {
@@ -57,3 +74,8 @@ export type ClusterStatus = "CREATING" | "ACTIVE" | "DELETING" | "FAILED" | "UPD
 export type AWSMetadata = {
     Zones: string[];
 };
+
+export type ClusterPreflightReport = {
+	status: 'passed' | 'warning' | 'failed';
+	checks: PreflightCheck[];
+}
@@ -9,6 +9,93 @@ export interface CreateMattermostWorkspaceRequest {
     domainName: string;
     enterpriseLicense?: string;
     installationName: string;
+    cnpgDatabaseConfig?: CNPGDatabaseConfig;
+    rdsDatabaseConfig?: CreateManagedDatabaseRequest;
+    awsS3FilestoreConfig?: CreateBucketRequest;
+    minioFilestoreConfig?: MinioFilestore;
+}
+
+export interface PreflightCheck {
+    name: string;
+    status: 'passed' | 'failed' | 'skipped' | 'warning';
+    message?: string;
+}
+
+export interface InstallationValidation {
+    valid: boolean;
+    checks: PreflightCheck[];
+}
+
+export interface CreateManagedDatabaseRequest {
+    installationName?: string;
+    identifier?: string;
+    engine?: 'aurora-postgresql' | 'postgres';
+    engineVersion?: string;
+    instanceClass?: string;
+    replicas?: number;
+    multiAZ?: boolean;
+    allocatedStorage?: number;
+    databaseName?: string;
+    username?: string;
+}
+
+export interface ManagedDatabase {
+    identifier: string;
+    engine: string;
+    engineVersion: string;
+    endpoint: string;
+    readerEndpoint?: string;
+    port: number;
+    databaseName: string;
+    username: string;
+    securityGroupId?: string;
+}
+
+export interface CNPGDatabaseConfig {
+    instances?: number;
+    storageSize?: string;
+    storageClass?: string;
+    resources?: {
+        requests?: Record<string, string>;
+        limits?: Record<string, string>;
+    };
+    postgresVersion?: string;
+    parameters?: Record<string, string>;
+    pooler?: CNPGPoolerConfig;
+    backup?: CNPGBackupConfig;
+}
+
+export interface CNPGBackupConfig {
+    destinationPath: string;
+    endpointURL?: string;
+    region?: string;
+    accessKeyId?: string;
+    secretAccessKey?: string;
+    schedule?: string;
+    retentionPolicy?: string;
+}
+
+export interface DatabaseBackup {
+    name: string;
+    clusterName: string;
+    phase: string;
+    method: string;
+    startedAt?: string;
+    stoppedAt?: string;
+    error?: string;
+}
+
+export interface DatabaseRestore {
+    clusterName: string;
+    namespace: string;
+    targetTime?: string;
+}
+
+export interface CNPGPoolerConfig {
+    enabled: boolean;
+    instances?: number;
+    poolMode?: 'session' | 'transaction';
+    parameters?: Record<string, string>;
 }
 
 export interface ExistingDBConnection {
@@ -30,6 +117,20 @@ export interface S3FileStore {
     accessKeySecret: string;
     bucket: string;
     secret?: string;
+    useServiceAccount?: boolean;
+}
+
+export interface CreateBucketRequest {
+    bucketName?: string;
+    access?: 'iam-user' | 'irsa';
+    noncurrentVersionExpirationDays?: number;
+}
+
+export interface MinioFilestore {
+    replicas?: number;
+    storageSize?: string;
+    storageClass?: string;
+    bucketName?: string;
 }
 
 export interface PatchMattermostWorkspaceRequest {
@@ -43,6 +144,7 @@ export interface PatchMattermostWorkspaceRequest {
     fileStorePatch?: PatchMattermostWorkspaceFilestore;
     databasePatch?: ExistingDBConnection;
     mattermostEnv?: MattermostEnvItem[];
+    cnpgDatabasePatch?: CNPGDatabaseConfig;
 }
 
 export interface PatchMattermostWorkspaceFilestore {
@@ -143,7 +245,7 @@ export enum FilestoreType {
     InClusterLocal = 'InClusterLocal',
     ExistingS3 = 'ExistingS3',
     AWSS3 = 'AWSS3',
-    Minio = 'Minio',
+    InClusterMinio = 'InClusterMinio',
 }
 
 export interface FileStore {
@@ -0,0 +1,21 @@
+export type JobStatus = 'queued' | 'running' | 'succeeded' | 'failed';
+
+export interface JobStep {
+    time: string;
+    level: string;
+    message: string;
+}
+
+export interface Job<T = unknown> {
+    id: string;
+    type: string;
+    provider: string;
+    clusterName?: string;
+    target?: string;
+    status: JobStatus;
+    steps: JobStep[];
+    error?: string;
+    result?: T;
+    createdAt: string;
+    updatedAt: string;
+}
@@ -0,0 +1,17 @@
+// Optional operations a provider supports, from GET /api/v1/providers. Unsupported operations respond with a 501.
+export interface ProviderCapabilities {
+    createCluster: boolean;
+    deleteCluster: boolean;
+    upgradeCluster: boolean;
+    nodegroups: boolean;
+    updateNodegroup: boolean;
+    deleteNodegroup: boolean;
+    regions: boolean;
+    roles: boolean;
+}
+
+export interface Provider {
+    name: string;
+    displayName: string;
+    capabilities: ProviderCapabilities;
+}
@@ -5,6 +5,15 @@ export type State = {
     credentials: CloudCredentials;
     provider: string;
     clusterName: string;
+    mirror?: MirrorConfig;
+}
+
+// Internal chart and image mirrors for clusters without internet access. Only one of chartRegistry and
+// chartDirectory can be set.
+export type MirrorConfig = {
+    chartRegistry?: string;
+    chartDirectory?: string;
+    imageRegistry?: string;
 }
 
 export type CloudCredentials = {
@@ -16,6 +25,16 @@ export type CloudCredentials = {
     kubeconfigType: string;
 }
 
+// Empty fields use the provider's defaults. values are helm values merged over everything else.
+export type DeployNginxRequest = {
+    tlsMode?: 'none' | 'acm' | 'cert-manager';
+    certificateArn?: string;
+    loadBalancerType?: 'nlb' | 'clb' | 'internal';
+    serviceAnnotations?: { [key: string]: string };
+    values?: { [key: string]: unknown };
+    version?: string;
+}
+
 export type Release = {
     Name: string;
     Version: number;
@@ -186,4 +205,27 @@ export interface Namespace {
     metadata: Metadata;
     spec: Spec;
     status: Status;
-}
\ No newline at end of file
+}
+// A revision from GET .../releases/{namespace}/{release}/history, newest first.
+export type ReleaseRevision = {
+    revision: number;
+    chart: string;
+    chartVersion: string;
+    appVersion: string;
+    status: string;
+    description: string;
+    updated: string;
+};
+
+// A helm value that changed between two revisions. from or to is missing when the value was added or removed.
+export type ValueChange = {
+    path: string;
+    from?: unknown;
+    to?: unknown;
+};
+
+export type ReleaseValuesDiff = {
+    from: number;
+    to: number;
+    changes: ValueChange[];
+};